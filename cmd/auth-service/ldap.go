@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/ldap"
+)
+
+// loadAuthenticators builds the login routes from the LDAP config file.
+// Without a file every login checks the local password. A directory may
+// take over existing local accounts only in the email domains routed to it.
+func loadAuthenticators(path string, log *slog.Logger, tx app.TxManager, identities domain.IdentityRepository, users domain.UserRepository, pwd app.PasswordService, register *usecase.RegisterUserUseCase) (usecase.AuthenticatorRoutes, error) {
+	if path == "" {
+		return usecase.AuthenticatorRoutes{}, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return usecase.AuthenticatorRoutes{}, fmt.Errorf("read ldap config: %w", err)
+	}
+	var file struct {
+		Directories map[string]ldap.Config `json:"directories"`
+		Tenants     map[string]string      `json:"tenants"`
+		Domains     map[string]string      `json:"domains"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return usecase.AuthenticatorRoutes{}, fmt.Errorf("parse ldap config: %w", err)
+	}
+
+	password := usecase.NewPasswordAuthenticator(users, pwd)
+	named := map[string]app.Authenticator{"password": password}
+	owned := make(map[string][]string)
+	for d, name := range file.Domains {
+		owned[name] = append(owned[name], d)
+	}
+	for name, cfg := range file.Directories {
+		if name == "password" {
+			return usecase.AuthenticatorRoutes{}, fmt.Errorf("ldap config: directory name %q is reserved", name)
+		}
+		if cfg.URL == "" || cfg.BaseDN == "" {
+			return usecase.AuthenticatorRoutes{}, fmt.Errorf("ldap config: directory %q: url and base_dn are required", name)
+		}
+		named[name] = usecase.NewDirectoryAuthenticator(log, "ldap", ldap.NewDirectory(cfg), cfg.URL, owned[name], tx, identities, users, register)
+	}
+	resolve := func(kind string, routes map[string]string, key func(string) string) (map[string]app.Authenticator, error) {
+		out := make(map[string]app.Authenticator, len(routes))
+		for k, name := range routes {
+			a, ok := named[name]
+			if !ok {
+				return nil, fmt.Errorf("ldap config: %s %q routes to unknown directory %q", kind, k, name)
+			}
+			out[key(k)] = a
+		}
+		return out, nil
+	}
+	tenants, err := resolve("tenant", file.Tenants, func(k string) string { return k })
+	if err != nil {
+		return usecase.AuthenticatorRoutes{}, err
+	}
+	domains, err := resolve("domain", file.Domains, strings.ToLower)
+	if err != nil {
+		return usecase.AuthenticatorRoutes{}, err
+	}
+	return usecase.AuthenticatorRoutes{Default: password, Tenants: tenants, Domains: domains}, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/config"
+	"go-auth/internal/infrastructure/mail"
+	"go-auth/internal/infrastructure/memory"
+)
+
+// mailer bundles the outbound mail transport with the templates rendered
+// onto it. send is asynchronous: request handlers never wait on SMTP.
+type mailer struct {
+	send      app.Mailer
+	templates *app.MailTemplates
+	close     func()
+}
+
+func openMailer(cfg config.MailConfig, productName string, log *slog.Logger) (*mailer, error) {
+	var transport app.Mailer
+	switch cfg.Transport {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("MAIL_TRANSPORT=smtp requires SMTP_HOST")
+		}
+		transport = mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+			StartTLS: cfg.SMTPStartTLS,
+		})
+	case "maildir":
+		md, err := mail.NewMaildir(cfg.MaildirPath, cfg.From)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("mail is written to a local maildir", "path", cfg.MaildirPath)
+		transport = md
+	case "memory":
+		transport = memory.NewMailbox()
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+
+	brand, tenants, err := loadBranding(cfg.BrandingFile)
+	if err != nil {
+		return nil, err
+	}
+	if brand.ProductName == "" {
+		brand.ProductName = productName
+	}
+	if brand.From == "" {
+		brand.From = cfg.From
+	}
+
+	queue := mail.NewQueue(transport, log, mail.QueueOptions{})
+	return &mailer{
+		send:      queue,
+		templates: app.NewMailTemplates(brand, tenants),
+		close: func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := queue.Close(ctx); err != nil {
+				log.Warn("mail queue not drained", "error", err)
+			}
+		},
+	}, nil
+}
+
+func loadBranding(path string) (app.Branding, map[string]app.Branding, error) {
+	if path == "" {
+		return app.Branding{}, nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return app.Branding{}, nil, fmt.Errorf("read mail branding: %w", err)
+	}
+	var file struct {
+		Default app.Branding            `json:"default"`
+		Tenants map[string]app.Branding `json:"tenants"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return app.Branding{}, nil, fmt.Errorf("parse mail branding: %w", err)
+	}
+	return file.Default, file.Tenants, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log/slog"
 	"os"
 
@@ -13,10 +14,16 @@ import (
 	"go-auth/internal/app/usecase"
 	"go-auth/internal/config"
 
-	// "go-auth/internal/infrastructure/memory" // Deprecated
-	"go-auth/internal/infrastructure/postgres"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/oidc"
+	"go-auth/internal/infrastructure/redis"
+	"go-auth/internal/infrastructure/saml"
+	"go-auth/internal/infrastructure/webhook"
+	"go-auth/internal/security/captcha"
 	"go-auth/internal/security/jwt"
 	"go-auth/internal/security/password"
+	"go-auth/internal/security/pow"
+	"go-auth/internal/security/throttle"
 	httpv1 "go-auth/internal/transport/http"
 )
 
@@ -36,18 +43,54 @@ func main() {
 	)
 
 	// 3. Init Infrastructure
-	// DB Pool
-	dbPool, err := postgres.InitPool(context.Background(), cfg.Postgres.DSN, logger)
+	// Storage: Postgres, SQLite или in-memory (STORAGE), по умолчанию по схеме DATABASE_URL
+	store, err := openStorage(context.Background(), cfg.Storage.Backend, cfg.Postgres.DSN, logger)
 	if err != nil {
-		logger.Error("failed to connect to db", "error", err)
+		logger.Error("failed to open storage", "error", err)
 		os.Exit(1)
 	}
-	defer dbPool.Close()
+	defer store.close()
 
-	// Migrations выполняются через docker-entrypoint-initdb.d и CI/CD, приложение не исполняет DDL
+	// Migrations: встроены в бинарник, применяются командой `migrate` или при старте (MIGRATE_ON_START)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if store.migrator == nil {
+			logger.Error("storage backend has no migrations", "backend", cfg.Storage.Backend)
+			os.Exit(1)
+		}
+		if err := runMigrate(context.Background(), store.migrator, os.Args[2:], os.Stdout); err != nil {
+			logger.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.Postgres.MigrateOnStart && store.migrator != nil {
+		if _, err := store.migrator.Up(context.Background()); err != nil {
+			logger.Error("failed to apply migrations", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Mail: SMTP, maildir or in-memory (MAIL_TRANSPORT), sent from a background queue
+	mailer, err := openMailer(cfg.Mail, cfg.App.Name, logger)
+	if err != nil {
+		logger.Error("failed to set up mail", "error", err)
+		os.Exit(1)
+	}
+	defer mailer.close()
+
+	smsSender, err := openSMSSender(cfg.SMS, logger)
+	if err != nil {
+		logger.Error("failed to set up sms", "error", err)
+		os.Exit(1)
+	}
 
-	userRepo := postgres.NewUserRepository(dbPool)
-	refreshRepo := postgres.NewRefreshRepository(dbPool)
+	txManager := store.tx
+	userRepo := store.users
+	refreshRepo := store.refresh
+	auditRepo := store.audit
+	auditLog := app.NewAuditRecorder(auditRepo)
+	outboxRepo := store.outbox
+	webhookRepo := store.webhooks
 	var pwdService app.PasswordService
 	if cfg.Security.BcryptCost > 0 {
 		pwdService = password.NewWithCost(cfg.Security.BcryptCost)
@@ -55,8 +98,45 @@ func main() {
 		pwdService = password.New()
 	}
 
+	var (
+		limitStore     app.RateLimitStore
+		attemptCounter app.AttemptCounter
+	)
+	switch cfg.Limits.Store {
+	case "redis":
+		redisClient, err := redis.InitClient(context.Background(), cfg.Redis.Addr, logger)
+		if err != nil {
+			logger.Error("failed to connect to redis", "error", err)
+			os.Exit(1)
+		}
+		defer redisClient.Close()
+		limitStore = redis.NewRateLimitStore(redisClient)
+		attemptCounter = redis.NewAttemptCounter(redisClient)
+	default:
+		memLimits := memory.NewRateLimitStore(time.Minute)
+		defer memLimits.Close()
+		memAttempts := memory.NewAttemptCounter(time.Minute)
+		defer memAttempts.Close()
+		limitStore, attemptCounter = memLimits, memAttempts
+	}
+
+	var challenges app.ChallengeProvider
+	if cfg.Captcha.VerifyURL != "" {
+		challenges = captcha.NewSiteVerify(cfg.Captcha.VerifyURL, cfg.Captcha.SiteKey, cfg.Captcha.Secret)
+	} else if cfg.PoW.Mode != "off" {
+		powSecret := []byte(cfg.PoW.Secret)
+		if len(powSecret) == 0 {
+			// Challenges issued by one replica will not verify on another.
+			logger.Warn("POW_SECRET not set, using an ephemeral key")
+			powSecret = make([]byte, 32)
+			_, _ = rand.Read(powSecret)
+		}
+		challenges = pow.NewIssuer(powSecret, cfg.PoW.Difficulty, 5*time.Minute, attemptCounter)
+	}
+	loginGuard := throttle.NewLoginGuard(attemptCounter, challenges, throttle.DefaultPolicy())
+
 	// 4. Init Application / UseCases
-	registerUC := usecase.NewRegisterUserUseCase(logger, userRepo, pwdService)
+	registerUC := usecase.NewRegisterUserUseCase(logger, txManager, userRepo, outboxRepo, pwdService, auditLog)
 
 	// Token service and Login use case
 	tokenCfg := app.TokenConfig{
@@ -68,9 +148,77 @@ func main() {
 		Audience:      cfg.App.Name,
 	}
 	tokenService := jwt.NewJWTService(tokenCfg)
-	loginUC := usecase.NewLoginUserUseCase(logger, userRepo, pwdService, tokenService, refreshRepo)
-	refreshUC := usecase.NewRefreshUseCase(tokenService, refreshRepo)
-	logoutUC := usecase.NewLogoutUseCase(refreshRepo)
+	loginUC := usecase.NewLoginUserUseCase(logger, userRepo, pwdService, tokenService, refreshRepo, loginGuard, auditLog)
+	refreshUC := usecase.NewRefreshUseCase(logger, txManager, tokenService, refreshRepo, userRepo, auditLog)
+	logoutUC := usecase.NewLogoutUseCase(logger, refreshRepo, auditLog)
+	profileUC := usecase.NewProfileUseCase(logger, userRepo)
+	magicLinkUC := usecase.NewMagicLinkUseCase(logger, usecase.MagicLinkConfig{
+		TTL:                 cfg.MagicLink.TTL,
+		URL:                 cfg.MagicLink.URL,
+		AutoRegisterTenants: cfg.MagicLink.AutoRegisterTenants,
+		Domains:             cfg.Tenants.Domains,
+	}, store.magic, userRepo, registerUC, loginUC, mailer.send, mailer.templates, auditLog)
+	phoneUC := usecase.NewPhoneUseCase(logger, usecase.OTPConfig{
+		TTL:         cfg.OTP.TTL,
+		MaxAttempts: cfg.OTP.MaxAttempts,
+	}, userRepo, store.otps, smsSender, loginUC, auditLog)
+	loginUC.UseSecondFactor(phoneUC)
+	authenticators, err := loadAuthenticators(cfg.LDAP.File, logger, txManager, store.identity, userRepo, pwdService, registerUC)
+	if err != nil {
+		logger.Error("failed to load LDAP directories", "error", err)
+		os.Exit(1)
+	}
+	loginUC.UseAuthenticators(authenticators)
+	oidcProviders, err := loadOIDCProviders(cfg.OIDC.ProvidersFile)
+	if err != nil {
+		logger.Error("failed to load OIDC providers", "error", err)
+		os.Exit(1)
+	}
+	federatedUC := usecase.NewFederatedLoginUseCase(logger, usecase.FederatedLoginConfig{
+		Providers:   oidcProviders,
+		RedirectURL: cfg.OIDC.RedirectURL,
+		TTL:         cfg.OIDC.TTL,
+		Domains:     cfg.Tenants.Domains,
+	}, txManager, store.fedLogin, store.identity, userRepo, registerUC, loginUC, oidc.NewClient(oidc.Options{}), auditLog)
+	samlIdPs, err := loadSAMLIdPs(context.Background(), cfg.SAML.IdPsFile)
+	if err != nil {
+		logger.Error("failed to load SAML identity providers", "error", err)
+		os.Exit(1)
+	}
+	samlUC := usecase.NewSAMLLoginUseCase(logger, usecase.SAMLLoginConfig{
+		IdPs:    samlIdPs,
+		TTL:     cfg.SAML.TTL,
+		Domains: cfg.Tenants.Domains,
+	}, txManager, store.fedLogin, store.identity, userRepo, registerUC, loginUC, saml.NewServiceProvider(saml.Options{
+		EntityID:  cfg.SAML.BaseURL + "/api/v1/auth/saml/metadata",
+		ACSURL:    cfg.SAML.BaseURL + "/api/v1/auth/saml/acs",
+		ClockSkew: cfg.SAML.ClockSkew,
+	}), auditLog)
+	listAuditUC := usecase.NewListAuditEventsUseCase(auditRepo)
+	webhooksUC := usecase.NewManageWebhooksUseCase(webhookRepo)
+	provisioningUC := usecase.NewProvisioningUseCase(logger, txManager, cfg.Tenants.Domains, store.members, store.roles, userRepo, refreshRepo, store.pats, registerUC, auditLog)
+	scimTokensUC := usecase.NewManageSCIMTokensUseCase(store.scim)
+	personalTokensUC := usecase.NewPersonalTokensUseCase(logger, store.pats, auditLog)
+	serviceAccountsUC := usecase.NewServiceAccountsUseCase(logger, usecase.ServiceAccountsConfig{
+		Audience: cfg.OAuth.BaseURL + "/api/v1/oauth/token",
+	}, store.services, store.roles, tokenService, jwt.NewAssertionVerifier(time.Hour, time.Minute), auditLog)
+	var tokenExchangeUC *usecase.TokenExchangeUseCase
+	if cfg.OAuth.ExchangePolicyFile != "" {
+		exchangePolicy, err := loadTokenExchangePolicy(cfg.OAuth.ExchangePolicyFile)
+		if err != nil {
+			logger.Error("failed to load token exchange policy", "error", err)
+			os.Exit(1)
+		}
+		tokenExchangeUC = usecase.NewTokenExchangeUseCase(logger, exchangePolicy, userRepo, tokenService, auditLog)
+	}
+	adminUsersUC := usecase.NewAdminUsersUseCase(logger, txManager, userRepo, refreshRepo, store.pats, store.members, outboxRepo, auditLog)
+	impersonationUC := usecase.NewImpersonationUseCase(logger, cfg.Admin.ImpersonationTTL, userRepo, store.imps, tokenService, auditLog)
+
+	// Outbox dispatcher: delivers identity events as signed webhooks
+	dispatchUC := usecase.NewDispatchWebhooksUseCase(logger, webhookRepo, webhook.NewSender(10*time.Second))
+	go dispatchUC.Run(context.Background(), cfg.Webhooks.DispatchInterval)
+	// Audits the end of impersonations nobody ended before they expired
+	go impersonationUC.Run(context.Background(), time.Minute)
 
 	// 5. Init Transport (HTTP - Gin)
 	if cfg.App.Environment == "production" {
@@ -80,9 +228,39 @@ func main() {
 	r := gin.Default()
 	_ = r.SetTrustedProxies(nil)
 	r.Use(httpv1.RequestID())
+	r.Use(httpv1.Errors(logger))
 	r.Use(httpv1.CORS())
 	r.Use(httpv1.SecurityHeaders())
-	r.Use(httpv1.RateLimit(100, time.Minute))
+	r.Use(httpv1.RateLimit(limitStore, logger,
+		httpv1.RateLimitRule{Policy: app.RateLimitPolicy{Name: "global", Limit: 100, Window: time.Minute}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/login", Policy: app.RateLimitPolicy{Name: "login-ip", Limit: 20, Window: time.Minute}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/login", Policy: app.RateLimitPolicy{Name: "login-email", Limit: 5, Window: time.Minute}, Key: httpv1.KeyByEmail},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/register", Policy: app.RateLimitPolicy{Name: "register-ip", Limit: 10, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/magic-link", Policy: app.RateLimitPolicy{Name: "magic-link-ip", Limit: 10, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/magic-link", Policy: app.RateLimitPolicy{Name: "magic-link-email", Limit: 3, Window: 15 * time.Minute}, Key: httpv1.KeyByEmail},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/phone/login", Policy: app.RateLimitPolicy{Name: "phone-login-ip", Limit: 10, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/phone/login", Policy: app.RateLimitPolicy{Name: "phone-login-phone", Limit: 3, Window: 15 * time.Minute}, Key: httpv1.KeyByPhone},
+		httpv1.RateLimitRule{Route: "/api/v1/users/me/phone", Policy: app.RateLimitPolicy{Name: "phone-verify-ip", Limit: 10, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/users/me/phone", Policy: app.RateLimitPolicy{Name: "phone-verify-phone", Limit: 3, Window: 15 * time.Minute}, Key: httpv1.KeyByPhone},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/oidc/:provider/authorize", Policy: app.RateLimitPolicy{Name: "oidc-authorize-ip", Limit: 30, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/oidc/callback", Policy: app.RateLimitPolicy{Name: "oidc-callback-ip", Limit: 30, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/saml/:provider/login", Policy: app.RateLimitPolicy{Name: "saml-login-ip", Limit: 30, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/saml/acs", Policy: app.RateLimitPolicy{Name: "saml-acs-ip", Limit: 30, Window: time.Hour}, Key: httpv1.KeyByIP},
+		httpv1.RateLimitRule{Route: "/api/v1/auth/refresh", Policy: app.RateLimitPolicy{Name: "refresh-client", Limit: 30, Window: time.Minute}, Key: httpv1.KeyByClientID},
+		httpv1.RateLimitRule{Route: "/api/v1/oauth/token", Policy: app.RateLimitPolicy{Name: "oauth-token-ip", Limit: 60, Window: time.Minute}, Key: httpv1.KeyByIP},
+	))
+	if challenges != nil {
+		elevated := func(c *gin.Context) bool { return loginGuard.Elevated(c.Request.Context(), c.ClientIP()) }
+		if cfg.PoW.Mode == "always" {
+			elevated = func(*gin.Context) bool { return true }
+		}
+		r.Use(httpv1.RequireChallenge(challenges, elevated, logger,
+			"/api/v1/auth/register",
+			"/api/v1/auth/login",
+			"/api/v1/auth/magic-link",
+			"/api/v1/auth/phone/login",
+		))
+	}
 
 	// Health endpoint at root path for container healthcheck
 	r.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok"}) })
@@ -92,6 +270,29 @@ func main() {
 
 	authHandler := httpv1.NewAuthHandler(logger, registerUC, loginUC, refreshUC, logoutUC)
 	authHandler.RegisterRoutes(v1)
+	httpv1.NewMagicLinkHandler(logger, magicLinkUC, cfg.App.Environment == "production").RegisterRoutes(v1)
+	phoneHandler := httpv1.NewPhoneHandler(logger, phoneUC)
+	phoneHandler.RegisterRoutes(v1)
+	// Impersonation tokens may read the user's account, but not change its
+	// credentials, tokens or second factor.
+	userGroup := v1.Group("", httpv1.Authenticate(tokenService, personalTokensUC, impersonationUC))
+	httpv1.NewProfileHandler(logger, profileUC).RegisterRoutes(userGroup.Group("", httpv1.RequireScope("user")))
+	phoneHandler.RegisterUserRoutes(userGroup.Group("", httpv1.ForbidImpersonation(), httpv1.RequireScope("user")))
+	httpv1.NewPersonalTokenHandler(logger, personalTokensUC).RegisterRoutes(userGroup.Group("", httpv1.ForbidImpersonation(), httpv1.RequireSession()))
+	httpv1.NewFederatedHandler(logger, federatedUC, cfg.App.Environment == "production").RegisterRoutes(v1)
+	httpv1.NewSAMLHandler(logger, samlUC, cfg.App.Environment == "production").RegisterRoutes(v1)
+	scimHandler := httpv1.NewSCIMHandler(logger, provisioningUC, scimTokensUC, cfg.SCIM.BaseURL+"/api/v1/scim/v2")
+	scimHandler.RegisterRoutes(v1)
+	serviceAccountHandler := httpv1.NewServiceAccountHandler(logger, serviceAccountsUC, tokenExchangeUC)
+	serviceAccountHandler.RegisterRoutes(v1)
+
+	adminGroup := v1.Group("", httpv1.Authenticate(tokenService, personalTokensUC, impersonationUC), httpv1.ForbidImpersonation(), httpv1.RequireAdmin(cfg.Admin.UserIDs), httpv1.RequireScope("admin"))
+	adminHandler := httpv1.NewAdminHandler(logger, listAuditUC, webhooksUC)
+	adminHandler.RegisterRoutes(adminGroup)
+	scimHandler.RegisterAdminRoutes(adminGroup)
+	serviceAccountHandler.RegisterAdminRoutes(adminGroup)
+	httpv1.NewAdminUserHandler(logger, adminUsersUC).RegisterRoutes(adminGroup)
+	httpv1.NewImpersonationHandler(logger, impersonationUC).RegisterRoutes(adminGroup)
 
 	logger.Info("server started", "port", cfg.HTTP.Port)
 	if err := r.Run(":" + cfg.HTTP.Port); err != nil {
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go-auth/internal/infrastructure/migrate"
+)
+
+const migrateUsage = "usage: auth-service migrate up | down [N] | goto VERSION | status"
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(ctx context.Context, runner *migrate.Runner, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "up":
+		n, err := runner.Up(ctx)
+		fmt.Fprintf(out, "applied %d migration(s)\n", n)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				return errors.New(migrateUsage)
+			}
+			steps = v
+		}
+		n, err := runner.Down(ctx, steps)
+		fmt.Fprintf(out, "reverted %d migration(s)\n", n)
+		return err
+	case "goto":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return errors.New(migrateUsage)
+		}
+		n, err := runner.Goto(ctx, version)
+		fmt.Fprintf(out, "ran %d migration(s)\n", n)
+		return err
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"go-auth/internal/app"
+)
+
+// loadOIDCProviders reads the upstream provider file. Without a file no
+// provider is offered.
+func loadOIDCProviders(path string) (app.OIDCProviders, error) {
+	if path == "" {
+		return app.OIDCProviders{}, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return app.OIDCProviders{}, fmt.Errorf("read oidc providers: %w", err)
+	}
+	var providers app.OIDCProviders
+	if err := json.Unmarshal(raw, &providers); err != nil {
+		return app.OIDCProviders{}, fmt.Errorf("parse oidc providers: %w", err)
+	}
+	lists := map[string][]app.OIDCProvider{"default": providers.Default}
+	for tenant, list := range providers.Tenants {
+		lists["tenant "+tenant] = list
+	}
+	for scope, list := range lists {
+		seen := make(map[string]bool)
+		for _, p := range list {
+			if p.ID == "" || p.Issuer == "" || p.ClientID == "" {
+				return app.OIDCProviders{}, fmt.Errorf("oidc providers: %s: id, issuer and client_id are required", scope)
+			}
+			if seen[p.ID] {
+				return app.OIDCProviders{}, fmt.Errorf("oidc providers: %s: duplicate id %q", scope, p.ID)
+			}
+			seen[p.ID] = true
+		}
+	}
+	return providers, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/saml"
+)
+
+// loadSAMLIdPs reads the SAML IdP file and imports each IdP's metadata.
+// Without a file no IdP is offered.
+func loadSAMLIdPs(ctx context.Context, path string) (app.SAMLIdPs, error) {
+	if path == "" {
+		return app.SAMLIdPs{}, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return app.SAMLIdPs{}, fmt.Errorf("read saml idps: %w", err)
+	}
+	var idps app.SAMLIdPs
+	if err := json.Unmarshal(raw, &idps); err != nil {
+		return app.SAMLIdPs{}, fmt.Errorf("parse saml idps: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	lists := map[string][]app.SAMLIdP{"default": idps.Default}
+	for tenant, list := range idps.Tenants {
+		lists["tenant "+tenant] = list
+	}
+	for scope, list := range lists {
+		seen := make(map[string]bool)
+		for i, idp := range list {
+			if idp.ID == "" {
+				return app.SAMLIdPs{}, fmt.Errorf("saml idps: %s: id is required", scope)
+			}
+			if seen[idp.ID] {
+				return app.SAMLIdPs{}, fmt.Errorf("saml idps: %s: duplicate id %q", scope, idp.ID)
+			}
+			seen[idp.ID] = true
+			md, err := saml.LoadMetadata(ctx, client, idp)
+			if err != nil {
+				return app.SAMLIdPs{}, fmt.Errorf("saml idps: %s: %s: %w", scope, idp.ID, err)
+			}
+			// list shares its backing array with idps.
+			list[i].Metadata = md
+		}
+	}
+	return idps, nil
+}
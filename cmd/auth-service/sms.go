@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/config"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/sms"
+)
+
+func openSMSSender(cfg config.SMSConfig, log *slog.Logger) (app.SMSSender, error) {
+	switch cfg.Transport {
+	case "http":
+		if cfg.GatewayURL == "" {
+			return nil, fmt.Errorf("SMS_TRANSPORT=http requires SMS_GATEWAY_URL")
+		}
+		return sms.NewGateway(sms.GatewayConfig{URL: cfg.GatewayURL, Token: cfg.GatewayToken, From: cfg.From}), nil
+	case "file":
+		s, err := sms.NewFileSender(cfg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("sms is written to a local file", "path", cfg.FilePath)
+		return s, nil
+	case "memory":
+		return memory.NewSMSOutbox(), nil
+	default:
+		return nil, fmt.Errorf("unknown sms transport %q", cfg.Transport)
+	}
+}
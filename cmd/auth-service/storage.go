@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/migrate"
+	"go-auth/internal/infrastructure/postgres"
+	"go-auth/internal/infrastructure/sqlite"
+	"go-auth/migrations"
+)
+
+// storage bundles the repositories of one storage backend.
+type storage struct {
+	tx       app.TxManager
+	users    domain.UserRepository
+	refresh  domain.RefreshTokenRepository
+	audit    domain.AuditRepository
+	outbox   domain.OutboxRepository
+	webhooks domain.WebhookRepository
+	magic    domain.MagicLinkRepository
+	otps     domain.OTPRepository
+	identity domain.IdentityRepository
+	fedLogin domain.FederatedLoginRepository
+	members  domain.MembershipRepository
+	roles    domain.RoleRepository
+	scim     domain.SCIMTokenRepository
+	pats     domain.PersonalAccessTokenRepository
+	services domain.ServiceAccountRepository
+	imps     domain.ImpersonationRepository
+	migrator *migrate.Runner // nil for backends without a schema
+	close    func()
+}
+
+// openStorage opens the named backend. With an empty backend it is picked by
+// the scheme of dsn: sqlite:// selects SQLite, anything else Postgres.
+func openStorage(ctx context.Context, backend, dsn string, log *slog.Logger) (*storage, error) {
+	if backend == "" {
+		backend = "postgres"
+		if strings.HasPrefix(dsn, sqlite.Scheme) {
+			backend = "sqlite"
+		}
+	}
+
+	switch backend {
+	case "memory":
+		log.Warn("using in-memory storage, all data is lost on restart")
+		webhooks := memory.NewWebhookRepository()
+		members, roles := memory.NewTenantRepositories()
+		return &storage{
+			tx:       memory.NewTxManager(),
+			users:    memory.NewUserRepository(),
+			refresh:  memory.NewRefreshRepository(),
+			audit:    memory.NewAuditRepository(),
+			outbox:   webhooks,
+			webhooks: webhooks,
+			magic:    memory.NewMagicLinkRepository(),
+			otps:     memory.NewOTPRepository(),
+			identity: memory.NewIdentityRepository(),
+			fedLogin: memory.NewFederatedLoginRepository(),
+			members:  members,
+			roles:    roles,
+			scim:     memory.NewSCIMTokenRepository(),
+			pats:     memory.NewPersonalAccessTokenRepository(),
+			services: memory.NewServiceAccountRepository(),
+			imps:     memory.NewImpersonationRepository(),
+			close:    func() {},
+		}, nil
+	case "sqlite":
+		db, err := sqlite.Open(ctx, dsn, log)
+		if err != nil {
+			return nil, err
+		}
+		migrator, err := migrate.New(sqlite.NewMigrationDriver(db), sqlite.Migrations, log)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		return &storage{
+			tx:       sqlite.NewTxManager(db),
+			users:    sqlite.NewUserRepository(db),
+			refresh:  sqlite.NewRefreshRepository(db),
+			audit:    sqlite.NewAuditRepository(db),
+			outbox:   sqlite.NewOutboxRepository(db),
+			webhooks: sqlite.NewWebhookRepository(db),
+			magic:    sqlite.NewMagicLinkRepository(db),
+			otps:     sqlite.NewOTPRepository(db),
+			identity: sqlite.NewIdentityRepository(db),
+			fedLogin: sqlite.NewFederatedLoginRepository(db),
+			members:  sqlite.NewMembershipRepository(db),
+			roles:    sqlite.NewRoleRepository(db),
+			scim:     sqlite.NewSCIMTokenRepository(db),
+			pats:     sqlite.NewPersonalAccessTokenRepository(db),
+			services: sqlite.NewServiceAccountRepository(db),
+			imps:     sqlite.NewImpersonationRepository(db),
+			migrator: migrator,
+			close:    func() { _ = db.Close() },
+		}, nil
+	case "postgres":
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+
+	pool, err := postgres.InitPool(ctx, dsn, log)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrate.New(postgres.NewMigrationDriver(pool), migrations.FS, log)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &storage{
+		tx:       postgres.NewTxManager(pool),
+		users:    postgres.NewUserRepository(pool),
+		refresh:  postgres.NewRefreshRepository(pool),
+		audit:    postgres.NewAuditRepository(pool),
+		outbox:   postgres.NewOutboxRepository(pool),
+		webhooks: postgres.NewWebhookRepository(pool),
+		magic:    postgres.NewMagicLinkRepository(pool),
+		otps:     postgres.NewOTPRepository(pool),
+		identity: postgres.NewIdentityRepository(pool),
+		fedLogin: postgres.NewFederatedLoginRepository(pool),
+		members:  postgres.NewMembershipRepository(pool),
+		roles:    postgres.NewRoleRepository(pool),
+		scim:     postgres.NewSCIMTokenRepository(pool),
+		pats:     postgres.NewPersonalAccessTokenRepository(pool),
+		services: postgres.NewServiceAccountRepository(pool),
+		imps:     postgres.NewImpersonationRepository(pool),
+		migrator: migrator,
+		close:    pool.Close,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"go-auth/internal/app"
+)
+
+// loadTokenExchangePolicy reads the token exchange policy file.
+func loadTokenExchangePolicy(path string) (app.TokenExchangePolicy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return app.TokenExchangePolicy{}, fmt.Errorf("read token exchange policy: %w", err)
+	}
+	var policy app.TokenExchangePolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return app.TokenExchangePolicy{}, fmt.Errorf("parse token exchange policy: %w", err)
+	}
+	for client, rules := range policy.Clients {
+		seen := make(map[string]bool)
+		for _, r := range rules {
+			if r.Audience == "" {
+				return app.TokenExchangePolicy{}, fmt.Errorf("token exchange policy: client %s: audience is required", client)
+			}
+			if seen[r.Audience] {
+				return app.TokenExchangePolicy{}, fmt.Errorf("token exchange policy: client %s: duplicate audience %q", client, r.Audience)
+			}
+			seen[r.Audience] = true
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+// AuditLog receives security events from use cases.
+type AuditLog interface {
+	Record(ctx context.Context, event domain.AuditEvent) error
+}
+
+// RequestMeta describes the HTTP request a use case runs on behalf of.
+type RequestMeta struct {
+	RequestID string
+	IP        string
+	UserAgent string
+	// Locale is the language negotiated from Accept-Language.
+	Locale string
+	// ImpersonatorID is the admin behind an impersonation token.
+	ImpersonatorID string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+// AuditRecorder is the AuditLog used by the service: it stamps events with
+// the time and request metadata and appends them to a repository.
+type AuditRecorder struct {
+	repo domain.AuditRepository
+	now  func() time.Time
+}
+
+func NewAuditRecorder(repo domain.AuditRepository) *AuditRecorder {
+	return &AuditRecorder{repo: repo, now: time.Now}
+}
+
+func (r *AuditRecorder) Record(ctx context.Context, event domain.AuditEvent) error {
+	meta := RequestMetaFrom(ctx)
+	if event.RequestID == "" {
+		event.RequestID = meta.RequestID
+	}
+	if event.IP == "" {
+		event.IP = meta.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = meta.UserAgent
+	}
+	if meta.ImpersonatorID != "" {
+		// Events caused while impersonating name the admin too.
+		metadata := make(map[string]string, len(event.Metadata)+1)
+		for k, v := range event.Metadata {
+			metadata[k] = v
+		}
+		metadata["impersonator_id"] = meta.ImpersonatorID
+		event.Metadata = metadata
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = r.now()
+	}
+	event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+	return r.repo.Append(ctx, &event)
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+
+	"go-auth/internal/domain"
+)
+
+// Credentials are what a user typed into the login form.
+type Credentials struct {
+	TenantID string
+	Email    string
+	Password string
+}
+
+// CredentialsError rejects a login. UserID is set when the account is
+// known; Reason is a short code for logs and audit ("unknown_email",
+// "invalid_password").
+type CredentialsError struct {
+	UserID string
+	Reason string
+}
+
+func (e *CredentialsError) Error() string { return "credentials rejected: " + e.Reason }
+
+// Authenticator checks credentials against one credential store and returns
+// the local user they belong to.
+type Authenticator interface {
+	// Method names the authenticator in audit events ("password", "ldap").
+	Method() string
+	// Authenticate fails with *CredentialsError when the store rejects the
+	// credentials. Other errors mean the store could not be asked.
+	Authenticate(ctx context.Context, creds Credentials) (*domain.User, error)
+}
+
+// DirectoryEntry is a user as a directory service describes them.
+type DirectoryEntry struct {
+	DN     string
+	Email  string
+	Locale string
+}
+
+// Directory verifies credentials against an external user directory such
+// as LDAP or Active Directory.
+type Directory interface {
+	// Bind fails with *CredentialsError when the directory rejects login and
+	// password, and wraps domain.ErrUnavailable when it cannot be reached.
+	Bind(ctx context.Context, login, password string) (*DirectoryEntry, error)
+}
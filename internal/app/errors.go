@@ -1,17 +1,52 @@
 package app
 
 type AppError struct {
-	Code string
-	Msg  string
+	Code    string
+	Msg     string
+	Details map[string]any
+	Fields  []FieldError
+}
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
 
 func (e AppError) Error() string { return e.Msg }
 
 func NewError(code, msg string) AppError { return AppError{Code: code, Msg: msg} }
 
+// WithDetails returns a copy of e carrying machine-readable details for clients.
+func (e AppError) WithDetails(details map[string]any) AppError {
+	e.Details = details
+	return e
+}
+
+// WithFields returns a copy of e listing the input fields that were rejected.
+func (e AppError) WithFields(fields ...FieldError) AppError {
+	e.Fields = fields
+	return e
+}
+
 const (
 	ErrCodeInvalidCredentials = "AUTH_INVALID_CREDENTIALS"
 	ErrCodeEmailExists        = "AUTH_EMAIL_EXISTS"
 	ErrCodeValidation         = "VALIDATION_ERROR"
 	ErrCodeInternal           = "INTERNAL_ERROR"
+	ErrCodeUnavailable        = "SERVICE_UNAVAILABLE"
+	ErrCodeRateLimited        = "RATE_LIMITED"
+	ErrCodeChallengeRequired  = "AUTH_CHALLENGE_REQUIRED"
+	ErrCodeSecondFactor       = "AUTH_SECOND_FACTOR_REQUIRED"
+	ErrCodeOTPAttempts        = "AUTH_OTP_ATTEMPTS_EXCEEDED"
+	ErrCodeAccountDisabled    = "AUTH_ACCOUNT_DISABLED"
+	ErrCodeUnauthorized       = "UNAUTHORIZED"
+	ErrCodeForbidden          = "FORBIDDEN"
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeConflict           = "CONFLICT"
+	// ErrCodePreconditionFailed: the resource changed since the version the
+	// client based its write on.
+	ErrCodePreconditionFailed = "PRECONDITION_FAILED"
 )
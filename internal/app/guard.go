@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttempt describes who is trying to log in.
+type LoginAttempt struct {
+	Email     string
+	IP        string
+	Challenge string // solution to a previously issued challenge, if any
+	// ChallengePassed is set when the transport already verified a challenge
+	// for this request, so the guard must not verify (and spend) it again.
+	ChallengePassed bool
+}
+
+type LoginVerdict int
+
+const (
+	LoginAllow LoginVerdict = iota
+	LoginChallenge
+	LoginBlock
+)
+
+func (v LoginVerdict) String() string {
+	switch v {
+	case LoginAllow:
+		return "allow"
+	case LoginChallenge:
+		return "challenge"
+	case LoginBlock:
+		return "block"
+	}
+	return "unknown"
+}
+
+// LoginDecision is returned by LoginGuard.Check before credentials are verified.
+type LoginDecision struct {
+	Verdict    LoginVerdict
+	Challenge  *Challenge    // set when Verdict is LoginChallenge
+	RetryAfter time.Duration // set when Verdict is LoginBlock
+	Reason     string        // dimension that tripped the guard, for logs
+}
+
+// LoginGuard throttles password login based on recent failures.
+type LoginGuard interface {
+	Check(ctx context.Context, attempt LoginAttempt) (LoginDecision, error)
+	RecordFailure(ctx context.Context, attempt LoginAttempt) error
+	RecordSuccess(ctx context.Context, attempt LoginAttempt) error
+}
+
+// Challenge tells a client what it must solve before retrying.
+type Challenge struct {
+	Type   string            `json:"type"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// ChallengeProvider issues and verifies challenges (CAPTCHA, proof-of-work, ...).
+type ChallengeProvider interface {
+	Issue(ctx context.Context) (Challenge, error)
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+// AttemptCounter counts events per key in fixed windows that start with the
+// first event.
+type AttemptCounter interface {
+	Incr(ctx context.Context, key string, window time.Duration) (count int, ttl time.Duration, err error)
+	Get(ctx context.Context, key string) (count int, ttl time.Duration, err error)
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"go-auth/internal/i18n"
+)
+
+// Email is one outgoing message. Text is always sent; HTML, when set, is
+// offered as the preferred alternative.
+type Email struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email. Implementations may queue the message and return
+// before it reaches the recipient's server.
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// Branding is the look of outgoing email. Empty fields of a tenant override
+// inherit the default branding.
+type Branding struct {
+	ProductName  string `json:"product_name"`
+	From         string `json:"from"`
+	LogoURL      string `json:"logo_url"`
+	PrimaryColor string `json:"primary_color"`
+	Footer       string `json:"footer"`
+}
+
+func (b Branding) merge(over Branding) Branding {
+	if over.ProductName != "" {
+		b.ProductName = over.ProductName
+	}
+	if over.From != "" {
+		b.From = over.From
+	}
+	if over.LogoURL != "" {
+		b.LogoURL = over.LogoURL
+	}
+	if over.PrimaryColor != "" {
+		b.PrimaryColor = over.PrimaryColor
+	}
+	if over.Footer != "" {
+		b.Footer = over.Footer
+	}
+	return b
+}
+
+// MailTemplates renders the i18n email templates into multipart-ready
+// messages, wrapping the text in a branded HTML layout.
+type MailTemplates struct {
+	brand   Branding
+	tenants map[string]Branding
+	layout  *template.Template
+}
+
+func NewMailTemplates(brand Branding, tenants map[string]Branding) *MailTemplates {
+	if brand.PrimaryColor == "" {
+		brand.PrimaryColor = "#2563eb"
+	}
+	return &MailTemplates{
+		brand:   brand,
+		tenants: tenants,
+		layout:  template.Must(template.New("layout").Parse(mailLayout)),
+	}
+}
+
+// Branding returns the effective branding for tenantID.
+func (t *MailTemplates) Branding(tenantID string) Branding {
+	if over, ok := t.tenants[tenantID]; ok && tenantID != "" {
+		return t.brand.merge(over)
+	}
+	return t.brand
+}
+
+// Render builds email template id for the recipient to in locale. data is
+// passed to the template; a "Link" entry is rendered as a button in HTML.
+func (t *MailTemplates) Render(tenantID, locale, id, to string, data map[string]any) (Email, error) {
+	subject, text, err := i18n.RenderEmail(locale, id, data)
+	if err != nil {
+		return Email{}, err
+	}
+	brand := t.Branding(tenantID)
+	if brand.ProductName != "" {
+		subject = brand.ProductName + ": " + subject
+	}
+	if brand.Footer != "" {
+		text += "\n-- \n" + brand.Footer + "\n"
+	}
+
+	link, _ := data["Link"].(string)
+	var paragraphs []mailParagraph
+	for _, p := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "-- \n") {
+			continue
+		}
+		paragraphs = append(paragraphs, mailParagraph{Text: p, IsLink: link != "" && p == link})
+	}
+	var html bytes.Buffer
+	if err := t.layout.Execute(&html, map[string]any{
+		"Brand":      brand,
+		"Subject":    subject,
+		"Paragraphs": paragraphs,
+		"Lang":       locale,
+	}); err != nil {
+		return Email{}, fmt.Errorf("render email layout: %w", err)
+	}
+	return Email{From: brand.From, To: to, Subject: subject, Text: text, HTML: html.String()}, nil
+}
+
+type mailParagraph struct {
+	Text   string
+	IsLink bool
+}
+
+const mailLayout = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="margin:0;padding:24px;background:#f4f4f5;font-family:Arial,Helvetica,sans-serif;color:#18181b">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0"><tr><td align="center">
+<table role="presentation" width="560" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:8px;padding:32px">
+<tr><td style="padding-bottom:24px;border-bottom:3px solid {{.Brand.PrimaryColor}}">
+{{- if .Brand.LogoURL}}<img src="{{.Brand.LogoURL}}" alt="{{.Brand.ProductName}}" height="32">{{else}}<strong style="font-size:20px">{{.Brand.ProductName}}</strong>{{end -}}
+</td></tr>
+{{- range .Paragraphs}}
+<tr><td style="padding-top:16px;font-size:15px;line-height:1.5">
+{{- if .IsLink}}<a href="{{.Text}}" style="display:inline-block;padding:12px 20px;background:{{$.Brand.PrimaryColor}};color:#ffffff;text-decoration:none;border-radius:6px">{{.Text}}</a>
+{{- else}}{{.Text}}{{end -}}
+</td></tr>
+{{- end}}
+{{- if .Brand.Footer}}
+<tr><td style="padding-top:32px;font-size:12px;color:#71717a">{{.Brand.Footer}}</td></tr>
+{{- end}}
+</table>
+</td></tr></table>
+</body>
+</html>
+`
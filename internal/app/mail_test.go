@@ -0,0 +1,42 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"go-auth/internal/i18n"
+)
+
+func TestMailTemplates_RenderWithTenantBranding(t *testing.T) {
+	tmpl := NewMailTemplates(
+		Branding{ProductName: "Go Auth", From: "no-reply@example.com", Footer: "Go Auth Inc."},
+		map[string]Branding{"acme": {ProductName: "Acme ID", PrimaryColor: "#ff0000"}},
+	)
+	data := map[string]any{"Link": "https://acme.test/verify?t=a&b", "ExpiresIn": "24h"}
+
+	e, err := tmpl.Render("acme", "ru", i18n.EmailVerify, "u@acme.test", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.To != "u@acme.test" || e.From != "no-reply@example.com" || !strings.HasPrefix(e.Subject, "Acme ID: ") {
+		t.Fatalf("envelope: %+v", e)
+	}
+	if !strings.Contains(e.Text, "Подтвердите") || !strings.Contains(e.Text, "Go Auth Inc.") {
+		t.Fatalf("text: %q", e.Text)
+	}
+	if !strings.Contains(e.HTML, `href="https://acme.test/verify?t=a&amp;b"`) || !strings.Contains(e.HTML, "#ff0000") || !strings.Contains(e.HTML, `lang="ru"`) {
+		t.Fatalf("html: %s", e.HTML)
+	}
+
+	e, err = tmpl.Render("other", "en", i18n.EmailVerify, "u@example.com", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(e.Subject, "Go Auth: ") || !strings.Contains(e.HTML, "#2563eb") {
+		t.Fatalf("default branding: %q %s", e.Subject, e.HTML)
+	}
+
+	if _, err := tmpl.Render("", "en", "nope", "u@example.com", data); err == nil {
+		t.Fatal("unknown template rendered")
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUpstreamRejected is returned by OIDCClient when the upstream provider
+// refuses the code or its ID token fails validation, as opposed to the
+// provider being unreachable.
+var ErrUpstreamRejected = errors.New("oidc: upstream login rejected")
+
+// OIDCProvider is an upstream OpenID Connect provider a tenant lets users
+// sign in with.
+type OIDCProvider struct {
+	// ID names the provider in URLs, e.g. "google". It is unique per tenant.
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+	// AutoRegister signs up upstream accounts that match no user.
+	AutoRegister bool `json:"auto_register"`
+	// TrustEmail links an upstream account to the user with the same email
+	// when the provider asserts the address is verified.
+	TrustEmail bool `json:"trust_email"`
+}
+
+// OIDCProviders is the per-tenant provider configuration. Tenants without
+// an entry use Default.
+type OIDCProviders struct {
+	Default []OIDCProvider            `json:"default"`
+	Tenants map[string][]OIDCProvider `json:"tenants"`
+}
+
+// For returns the providers available to tenantID.
+func (p OIDCProviders) For(tenantID string) []OIDCProvider {
+	if providers, ok := p.Tenants[tenantID]; ok && tenantID != "" {
+		return providers
+	}
+	return p.Default
+}
+
+// Owner returns tenantID when the tenant configured its own providers, or ""
+// when it uses the operator's Default.
+func (p OIDCProviders) Owner(tenantID string) string {
+	if _, ok := p.Tenants[tenantID]; ok {
+		return tenantID
+	}
+	return ""
+}
+
+// Find returns the provider with id available to tenantID.
+func (p OIDCProviders) Find(tenantID, id string) (OIDCProvider, bool) {
+	for _, provider := range p.For(tenantID) {
+		if provider.ID == id {
+			return provider, true
+		}
+	}
+	return OIDCProvider{}, false
+}
+
+// OIDCAuthRequest starts an authorization-code flow. The client derives the
+// PKCE challenge from CodeVerifier.
+type OIDCAuthRequest struct {
+	RedirectURL  string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// OIDCExchange redeems an authorization code. Nonce is the value sent with
+// the authorization request; the ID token must carry it.
+type OIDCExchange struct {
+	Code         string
+	RedirectURL  string
+	CodeVerifier string
+	Nonce        string
+}
+
+// UpstreamIdentity is what a validated ID token says about the user.
+type UpstreamIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCClient talks to upstream providers.
+type OIDCClient interface {
+	// AuthCodeURL returns the provider's authorization URL for req.
+	AuthCodeURL(ctx context.Context, provider OIDCProvider, req OIDCAuthRequest) (string, error)
+	// Exchange redeems the code and validates the returned ID token. It
+	// fails with ErrUpstreamRejected if the provider refuses the code or the
+	// token is invalid.
+	Exchange(ctx context.Context, provider OIDCProvider, req OIDCExchange) (*UpstreamIdentity, error)
+}
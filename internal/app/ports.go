@@ -1,7 +1,19 @@
 package app
 
+import (
+	"context"
+
+	"go-auth/internal/domain"
+)
+
 // PasswordService defines the interface for password hashing and verification.
 type PasswordService interface {
 	Hash(password string) (string, error)
 	Compare(hashedPassword, password string) error
 }
+
+// WebhookSender delivers one signed event to a subscriber endpoint. It returns
+// the HTTP status code when a response was received.
+type WebhookSender interface {
+	Send(ctx context.Context, url, secret, deliveryID string, event domain.OutboxEvent) (statusCode int, err error)
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitPolicy allows Limit requests per Window for a single key.
+// Name is reported to clients in the RateLimit-Policy header.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult is the outcome of a single RateLimitStore.Allow call.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // until the full budget is available again
+	RetryAfter time.Duration // zero when Allowed
+}
+
+// RateLimitStore tracks request budgets (GCRA) per key.
+// Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+	// Refund gives back one request Allow let through for key, when a later
+	// rule rejected that request.
+	Refund(ctx context.Context, key string, policy RateLimitPolicy) error
+}
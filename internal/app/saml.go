@@ -0,0 +1,119 @@
+package app
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+// ErrAssertionRejected is returned by SAMLServiceProvider when a response
+// fails validation: a bad signature, the wrong audience or recipient, an
+// expired assertion or an unsuccessful status.
+var ErrAssertionRejected = errors.New("saml: assertion rejected")
+
+// SAMLIdP is a SAML 2.0 identity provider a tenant lets users sign in with.
+// The configuration names where to import the IdP metadata from; Metadata
+// holds the imported result.
+type SAMLIdP struct {
+	// ID names the IdP in URLs, e.g. "okta". It is unique per tenant.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// MetadataURL or MetadataFile is where the IdP metadata is imported
+	// from. EntityID picks the IdP when the metadata describes several.
+	MetadataURL  string           `json:"metadata_url"`
+	MetadataFile string           `json:"metadata_file"`
+	EntityID     string           `json:"entity_id"`
+	Attributes   SAMLAttributeMap `json:"attributes"`
+	Metadata     SAMLIdPMetadata  `json:"-"`
+	// AutoRegister signs up asserted accounts that match no user.
+	AutoRegister bool `json:"auto_register"`
+	// TrustEmail links an asserted account to the user with the same email.
+	TrustEmail bool `json:"trust_email"`
+}
+
+// SAMLAttributeMap names the assertion attributes that carry user fields.
+// Empty names fall back to well-known attribute names; an empty Subject
+// uses the NameID, which must then be persistent.
+type SAMLAttributeMap struct {
+	Subject string `json:"subject"`
+	Email   string `json:"email"`
+	Locale  string `json:"locale"`
+}
+
+// SAMLIdPMetadata is what the service provider needs from IdP metadata.
+type SAMLIdPMetadata struct {
+	EntityID string
+	// SSOURL is the HTTP-Redirect binding endpoint for AuthnRequests.
+	SSOURL       string
+	Certificates []*x509.Certificate
+}
+
+// SAMLIdPs is the per-tenant IdP configuration. Tenants without an entry
+// use Default.
+type SAMLIdPs struct {
+	Default []SAMLIdP            `json:"default"`
+	Tenants map[string][]SAMLIdP `json:"tenants"`
+}
+
+// For returns the IdPs available to tenantID.
+func (p SAMLIdPs) For(tenantID string) []SAMLIdP {
+	if idps, ok := p.Tenants[tenantID]; ok && tenantID != "" {
+		return idps
+	}
+	return p.Default
+}
+
+// Owner returns tenantID when the tenant configured its own IdPs, or ""
+// when it uses the operator's Default.
+func (p SAMLIdPs) Owner(tenantID string) string {
+	if _, ok := p.Tenants[tenantID]; ok {
+		return tenantID
+	}
+	return ""
+}
+
+// Find returns the IdP with id available to tenantID.
+func (p SAMLIdPs) Find(tenantID, id string) (SAMLIdP, bool) {
+	for _, idp := range p.For(tenantID) {
+		if idp.ID == id {
+			return idp, true
+		}
+	}
+	return SAMLIdP{}, false
+}
+
+// SAMLAuthnRequest starts an SP-initiated login. ID must come back as the
+// response's InResponseTo.
+type SAMLAuthnRequest struct {
+	ID         string
+	RelayState string
+}
+
+// SAMLAssertion is what a validated assertion says about the user.
+type SAMLAssertion struct {
+	Issuer       string
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	Attributes   map[string][]string
+}
+
+// Attribute returns the first value of the named attribute.
+func (a *SAMLAssertion) Attribute(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// SAMLServiceProvider is this service acting as a SAML 2.0 SP.
+type SAMLServiceProvider interface {
+	// Metadata returns the SP metadata document to register at IdPs.
+	Metadata() ([]byte, error)
+	// AuthnRequestURL returns the IdP URL carrying req over the
+	// HTTP-Redirect binding.
+	AuthnRequestURL(idp SAMLIdP, req SAMLAuthnRequest) (string, error)
+	// ParseResponse decodes and validates a base64 response received over
+	// the HTTP-POST binding in answer to requestID. It fails with
+	// ErrAssertionRejected if the response is not valid.
+	ParseResponse(idp SAMLIdP, encoded, requestID string) (*SAMLAssertion, error)
+}
@@ -0,0 +1,14 @@
+package app
+
+import "context"
+
+// SMS is one outgoing text message to an E.164 number.
+type SMS struct {
+	To   string
+	Text string
+}
+
+// SMSSender delivers text messages.
+type SMSSender interface {
+	Send(ctx context.Context, sms SMS) error
+}
@@ -0,0 +1,22 @@
+package app
+
+import "strings"
+
+// TenantDomains maps email domains to the tenant that owns them. Owning a
+// domain lets a tenant take charge of existing accounts with addresses in
+// it, which it cannot do for any other account.
+type TenantDomains map[string]string
+
+// Tenant returns the tenant that owns the domain of email, or "".
+func (d TenantDomains) Tenant(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return ""
+	}
+	return d[strings.ToLower(email[at+1:])]
+}
+
+// Owns reports whether tenantID owns the domain of email.
+func (d TenantDomains) Owns(tenantID, email string) bool {
+	return tenantID != "" && d.Tenant(email) == tenantID
+}
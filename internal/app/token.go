@@ -20,3 +20,136 @@ type TokenConfig struct {
 	Issuer        string
 	Audience      string
 }
+
+// ServiceClaims describe a service account in its access token.
+type ServiceClaims struct {
+	AccountID string
+	TenantID  string
+	// Roles are role names.
+	Roles []string
+}
+
+// ServiceTokenIssuer mints access tokens for service accounts. They carry
+// no refresh token; the account authenticates again when one expires.
+type ServiceTokenIssuer interface {
+	GenerateServiceToken(claims ServiceClaims) (string, error)
+	AccessTTL() time.Duration
+}
+
+// AssertionKey is a PEM public key a client signs JWT assertions with.
+type AssertionKey struct {
+	ID        string
+	PublicKey string
+}
+
+// JWTAssertion holds the claims of a verified RFC 7523 assertion.
+type JWTAssertion struct {
+	Issuer    string
+	Subject   string
+	KeyID     string // the key that verified the signature
+	ExpiresAt time.Time
+}
+
+// AssertionVerifier checks JWT assertions clients sign with their own keys
+// (RFC 7523).
+type AssertionVerifier interface {
+	// CheckPublicKey reports whether pem holds a public key assertions can
+	// be verified with.
+	CheckPublicKey(pem string) error
+	// Issuer returns the unverified "iss" claim, for finding the keys.
+	Issuer(assertion string) (string, error)
+	// Verify checks the signature against keys, or only the key named by
+	// the "kid" header if there is one, along with audience and expiry.
+	Verify(assertion string, keys []AssertionKey, audience string) (*JWTAssertion, error)
+}
+
+// ImpersonationClaims describe an access token an admin uses to act as a
+// user. The token names the admin in its "act" claim (RFC 8693).
+type ImpersonationClaims struct {
+	ImpersonationID string
+	UserID          string
+	ActorID         string
+	ExpiresAt       time.Time
+}
+
+// ImpersonationTokens mints and checks impersonation tokens. Like service
+// tokens they carry no refresh token. ValidateToken rejects them, so only
+// routes that know about impersonation accept them.
+type ImpersonationTokens interface {
+	GenerateImpersonationToken(claims ImpersonationClaims) (string, error)
+	ValidateImpersonationToken(token string) (*ImpersonationClaims, error)
+}
+
+// Actor is an RFC 8693 "act" claim. Actor nests the previous actor of a
+// delegation chain.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// Depth is the number of actors in the chain.
+func (a *Actor) Depth() int {
+	n := 0
+	for ; a != nil; a = a.Actor {
+		n++
+	}
+	return n
+}
+
+// SubjectToken is a validated token presented for exchange: a user access
+// token or a token obtained by an earlier exchange.
+type SubjectToken struct {
+	UserID string
+	// Scopes bound a token from an earlier exchange; nil for a user
+	// access token, which is not limited by scope.
+	Scopes []string
+	// Actor is the delegation chain of a token from an earlier exchange.
+	Actor *Actor
+	// Audience is who a token from an earlier exchange was issued to; only
+	// they may exchange it again.
+	Audience  []string
+	ExpiresAt time.Time
+}
+
+// ExchangeClaims describe a token issued by token exchange: it acts for
+// UserID, is aimed at Audience, and names ClientID as the current actor
+// on top of the chain in Actor.
+type ExchangeClaims struct {
+	UserID    string
+	ClientID  string
+	Audience  string
+	Scopes    []string
+	Actor     *Actor
+	ExpiresAt time.Time
+}
+
+// TokenExchanger backs the token exchange grant (RFC 8693). It is
+// implemented by the TokenService, so subject tokens are checked the same
+// way as any access token it issued.
+type TokenExchanger interface {
+	ValidateSubjectToken(token string) (*SubjectToken, error)
+	GenerateExchangedToken(claims ExchangeClaims) (string, error)
+	AccessTTL() time.Duration
+}
+
+// TokenExchangePolicy lists, per client (service account ID), the
+// audiences it may exchange tokens for.
+type TokenExchangePolicy struct {
+	Clients map[string][]TokenExchangeRule `json:"clients"`
+}
+
+// TokenExchangeRule allows exchanging for Audience with at most Scopes.
+type TokenExchangeRule struct {
+	Audience string   `json:"audience"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Rule returns the rule that lets clientID exchange for audience.
+func (p TokenExchangePolicy) Rule(clientID, audience string) (TokenExchangeRule, bool) {
+	for _, r := range p.Clients[clientID] {
+		if r.Audience == audience {
+			return r, true
+		}
+	}
+	return TokenExchangeRule{}, false
+}
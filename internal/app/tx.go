@@ -0,0 +1,10 @@
+package app
+
+import "context"
+
+// TxManager runs fn as a unit of work: every repository call made with the
+// ctx passed to fn commits or rolls back together. Calls made while a unit of
+// work is already active join it.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// upstreamAccount is an account asserted by an upstream identity provider
+// after its response has been validated.
+type upstreamAccount struct {
+	// Provider is the configured provider ID, recorded in the audit log.
+	Provider string
+	// Issuer and Subject identify the account; they become the link.
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Locale        string
+	TenantID      string
+	// Owner is the tenant that configured the provider, or "" for the
+	// operator's default providers. A tenant's provider may only link or sign
+	// up addresses in the tenant's domains, so it cannot reach accounts that
+	// belong to anyone else.
+	Owner string
+	// TrustEmail and AutoRegister come from the provider's configuration.
+	TrustEmail   bool
+	AutoRegister bool
+}
+
+// accountLinker resolves upstream accounts to local users, shared by the
+// OIDC and SAML logins.
+type accountLinker struct {
+	tx         app.TxManager
+	domains    app.TenantDomains
+	identities domain.IdentityRepository
+	users      domain.UserRepository
+	register   *RegisterUserUseCase
+	audit      app.AuditLog
+}
+
+// resolve returns the user linked to the upstream account, else links the
+// user with a trusted verified email, else signs up when the provider
+// allows it. Both need the email in the owner's domains for a tenant's
+// provider.
+func (l accountLinker) resolve(ctx context.Context, log *slog.Logger, upstream upstreamAccount) (*domain.User, error) {
+	identity, err := l.identities.FindBySubject(ctx, upstream.Issuer, upstream.Subject)
+	switch {
+	case err == nil:
+		user, err := l.users.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, storageError(log, err, "Failed to fetch user")
+		}
+		return user, nil
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, storageError(log, err, "Failed to fetch identity")
+	}
+
+	var user *domain.User
+	err = withinTx(ctx, l.tx, func(ctx context.Context) error {
+		user, err = l.users.FindByEmail(ctx, upstream.Email)
+		switch {
+		case err == nil:
+			if !upstream.TrustEmail || !upstream.EmailVerified || !l.reaches(upstream, user.Email) {
+				log.Warn("upstream email matches an unlinked account")
+				return app.NewError(app.ErrCodeEmailExists, "An account with this email already exists")
+			}
+		case !errors.Is(err, domain.ErrNotFound):
+			return storageError(log, err, "Failed to fetch user")
+		case !upstream.AutoRegister || upstream.Email == "" || !l.reaches(upstream, upstream.Email):
+			log.Info("no account linked to upstream identity")
+			return app.NewError(app.ErrCodeForbidden, "No account is linked to this identity")
+		default:
+			if user, err = l.signUp(ctx, log, upstream); err != nil {
+				return err
+			}
+		}
+
+		err := l.identities.Create(ctx, &domain.Identity{
+			UserID:   user.ID,
+			Provider: upstream.Issuer,
+			Subject:  upstream.Subject,
+			Email:    upstream.Email,
+		})
+		if errors.Is(err, domain.ErrConflict) {
+			// A concurrent login linked the account first; retry as that login.
+			return app.NewError(app.ErrCodeConflict, "Identity is being linked, try again")
+		}
+		if err != nil {
+			return storageError(log, err, "Failed to link identity")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, log, l.audit, domain.AuditEvent{
+		Type:     domain.AuditIdentityLinked,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		TenantID: upstream.TenantID,
+		Metadata: map[string]string{"provider": upstream.Provider, "issuer": upstream.Issuer, "subject": upstream.Subject},
+	})
+	return user, nil
+}
+
+// reaches reports whether the provider may link or sign up email.
+func (l accountLinker) reaches(upstream upstreamAccount, email string) bool {
+	return upstream.Owner == "" || l.domains.Owns(upstream.Owner, email)
+}
+
+// signUp registers the upstream email with an unusable random password; the
+// user can set a real one later through password reset.
+func (l accountLinker) signUp(ctx context.Context, log *slog.Logger, upstream upstreamAccount) (*domain.User, error) {
+	password, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.register.Handle(ctx, RegisterUserCmd{Email: upstream.Email, Password: password, Locale: upstream.Locale, TenantID: upstream.TenantID}); err != nil {
+		return nil, err
+	}
+	user, err := l.users.FindByEmail(ctx, upstream.Email)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	return user, nil
+}
@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+type SearchUsersResult struct {
+	Users      []domain.User
+	NextCursor *domain.UserCursor // nil when there are no more pages
+}
+
+// AdminUsersUseCase lets operators find and manage user accounts. Every
+// change is audited with the operator as actor.
+type AdminUsersUseCase struct {
+	log      *slog.Logger
+	tx       app.TxManager
+	users    domain.UserRepository
+	sessions domain.RefreshTokenRepository
+	// personalTokens are deleted along with sessions when an account is
+	// disabled, as when it is deprovisioned.
+	personalTokens domain.PersonalAccessTokenRepository
+	// memberships decide which tenants hear about changes to the user.
+	memberships domain.MembershipRepository
+	outbox      domain.OutboxRepository
+	audit       app.AuditLog
+	now         func() time.Time
+}
+
+// NewAdminUsersUseCase creates the use case. tx, memberships and outbox
+// may be nil; without an outbox no user events are published, and without
+// memberships they are published outside any tenant.
+func NewAdminUsersUseCase(log *slog.Logger, tx app.TxManager, users domain.UserRepository, sessions domain.RefreshTokenRepository,
+	personalTokens domain.PersonalAccessTokenRepository, memberships domain.MembershipRepository, outbox domain.OutboxRepository, audit app.AuditLog) *AdminUsersUseCase {
+	return &AdminUsersUseCase{
+		log:            log,
+		tx:             tx,
+		users:          users,
+		sessions:       sessions,
+		personalTokens: personalTokens,
+		memberships:    memberships,
+		outbox:         outbox,
+		audit:          audit,
+		now:            time.Now,
+	}
+}
+
+// Search returns a page of users matching filter, newest first.
+func (uc *AdminUsersUseCase) Search(ctx context.Context, filter domain.UserFilter) (*SearchUsersResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit > maxUserPageSize {
+		filter.Limit = maxUserPageSize
+	}
+	users, err := uc.users.Search(ctx, filter)
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "SearchUsers"), err, "Failed to search users")
+	}
+	res := &SearchUsersResult{Users: users}
+	if len(users) == filter.Limit {
+		last := users[len(users)-1]
+		res.NextCursor = &domain.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return res, nil
+}
+
+func (uc *AdminUsersUseCase) Get(ctx context.Context, id string) (*domain.User, error) {
+	user, err := uc.users.FindByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "GetUser"), err, "Failed to load user")
+	}
+	return user, nil
+}
+
+// SetDisabled disables or re-enables the account. Disabling also ends the
+// user's sessions and deletes its personal access tokens; access tokens
+// already issued stay valid until they expire.
+func (uc *AdminUsersUseCase) SetDisabled(ctx context.Context, actorID, id string, disabled bool) (*domain.User, error) {
+	log := uc.log.With("op", "SetUserDisabled", "user_id", id, "disabled", disabled)
+	if disabled && id == actorID {
+		return nil, app.NewError(app.ErrCodeValidation, "You cannot disable your own account")
+	}
+	var user *domain.User
+	changed := false
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if user, err = uc.Get(ctx, id); err != nil {
+			return err
+		}
+		if user.Disabled() == disabled {
+			return nil
+		}
+		now := uc.now().UTC()
+		user.DisabledAt, user.UpdatedAt = nil, now
+		if disabled {
+			user.DisabledAt = &now
+		}
+		if err := uc.users.UpdateStatus(ctx, user); err != nil {
+			return storageError(log, err, "Failed to update user")
+		}
+		changed = true
+		if !disabled {
+			return nil
+		}
+		if err := uc.sessions.RevokeAllByUser(ctx, id); err != nil {
+			return storageError(log, err, "Failed to revoke sessions")
+		}
+		if err := uc.personalTokens.DeleteAllByUser(ctx, id); err != nil {
+			return storageError(log, err, "Failed to revoke personal access tokens")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		eventType := domain.AuditUserEnabled
+		if disabled {
+			eventType = domain.AuditUserDisabled
+		}
+		uc.record(ctx, log, eventType, actorID, user)
+		log.Info("user status changed")
+	}
+	return user, nil
+}
+
+// Logout ends all of the user's sessions. Access tokens already issued stay
+// valid until they expire.
+func (uc *AdminUsersUseCase) Logout(ctx context.Context, actorID, id string) error {
+	log := uc.log.With("op", "ForceLogout", "user_id", id)
+	user, err := uc.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := uc.sessions.RevokeAllByUser(ctx, id); err != nil {
+		return storageError(log, err, "Failed to revoke sessions")
+	}
+	uc.record(ctx, log, domain.AuditUserSessionsRevoked, actorID, user)
+	log.Info("user sessions revoked")
+	return nil
+}
+
+// VerifyEmail marks the user's email as verified and publishes
+// user.email_verified. Verifying a verified user changes nothing.
+func (uc *AdminUsersUseCase) VerifyEmail(ctx context.Context, actorID, id string) (*domain.User, error) {
+	log := uc.log.With("op", "VerifyUserEmail", "user_id", id)
+	var user *domain.User
+	changed := false
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if user, err = uc.Get(ctx, id); err != nil {
+			return err
+		}
+		if user.IsVerified {
+			return nil
+		}
+		user.IsVerified, user.UpdatedAt = true, uc.now().UTC()
+		if err := uc.users.UpdateStatus(ctx, user); err != nil {
+			return storageError(log, err, "Failed to update user")
+		}
+		changed = true
+		return uc.publish(ctx, log, domain.EventUserEmailVerified, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		uc.record(ctx, log, domain.AuditUserEmailVerified, actorID, user)
+		log.Info("user email verified by admin")
+	}
+	return user, nil
+}
+
+// Delete removes the account for good and publishes user.deleted.
+func (uc *AdminUsersUseCase) Delete(ctx context.Context, actorID, id string) error {
+	log := uc.log.With("op", "DeleteUser", "user_id", id)
+	if id == actorID {
+		return app.NewError(app.ErrCodeValidation, "You cannot delete your own account")
+	}
+	var user *domain.User
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if user, err = uc.Get(ctx, id); err != nil {
+			return err
+		}
+		// SQL backends cascade these, but not every backend does.
+		if err := uc.sessions.RevokeAllByUser(ctx, id); err != nil {
+			return storageError(log, err, "Failed to revoke sessions")
+		}
+		if err := uc.personalTokens.DeleteAllByUser(ctx, id); err != nil {
+			return storageError(log, err, "Failed to revoke personal access tokens")
+		}
+		// Publish first: the memberships go with the user.
+		if err := uc.publish(ctx, log, domain.EventUserDeleted, user); err != nil {
+			return err
+		}
+		if err := uc.users.Delete(ctx, id); errors.Is(err, domain.ErrNotFound) {
+			return app.NewError(app.ErrCodeNotFound, "User not found")
+		} else if err != nil {
+			return storageError(log, err, "Failed to delete user")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	uc.record(ctx, log, domain.AuditUserDeleted, actorID, user)
+	log.Info("user deleted by admin")
+	return nil
+}
+
+// publish enqueues one event per tenant the user belongs to, so that each
+// tenant's subscriptions hear about it, or a single event outside any
+// tenant for users without memberships.
+func (uc *AdminUsersUseCase) publish(ctx context.Context, log *slog.Logger, eventType string, user *domain.User) error {
+	if uc.outbox == nil {
+		return nil
+	}
+	tenants := []string{""}
+	if uc.memberships != nil {
+		memberships, err := uc.memberships.ListByUser(ctx, user.ID)
+		if err != nil {
+			return storageError(log, err, "Failed to load memberships")
+		}
+		if len(memberships) > 0 {
+			tenants = tenants[:0]
+			for _, m := range memberships {
+				tenants = append(tenants, m.TenantID)
+			}
+		}
+	}
+	for _, tenantID := range tenants {
+		if err := uc.outbox.Enqueue(ctx, domain.NewUserEvent(eventType, tenantID, user)); err != nil {
+			return storageError(log, err, "Failed to enqueue user event")
+		}
+	}
+	return nil
+}
+
+func (uc *AdminUsersUseCase) record(ctx context.Context, log *slog.Logger, eventType domain.AuditEventType, actorID string, user *domain.User) {
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     eventType,
+		ActorID:  actorID,
+		TargetID: user.ID,
+		Metadata: map[string]string{"email": user.Email},
+	})
+}
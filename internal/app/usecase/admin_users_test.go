@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// adminUsers returns the admin user management use case over the fixture.
+func (f *fixture) adminUsers() *AdminUsersUseCase {
+	return NewAdminUsersUseCase(f.log, f.tx, f.users, f.sessions, f.pats, f.members, f.outbox, f.audit)
+}
+
+func TestAdminUsers_DisableBlocksLoginAndRefresh(t *testing.T) {
+	f := newFixture()
+	uc := f.adminUsers()
+	refresh := NewRefreshUseCase(f.log, nil, sessionTokens{}, f.sessions, f.users, nil)
+	pats := NewPersonalTokensUseCase(f.log, f.pats, nil)
+	ctx := context.Background()
+	u := f.user(t, "u@ex.com", "p")
+	session, err := f.login.Handle(ctx, LoginUserCmd{Email: u.Email, Password: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pats.Create(ctx, u.ID, CreatePersonalTokenCmd{Name: "ci", Scopes: []string{domain.ScopeUserRead}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := uc.SetDisabled(ctx, "admin", "admin", true); !isCode(err, app.ErrCodeValidation) {
+		t.Fatalf("disable self: %v", err)
+	}
+	got, err := uc.SetDisabled(ctx, "admin", u.ID, true)
+	if err != nil || got.DisabledAt == nil {
+		t.Fatalf("disable = %+v, %v", got, err)
+	}
+	if last := f.audit.last(); last.Type != domain.AuditUserDisabled || last.ActorID != "admin" || last.TargetID != u.ID {
+		t.Fatalf("audit = %+v", last)
+	}
+	for _, tc := range []struct {
+		name     string
+		signIn   func() (*LoginUserResult, error)
+		wantCode string
+	}{
+		{"password", func() (*LoginUserResult, error) {
+			return f.login.Handle(ctx, LoginUserCmd{Email: u.Email, Password: "p"})
+		}, app.ErrCodeAccountDisabled},
+		// A wrong password must not reveal the state.
+		{"wrong password", func() (*LoginUserResult, error) {
+			return f.login.Handle(ctx, LoginUserCmd{Email: u.Email, Password: "wrong"})
+		}, app.ErrCodeInvalidCredentials},
+		{"passwordless", func() (*LoginUserResult, error) {
+			return f.login.IssueTokens(ctx, got, "magic_link")
+		}, app.ErrCodeAccountDisabled},
+		{"refresh of the revoked session", func() (*LoginUserResult, error) {
+			return refresh.Handle(ctx, RefreshCmd{RefreshToken: session.RefreshToken})
+		}, app.ErrCodeInvalidCredentials},
+	} {
+		if _, err := tc.signIn(); !isCode(err, tc.wantCode) {
+			t.Fatalf("%s while disabled: got %v, want %s", tc.name, err, tc.wantCode)
+		}
+	}
+	if tokens, _ := pats.List(ctx, u.ID); len(tokens) != 0 {
+		t.Fatalf("personal tokens survived disabling: %+v", tokens)
+	}
+
+	if _, err := uc.SetDisabled(ctx, "admin", u.ID, false); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	session, err = f.login.Handle(ctx, LoginUserCmd{Email: u.Email, Password: "p"})
+	if err != nil {
+		t.Fatalf("login after enabling: %v", err)
+	}
+	// Disabling straight in storage leaves the session in place; refresh
+	// must still refuse it.
+	now := time.Now()
+	got.DisabledAt = &now
+	if err := f.users.UpdateStatus(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := refresh.Handle(ctx, RefreshCmd{RefreshToken: session.RefreshToken}); !isCode(err, app.ErrCodeAccountDisabled) {
+		t.Fatalf("refresh while disabled: %v", err)
+	}
+}
+
+func TestAdminUsers_Search(t *testing.T) {
+	f := newFixture()
+	uc := f.adminUsers()
+	ctx := context.Background()
+	var ids []string
+	for _, email := range []string{"ann@ex.com", "bob@ex.com", "anna@ex.com"} {
+		ids = append(ids, f.user(t, email, "p").ID)
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := uc.VerifyEmail(ctx, "admin", ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	verified := true
+	first, err := uc.Search(ctx, domain.UserFilter{EmailPrefix: "AN", Limit: 1})
+	if err != nil || first.NextCursor == nil {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+	// Newest first; the cursor continues where the previous page ended.
+	for _, tc := range []struct {
+		name   string
+		filter domain.UserFilter
+		want   []string
+	}{
+		{"first page by email prefix", domain.UserFilter{EmailPrefix: "AN", Limit: 1}, []string{ids[2]}},
+		{"second page by email prefix", domain.UserFilter{EmailPrefix: "AN", Limit: 1, Before: first.NextCursor}, []string{ids[0]}},
+		{"verified", domain.UserFilter{Verified: &verified}, []string{ids[1]}},
+		{"everyone", domain.UserFilter{}, []string{ids[2], ids[1], ids[0]}},
+	} {
+		res, err := uc.Search(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var got []string
+		for _, u := range res.Users {
+			got = append(got, u.ID)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("%s = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAdminUsers_VerifyLogoutDelete(t *testing.T) {
+	f := newFixture()
+	uc := f.adminUsers()
+	refresh := NewRefreshUseCase(f.log, nil, sessionTokens{}, f.sessions, f.users, nil)
+	ctx := context.Background()
+	bob := f.user(t, "bob@ex.com", "p")
+
+	u, err := uc.VerifyEmail(ctx, "admin", bob.ID)
+	if err != nil || !u.IsVerified {
+		t.Fatalf("verify = %+v, %v", u, err)
+	}
+	if _, err := uc.VerifyEmail(ctx, "admin", bob.ID); err != nil || len(*f.outbox) != 1 || (*f.outbox)[0].Type != domain.EventUserEmailVerified {
+		t.Fatalf("verify twice: %v, outbox %+v", err, *f.outbox)
+	}
+
+	session, err := f.login.Handle(ctx, LoginUserCmd{Email: "bob@ex.com", Password: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.Logout(ctx, "admin", bob.ID); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := refresh.Handle(ctx, RefreshCmd{RefreshToken: session.RefreshToken}); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("refresh after forced logout: %v", err)
+	}
+	if err := uc.Logout(ctx, "admin", "missing"); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("logout missing: %v", err)
+	}
+
+	if err := uc.Delete(ctx, "admin", "admin"); !isCode(err, app.ErrCodeValidation) {
+		t.Fatalf("delete self: %v", err)
+	}
+	for _, tenant := range []string{"acme", "globex"} {
+		if err := f.members.Create(ctx, &domain.Membership{TenantID: tenant, UserID: bob.ID, UserName: "bob", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := uc.Delete(ctx, "admin", bob.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := uc.Get(ctx, bob.ID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("get deleted: %v", err)
+	}
+	// One event per tenant the user belonged to.
+	var tenants []string
+	for _, e := range (*f.outbox)[1:] {
+		if e.Type != domain.EventUserDeleted || e.AggregateID != bob.ID {
+			t.Fatalf("outbox = %+v", e)
+		}
+		tenants = append(tenants, e.TenantID)
+	}
+	if slices.Sort(tenants); !slices.Equal(tenants, []string{"acme", "globex"}) {
+		t.Fatalf("user.deleted tenants = %v", tenants)
+	}
+	if last := f.audit.last(); last.Type != domain.AuditUserDeleted || last.Metadata["email"] != "bob@ex.com" {
+		t.Fatalf("audit = %+v", last)
+	}
+	if err := uc.Delete(ctx, "admin", bob.ID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("delete twice: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// recordAudit sends event to audit if configured. Audit failures are logged
+// but never fail the operation being audited.
+func recordAudit(ctx context.Context, log *slog.Logger, audit app.AuditLog, event domain.AuditEvent) {
+	if audit == nil {
+		return
+	}
+	if err := audit.Record(ctx, event); err != nil {
+		log.Error("failed to record audit event", "type", event.Type, "error", err)
+	}
+}
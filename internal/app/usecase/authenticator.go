@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// PasswordAuthenticator checks the password hash stored with the user.
+type PasswordAuthenticator struct {
+	users domain.UserRepository
+	pwd   app.PasswordService
+}
+
+func NewPasswordAuthenticator(users domain.UserRepository, pwd app.PasswordService) *PasswordAuthenticator {
+	return &PasswordAuthenticator{users: users, pwd: pwd}
+}
+
+func (a *PasswordAuthenticator) Method() string { return "password" }
+
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, creds app.Credentials) (*domain.User, error) {
+	user, err := a.users.FindByEmail(ctx, creds.Email)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, &app.CredentialsError{Reason: "unknown_email"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := a.pwd.Compare(user.Password, creds.Password); err != nil {
+		return nil, &app.CredentialsError{UserID: user.ID, Reason: "invalid_password"}
+	}
+	return user, nil
+}
+
+// DirectoryAuthenticator binds to an external directory and provisions a
+// local user the first time a directory account signs in. Each directory
+// account is linked to its user as an identity keyed by the directory URL
+// and the entry's DN, so later logins never go by email.
+type DirectoryAuthenticator struct {
+	log        *slog.Logger
+	method     string
+	dir        app.Directory
+	issuer     string
+	domains    map[string]bool
+	tx         app.TxManager
+	identities domain.IdentityRepository
+	users      domain.UserRepository
+	register   *RegisterUserUseCase
+}
+
+// NewDirectoryAuthenticator creates an authenticator for dir; method names
+// it in audit events, e.g. "ldap", and issuer is the directory URL. The
+// directory may take over or provision local accounts only in domains, the
+// email domains routed to it.
+func NewDirectoryAuthenticator(log *slog.Logger, method string, dir app.Directory, issuer string, domains []string, tx app.TxManager, identities domain.IdentityRepository, users domain.UserRepository, register *RegisterUserUseCase) *DirectoryAuthenticator {
+	owned := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		owned[strings.ToLower(d)] = true
+	}
+	return &DirectoryAuthenticator{log: log, method: method, dir: dir, issuer: issuer, domains: owned, tx: tx, identities: identities, users: users, register: register}
+}
+
+func (a *DirectoryAuthenticator) Method() string { return a.method }
+
+func (a *DirectoryAuthenticator) Authenticate(ctx context.Context, creds app.Credentials) (*domain.User, error) {
+	entry, err := a.dir.Bind(ctx, creds.Email, creds.Password)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := a.identities.FindBySubject(ctx, a.issuer, entry.DN)
+	if err == nil {
+		return a.users.FindByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	email := strings.ToLower(entry.Email)
+	if email == "" {
+		email = strings.ToLower(creds.Email)
+	}
+	var user *domain.User
+	err = withinTx(ctx, a.tx, func(ctx context.Context) error {
+		user, err = a.users.FindByEmail(ctx, email)
+		switch {
+		case err == nil:
+			if !a.owns(email) {
+				a.log.Warn("directory entry matches an account outside the directory's domains", "method", a.method, "dn", entry.DN, "user_id", user.ID)
+				return &app.CredentialsError{UserID: user.ID, Reason: "unlinked_account"}
+			}
+		case !errors.Is(err, domain.ErrNotFound):
+			return err
+		case !a.owns(email):
+			a.log.Warn("directory entry has an email outside the directory's domains", "method", a.method, "dn", entry.DN)
+			return &app.CredentialsError{Reason: "foreign_email"}
+		default:
+			if user, err = a.provision(ctx, email, entry); err != nil {
+				return err
+			}
+		}
+		err := a.identities.Create(ctx, &domain.Identity{UserID: user.ID, Provider: a.issuer, Subject: entry.DN, Email: email})
+		if errors.Is(err, domain.ErrConflict) {
+			// A concurrent login linked the entry first; retry as that login.
+			return app.NewError(app.ErrCodeConflict, "Identity is being linked, try again")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.log.Info("linked directory account", "method", a.method, "user_id", user.ID, "dn", entry.DN)
+	return user, nil
+}
+
+func (a *DirectoryAuthenticator) owns(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	return at >= 0 && a.domains[email[at+1:]]
+}
+
+// provision registers the directory account with an unusable random
+// password, so it can only sign in through the directory.
+func (a *DirectoryAuthenticator) provision(ctx context.Context, email string, entry *app.DirectoryEntry) (*domain.User, error) {
+	password, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.register.Handle(ctx, RegisterUserCmd{Email: email, Password: password, Locale: entry.Locale}); err != nil {
+		return nil, err
+	}
+	a.log.Info("provisioned directory user", "method", a.method, "email", email, "dn", entry.DN)
+	return a.users.FindByEmail(ctx, email)
+}
+
+// AuthenticatorRoutes picks the authenticator for a login: the tenant's,
+// else the one for the email domain, else Default.
+type AuthenticatorRoutes struct {
+	Default app.Authenticator
+	Tenants map[string]app.Authenticator
+	Domains map[string]app.Authenticator // keyed by lower-case domain
+}
+
+func (r AuthenticatorRoutes) For(creds app.Credentials) app.Authenticator {
+	if a, ok := r.Tenants[creds.TenantID]; ok && creds.TenantID != "" {
+		return a
+	}
+	if i := strings.LastIndexByte(creds.Email, '@'); i >= 0 {
+		if a, ok := r.Domains[strings.ToLower(creds.Email[i+1:])]; ok {
+			return a
+		}
+	}
+	return r.Default
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/ldap"
+	"go-auth/internal/infrastructure/ldap/ldaptest"
+)
+
+// directory routes the fixture's logins for tenant acme and the domain
+// corp.example to an LDAP directory, next to two local accounts with the
+// password "local-pw": one in the directory's domain and one outside it.
+func (f *fixture) directory(t *testing.T) {
+	t.Helper()
+	srv := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=svc,dc=corp", Password: "svc"},
+		ldaptest.Entry{DN: "uid=alice,ou=people,dc=corp", Password: "directory-pw", Attributes: map[string][]string{
+			"uid":               {"alice"},
+			"mail":              {"alice@corp.example"},
+			"preferredLanguage": {"ru"},
+		}},
+		ldaptest.Entry{DN: "uid=bob,ou=people,dc=corp", Password: "bob-pw", Attributes: map[string][]string{
+			"uid":  {"bob"},
+			"mail": {"bob@corp.example"},
+		}},
+		ldaptest.Entry{DN: "uid=mallory,ou=people,dc=corp", Password: "mallory-pw", Attributes: map[string][]string{
+			"uid":  {"mallory"},
+			"mail": {"local@ex.com"},
+		}},
+		ldaptest.Entry{DN: "uid=eve,ou=people,dc=corp", Password: "eve-pw", Attributes: map[string][]string{
+			"uid":  {"eve"},
+			"mail": {"eve@globex.example"},
+		}},
+	)
+	corp := NewDirectoryAuthenticator(f.log, "ldap", ldap.NewDirectory(ldap.Config{
+		URL:          srv.URL,
+		BindDN:       "cn=svc,dc=corp",
+		BindPassword: "svc",
+		BaseDN:       "ou=people,dc=corp",
+		Filter:       "(uid={username})",
+		Attributes:   ldap.Attributes{Locale: "preferredLanguage"},
+	}), srv.URL, []string{"corp.example"}, f.tx, f.identities, f.users, f.register)
+	f.login.UseAuthenticators(AuthenticatorRoutes{
+		Tenants: map[string]app.Authenticator{"acme": corp},
+		Domains: map[string]app.Authenticator{"corp.example": corp},
+	})
+	f.user(t, "local@ex.com", "local-pw")
+	f.user(t, "bob@corp.example", "local-pw")
+}
+
+func TestDirectoryLogin(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		cmd  LoginUserCmd
+		// account signed in to; empty when the login must fail
+		wantEmail string
+		// audited method on success, reason on failure
+		wantMethod, wantReason string
+		// account a failure is audited against, if any
+		wantTarget string
+		// directory entry linked to the account, if any
+		wantLink string
+	}{
+		{
+			name:       "first login provisions the account",
+			cmd:        LoginUserCmd{Email: "alice@corp.example", Password: "directory-pw"},
+			wantEmail:  "alice@corp.example",
+			wantMethod: "ldap",
+			wantLink:   "uid=alice,ou=people,dc=corp",
+		},
+		{
+			// Only the directory decides; no local password is asked.
+			name:       "wrong directory password",
+			cmd:        LoginUserCmd{Email: "alice@corp.example", Password: "wrong"},
+			wantReason: "invalid_password",
+		},
+		{
+			name:       "password login outside the directory's domain",
+			cmd:        LoginUserCmd{Email: "local@ex.com", Password: "local-pw"},
+			wantEmail:  "local@ex.com",
+			wantMethod: "password",
+		},
+		{
+			// The tenant routes to the directory even for other email domains.
+			name:       "tenant login with a local password",
+			cmd:        LoginUserCmd{TenantID: "acme", Email: "local@ex.com", Password: "local-pw"},
+			wantReason: "unknown_email",
+		},
+		{
+			name:       "tenant login with directory credentials",
+			cmd:        LoginUserCmd{TenantID: "acme", Email: "alice@other.example", Password: "directory-pw"},
+			wantEmail:  "alice@corp.example",
+			wantMethod: "ldap",
+			wantLink:   "uid=alice,ou=people,dc=corp",
+		},
+		{
+			// A directory entry cannot sign in as a local account outside its domains.
+			name:       "entry matching a foreign account",
+			cmd:        LoginUserCmd{TenantID: "acme", Email: "mallory", Password: "mallory-pw"},
+			wantReason: "unlinked_account",
+			wantTarget: "local@ex.com",
+		},
+		{
+			// Nor can it claim an unused address outside its domains.
+			name:       "entry with an unused foreign mail",
+			cmd:        LoginUserCmd{TenantID: "acme", Email: "eve", Password: "eve-pw"},
+			wantReason: "foreign_email",
+		},
+		{
+			name:       "existing account in the directory's domain",
+			cmd:        LoginUserCmd{Email: "bob@corp.example", Password: "bob-pw"},
+			wantEmail:  "bob@corp.example",
+			wantMethod: "ldap",
+			wantLink:   "uid=bob,ou=people,dc=corp",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			f.directory(t)
+
+			res, err := f.login.Handle(ctx, tc.cmd)
+			last := f.audit.last()
+			if tc.wantEmail == "" {
+				if !isCode(err, app.ErrCodeInvalidCredentials) {
+					t.Fatalf("login: %v", err)
+				}
+				target := ""
+				if tc.wantTarget != "" {
+					u, _ := f.users.FindByEmail(ctx, tc.wantTarget)
+					target = u.ID
+				}
+				if last.Type != domain.AuditLoginFailed || last.Metadata["reason"] != tc.wantReason || last.TargetID != target {
+					t.Fatalf("audit = %+v", last)
+				}
+				// Nothing was provisioned or linked on the way.
+				for _, email := range []string{"local@ex.com", "bob@corp.example"} {
+					u, _ := f.users.FindByEmail(ctx, email)
+					if ids, _ := f.identities.ListByUser(ctx, u.ID); len(ids) != 0 {
+						t.Fatalf("%s linked: %+v", email, ids)
+					}
+				}
+				if _, err := f.users.FindByEmail(ctx, "eve@globex.example"); err == nil {
+					t.Fatal("account provisioned outside the directory's domains")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+			user, err := f.users.FindByEmail(ctx, tc.wantEmail)
+			if err != nil || res.AccessToken != "acc:"+user.ID {
+				t.Fatalf("user = %+v, %v; token %q", user, err, res.AccessToken)
+			}
+			if last.Type != domain.AuditLoginSucceeded || last.TargetID != user.ID || last.Metadata["method"] != tc.wantMethod {
+				t.Fatalf("audit = %+v", last)
+			}
+			ids, _ := f.identities.ListByUser(ctx, user.ID)
+			if tc.wantLink == "" && len(ids) != 0 || tc.wantLink != "" && (len(ids) != 1 || ids[0].Subject != tc.wantLink) {
+				t.Fatalf("identities = %+v", ids)
+			}
+			if tc.wantLink == "" {
+				return
+			}
+
+			// The next login reuses the linked account.
+			res, err = f.login.Handle(ctx, tc.cmd)
+			if err != nil || res.AccessToken != "acc:"+user.ID {
+				t.Fatalf("second login: %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestDirectoryLogin_ProvisionedProfile(t *testing.T) {
+	f := newFixture()
+	f.directory(t)
+	ctx := context.Background()
+	if _, err := f.login.Handle(ctx, LoginUserCmd{Email: "alice@corp.example", Password: "directory-pw"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := f.users.FindByEmail(ctx, "alice@corp.example")
+	if err != nil || user.Locale != "ru" {
+		t.Fatalf("provisioned user = %+v, %v", user, err)
+	}
+}
@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"errors"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// storageError logs an unexpected repository failure and hides it behind an
+// AppError: ErrCodeUnavailable if the storage could not be reached, so that
+// clients may retry, ErrCodeInternal otherwise.
+func storageError(log *slog.Logger, err error, msg string) error {
+	log.Error(msg, "error", err)
+	if errors.Is(err, domain.ErrUnavailable) {
+		return app.NewError(app.ErrCodeUnavailable, "Service temporarily unavailable")
+	}
+	return app.NewError(app.ErrCodeInternal, msg)
+}
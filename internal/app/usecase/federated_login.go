@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/security/tokenhash"
+)
+
+// FederatedLoginConfig configures sign-in through upstream OIDC providers.
+type FederatedLoginConfig struct {
+	Providers app.OIDCProviders
+	// RedirectURL is the page providers send the browser back to. It posts
+	// the code and state it receives to the callback endpoint.
+	RedirectURL string
+	// TTL bounds the time a user may spend at the provider. Default 10m.
+	TTL time.Duration
+	// Domains limits the accounts a tenant's own providers may link or sign
+	// up to those in the tenant's email domains.
+	Domains app.TenantDomains
+}
+
+// ProviderInfo is the public part of a provider's configuration.
+type ProviderInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type StartFederatedLoginCmd struct {
+	TenantID string
+	Provider string
+}
+
+// StartFederatedLoginResult carries the provider URL to send the browser to
+// and the binding the transport must tie to that browser; the callback only
+// succeeds together with it.
+type StartFederatedLoginResult struct {
+	AuthURL   string
+	Binding   string
+	ExpiresIn int64
+}
+
+type FederatedCallbackCmd struct {
+	Code    string
+	State   string
+	Binding string
+}
+
+// FederatedLoginUseCase signs users in through upstream OIDC providers and
+// links the upstream accounts to local users.
+type FederatedLoginUseCase struct {
+	accountLinker
+	log    *slog.Logger
+	cfg    FederatedLoginConfig
+	logins domain.FederatedLoginRepository
+	login  *LoginUserUseCase
+	client app.OIDCClient
+	now    func() time.Time
+}
+
+func NewFederatedLoginUseCase(
+	log *slog.Logger,
+	cfg FederatedLoginConfig,
+	tx app.TxManager,
+	logins domain.FederatedLoginRepository,
+	identities domain.IdentityRepository,
+	users domain.UserRepository,
+	register *RegisterUserUseCase,
+	login *LoginUserUseCase,
+	client app.OIDCClient,
+	audit app.AuditLog,
+) *FederatedLoginUseCase {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	return &FederatedLoginUseCase{
+		accountLinker: accountLinker{tx: tx, domains: cfg.Domains, identities: identities, users: users, register: register, audit: audit},
+		log:           log,
+		cfg:           cfg,
+		logins:        logins,
+		login:         login,
+		client:        client,
+		now:           time.Now,
+	}
+}
+
+// Providers lists the providers users of tenantID can sign in with.
+func (uc *FederatedLoginUseCase) Providers(tenantID string) []ProviderInfo {
+	providers := uc.cfg.Providers.For(tenantID)
+	out := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		name := p.Name
+		if name == "" {
+			name = p.ID
+		}
+		out = append(out, ProviderInfo{ID: p.ID, Name: name})
+	}
+	return out
+}
+
+// Start records a new authorization request and returns where to send the
+// browser. State, PKCE verifier and nonce are fresh for every attempt.
+func (uc *FederatedLoginUseCase) Start(ctx context.Context, cmd StartFederatedLoginCmd) (*StartFederatedLoginResult, error) {
+	log := uc.log.With("op", "StartFederatedLogin", "provider", cmd.Provider, "tenant_id", cmd.TenantID)
+	provider, ok := uc.cfg.Providers.Find(cmd.TenantID, cmd.Provider)
+	if !ok {
+		return nil, app.NewError(app.ErrCodeNotFound, "Unknown identity provider")
+	}
+
+	var secrets [4]string // state, binding, verifier, nonce
+	for i := range secrets {
+		token, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = token
+	}
+	state, binding, verifier, nonce := secrets[0], secrets[1], secrets[2], secrets[3]
+
+	authURL, err := uc.client.AuthCodeURL(ctx, provider, app.OIDCAuthRequest{
+		RedirectURL:  uc.cfg.RedirectURL,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		log.Error("identity provider discovery failed", "error", err)
+		return nil, app.NewError(app.ErrCodeUnavailable, "Identity provider is unavailable")
+	}
+
+	err = uc.logins.Create(ctx, &domain.FederatedLogin{
+		StateHash:    tokenhash.Hash(state),
+		BindingHash:  tokenhash.Hash(binding),
+		Provider:     provider.ID,
+		TenantID:     cmd.TenantID,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    uc.now().Add(uc.cfg.TTL).UTC(),
+	})
+	if err != nil {
+		return nil, storageError(log, err, "Failed to store login request")
+	}
+	return &StartFederatedLoginResult{AuthURL: authURL, Binding: binding, ExpiresIn: int64(uc.cfg.TTL.Seconds())}, nil
+}
+
+// Callback finishes a login the provider redirected back from and resolves
+// the upstream account to a user.
+func (uc *FederatedLoginUseCase) Callback(ctx context.Context, cmd FederatedCallbackCmd) (*LoginUserResult, error) {
+	log := uc.log.With("op", "FederatedLoginCallback")
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid or expired login request")
+	if cmd.Code == "" || cmd.State == "" || cmd.Binding == "" {
+		return nil, invalid
+	}
+	login, err := uc.logins.Consume(ctx, tokenhash.Hash(cmd.State), tokenhash.Hash(cmd.Binding), uc.now())
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Warn("invalid, expired or reused federated login state")
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to consume login request")
+	}
+	log = log.With("provider", login.Provider, "tenant_id", login.TenantID)
+	provider, ok := uc.cfg.Providers.Find(login.TenantID, login.Provider)
+	if !ok {
+		log.Warn("identity provider removed during login")
+		return nil, invalid
+	}
+
+	upstream, err := uc.client.Exchange(ctx, provider, app.OIDCExchange{
+		Code:         cmd.Code,
+		RedirectURL:  uc.cfg.RedirectURL,
+		CodeVerifier: login.CodeVerifier,
+		Nonce:        login.Nonce,
+	})
+	if errors.Is(err, app.ErrUpstreamRejected) {
+		log.Warn("identity provider rejected login", "error", err)
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Identity provider rejected the login")
+	}
+	if err != nil {
+		log.Error("identity provider unavailable", "error", err)
+		return nil, app.NewError(app.ErrCodeUnavailable, "Identity provider is unavailable")
+	}
+	log = log.With("subject", upstream.Subject)
+
+	user, err := uc.resolve(ctx, log, upstreamAccount{
+		Provider:      provider.ID,
+		Issuer:        upstream.Issuer,
+		Subject:       upstream.Subject,
+		Email:         upstream.Email,
+		EmailVerified: upstream.EmailVerified,
+		TenantID:      login.TenantID,
+		Owner:         uc.cfg.Providers.Owner(login.TenantID),
+		TrustEmail:    provider.TrustEmail,
+		AutoRegister:  provider.AutoRegister,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.login.IssueTokens(ctx, user, "oidc:"+provider.ID)
+}
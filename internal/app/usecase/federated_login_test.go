@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/oidc"
+	"go-auth/internal/infrastructure/oidc/oidctest"
+)
+
+// federated returns the OIDC login flow with acme's provider "fake" served
+// by the returned upstream.
+func (f *fixture) federated(t *testing.T, configure func(p *app.OIDCProvider)) (*FederatedLoginUseCase, *oidctest.Provider) {
+	t.Helper()
+	upstream := oidctest.NewProvider(t)
+	provider := upstream.Config("fake")
+	if configure != nil {
+		configure(&provider)
+	}
+	uc := NewFederatedLoginUseCase(f.log, FederatedLoginConfig{
+		Providers:   app.OIDCProviders{Tenants: map[string][]app.OIDCProvider{"acme": {provider}}},
+		RedirectURL: "https://app.example.com/oidc/callback",
+		Domains:     testDomains,
+	}, nil, memory.NewFederatedLoginRepository(), f.identities, f.users, f.register, f.login, oidc.NewClient(oidc.Options{}), nil)
+	return uc, upstream
+}
+
+// federatedSignIn runs the whole flow for user at the upstream provider.
+func federatedSignIn(t *testing.T, uc *FederatedLoginUseCase, upstream *oidctest.Provider, user oidctest.User) (*LoginUserResult, error) {
+	t.Helper()
+	upstream.SignIn(user)
+	start, err := uc.Start(context.Background(), StartFederatedLoginCmd{TenantID: "acme", Provider: "fake"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	code, state := upstream.Authorize(t, start.AuthURL)
+	return uc.Callback(context.Background(), FederatedCallbackCmd{Code: code, State: state, Binding: start.Binding})
+}
+
+func TestFederatedLogin_FirstLogin(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name         string
+		autoRegister bool
+		email        string
+		wantCode     string
+	}{
+		{"auto-registration", true, "alice@acme.com", ""},
+		{"auto-registration disabled", false, "alice@acme.com", app.ErrCodeForbidden},
+		// The tenant's provider cannot sign up addresses in another tenant's domain.
+		{"another tenant's domain", true, "ceo@globex.com", app.ErrCodeForbidden},
+		{"domain no tenant owns", true, "alice@ex.com", app.ErrCodeForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			uc, upstream := f.federated(t, func(p *app.OIDCProvider) { p.AutoRegister = tc.autoRegister })
+
+			res, err := federatedSignIn(t, uc, upstream, oidctest.User{Subject: "alice", Email: tc.email, EmailVerified: true})
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				if _, err := f.users.FindByEmail(ctx, tc.email); err == nil {
+					t.Fatal("account registered")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("first login: %v", err)
+			}
+			user, err := f.users.FindByEmail(ctx, tc.email)
+			if err != nil || res.AccessToken != "acc:"+user.ID {
+				t.Fatalf("user = %+v, %v; token %q", user, err, res.AccessToken)
+			}
+			linked, _ := f.identities.ListByUser(ctx, user.ID)
+			if len(linked) != 1 || linked[0].Provider != upstream.Issuer || linked[0].Subject != "alice" {
+				t.Fatalf("identities = %+v", linked)
+			}
+
+			// The link, not the email, identifies the user on the next login.
+			res, err = federatedSignIn(t, uc, upstream, oidctest.User{Subject: "alice", Email: "renamed@acme.com"})
+			if err != nil || res.AccessToken != "acc:"+user.ID {
+				t.Fatalf("second login: %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestFederatedLogin_ExistingEmail(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		email    string
+		trust    bool
+		verified bool
+		wantCode string
+	}{
+		{"untrusted provider", "bob@acme.com", false, true, app.ErrCodeEmailExists},
+		{"unverified email", "bob@acme.com", true, false, app.ErrCodeEmailExists},
+		{"account in another tenant's domain", "bob@globex.com", true, true, app.ErrCodeEmailExists},
+		{"account in a domain no tenant owns", "bob@ex.com", true, true, app.ErrCodeEmailExists},
+		{"trusted verified email", "bob@acme.com", true, true, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			bob := f.user(t, tc.email, "p")
+			uc, upstream := f.federated(t, func(p *app.OIDCProvider) { p.AutoRegister, p.TrustEmail = true, tc.trust })
+
+			res, err := federatedSignIn(t, uc, upstream, oidctest.User{Subject: "bob", Email: tc.email, EmailVerified: tc.verified})
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				return
+			}
+			if err != nil || res.AccessToken != "acc:"+bob.ID {
+				t.Fatalf("login = %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestFederatedLogin_Rejections(t *testing.T) {
+	f := newFixture()
+	uc, upstream := f.federated(t, nil)
+	ctx := context.Background()
+
+	if _, err := uc.Start(ctx, StartFederatedLoginCmd{TenantID: "globex", Provider: "fake"}); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("provider of another tenant: %v", err)
+	}
+
+	start, err := uc.Start(ctx, StartFederatedLoginCmd{TenantID: "acme", Provider: "fake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := upstream.Authorize(t, start.AuthURL)
+	// Callbacks run in order; the first one spends the state.
+	for _, tc := range []struct {
+		name string
+		cmd  FederatedCallbackCmd
+	}{
+		{"wrong binding", FederatedCallbackCmd{Code: code, State: state, Binding: "other-browser"}},
+		{"forged code", FederatedCallbackCmd{Code: "forged", State: state, Binding: start.Binding}},
+		{"reused state", FederatedCallbackCmd{Code: code, State: state, Binding: start.Binding}},
+	} {
+		if _, err := uc.Callback(ctx, tc.cmd); !isCode(err, app.ErrCodeInvalidCredentials) {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+	}
+}
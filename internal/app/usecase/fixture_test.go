@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/google/uuid"
+)
+
+type auditTrail []domain.AuditEvent
+
+func (a *auditTrail) Record(_ context.Context, e domain.AuditEvent) error {
+	*a = append(*a, e)
+	return nil
+}
+
+// last returns the most recent event; tests check it after each action.
+func (a *auditTrail) last() domain.AuditEvent {
+	if len(*a) == 0 {
+		return domain.AuditEvent{}
+	}
+	return (*a)[len(*a)-1]
+}
+
+type outboxTrail []*domain.OutboxEvent
+
+func (o *outboxTrail) Enqueue(_ context.Context, e *domain.OutboxEvent) error {
+	*o = append(*o, e)
+	return nil
+}
+
+// sessionTokens mints distinct refresh tokens and resolves them to the
+// user they were minted for.
+type sessionTokens struct{ fakeToken }
+
+func (sessionTokens) GenerateRefreshToken(userID string) (string, error) {
+	return "ref:" + userID + ":" + uuid.NewString(), nil
+}
+
+func (sessionTokens) ValidateRefresh(token string) (string, error) {
+	uid, _, _ := strings.Cut(strings.TrimPrefix(token, "ref:"), ":")
+	return uid, nil
+}
+
+// testDomains splits email domains between the two tenants the tests use;
+// ex.com belongs to neither.
+var testDomains = app.TenantDomains{"acme.com": "acme", "globex.com": "globex"}
+
+// fixture is the in-memory service the use-case tests run against: the
+// repositories, registration and login wired to them, and trails of the
+// audit events, outbox events, emails and texts they produced.
+type fixture struct {
+	log        *slog.Logger
+	tx         *memory.TxManager
+	users      *memory.UserRepository
+	identities *memory.IdentityRepository
+	sessions   *memory.RefreshRepository
+	members    *memory.MembershipRepository
+	roles      *memory.RoleRepository
+	pats       *memory.PersonalAccessTokenRepository
+	mail       *memory.Mailbox
+	sms        *memory.SMSOutbox
+	audit      *auditTrail
+	outbox     *outboxTrail
+	register   *RegisterUserUseCase
+	login      *LoginUserUseCase
+}
+
+func newFixture() *fixture {
+	members, roles := memory.NewTenantRepositories()
+	f := &fixture{
+		log:        slog.New(slog.NewTextHandler(testWriter{}, nil)),
+		tx:         memory.NewTxManager(),
+		users:      memory.NewUserRepository(),
+		identities: memory.NewIdentityRepository(),
+		sessions:   memory.NewRefreshRepository(),
+		members:    members,
+		roles:      roles,
+		pats:       memory.NewPersonalAccessTokenRepository(),
+		mail:       memory.NewMailbox(),
+		sms:        memory.NewSMSOutbox(),
+		audit:      &auditTrail{},
+		outbox:     &outboxTrail{},
+	}
+	f.register = NewRegisterUserUseCase(f.log, f.tx, f.users, f.outbox, &fakePwd{}, f.audit)
+	f.login = NewLoginUserUseCase(f.log, f.users, &fakePwd{}, sessionTokens{}, f.sessions, nil, f.audit)
+	return f
+}
+
+// user stores an account with the given password, bypassing registration
+// so that the trails only show what the test itself does.
+func (f *fixture) user(t *testing.T, email, password string) *domain.User {
+	t.Helper()
+	u := domain.NewUser(email, "hash:"+password)
+	if err := f.users.Create(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// magicLink returns the magic-link flow sending to the fixture's mailbox.
+func (f *fixture) magicLink(autoRegister ...string) *MagicLinkUseCase {
+	return NewMagicLinkUseCase(f.log, MagicLinkConfig{
+		TTL:                 10 * time.Minute,
+		URL:                 "https://app.example.com/magic",
+		AutoRegisterTenants: autoRegister,
+		Domains:             testDomains,
+	}, memory.NewMagicLinkRepository(), f.users, f.register, f.login, f.mail, app.NewMailTemplates(app.Branding{ProductName: "Acme"}, nil), nil)
+}
+
+// linkToken pulls the token out of the link in the last email to addr.
+func (f *fixture) linkToken(t *testing.T, addr string) string {
+	t.Helper()
+	msg, ok := f.mail.Last(addr)
+	if !ok {
+		t.Fatalf("no email to %s", addr)
+	}
+	i := strings.Index(msg.Text, "https://app.example.com/magic?")
+	if i < 0 {
+		t.Fatalf("no link in %q", msg.Text)
+	}
+	raw, _, _ := strings.Cut(msg.Text[i:], "\n")
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("token")
+}
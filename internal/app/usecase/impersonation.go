@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// expiredImpersonationBatch bounds how many expired impersonations one sweep
+// ends.
+const expiredImpersonationBatch = 100
+
+type StartImpersonationCmd struct {
+	ActorID string // the admin
+	UserID  string
+	Reason  string
+}
+
+// ImpersonationResult holds the access token of a new impersonation. There
+// is no refresh token; the admin starts a new impersonation when it expires.
+type ImpersonationResult struct {
+	Impersonation *domain.Impersonation
+	AccessToken   string
+	ExpiresIn     int // seconds
+}
+
+// ImpersonationUseCase lets admins act as a user to see what the user sees.
+// Starting and ending are audited with the admin as actor; events caused
+// with the token carry the admin as impersonator_id.
+type ImpersonationUseCase struct {
+	log    *slog.Logger
+	ttl    time.Duration
+	users  domain.UserRepository
+	repo   domain.ImpersonationRepository
+	tokens app.ImpersonationTokens
+	audit  app.AuditLog
+	now    func() time.Time
+}
+
+func NewImpersonationUseCase(log *slog.Logger, ttl time.Duration, users domain.UserRepository, repo domain.ImpersonationRepository,
+	tokens app.ImpersonationTokens, audit app.AuditLog) *ImpersonationUseCase {
+	return &ImpersonationUseCase{log: log, ttl: ttl, users: users, repo: repo, tokens: tokens, audit: audit, now: time.Now}
+}
+
+// Start opens an impersonation of cmd.UserID and mints its access token.
+func (uc *ImpersonationUseCase) Start(ctx context.Context, cmd StartImpersonationCmd) (*ImpersonationResult, error) {
+	log := uc.log.With("op", "StartImpersonation", "actor_id", cmd.ActorID, "user_id", cmd.UserID)
+	if cmd.UserID == cmd.ActorID {
+		return nil, app.NewError(app.ErrCodeValidation, "You cannot impersonate yourself")
+	}
+	user, err := uc.users.FindByID(ctx, cmd.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to load user")
+	}
+	if user.Disabled() {
+		return nil, app.NewError(app.ErrCodeAccountDisabled, "Account is disabled")
+	}
+
+	now := uc.now().UTC()
+	// JWT expiry has second precision; the record expires with the token.
+	imp := &domain.Impersonation{
+		ActorID:   cmd.ActorID,
+		UserID:    user.ID,
+		Reason:    cmd.Reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(uc.ttl).Truncate(time.Second),
+	}
+	if err := uc.repo.Create(ctx, imp); err != nil {
+		return nil, storageError(log, err, "Failed to start impersonation")
+	}
+	token, err := uc.tokens.GenerateImpersonationToken(app.ImpersonationClaims{
+		ImpersonationID: imp.ID,
+		UserID:          imp.UserID,
+		ActorID:         imp.ActorID,
+		ExpiresAt:       imp.ExpiresAt,
+	})
+	if err != nil {
+		return nil, app.NewError(app.ErrCodeInternal, "Failed to generate access token")
+	}
+
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditImpersonationStarted,
+		ActorID:  imp.ActorID,
+		TargetID: imp.UserID,
+		Metadata: map[string]string{
+			"impersonation_id": imp.ID,
+			"email":            user.Email,
+			"reason":           imp.Reason,
+			"expires_at":       imp.ExpiresAt.Format(time.RFC3339),
+		},
+	})
+	log.Info("impersonation started", "impersonation_id", imp.ID)
+	return &ImpersonationResult{
+		Impersonation: imp,
+		AccessToken:   token,
+		ExpiresIn:     int(imp.ExpiresAt.Sub(now).Seconds()),
+	}, nil
+}
+
+// Authenticate resolves an impersonation token to its impersonation, which
+// must not have expired or been ended.
+func (uc *ImpersonationUseCase) Authenticate(ctx context.Context, token string) (*domain.Impersonation, error) {
+	unauthorized := app.NewError(app.ErrCodeUnauthorized, "Unauthorized")
+	claims, err := uc.tokens.ValidateImpersonationToken(token)
+	if err != nil {
+		return nil, unauthorized
+	}
+	imp, err := uc.repo.FindByID(ctx, claims.ImpersonationID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, unauthorized
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "AuthenticateImpersonation"), err, "Failed to load impersonation")
+	}
+	if imp.UserID != claims.UserID || imp.ActorID != claims.ActorID || !imp.Active(uc.now()) {
+		return nil, unauthorized
+	}
+	return imp, nil
+}
+
+func (uc *ImpersonationUseCase) Get(ctx context.Context, id string) (*domain.Impersonation, error) {
+	imp, err := uc.repo.FindByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "Impersonation not found")
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "GetImpersonation"), err, "Failed to load impersonation")
+	}
+	return imp, nil
+}
+
+// End stops an impersonation before it expires; its token stops working at
+// once. Ending an impersonation that has already ended changes nothing.
+func (uc *ImpersonationUseCase) End(ctx context.Context, actorID, id string) (*domain.Impersonation, error) {
+	log := uc.log.With("op", "EndImpersonation", "actor_id", actorID, "impersonation_id", id)
+	imp, err := uc.Get(ctx, id)
+	if err != nil || imp.EndedAt != nil {
+		return imp, err
+	}
+	now := uc.now().UTC()
+	err = uc.repo.End(ctx, id, now, "ended")
+	if errors.Is(err, domain.ErrNotFound) {
+		// Ended concurrently, by an admin or the expiry sweep.
+		return uc.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to end impersonation")
+	}
+	imp.EndedAt, imp.EndReason = &now, "ended"
+	uc.recordEnd(ctx, log, actorID, imp)
+	log.Info("impersonation ended")
+	return imp, nil
+}
+
+// EndExpired ends impersonations whose token has expired, so that every
+// impersonation has an audited end. It returns how many it ended.
+func (uc *ImpersonationUseCase) EndExpired(ctx context.Context) (int, error) {
+	log := uc.log.With("op", "EndExpiredImpersonations")
+	expired, err := uc.repo.ListExpired(ctx, uc.now(), expiredImpersonationBatch)
+	if err != nil {
+		return 0, err
+	}
+	ended := 0
+	for i := range expired {
+		imp := &expired[i]
+		err := uc.repo.End(ctx, imp.ID, imp.ExpiresAt, "expired")
+		if errors.Is(err, domain.ErrNotFound) {
+			continue // another replica got there first
+		}
+		if err != nil {
+			return ended, err
+		}
+		at := imp.ExpiresAt
+		imp.EndedAt, imp.EndReason = &at, "expired"
+		uc.recordEnd(ctx, log, imp.ActorID, imp)
+		ended++
+	}
+	return ended, nil
+}
+
+// Run calls EndExpired every interval until ctx is cancelled.
+func (uc *ImpersonationUseCase) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := uc.EndExpired(ctx); err != nil && ctx.Err() == nil {
+			uc.log.Error("ending expired impersonations failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// recordEnd audits the end of imp. actorID is the admin who ended it, or
+// the impersonating admin when it expired.
+func (uc *ImpersonationUseCase) recordEnd(ctx context.Context, log *slog.Logger, actorID string, imp *domain.Impersonation) {
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditImpersonationEnded,
+		ActorID:  actorID,
+		TargetID: imp.UserID,
+		Metadata: map[string]string{
+			"impersonation_id": imp.ID,
+			"impersonator_id":  imp.ActorID,
+			"end_reason":       imp.EndReason,
+		},
+	})
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/security/jwt"
+)
+
+func TestImpersonation_StartAuthenticateEnd(t *testing.T) {
+	ctx := context.Background()
+	users, audit := memory.NewUserRepository(), &auditTrail{}
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Hour})
+	uc := NewImpersonationUseCase(slog.New(slog.NewTextHandler(testWriter{}, nil)), 10*time.Minute, users, memory.NewImpersonationRepository(), tokens, audit)
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := uc.Start(ctx, StartImpersonationCmd{ActorID: "admin", UserID: "admin"}); !isCode(err, app.ErrCodeValidation) {
+		t.Fatalf("impersonate self: %v", err)
+	}
+	if _, err := uc.Start(ctx, StartImpersonationCmd{ActorID: "admin", UserID: "missing"}); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("impersonate missing user: %v", err)
+	}
+	res, err := uc.Start(ctx, StartImpersonationCmd{ActorID: "admin", UserID: u.ID, Reason: "ticket 42"})
+	if err != nil || res.ExpiresIn <= 0 || res.ExpiresIn > 600 {
+		t.Fatalf("start = %+v, %v", res, err)
+	}
+	if last := (*audit)[len(*audit)-1]; last.Type != domain.AuditImpersonationStarted || last.ActorID != "admin" || last.TargetID != u.ID ||
+		last.Metadata["reason"] != "ticket 42" || last.Metadata["impersonation_id"] != res.Impersonation.ID {
+		t.Fatalf("audit = %+v", last)
+	}
+	if _, err := tokens.ValidateToken(res.AccessToken); err == nil {
+		t.Fatal("impersonation token accepted as a user token")
+	}
+	imp, err := uc.Authenticate(ctx, res.AccessToken)
+	if err != nil || imp.UserID != u.ID || imp.ActorID != "admin" {
+		t.Fatalf("authenticate = %+v, %v", imp, err)
+	}
+
+	if _, err := uc.End(ctx, "other-admin", res.Impersonation.ID); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	if last := (*audit)[len(*audit)-1]; last.Type != domain.AuditImpersonationEnded || last.ActorID != "other-admin" ||
+		last.Metadata["impersonator_id"] != "admin" || last.Metadata["end_reason"] != "ended" {
+		t.Fatalf("audit = %+v", last)
+	}
+	if _, err := uc.Authenticate(ctx, res.AccessToken); !isCode(err, app.ErrCodeUnauthorized) {
+		t.Fatalf("authenticate after end: %v", err)
+	}
+	events := len(*audit)
+	if imp, err := uc.End(ctx, "admin", res.Impersonation.ID); err != nil || imp.EndReason != "ended" || len(*audit) != events {
+		t.Fatalf("end twice = %+v, %v", imp, err)
+	}
+}
+
+func TestImpersonation_ExpiryIsAudited(t *testing.T) {
+	ctx := context.Background()
+	users, audit := memory.NewUserRepository(), &auditTrail{}
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Hour})
+	uc := NewImpersonationUseCase(slog.New(slog.NewTextHandler(testWriter{}, nil)), 10*time.Minute, users, memory.NewImpersonationRepository(), tokens, audit)
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	res, err := uc.Start(ctx, StartImpersonationCmd{ActorID: "admin", UserID: u.ID, Reason: "ticket 42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := uc.EndExpired(ctx); err != nil || n != 0 {
+		t.Fatalf("sweep before expiry = %d, %v", n, err)
+	}
+	uc.now = func() time.Time { return time.Now().Add(11 * time.Minute) }
+	if _, err := uc.Authenticate(ctx, res.AccessToken); !isCode(err, app.ErrCodeUnauthorized) {
+		t.Fatalf("authenticate after expiry: %v", err)
+	}
+	if n, err := uc.EndExpired(ctx); err != nil || n != 1 {
+		t.Fatalf("sweep = %d, %v", n, err)
+	}
+	if last := (*audit)[len(*audit)-1]; last.Type != domain.AuditImpersonationEnded || last.ActorID != "admin" || last.Metadata["end_reason"] != "expired" {
+		t.Fatalf("audit = %+v", last)
+	}
+	if n, err := uc.EndExpired(ctx); err != nil || n != 0 {
+		t.Fatalf("second sweep = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"go-auth/internal/domain"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type ListAuditEventsResult struct {
+	Events     []domain.AuditEvent
+	NextCursor int64 // zero when there are no more pages
+}
+
+type ListAuditEventsUseCase struct {
+	repo domain.AuditRepository
+}
+
+func NewListAuditEventsUseCase(repo domain.AuditRepository) *ListAuditEventsUseCase {
+	return &ListAuditEventsUseCase{repo: repo}
+}
+
+func (uc *ListAuditEventsUseCase) Handle(ctx context.Context, filter domain.AuditFilter) (*ListAuditEventsResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+	events, err := uc.repo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	res := &ListAuditEventsResult{Events: events}
+	if len(events) == filter.Limit {
+		res.NextCursor = events[len(events)-1].Seq
+	}
+	return res, nil
+}
@@ -2,8 +2,10 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"go-auth/internal/app"
@@ -12,8 +14,13 @@ import (
 )
 
 type LoginUserCmd struct {
-	Email    string
-	Password string
+	TenantID  string
+	Email     string
+	Password  string
+	IP        string
+	Challenge string
+	// ChallengePassed marks a challenge already verified by the transport.
+	ChallengePassed bool
 }
 
 type LoginUserResult struct {
@@ -23,11 +30,13 @@ type LoginUserResult struct {
 }
 
 type LoginUserUseCase struct {
-	log          *slog.Logger
-	userRepo     domain.UserRepository
-	pwdService   app.PasswordService
-	tokenService app.TokenService
-	refreshRepo  domain.RefreshTokenRepository
+	log            *slog.Logger
+	authenticators AuthenticatorRoutes
+	tokenService   app.TokenService
+	refreshRepo    domain.RefreshTokenRepository
+	guard          app.LoginGuard
+	secondFactor   SecondFactor
+	audit          app.AuditLog
 }
 
 func NewLoginUserUseCase(
@@ -36,36 +45,118 @@ func NewLoginUserUseCase(
 	pwdService app.PasswordService,
 	tokenService app.TokenService,
 	refreshRepo domain.RefreshTokenRepository,
+	guard app.LoginGuard,
+	audit app.AuditLog,
 ) *LoginUserUseCase {
 	return &LoginUserUseCase{
-		log:          log,
-		userRepo:     userRepo,
-		pwdService:   pwdService,
+		log: log,
+		authenticators: AuthenticatorRoutes{
+			Default: NewPasswordAuthenticator(userRepo, pwdService),
+		},
 		tokenService: tokenService,
 		refreshRepo:  refreshRepo,
+		guard:        guard,
+		audit:        audit,
 	}
 }
 
 func (uc *LoginUserUseCase) Handle(ctx context.Context, cmd LoginUserCmd) (*LoginUserResult, error) {
 	log := uc.log.With("op", "LoginUser", "email", cmd.Email)
+	attempt := app.LoginAttempt{
+		Email:           cmd.Email,
+		IP:              cmd.IP,
+		Challenge:       cmd.Challenge,
+		ChallengePassed: cmd.ChallengePassed,
+	}
 
-	// 1. Find user by email
-	user, err := uc.userRepo.FindByEmail(ctx, cmd.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	// 0. Throttle credential stuffing before touching the password hash
+	if err := uc.checkGuard(ctx, log, attempt); err != nil {
+		return nil, err
 	}
-	if user == nil {
+
+	// 1. Verify credentials with the tenant's or email domain's authenticator
+	creds := app.Credentials{TenantID: cmd.TenantID, Email: cmd.Email, Password: cmd.Password}
+	auth := uc.authenticators.For(creds)
+	user, err := auth.Authenticate(ctx, creds)
+	var rejected *app.CredentialsError
+	if errors.As(err, &rejected) {
+		log.Warn("invalid credentials", "method", auth.Method(), "reason", rejected.Reason)
+		uc.recordFailure(ctx, log, attempt, rejected.UserID, rejected.Reason)
 		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid credentials")
 	}
-
-	// 2. Verify password
-	err = uc.pwdService.Compare(user.Password, cmd.Password)
+	var ae app.AppError
+	if errors.As(err, &ae) {
+		return nil, ae
+	}
 	if err != nil {
-		log.Warn("invalid password attempt")
-		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid credentials")
+		return nil, storageError(log, err, "Failed to verify credentials")
+	}
+	if uc.guard != nil {
+		if err := uc.guard.RecordSuccess(ctx, attempt); err != nil {
+			log.Warn("failed to reset login failures", "error", err)
+		}
+	}
+
+	// 2. Generate tokens, or ask for a second factor if the user enrolled one
+	return uc.IssueTokens(ctx, user, auth.Method())
+}
+
+// SecondFactor is an extra check after a correct password or magic link.
+// When Required, login answers AUTH_SECOND_FACTOR_REQUIRED with the
+// Challenge details instead of tokens, and the factor issues tokens once it
+// is passed.
+type SecondFactor interface {
+	Required(user *domain.User) bool
+	Challenge(ctx context.Context, user *domain.User) (map[string]any, error)
+}
+
+// secondFactorApplies reports whether a login by method must still pass
+// the user's second factor. First-party logins (password, directory, magic
+// link) must; SMS logins are the factor itself, and federated logins
+// (oidc:, saml:) leave it to the identity provider.
+func secondFactorApplies(method string) bool {
+	switch {
+	case method == "sms", method == "password+sms":
+		return false
+	case strings.HasPrefix(method, "oidc:"), strings.HasPrefix(method, samlProviderPrefix):
+		return false
 	}
+	return true
+}
 
-	// 3. Generate tokens
+// UseSecondFactor enables f in the login pipeline. It is a setter rather
+// than a constructor argument because factors issue tokens through uc.
+func (uc *LoginUserUseCase) UseSecondFactor(f SecondFactor) {
+	uc.secondFactor = f
+}
+
+// UseAuthenticators replaces how credentials are checked. Routes without a
+// Default keep the password check against the stored hash.
+func (uc *LoginUserUseCase) UseAuthenticators(routes AuthenticatorRoutes) {
+	if routes.Default == nil {
+		routes.Default = uc.authenticators.Default
+	}
+	uc.authenticators = routes
+}
+
+// IssueTokens starts a session for an already authenticated user: it mints
+// the access/refresh pair, stores the refresh token and audits the login.
+// method names how the user authenticated ("password", "magic_link", ...).
+// Users with a second factor get its challenge instead, unless method
+// already includes it; see secondFactorApplies.
+func (uc *LoginUserUseCase) IssueTokens(ctx context.Context, user *domain.User, method string) (*LoginUserResult, error) {
+	log := uc.log.With("op", "IssueTokens", "user_id", user.ID, "method", method)
+	if err := uc.checkEnabled(ctx, log, user, method); err != nil {
+		return nil, err
+	}
+	if uc.secondFactor != nil && secondFactorApplies(method) && uc.secondFactor.Required(user) {
+		details, err := uc.secondFactor.Challenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("second factor required")
+		return nil, app.NewError(app.ErrCodeSecondFactor, "Second factor required").WithDetails(details)
+	}
 	accessToken, err := uc.tokenService.GenerateAccessToken(user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
@@ -77,10 +168,18 @@ func (uc *LoginUserUseCase) Handle(ctx context.Context, cmd LoginUserCmd) (*Logi
 	}
 
 	if uc.refreshRepo != nil {
-		_ = uc.refreshRepo.Save(ctx, user.ID, tokenhash.Hash(refreshToken), time.Now().Add(uc.tokenService.RefreshTTL()))
+		if err := uc.refreshRepo.Save(ctx, user.ID, tokenhash.Hash(refreshToken), time.Now().Add(uc.tokenService.RefreshTTL())); err != nil {
+			return nil, storageError(log, err, "Failed to store refresh token")
+		}
 	}
 
-	log.Info("user logged in successfully", "user_id", user.ID)
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditLoginSucceeded,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]string{"email": user.Email, "method": method},
+	})
+	log.Info("user logged in successfully")
 
 	return &LoginUserResult{
 		AccessToken:  accessToken,
@@ -89,6 +188,68 @@ func (uc *LoginUserUseCase) Handle(ctx context.Context, cmd LoginUserCmd) (*Logi
 	}, nil
 }
 
+// checkEnabled rejects disabled accounts. It runs only after the user
+// proved who they are, so the answer reveals nothing to anyone else.
+func (uc *LoginUserUseCase) checkEnabled(ctx context.Context, log *slog.Logger, user *domain.User, method string) error {
+	if !user.Disabled() {
+		return nil
+	}
+	log.Warn("login of disabled user rejected")
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditLoginFailed,
+		TargetID: user.ID,
+		Metadata: map[string]string{"email": user.Email, "method": method, "reason": "account_disabled"},
+	})
+	return app.NewError(app.ErrCodeAccountDisabled, "Account is disabled")
+}
+
+func (uc *LoginUserUseCase) checkGuard(ctx context.Context, log *slog.Logger, attempt app.LoginAttempt) error {
+	if uc.guard == nil {
+		return nil
+	}
+	decision, err := uc.guard.Check(ctx, attempt)
+	if err != nil {
+		// Fail open: an unavailable counter store must not lock everyone out.
+		log.Error("login guard unavailable", "error", err)
+		return nil
+	}
+	if decision.Verdict != app.LoginAllow {
+		recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+			Type: domain.AuditLoginBlocked,
+			Metadata: map[string]string{
+				"email":   attempt.Email,
+				"reason":  decision.Reason,
+				"verdict": decision.Verdict.String(),
+			},
+		})
+	}
+	switch decision.Verdict {
+	case app.LoginBlock:
+		log.Warn("login blocked", "reason", decision.Reason, "ip", attempt.IP)
+		return app.NewError(app.ErrCodeRateLimited, "Too many failed login attempts").
+			WithDetails(map[string]any{"retry_after": int64((decision.RetryAfter + time.Second - 1) / time.Second)})
+	case app.LoginChallenge:
+		log.Warn("login challenge required", "reason", decision.Reason, "ip", attempt.IP)
+		return app.NewError(app.ErrCodeChallengeRequired, "Challenge required").
+			WithDetails(map[string]any{"challenge": decision.Challenge})
+	}
+	return nil
+}
+
+func (uc *LoginUserUseCase) recordFailure(ctx context.Context, log *slog.Logger, attempt app.LoginAttempt, userID, reason string) {
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditLoginFailed,
+		TargetID: userID,
+		Metadata: map[string]string{"email": attempt.Email, "reason": reason},
+	})
+	if uc.guard == nil {
+		return
+	}
+	if err := uc.guard.RecordFailure(ctx, attempt); err != nil {
+		log.Error("failed to record login failure", "error", err)
+	}
+}
+
 func (uc *LoginUserUseCase) TokenUserID(token string) (string, error) {
 	return uc.tokenService.ValidateToken(token)
 }
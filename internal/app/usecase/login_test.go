@@ -29,17 +29,25 @@ func (fakePwd2) Compare(h, p string) error {
 	return errors.New("bad")
 }
 
-type memRepo2 struct{ u *domain.User }
+// memRepo2 holds a single user; methods the tests do not need panic via
+// the nil embedded interface.
+type memRepo2 struct {
+	domain.UserRepository
+	u *domain.User
+}
 
 func (r *memRepo2) Create(ctx context.Context, u *domain.User) error { r.u = u; return nil }
 func (r *memRepo2) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if r.u == nil || r.u.Email != email {
+		return nil, domain.ErrNotFound
+	}
 	return r.u, nil
 }
 
 func TestLogin_Success(t *testing.T) {
 	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
 	repo := &memRepo2{u: &domain.User{ID: "id-1", Email: "u@ex.com", Password: "p"}}
-    uc := NewLoginUserUseCase(log, repo, app.PasswordService(fakePwd2{}), app.TokenService(fakeToken{}), nil)
+    uc := NewLoginUserUseCase(log, repo, app.PasswordService(fakePwd2{}), app.TokenService(fakeToken{}), nil, nil, nil)
 	res, err := uc.Handle(context.Background(), LoginUserCmd{Email: "u@ex.com", Password: "p"})
 	if err != nil || res.AccessToken == "" || res.RefreshToken == "" {
 		t.Fatalf("login failed: %v", err)
@@ -49,8 +57,37 @@ func TestLogin_Success(t *testing.T) {
 func TestLogin_InvalidPassword(t *testing.T) {
 	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
 	repo := &memRepo2{u: &domain.User{ID: "id-1", Email: "u@ex.com", Password: "p"}}
-    uc := NewLoginUserUseCase(log, repo, app.PasswordService(fakePwd2{}), app.TokenService(fakeToken{}), nil)
+    uc := NewLoginUserUseCase(log, repo, app.PasswordService(fakePwd2{}), app.TokenService(fakeToken{}), nil, nil, nil)
 	if _, err := uc.Handle(context.Background(), LoginUserCmd{Email: "u@ex.com", Password: "wrong"}); err == nil {
 		t.Fatalf("expected invalid credentials")
 	}
 }
+
+type stubGuard struct {
+	decision app.LoginDecision
+	failures int
+}
+
+func (g *stubGuard) Check(context.Context, app.LoginAttempt) (app.LoginDecision, error) {
+	return g.decision, nil
+}
+func (g *stubGuard) RecordFailure(context.Context, app.LoginAttempt) error { g.failures++; return nil }
+func (g *stubGuard) RecordSuccess(context.Context, app.LoginAttempt) error { return nil }
+
+func TestLogin_GuardChallengeAndFailures(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
+	repo := &memRepo2{u: &domain.User{ID: "id-1", Email: "u@ex.com", Password: "p"}}
+	guard := &stubGuard{}
+	uc := NewLoginUserUseCase(log, repo, app.PasswordService(fakePwd2{}), app.TokenService(fakeToken{}), nil, guard, nil)
+
+	if _, err := uc.Handle(context.Background(), LoginUserCmd{Email: "u@ex.com", Password: "wrong"}); err == nil || guard.failures != 1 {
+		t.Fatalf("expected recorded failure, err=%v failures=%d", err, guard.failures)
+	}
+
+	guard.decision = app.LoginDecision{Verdict: app.LoginChallenge, Challenge: &app.Challenge{Type: "pow"}}
+	_, err := uc.Handle(context.Background(), LoginUserCmd{Email: "u@ex.com", Password: "p"})
+	ae, ok := err.(app.AppError)
+	if !ok || ae.Code != app.ErrCodeChallengeRequired || ae.Details["challenge"] == nil {
+		t.Fatalf("expected challenge error, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+	"go-auth/internal/security/tokenhash"
+)
+
+// MagicLinkConfig configures passwordless email login.
+type MagicLinkConfig struct {
+	TTL time.Duration
+	// URL is the page that consumes links; the token is appended as ?token=.
+	URL string
+	// AutoRegisterTenants lists tenants whose unknown emails are signed up
+	// on first use. "*" allows every tenant. An email belongs to the tenant
+	// that owns its domain in Domains, whatever tenant the request names.
+	AutoRegisterTenants []string
+	Domains             app.TenantDomains
+}
+
+type RequestMagicLinkCmd struct {
+	Email    string
+	TenantID string
+}
+
+// RequestMagicLinkResult carries the nonce the transport must bind to the
+// requesting browser; the link only works together with it.
+type RequestMagicLinkResult struct {
+	Nonce     string
+	ExpiresIn int64
+}
+
+type ConsumeMagicLinkCmd struct {
+	Token string
+	Nonce string
+}
+
+// MagicLinkUseCase emails single-use login links and exchanges them for the
+// same token pair a password login produces.
+type MagicLinkUseCase struct {
+	log       *slog.Logger
+	cfg       MagicLinkConfig
+	links     domain.MagicLinkRepository
+	users     domain.UserRepository
+	register  *RegisterUserUseCase
+	login     *LoginUserUseCase
+	mailer    app.Mailer
+	templates *app.MailTemplates
+	audit     app.AuditLog
+	now       func() time.Time
+}
+
+func NewMagicLinkUseCase(
+	log *slog.Logger,
+	cfg MagicLinkConfig,
+	links domain.MagicLinkRepository,
+	users domain.UserRepository,
+	register *RegisterUserUseCase,
+	login *LoginUserUseCase,
+	mailer app.Mailer,
+	templates *app.MailTemplates,
+	audit app.AuditLog,
+) *MagicLinkUseCase {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 15 * time.Minute
+	}
+	return &MagicLinkUseCase{
+		log:       log,
+		cfg:       cfg,
+		links:     links,
+		users:     users,
+		register:  register,
+		login:     login,
+		mailer:    mailer,
+		templates: templates,
+		audit:     audit,
+		now:       time.Now,
+	}
+}
+
+// Request emails a login link to cmd.Email. To avoid revealing which
+// addresses have accounts, unknown emails get the same result without a
+// link unless the tenant owning their domain allows auto-registration.
+func (uc *MagicLinkUseCase) Request(ctx context.Context, cmd RequestMagicLinkCmd) (*RequestMagicLinkResult, error) {
+	log := uc.log.With("op", "RequestMagicLink", "email", cmd.Email, "tenant_id", cmd.TenantID)
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	res := &RequestMagicLinkResult{Nonce: nonce, ExpiresIn: int64(uc.cfg.TTL.Seconds())}
+
+	tenantID := cmd.TenantID
+	user, err := uc.users.FindByEmail(ctx, cmd.Email)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		tenantID = uc.cfg.Domains.Tenant(cmd.Email)
+		if !uc.autoRegister(tenantID) {
+			log.Info("magic link requested for unknown email", "owner_tenant_id", tenantID)
+			return res, nil
+		}
+	case err != nil:
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	link := &domain.MagicLink{
+		Email:        cmd.Email,
+		TenantID:     tenantID,
+		TokenHash:    tokenhash.Hash(token),
+		NonceHash:    tokenhash.Hash(nonce),
+		AutoRegister: user == nil,
+		ExpiresAt:    uc.now().Add(uc.cfg.TTL).UTC(),
+	}
+	if err := uc.links.Create(ctx, link); err != nil {
+		return nil, storageError(log, err, "Failed to store magic link")
+	}
+
+	preferred := ""
+	if user != nil {
+		preferred = user.Locale
+	}
+	email, err := uc.templates.Render(tenantID, i18n.Negotiate(app.RequestMetaFrom(ctx).Locale, preferred), i18n.EmailMagicLink, cmd.Email, map[string]any{
+		"Link":    uc.linkURL(token),
+		"Minutes": int(uc.cfg.TTL.Minutes()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render magic link email: %w", err)
+	}
+	if err := uc.mailer.Send(ctx, email); err != nil {
+		log.Error("failed to queue magic link email", "error", err)
+		return nil, app.NewError(app.ErrCodeUnavailable, "Service temporarily unavailable")
+	}
+
+	event := domain.AuditEvent{
+		Type:     domain.AuditMagicLinkRequested,
+		TenantID: tenantID,
+		Metadata: map[string]string{"email": cmd.Email},
+	}
+	if user != nil {
+		event.TargetID = user.ID
+	}
+	recordAudit(ctx, log, uc.audit, event)
+	return res, nil
+}
+
+// Consume exchanges a link token and its browser nonce for a session,
+// registering the user first when the link allows it.
+func (uc *MagicLinkUseCase) Consume(ctx context.Context, cmd ConsumeMagicLinkCmd) (*LoginUserResult, error) {
+	log := uc.log.With("op", "ConsumeMagicLink")
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid or expired link")
+	if cmd.Token == "" || cmd.Nonce == "" {
+		return nil, invalid
+	}
+	link, err := uc.links.Consume(ctx, tokenhash.Hash(cmd.Token), tokenhash.Hash(cmd.Nonce), uc.now())
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Warn("invalid, expired or reused magic link")
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to consume magic link")
+	}
+	log = log.With("email", link.Email)
+
+	user, err := uc.users.FindByEmail(ctx, link.Email)
+	if errors.Is(err, domain.ErrNotFound) && link.AutoRegister {
+		user, err = uc.signUp(ctx, link.Email, link.TenantID)
+	}
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Warn("magic link user no longer exists")
+		return nil, invalid
+	}
+	var ae app.AppError
+	if errors.As(err, &ae) {
+		return nil, ae
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	return uc.login.IssueTokens(ctx, user, "magic_link")
+}
+
+// signUp registers email in tenantID with an unusable random password; the
+// user can set a real one later through password reset.
+func (uc *MagicLinkUseCase) signUp(ctx context.Context, email, tenantID string) (*domain.User, error) {
+	password, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = uc.register.Handle(ctx, RegisterUserCmd{Email: email, Password: password, TenantID: tenantID})
+	var ae app.AppError
+	if err != nil && !(errors.As(err, &ae) && ae.Code == app.ErrCodeEmailExists) {
+		return nil, err
+	}
+	return uc.users.FindByEmail(ctx, email)
+}
+
+func (uc *MagicLinkUseCase) autoRegister(tenantID string) bool {
+	if tenantID == "" {
+		return false
+	}
+	for _, t := range uc.cfg.AutoRegisterTenants {
+		if t == "*" || t == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+func (uc *MagicLinkUseCase) linkURL(token string) string {
+	sep := "?"
+	if strings.Contains(uc.cfg.URL, "?") {
+		sep = "&"
+	}
+	return uc.cfg.URL + sep + "token=" + url.QueryEscape(token)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-auth/internal/app"
+)
+
+func TestMagicLink_Request(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name         string
+		autoRegister []string
+		cmd          RequestMagicLinkCmd
+		wantLink     bool
+		wantTenant   string // tenant of the registration the link made, if any
+	}{
+		{"existing account", nil, RequestMagicLinkCmd{Email: "u@ex.com"}, true, ""},
+		{"unknown email", nil, RequestMagicLinkCmd{Email: "nobody@ex.com"}, false, ""},
+		{"auto-registering tenant's domain", []string{"acme"}, RequestMagicLinkCmd{Email: "new@acme.com"}, true, "acme"},
+		// The tenant named in the request does not decide; the domain owner does.
+		{"another tenant's domain", []string{"acme"}, RequestMagicLinkCmd{Email: "other@globex.com", TenantID: "acme"}, false, ""},
+		{"domain no tenant owns", []string{"acme"}, RequestMagicLinkCmd{Email: "stranger@ex.com", TenantID: "acme"}, false, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			f.user(t, "u@ex.com", "p")
+			uc := f.magicLink(tc.autoRegister...)
+
+			// Every request looks like success, whether or not a link went out.
+			res, err := uc.Request(ctx, tc.cmd)
+			if err != nil || res.Nonce == "" || res.ExpiresIn != 600 {
+				t.Fatalf("request = %+v, %v", res, err)
+			}
+			if !tc.wantLink {
+				if n := len(f.mail.Messages()); n != 0 {
+					t.Fatalf("sent %d emails", n)
+				}
+				return
+			}
+			tokens, err := uc.Consume(ctx, ConsumeMagicLinkCmd{Token: f.linkToken(t, tc.cmd.Email), Nonce: res.Nonce})
+			if err != nil {
+				t.Fatalf("consume: %v", err)
+			}
+			user, err := f.users.FindByEmail(ctx, tc.cmd.Email)
+			if err != nil || tokens.AccessToken != "acc:"+user.ID {
+				t.Fatalf("user = %+v, %v; token %q", user, err, tokens.AccessToken)
+			}
+			events := *f.outbox
+			if tc.wantTenant == "" && len(events) != 0 || tc.wantTenant != "" && (len(events) != 1 || events[0].TenantID != tc.wantTenant) {
+				t.Fatalf("outbox = %+v", events)
+			}
+		})
+	}
+}
+
+func TestMagicLink_Consume(t *testing.T) {
+	f := newFixture()
+	f.user(t, "u@ex.com", "p")
+	uc := f.magicLink()
+	ctx := context.Background()
+	res, err := uc.Request(ctx, RequestMagicLinkCmd{Email: "u@ex.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := f.linkToken(t, "u@ex.com")
+
+	// Attempts run in order against the same link.
+	for _, tc := range []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr bool
+	}{
+		{"unknown token", "forged", res.Nonce, true},
+		{"other browser", token, "other-browser", true},
+		{"right browser", token, res.Nonce, false},
+		{"reused link", token, res.Nonce, true},
+	} {
+		_, err := uc.Consume(ctx, ConsumeMagicLinkCmd{Token: tc.token, Nonce: tc.nonce})
+		if tc.wantErr && !isCode(err, app.ErrCodeInvalidCredentials) || !tc.wantErr && err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func isCode(err error, code string) bool {
+	var ae app.AppError
+	return errors.As(err, &ae) && ae.Code == code
+}
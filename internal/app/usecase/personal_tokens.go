@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/security/tokenhash"
+)
+
+const (
+	// personalTokenPrefix marks personal access tokens so that the bearer
+	// middleware can tell them from JWTs and secret scanners can find them.
+	personalTokenPrefix = "pat_"
+	// personalTokenShown is how much of a token is kept in the clear.
+	personalTokenShown = len(personalTokenPrefix) + 6
+	// personalTokenTouchEvery limits last-used writes to one per interval
+	// per token.
+	personalTokenTouchEvery = time.Minute
+)
+
+// PersonalTokenScopes lists the scopes a personal access token can carry.
+var PersonalTokenScopes = []string{domain.ScopeUserRead, domain.ScopeUserWrite, domain.ScopeAdminRead, domain.ScopeAdminWrite}
+
+type CreatePersonalTokenCmd struct {
+	Name   string
+	Scopes []string
+	// ExpiresAt is optional; nil creates a token that does not expire.
+	ExpiresAt *time.Time
+}
+
+// PersonalTokensUseCase manages users' personal access tokens and resolves
+// them for the bearer-auth middleware.
+type PersonalTokensUseCase struct {
+	log   *slog.Logger
+	repo  domain.PersonalAccessTokenRepository
+	audit app.AuditLog
+	now   func() time.Time
+}
+
+func NewPersonalTokensUseCase(log *slog.Logger, repo domain.PersonalAccessTokenRepository, audit app.AuditLog) *PersonalTokensUseCase {
+	return &PersonalTokensUseCase{log: log, repo: repo, audit: audit, now: time.Now}
+}
+
+// Create issues a token for the user and returns it along with the bearer
+// credential, which is not exposed again afterwards.
+func (uc *PersonalTokensUseCase) Create(ctx context.Context, userID string, cmd CreatePersonalTokenCmd) (*domain.PersonalAccessToken, string, error) {
+	log := uc.log.With("op", "CreatePersonalToken", "user_id", userID)
+	name := strings.TrimSpace(cmd.Name)
+	if name == "" {
+		return nil, "", app.NewError(app.ErrCodeValidation, "Token name is required")
+	}
+	if len(cmd.Scopes) == 0 {
+		return nil, "", app.NewError(app.ErrCodeValidation, "At least one scope is required")
+	}
+	for _, scope := range cmd.Scopes {
+		if !slices.Contains(PersonalTokenScopes, scope) {
+			return nil, "", app.NewError(app.ErrCodeValidation, fmt.Sprintf("Unknown scope %q", scope)).
+				WithDetails(map[string]any{"scopes": PersonalTokenScopes})
+		}
+	}
+	now := uc.now().UTC()
+	if cmd.ExpiresAt != nil && !cmd.ExpiresAt.After(now) {
+		return nil, "", app.NewError(app.ErrCodeValidation, "Expiry must be in the future")
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	secret = personalTokenPrefix + secret
+	scopes := slices.Clone(cmd.Scopes)
+	slices.Sort(scopes)
+	token := &domain.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:personalTokenShown],
+		Scopes:    slices.Compact(scopes),
+		TokenHash: tokenhash.Hash(secret),
+		CreatedAt: now,
+	}
+	if cmd.ExpiresAt != nil {
+		expiresAt := cmd.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+	if err := uc.repo.Create(ctx, token); err != nil {
+		return nil, "", storageError(log, err, "Failed to create token")
+	}
+
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditPersonalTokenCreated,
+		ActorID:  userID,
+		TargetID: userID,
+		Metadata: map[string]string{"token_id": token.ID, "name": token.Name, "scopes": strings.Join(token.Scopes, " ")},
+	})
+	return token, secret, nil
+}
+
+// List returns the user's tokens, oldest first.
+func (uc *PersonalTokensUseCase) List(ctx context.Context, userID string) ([]domain.PersonalAccessToken, error) {
+	tokens, err := uc.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "ListPersonalTokens"), err, "Failed to list tokens")
+	}
+	return tokens, nil
+}
+
+// Revoke deletes one of the user's tokens.
+func (uc *PersonalTokensUseCase) Revoke(ctx context.Context, userID, id string) error {
+	log := uc.log.With("op", "RevokePersonalToken", "user_id", userID)
+	if err := notFoundAs(uc.repo.Delete(ctx, userID, id), domain.ErrNotFound, "Token not found"); err != nil {
+		return err
+	}
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditPersonalTokenRevoked,
+		ActorID:  userID,
+		TargetID: userID,
+		Metadata: map[string]string{"token_id": id},
+	})
+	return nil
+}
+
+// IsPersonalToken reports whether a bearer credential is a personal access
+// token rather than a JWT.
+func IsPersonalToken(secret string) bool {
+	return strings.HasPrefix(secret, personalTokenPrefix)
+}
+
+// Authenticate returns the unexpired token whose bearer credential is
+// secret and records its use.
+func (uc *PersonalTokensUseCase) Authenticate(ctx context.Context, secret string) (*domain.PersonalAccessToken, error) {
+	log := uc.log.With("op", "AuthenticatePersonalToken")
+	if !IsPersonalToken(secret) {
+		return nil, app.NewError(app.ErrCodeUnauthorized, "Unauthorized")
+	}
+	token, err := uc.repo.FindByHash(ctx, tokenhash.Hash(secret))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeUnauthorized, "Unauthorized")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to look up token")
+	}
+	now := uc.now().UTC()
+	if token.Expired(now) {
+		return nil, app.NewError(app.ErrCodeUnauthorized, "Token expired")
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= personalTokenTouchEvery {
+		// Losing a last-used update is not worth failing the request.
+		if err := uc.repo.Touch(ctx, token.ID, now); err != nil {
+			log.Warn("failed to record token use", "token_id", token.ID, "error", err)
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+)
+
+func TestPersonalTokens_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	audit := &auditTrail{}
+	uc := NewPersonalTokensUseCase(slog.New(slog.NewTextHandler(testWriter{}, nil)), memory.NewPersonalAccessTokenRepository(), audit)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	uc.now = func() time.Time { return now }
+
+	for _, cmd := range []CreatePersonalTokenCmd{
+		{Name: " ", Scopes: []string{domain.ScopeUserRead}},
+		{Name: "ci"},
+		{Name: "ci", Scopes: []string{"root"}},
+		{Name: "ci", Scopes: []string{domain.ScopeUserRead}, ExpiresAt: &now},
+	} {
+		if _, _, err := uc.Create(ctx, "u1", cmd); !isCode(err, app.ErrCodeValidation) {
+			t.Fatalf("create %+v: got %v, want validation error", cmd, err)
+		}
+	}
+
+	expires := now.Add(time.Hour)
+	token, secret, err := uc.Create(ctx, "u1", CreatePersonalTokenCmd{
+		Name:      "ci",
+		Scopes:    []string{domain.ScopeUserWrite, domain.ScopeUserRead, domain.ScopeUserWrite},
+		ExpiresAt: &expires,
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(secret, "pat_") || !strings.HasPrefix(secret, token.Prefix) || strings.Contains(token.TokenHash, secret) ||
+		len(token.Scopes) != 2 || token.Scopes[0] != domain.ScopeUserRead {
+		t.Fatalf("token = %+v, secret %q", token, secret)
+	}
+	if last := (*audit)[len(*audit)-1]; last.Type != domain.AuditPersonalTokenCreated || last.Metadata["token_id"] != token.ID {
+		t.Fatalf("audit = %+v", last)
+	}
+
+	got, err := uc.Authenticate(ctx, secret)
+	if err != nil || got.UserID != "u1" || got.LastUsedAt == nil || !got.LastUsedAt.Equal(now) {
+		t.Fatalf("authenticate = %+v, %v", got, err)
+	}
+	if _, err := uc.Authenticate(ctx, secret+"x"); !isCode(err, app.ErrCodeUnauthorized) {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	now = expires
+	if _, err := uc.Authenticate(ctx, secret); !isCode(err, app.ErrCodeUnauthorized) {
+		t.Fatalf("expired token: %v", err)
+	}
+
+	if err := uc.Revoke(ctx, "u2", token.ID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("revoke another user's token: %v", err)
+	}
+	if err := uc.Revoke(ctx, "u1", token.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if list, err := uc.List(ctx, "u1"); err != nil || len(list) != 0 {
+		t.Fatalf("list after revoke = %+v, %v", list, err)
+	}
+}
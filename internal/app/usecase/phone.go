@@ -0,0 +1,339 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+	"go-auth/internal/security/tokenhash"
+)
+
+// OTPConfig configures one-time codes sent by SMS.
+type OTPConfig struct {
+	TTL time.Duration
+	// MaxAttempts bounds how many codes may be tried against one OTP.
+	MaxAttempts int
+	Digits      int
+}
+
+// OTPChallenge identifies a sent code. Clients answer with the ID and the
+// code the user received.
+type OTPChallenge struct {
+	ID string
+	// Phone is masked, for telling the user where to look.
+	Phone     string
+	ExpiresIn int64
+}
+
+type ConfirmPhoneCmd struct {
+	ChallengeID string
+	Code        string
+	// SecondFactor enrolls the phone as a login second factor.
+	SecondFactor bool
+}
+
+type VerifyOTPCmd struct {
+	ChallengeID string
+	Code        string
+}
+
+// PhoneUseCase verifies phone numbers and logs users in with SMS codes,
+// either instead of a password or after it as a second factor.
+type PhoneUseCase struct {
+	log   *slog.Logger
+	cfg   OTPConfig
+	users domain.UserRepository
+	otps  domain.OTPRepository
+	sms   app.SMSSender
+	login *LoginUserUseCase
+	audit app.AuditLog
+	now   func() time.Time
+}
+
+func NewPhoneUseCase(
+	log *slog.Logger,
+	cfg OTPConfig,
+	users domain.UserRepository,
+	otps domain.OTPRepository,
+	sms app.SMSSender,
+	login *LoginUserUseCase,
+	audit app.AuditLog,
+) *PhoneUseCase {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Digits <= 0 {
+		cfg.Digits = 6
+	}
+	return &PhoneUseCase{
+		log:   log,
+		cfg:   cfg,
+		users: users,
+		otps:  otps,
+		sms:   sms,
+		login: login,
+		audit: audit,
+		now:   time.Now,
+	}
+}
+
+// StartVerification texts a code to phone so userID can prove they own it.
+func (uc *PhoneUseCase) StartVerification(ctx context.Context, userID, phone string) (*OTPChallenge, error) {
+	log := uc.log.With("op", "StartPhoneVerification", "user_id", userID)
+	phone, err := parsePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.findUser(ctx, log, userID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := uc.users.FindByPhone(ctx, phone)
+	switch {
+	case err == nil && owner.ID != user.ID:
+		return nil, app.NewError(app.ErrCodeConflict, "Phone number is already in use")
+	case err != nil && !errors.Is(err, domain.ErrNotFound):
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	return uc.issue(ctx, log, domain.OTPVerifyPhone, user, phone)
+}
+
+// ConfirmVerification stores the phone once the user proves they received
+// the code, replacing any previous number.
+func (uc *PhoneUseCase) ConfirmVerification(ctx context.Context, userID string, cmd ConfirmPhoneCmd) (*domain.User, error) {
+	log := uc.log.With("op", "ConfirmPhoneVerification", "user_id", userID)
+	otp, err := uc.verify(ctx, log, domain.OTPVerifyPhone, userID, cmd.ChallengeID, cmd.Code)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.findUser(ctx, log, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := uc.now().UTC()
+	user.Phone, user.PhoneVerifiedAt, user.SMSSecondFactor, user.UpdatedAt = otp.Phone, &now, cmd.SecondFactor, now
+	if err := uc.users.UpdatePhone(ctx, user); errors.Is(err, domain.ErrConflict) {
+		return nil, app.NewError(app.ErrCodeConflict, "Phone number is already in use")
+	} else if err != nil {
+		return nil, storageError(log, err, "Failed to update phone")
+	}
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditPhoneVerified,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]string{"phone": domain.MaskPhone(user.Phone), "second_factor": fmt.Sprint(user.SMSSecondFactor)},
+	})
+	return user, nil
+}
+
+// SetSecondFactor turns the SMS second factor on or off for a user with a
+// verified phone.
+func (uc *PhoneUseCase) SetSecondFactor(ctx context.Context, userID string, enabled bool) (*domain.User, error) {
+	log := uc.log.With("op", "SetSMSSecondFactor", "user_id", userID)
+	user, err := uc.findUser(ctx, log, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Phone == "" {
+		return nil, app.NewError(app.ErrCodeConflict, "Verify a phone number first")
+	}
+	user.SMSSecondFactor, user.UpdatedAt = enabled, uc.now().UTC()
+	if err := uc.users.UpdatePhone(ctx, user); err != nil {
+		return nil, storageError(log, err, "Failed to update phone")
+	}
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditPhoneUpdated,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]string{"second_factor": fmt.Sprint(enabled)},
+	})
+	return user, nil
+}
+
+// RequestLogin texts a sign-in code to the user with the verified phone.
+// Unknown numbers get an indistinguishable challenge that never succeeds.
+func (uc *PhoneUseCase) RequestLogin(ctx context.Context, phone string) (*OTPChallenge, error) {
+	log := uc.log.With("op", "RequestPhoneLogin")
+	phone, err := parsePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.users.FindByPhone(ctx, phone)
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Info("phone login requested for unknown number")
+		return &OTPChallenge{ID: uuid.NewString(), Phone: domain.MaskPhone(phone), ExpiresIn: int64(uc.cfg.TTL.Seconds())}, nil
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	return uc.issue(ctx, log.With("user_id", user.ID), domain.OTPPhoneLogin, user, phone)
+}
+
+// Login exchanges a phone-login code for tokens.
+func (uc *PhoneUseCase) Login(ctx context.Context, cmd VerifyOTPCmd) (*LoginUserResult, error) {
+	return uc.complete(ctx, uc.log.With("op", "PhoneLogin"), domain.OTPPhoneLogin, cmd, "sms")
+}
+
+// CompleteSecondFactor exchanges the code sent after a correct password for
+// tokens.
+func (uc *PhoneUseCase) CompleteSecondFactor(ctx context.Context, cmd VerifyOTPCmd) (*LoginUserResult, error) {
+	return uc.complete(ctx, uc.log.With("op", "CompleteSecondFactor"), domain.OTPSecondFactor, cmd, "password+sms")
+}
+
+// Required implements SecondFactor.
+func (uc *PhoneUseCase) Required(user *domain.User) bool {
+	return user.SMSSecondFactor && user.Phone != ""
+}
+
+// Challenge implements SecondFactor by texting a code to the user's phone.
+func (uc *PhoneUseCase) Challenge(ctx context.Context, user *domain.User) (map[string]any, error) {
+	ch, err := uc.issue(ctx, uc.log.With("op", "SecondFactorChallenge", "user_id", user.ID), domain.OTPSecondFactor, user, user.Phone)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"method":       "sms",
+		"challenge_id": ch.ID,
+		"phone":        ch.Phone,
+		"expires_in":   ch.ExpiresIn,
+	}, nil
+}
+
+func (uc *PhoneUseCase) complete(ctx context.Context, log *slog.Logger, purpose string, cmd VerifyOTPCmd, method string) (*LoginUserResult, error) {
+	otp, err := uc.verify(ctx, log, purpose, "", cmd.ChallengeID, cmd.Code)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.users.FindByID(ctx, otp.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid or expired code")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	if user.Phone != otp.Phone {
+		log.Warn("phone changed since the code was sent", "user_id", user.ID)
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid or expired code")
+	}
+	return uc.login.IssueTokens(ctx, user, method)
+}
+
+func (uc *PhoneUseCase) issue(ctx context.Context, log *slog.Logger, purpose string, user *domain.User, phone string) (*OTPChallenge, error) {
+	code, err := randomCode(uc.cfg.Digits)
+	if err != nil {
+		return nil, err
+	}
+	otp := &domain.OTP{
+		Purpose:   purpose,
+		UserID:    user.ID,
+		Phone:     phone,
+		CodeHash:  hashCode(phone, code),
+		ExpiresAt: uc.now().Add(uc.cfg.TTL).UTC(),
+	}
+	if err := uc.otps.Create(ctx, otp); err != nil {
+		return nil, storageError(log, err, "Failed to store code")
+	}
+
+	locale := i18n.Negotiate(app.RequestMetaFrom(ctx).Locale, user.Locale)
+	text, err := i18n.RenderSMS(locale, purpose, map[string]any{"Code": code, "Minutes": int(uc.cfg.TTL.Minutes())})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render sms: %w", err)
+	}
+	if err := uc.sms.Send(ctx, app.SMS{To: phone, Text: text}); err != nil {
+		log.Error("failed to send sms", "error", err)
+		return nil, app.NewError(app.ErrCodeUnavailable, "Service temporarily unavailable")
+	}
+
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditOTPSent,
+		TargetID: user.ID,
+		Metadata: map[string]string{"purpose": purpose, "phone": domain.MaskPhone(phone)},
+	})
+	return &OTPChallenge{ID: otp.ID, Phone: domain.MaskPhone(phone), ExpiresIn: int64(uc.cfg.TTL.Seconds())}, nil
+}
+
+// verify checks code against challenge id and consumes it. The attempt is
+// counted before the comparison, so at most MaxAttempts guesses are ever
+// compared. userID, if set, must own the code.
+func (uc *PhoneUseCase) verify(ctx context.Context, log *slog.Logger, purpose, userID, id, code string) (*domain.OTP, error) {
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid or expired code")
+	if id == "" || code == "" {
+		return nil, invalid
+	}
+	otp, err := uc.otps.RecordAttempt(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to check code")
+	}
+	now := uc.now()
+	if otp.Purpose != purpose || (userID != "" && otp.UserID != userID) || otp.ConsumedAt != nil || !otp.ExpiresAt.After(now) {
+		return nil, invalid
+	}
+	if otp.Attempts > uc.cfg.MaxAttempts {
+		return nil, app.NewError(app.ErrCodeOTPAttempts, "Too many attempts, request a new code")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(otp.Phone, code)), []byte(otp.CodeHash)) != 1 {
+		log.Warn("wrong otp code", "purpose", purpose, "attempts", otp.Attempts)
+		recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+			Type:     domain.AuditOTPFailed,
+			TargetID: otp.UserID,
+			Metadata: map[string]string{"purpose": purpose, "attempts": fmt.Sprint(otp.Attempts)},
+		})
+		return nil, invalid.WithDetails(map[string]any{"attempts_left": uc.cfg.MaxAttempts - otp.Attempts})
+	}
+	if err := uc.otps.Consume(ctx, otp.ID, now); errors.Is(err, domain.ErrNotFound) {
+		return nil, invalid
+	} else if err != nil {
+		return nil, storageError(log, err, "Failed to consume code")
+	}
+	return otp, nil
+}
+
+func (uc *PhoneUseCase) findUser(ctx context.Context, log *slog.Logger, id string) (*domain.User, error) {
+	user, err := uc.users.FindByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	return user, nil
+}
+
+func parsePhone(raw string) (string, error) {
+	phone, ok := domain.NormalizePhone(raw)
+	if !ok {
+		return "", app.NewError(app.ErrCodeValidation, "Invalid phone number").
+			WithFields(app.FieldError{Field: "phone", Code: "e164", Message: "must be a phone number in international format"})
+	}
+	return phone, nil
+}
+
+// hashCode binds a code to the number it was sent to.
+func hashCode(phone, code string) string {
+	return tokenhash.Hash(phone + ":" + code)
+}
+
+func randomCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
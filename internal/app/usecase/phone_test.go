@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+)
+
+// phone returns the SMS flows, installed as the fixture login's second factor.
+func (f *fixture) phone(cfg OTPConfig) *PhoneUseCase {
+	uc := NewPhoneUseCase(f.log, cfg, f.users, memory.NewOTPRepository(), f.sms, f.login, nil)
+	f.login.UseSecondFactor(uc)
+	return uc
+}
+
+// code returns the code in the last SMS to phone; texts start with it.
+func (f *fixture) code(t *testing.T, phone string) string {
+	t.Helper()
+	msg, ok := f.sms.Last(phone)
+	if !ok {
+		t.Fatalf("no sms to %s", phone)
+	}
+	code, _, _ := strings.Cut(msg.Text, " ")
+	return code
+}
+
+func (f *fixture) verifyPhone(t *testing.T, uc *PhoneUseCase, user *domain.User, phone string, secondFactor bool) {
+	t.Helper()
+	ctx := context.Background()
+	ch, err := uc.StartVerification(ctx, user.ID, phone)
+	if err != nil {
+		t.Fatalf("start verification: %v", err)
+	}
+	if _, err := uc.ConfirmVerification(ctx, user.ID, ConfirmPhoneCmd{ChallengeID: ch.ID, Code: f.code(t, phone), SecondFactor: secondFactor}); err != nil {
+		t.Fatalf("confirm verification: %v", err)
+	}
+}
+
+func TestPhone_Verification(t *testing.T) {
+	f := newFixture()
+	u := f.user(t, "u@ex.com", "p")
+	uc := f.phone(OTPConfig{})
+	ctx := context.Background()
+
+	if _, err := uc.StartVerification(ctx, u.ID, "555-0123"); !isCode(err, app.ErrCodeValidation) {
+		t.Fatalf("non-E.164 phone: %v", err)
+	}
+	ch, err := uc.StartVerification(ctx, u.ID, "+1 (415) 555-0123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Phone != "+1********23" || ch.ExpiresIn != 300 {
+		t.Fatalf("challenge = %+v", ch)
+	}
+	code := f.code(t, "+14155550123")
+
+	// Attempts run in order against the same challenge.
+	for _, tc := range []struct {
+		name     string
+		userID   string
+		code     string
+		wantCode string
+		wantLeft int // attempts_left reported with the error, if checked
+	}{
+		{"wrong code", u.ID, "000000x", app.ErrCodeInvalidCredentials, 4},
+		{"other user's code", "someone-else", code, app.ErrCodeInvalidCredentials, 0},
+		{"right code", u.ID, code, "", 0},
+		{"reused code", u.ID, code, app.ErrCodeInvalidCredentials, 0},
+	} {
+		user, err := uc.ConfirmVerification(ctx, tc.userID, ConfirmPhoneCmd{ChallengeID: ch.ID, Code: tc.code, SecondFactor: true})
+		if tc.wantCode == "" {
+			if err != nil || user.Phone != "+14155550123" || user.PhoneVerifiedAt == nil || !user.SMSSecondFactor {
+				t.Fatalf("%s: user = %+v, %v", tc.name, user, err)
+			}
+			continue
+		}
+		var ae app.AppError
+		if !errors.As(err, &ae) || ae.Code != tc.wantCode || tc.wantLeft != 0 && ae.Details["attempts_left"] != tc.wantLeft {
+			t.Fatalf("%s: %#v", tc.name, err)
+		}
+	}
+}
+
+func TestPhone_AttemptLimit(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name     string
+		wrong    int
+		wantCode string
+	}{
+		{"within the limit", 1, ""},
+		{"limit used up", 2, app.ErrCodeOTPAttempts},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			u := f.user(t, "u@ex.com", "p")
+			uc := f.phone(OTPConfig{MaxAttempts: 2})
+			ch, err := uc.StartVerification(ctx, u.ID, "+14155550123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tc.wrong; i++ {
+				if _, err := uc.ConfirmVerification(ctx, u.ID, ConfirmPhoneCmd{ChallengeID: ch.ID, Code: "x"}); !isCode(err, app.ErrCodeInvalidCredentials) {
+					t.Fatalf("wrong code %d: %v", i, err)
+				}
+			}
+			_, err = uc.ConfirmVerification(ctx, u.ID, ConfirmPhoneCmd{ChallengeID: ch.ID, Code: f.code(t, "+14155550123")})
+			if tc.wantCode == "" && err != nil || tc.wantCode != "" && !isCode(err, tc.wantCode) {
+				t.Fatalf("right code: got %v, want %q", err, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestPhone_Login(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		requested string
+		sentTo    string // normalized number the code goes to, if any
+	}{
+		{"unknown number", "+14155550199", ""},
+		{"verified number", "+1 415 555 0123", "+14155550123"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			u := f.user(t, "u@ex.com", "p")
+			uc := f.phone(OTPConfig{})
+			f.verifyPhone(t, uc, u, "+14155550123", false)
+			sent := len(f.sms.Messages())
+
+			// An unknown number looks like success but gets no text.
+			ch, err := uc.RequestLogin(ctx, tc.requested)
+			if err != nil || ch.ID == "" {
+				t.Fatalf("request = %+v, %v", ch, err)
+			}
+			if tc.sentTo == "" {
+				if n := len(f.sms.Messages()) - sent; n != 0 {
+					t.Fatalf("sent %d sms to an unknown number", n)
+				}
+				if _, err := uc.Login(ctx, VerifyOTPCmd{ChallengeID: ch.ID, Code: "123456"}); !isCode(err, app.ErrCodeInvalidCredentials) {
+					t.Fatalf("fake challenge: %v", err)
+				}
+				return
+			}
+			res, err := uc.Login(ctx, VerifyOTPCmd{ChallengeID: ch.ID, Code: f.code(t, tc.sentTo)})
+			if err != nil || res.AccessToken != "acc:"+u.ID {
+				t.Fatalf("login = %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestPhone_SecondFactor(t *testing.T) {
+	ctx := context.Background()
+	type signIn func(t *testing.T, f *fixture, uc *PhoneUseCase, u *domain.User) (*LoginUserResult, error)
+	password := func(t *testing.T, f *fixture, _ *PhoneUseCase, _ *domain.User) (*LoginUserResult, error) {
+		return f.login.Handle(ctx, LoginUserCmd{Email: "u@ex.com", Password: "p"})
+	}
+	magicLink := func(t *testing.T, f *fixture, _ *PhoneUseCase, _ *domain.User) (*LoginUserResult, error) {
+		magic := f.magicLink()
+		res, err := magic.Request(ctx, RequestMagicLinkCmd{Email: "u@ex.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return magic.Consume(ctx, ConsumeMagicLinkCmd{Token: f.linkToken(t, "u@ex.com"), Nonce: res.Nonce})
+	}
+	federated := func(t *testing.T, f *fixture, _ *PhoneUseCase, u *domain.User) (*LoginUserResult, error) {
+		return f.login.IssueTokens(ctx, u, "oidc:corp")
+	}
+	turnedOff := func(t *testing.T, f *fixture, uc *PhoneUseCase, u *domain.User) (*LoginUserResult, error) {
+		if _, err := uc.SetSecondFactor(ctx, u.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		return password(t, f, uc, u)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		secondFactor  bool
+		signIn        signIn
+		wantChallenge bool
+	}{
+		{"password without second factor", false, password, false},
+		{"password", true, password, true},
+		{"magic link", true, magicLink, true},
+		// Federated logins leave the second factor to the identity provider.
+		{"federated", true, federated, false},
+		{"password after turning it off", true, turnedOff, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			u := f.user(t, "u@ex.com", "p")
+			uc := f.phone(OTPConfig{})
+			f.verifyPhone(t, uc, u, "+14155550123", tc.secondFactor)
+
+			res, err := tc.signIn(t, f, uc, u)
+			if !tc.wantChallenge {
+				if err != nil || res.AccessToken != "acc:"+u.ID {
+					t.Fatalf("login = %+v, %v", res, err)
+				}
+				return
+			}
+			var ae app.AppError
+			if !errors.As(err, &ae) || ae.Code != app.ErrCodeSecondFactor || ae.Details["method"] != "sms" {
+				t.Fatalf("login: %#v", err)
+			}
+			challengeID, _ := ae.Details["challenge_id"].(string)
+			code := f.code(t, "+14155550123")
+
+			if _, err := uc.Login(ctx, VerifyOTPCmd{ChallengeID: challengeID, Code: code}); !isCode(err, app.ErrCodeInvalidCredentials) {
+				t.Fatalf("second-factor code accepted for phone login: %v", err)
+			}
+			res, err = uc.CompleteSecondFactor(ctx, VerifyOTPCmd{ChallengeID: challengeID, Code: code})
+			if err != nil || res.AccessToken != "acc:"+u.ID {
+				t.Fatalf("complete second factor: %+v, %v", res, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// ProfileUseCase reads the signed-in user's own account.
+type ProfileUseCase struct {
+	log   *slog.Logger
+	users domain.UserRepository
+}
+
+func NewProfileUseCase(log *slog.Logger, users domain.UserRepository) *ProfileUseCase {
+	return &ProfileUseCase{log: log, users: users}
+}
+
+func (uc *ProfileUseCase) Get(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := uc.users.FindByID(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeUnauthorized, "Unauthorized")
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "GetProfile", "user_id", userID), err, "Failed to load user")
+	}
+	return user, nil
+}
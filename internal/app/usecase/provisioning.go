@@ -0,0 +1,634 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+	"go-auth/internal/security/tokenhash"
+)
+
+// scimTokenPrefix marks SCIM bearer tokens so that they are recognizable
+// in configuration and secret scanners.
+const scimTokenPrefix = "scim_"
+
+// ProvisionedUser is a tenant member as the tenant's identity provider
+// sees it.
+type ProvisionedUser struct {
+	domain.Membership
+	Email  string
+	Locale string
+	// Roles the user holds in the tenant, without their members.
+	Roles []domain.Role
+}
+
+// ProvisionedGroup is a tenant role with the user names of its members.
+type ProvisionedGroup struct {
+	domain.Role
+	UserNames map[string]string
+}
+
+type ProvisionUserCmd struct {
+	UserName    string
+	ExternalID  string
+	GivenName   string
+	FamilyName  string
+	DisplayName string
+	// Email defaults to UserName when that is an email address.
+	Email  string
+	Locale string
+	Active bool
+}
+
+type ProvisionGroupCmd struct {
+	Name       string
+	ExternalID string
+	// Members are user IDs of tenant members.
+	Members []string
+}
+
+// ProvisioningUseCase backs the SCIM API, through which a tenant's
+// identity provider manages the tenant's users and roles. Users are matched
+// to existing accounts by email, but only in email domains the tenant owns;
+// deprovisioning a user, by deactivating or deleting it, ends all of its
+// sessions and deletes its personal access tokens but keeps the account.
+//
+// Writes take the version the caller last saw; zero skips the check.
+type ProvisioningUseCase struct {
+	log         *slog.Logger
+	tx          app.TxManager
+	domains     app.TenantDomains
+	memberships domain.MembershipRepository
+	roles       domain.RoleRepository
+	users       domain.UserRepository
+	sessions    domain.RefreshTokenRepository
+	// personalTokens are deleted along with sessions on deprovisioning.
+	personalTokens domain.PersonalAccessTokenRepository
+	register       *RegisterUserUseCase
+	audit          app.AuditLog
+	now            func() time.Time
+}
+
+// NewProvisioningUseCase creates the use case. tx and domains may be nil;
+// without domains no tenant can take in existing accounts.
+func NewProvisioningUseCase(log *slog.Logger, tx app.TxManager, domains app.TenantDomains, memberships domain.MembershipRepository, roles domain.RoleRepository,
+	users domain.UserRepository, sessions domain.RefreshTokenRepository, personalTokens domain.PersonalAccessTokenRepository,
+	register *RegisterUserUseCase, audit app.AuditLog) *ProvisioningUseCase {
+	return &ProvisioningUseCase{
+		log:            log,
+		tx:             tx,
+		domains:        domains,
+		memberships:    memberships,
+		roles:          roles,
+		users:          users,
+		sessions:       sessions,
+		personalTokens: personalTokens,
+		register:       register,
+		audit:          audit,
+		now:            time.Now,
+	}
+}
+
+// CreateUser makes the user a member of the tenant, creating the account
+// unless one exists with the email. An existing account is only taken in
+// if the tenant owns its email domain; anyone else's account is a
+// conflict, as the tenant would otherwise gain control over it.
+func (uc *ProvisioningUseCase) CreateUser(ctx context.Context, tenantID string, cmd ProvisionUserCmd) (*ProvisionedUser, error) {
+	log := uc.log.With("op", "ProvisionUser", "tenant_id", tenantID)
+	if err := normalizeProvisionUser(&cmd); err != nil {
+		return nil, err
+	}
+
+	now := uc.now().UTC()
+	m := &domain.Membership{TenantID: tenantID, CreatedAt: now, UpdatedAt: now}
+	applyProvisionUser(m, cmd)
+	var user *domain.User
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		user, err = uc.users.FindByEmail(ctx, cmd.Email)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			if user, err = uc.signUp(ctx, log, tenantID, cmd); err != nil {
+				return err
+			}
+			m.CreatedAccount = true
+		case err != nil:
+			return storageError(log, err, "Failed to fetch user")
+		case !uc.domains.Owns(tenantID, user.Email):
+			log.Warn("refused to take in an account outside the tenant's domains", "user_id", user.ID)
+			return app.NewError(app.ErrCodeConflict, "An account with this email already exists")
+		}
+
+		m.UserID = user.ID
+		err = uc.memberships.Create(ctx, m)
+		if errors.Is(err, domain.ErrConflict) {
+			return app.NewError(app.ErrCodeConflict, "User is already provisioned or the user name is taken")
+		}
+		if err != nil {
+			return storageError(log, err, "Failed to create membership")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditUserProvisioned,
+		TargetID: user.ID,
+		TenantID: tenantID,
+		Metadata: map[string]string{"user_name": m.UserName},
+	})
+	return &ProvisionedUser{Membership: *m, Email: user.Email, Locale: user.Locale}, nil
+}
+
+// signUp registers a provisioned user with an unusable random password;
+// the user signs in through the tenant's identity provider or sets a
+// password through password reset.
+func (uc *ProvisioningUseCase) signUp(ctx context.Context, log *slog.Logger, tenantID string, cmd ProvisionUserCmd) (*domain.User, error) {
+	password, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.register.Handle(ctx, RegisterUserCmd{Email: cmd.Email, Password: password, Locale: cmd.Locale, TenantID: tenantID}); err != nil {
+		return nil, err
+	}
+	user, err := uc.users.FindByEmail(ctx, cmd.Email)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	return user, nil
+}
+
+func (uc *ProvisioningUseCase) GetUser(ctx context.Context, tenantID, userID string) (*ProvisionedUser, error) {
+	log := uc.log.With("op", "GetProvisionedUser", "tenant_id", tenantID)
+	m, err := uc.memberships.Get(ctx, tenantID, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch membership")
+	}
+	user, err := uc.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch user")
+	}
+	roles, err := uc.roles.ListByMember(ctx, tenantID, userID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list roles")
+	}
+	return &ProvisionedUser{Membership: *m, Email: user.Email, Locale: user.Locale, Roles: roles}, nil
+}
+
+// ListUsers returns every member of the tenant, oldest first.
+func (uc *ProvisioningUseCase) ListUsers(ctx context.Context, tenantID string) ([]ProvisionedUser, error) {
+	log := uc.log.With("op", "ListProvisionedUsers", "tenant_id", tenantID)
+	members, err := uc.memberships.List(ctx, tenantID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list memberships")
+	}
+	roles, err := uc.roles.List(ctx, tenantID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list roles")
+	}
+	held := map[string][]domain.Role{}
+	for _, role := range roles {
+		for _, userID := range role.Members {
+			held[userID] = append(held[userID], domain.Role{ID: role.ID, TenantID: role.TenantID, Name: role.Name})
+		}
+	}
+
+	out := make([]ProvisionedUser, 0, len(members))
+	for _, m := range members {
+		user, err := uc.users.FindByID(ctx, m.UserID)
+		if err != nil {
+			return nil, storageError(log, err, "Failed to fetch user")
+		}
+		out = append(out, ProvisionedUser{Membership: m, Email: user.Email, Locale: user.Locale, Roles: held[m.UserID]})
+	}
+	return out, nil
+}
+
+// ReplaceUser overwrites the user's profile in the tenant. The email can
+// only change if the tenant created the account and is the only one the
+// user belongs to, so that no tenant can redirect another's account.
+func (uc *ProvisioningUseCase) ReplaceUser(ctx context.Context, tenantID, userID string, version int64, cmd ProvisionUserCmd) (*ProvisionedUser, error) {
+	log := uc.log.With("op", "ReplaceProvisionedUser", "tenant_id", tenantID, "user_id", userID)
+	if err := normalizeProvisionUser(&cmd); err != nil {
+		return nil, err
+	}
+
+	var (
+		m           *domain.Membership
+		user        *domain.User
+		deactivated bool
+	)
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if m, err = uc.currentMembership(ctx, log, tenantID, userID, version); err != nil {
+			return err
+		}
+		if user, err = uc.users.FindByID(ctx, userID); err != nil {
+			return storageError(log, err, "Failed to fetch user")
+		}
+		if err := uc.updateProfile(ctx, log, m, user, cmd); err != nil {
+			return err
+		}
+
+		deactivated = m.Active && !cmd.Active
+		applyProvisionUser(m, cmd)
+		m.UpdatedAt = uc.now().UTC()
+		switch err := uc.memberships.Update(ctx, m); {
+		case errors.Is(err, domain.ErrNotFound):
+			return app.NewError(app.ErrCodePreconditionFailed, "User was modified concurrently")
+		case errors.Is(err, domain.ErrConflict):
+			return app.NewError(app.ErrCodeConflict, "User name is taken")
+		case err != nil:
+			return storageError(log, err, "Failed to update membership")
+		}
+
+		if deactivated {
+			return uc.revokeCredentials(ctx, log, userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if deactivated {
+		recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+			Type:     domain.AuditUserDeprovisioned,
+			TargetID: userID,
+			TenantID: tenantID,
+			Metadata: map[string]string{"user_name": m.UserName, "reason": "deactivated"},
+		})
+		log.Info("user deactivated, credentials revoked")
+	}
+	roles, err := uc.roles.ListByMember(ctx, tenantID, userID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list roles")
+	}
+	return &ProvisionedUser{Membership: *m, Email: user.Email, Locale: user.Locale, Roles: roles}, nil
+}
+
+func (uc *ProvisioningUseCase) updateProfile(ctx context.Context, log *slog.Logger, m *domain.Membership, user *domain.User, cmd ProvisionUserCmd) error {
+	locale := user.Locale
+	if cmd.Locale != "" {
+		locale = cmd.Locale
+	}
+	if user.Email == cmd.Email && user.Locale == locale {
+		return nil
+	}
+	if user.Email != cmd.Email {
+		if !m.CreatedAccount {
+			return app.NewError(app.ErrCodeForbidden, "The account was not created by this tenant; its email cannot be changed")
+		}
+		tenants, err := uc.memberships.ListByUser(ctx, user.ID)
+		if err != nil {
+			return storageError(log, err, "Failed to list memberships")
+		}
+		if len(tenants) > 1 {
+			return app.NewError(app.ErrCodeForbidden, "The user belongs to other tenants; its email cannot be changed")
+		}
+	}
+	user.Email, user.Locale, user.UpdatedAt = cmd.Email, locale, uc.now().UTC()
+	switch err := uc.users.UpdateProfile(ctx, user); {
+	case errors.Is(err, domain.ErrConflict):
+		return app.NewError(app.ErrCodeEmailExists, "Email already exists")
+	case err != nil:
+		return storageError(log, err, "Failed to update user")
+	}
+	return nil
+}
+
+// DeleteUser removes the user from the tenant and its roles.
+func (uc *ProvisioningUseCase) DeleteUser(ctx context.Context, tenantID, userID string, version int64) error {
+	log := uc.log.With("op", "DeleteProvisionedUser", "tenant_id", tenantID, "user_id", userID)
+	var m *domain.Membership
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if m, err = uc.currentMembership(ctx, log, tenantID, userID, version); err != nil {
+			return err
+		}
+		if err := uc.memberships.Delete(ctx, tenantID, userID); err != nil {
+			return storageError(log, err, "Failed to delete membership")
+		}
+		return uc.revokeCredentials(ctx, log, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditUserDeprovisioned,
+		TargetID: userID,
+		TenantID: tenantID,
+		Metadata: map[string]string{"user_name": m.UserName, "reason": "deleted"},
+	})
+	log.Info("user deprovisioned, credentials revoked")
+	return nil
+}
+
+// revokeCredentials ends the user's sessions and deletes its personal
+// access tokens.
+func (uc *ProvisioningUseCase) revokeCredentials(ctx context.Context, log *slog.Logger, userID string) error {
+	if err := uc.sessions.RevokeAllByUser(ctx, userID); err != nil {
+		return storageError(log, err, "Failed to revoke sessions")
+	}
+	if err := uc.personalTokens.DeleteAllByUser(ctx, userID); err != nil {
+		return storageError(log, err, "Failed to revoke personal access tokens")
+	}
+	return nil
+}
+
+// currentMembership returns the membership if it is at version.
+func (uc *ProvisioningUseCase) currentMembership(ctx context.Context, log *slog.Logger, tenantID, userID string, version int64) (*domain.Membership, error) {
+	m, err := uc.memberships.Get(ctx, tenantID, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch membership")
+	}
+	if version != 0 && m.Version != version {
+		return nil, app.NewError(app.ErrCodePreconditionFailed, "User has been modified")
+	}
+	return m, nil
+}
+
+func normalizeProvisionUser(cmd *ProvisionUserCmd) error {
+	cmd.UserName = strings.TrimSpace(cmd.UserName)
+	if cmd.UserName == "" {
+		return app.NewError(app.ErrCodeValidation, "userName is required")
+	}
+	if cmd.Email == "" && strings.Contains(cmd.UserName, "@") {
+		cmd.Email = cmd.UserName
+	}
+	if addr, err := mail.ParseAddress(cmd.Email); err != nil || addr.Address != cmd.Email {
+		return app.NewError(app.ErrCodeValidation, "A valid email address is required")
+	}
+	if cmd.Locale != "" {
+		// SCIM locales are language tags such as en-US.
+		cmd.Locale = i18n.Negotiate(cmd.Locale, "")
+	}
+	return nil
+}
+
+func applyProvisionUser(m *domain.Membership, cmd ProvisionUserCmd) {
+	m.UserName, m.ExternalID, m.Active = cmd.UserName, cmd.ExternalID, cmd.Active
+	m.GivenName, m.FamilyName, m.DisplayName = cmd.GivenName, cmd.FamilyName, cmd.DisplayName
+}
+
+func (uc *ProvisioningUseCase) CreateGroup(ctx context.Context, tenantID string, cmd ProvisionGroupCmd) (*ProvisionedGroup, error) {
+	log := uc.log.With("op", "ProvisionGroup", "tenant_id", tenantID)
+	if err := normalizeProvisionGroup(&cmd); err != nil {
+		return nil, err
+	}
+	now := uc.now().UTC()
+	role := &domain.Role{
+		TenantID:   tenantID,
+		Name:       cmd.Name,
+		ExternalID: cmd.ExternalID,
+		Members:    cmd.Members,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	var names map[string]string
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if names, err = uc.memberNames(ctx, log, tenantID, cmd.Members); err != nil {
+			return err
+		}
+		err = uc.roles.Create(ctx, role)
+		if errors.Is(err, domain.ErrConflict) {
+			return app.NewError(app.ErrCodeConflict, "Group name is taken")
+		}
+		if err != nil {
+			return storageError(log, err, "Failed to create role")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.auditMembers(ctx, log, role, nil, role.Members)
+	return &ProvisionedGroup{Role: *role, UserNames: names}, nil
+}
+
+func (uc *ProvisioningUseCase) GetGroup(ctx context.Context, tenantID, id string) (*ProvisionedGroup, error) {
+	log := uc.log.With("op", "GetProvisionedGroup", "tenant_id", tenantID)
+	role, err := uc.currentRole(ctx, log, tenantID, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	names, err := uc.memberNames(ctx, log, tenantID, role.Members)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvisionedGroup{Role: *role, UserNames: names}, nil
+}
+
+// ListGroups returns every role of the tenant, oldest first.
+func (uc *ProvisioningUseCase) ListGroups(ctx context.Context, tenantID string) ([]ProvisionedGroup, error) {
+	log := uc.log.With("op", "ListProvisionedGroups", "tenant_id", tenantID)
+	roles, err := uc.roles.List(ctx, tenantID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list roles")
+	}
+	members, err := uc.memberships.List(ctx, tenantID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list memberships")
+	}
+	names := make(map[string]string, len(members))
+	for _, m := range members {
+		names[m.UserID] = m.UserName
+	}
+	out := make([]ProvisionedGroup, 0, len(roles))
+	for _, role := range roles {
+		out = append(out, ProvisionedGroup{Role: role, UserNames: names})
+	}
+	return out, nil
+}
+
+func (uc *ProvisioningUseCase) ReplaceGroup(ctx context.Context, tenantID, id string, version int64, cmd ProvisionGroupCmd) (*ProvisionedGroup, error) {
+	log := uc.log.With("op", "ReplaceProvisionedGroup", "tenant_id", tenantID, "role_id", id)
+	if err := normalizeProvisionGroup(&cmd); err != nil {
+		return nil, err
+	}
+	var (
+		role     *domain.Role
+		previous []string
+		names    map[string]string
+	)
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if role, err = uc.currentRole(ctx, log, tenantID, id, version); err != nil {
+			return err
+		}
+		if names, err = uc.memberNames(ctx, log, tenantID, cmd.Members); err != nil {
+			return err
+		}
+		previous = role.Members
+		role.Name, role.ExternalID, role.Members, role.UpdatedAt = cmd.Name, cmd.ExternalID, cmd.Members, uc.now().UTC()
+		switch err := uc.roles.Update(ctx, role); {
+		case errors.Is(err, domain.ErrNotFound):
+			return app.NewError(app.ErrCodePreconditionFailed, "Group was modified concurrently")
+		case errors.Is(err, domain.ErrConflict):
+			return app.NewError(app.ErrCodeConflict, "Group name is taken")
+		case err != nil:
+			return storageError(log, err, "Failed to update role")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.auditMembers(ctx, log, role, previous, role.Members)
+	return &ProvisionedGroup{Role: *role, UserNames: names}, nil
+}
+
+func (uc *ProvisioningUseCase) DeleteGroup(ctx context.Context, tenantID, id string, version int64) error {
+	log := uc.log.With("op", "DeleteProvisionedGroup", "tenant_id", tenantID, "role_id", id)
+	var role *domain.Role
+	err := withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		var err error
+		if role, err = uc.currentRole(ctx, log, tenantID, id, version); err != nil {
+			return err
+		}
+		if err := uc.roles.Delete(ctx, tenantID, id); err != nil {
+			return storageError(log, err, "Failed to delete role")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	uc.auditMembers(ctx, log, role, role.Members, nil)
+	return nil
+}
+
+// currentRole returns the role if it is at version, or any version for 0.
+func (uc *ProvisioningUseCase) currentRole(ctx context.Context, log *slog.Logger, tenantID, id string, version int64) (*domain.Role, error) {
+	role, err := uc.roles.Get(ctx, tenantID, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "Group not found")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to fetch role")
+	}
+	if version != 0 && role.Version != version {
+		return nil, app.NewError(app.ErrCodePreconditionFailed, "Group has been modified")
+	}
+	return role, nil
+}
+
+// memberNames checks that every user is a member of the tenant and returns
+// their user names.
+func (uc *ProvisioningUseCase) memberNames(ctx context.Context, log *slog.Logger, tenantID string, userIDs []string) (map[string]string, error) {
+	names := make(map[string]string, len(userIDs))
+	for _, userID := range userIDs {
+		m, err := uc.memberships.Get(ctx, tenantID, userID)
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, app.NewError(app.ErrCodeValidation, fmt.Sprintf("Group member %s is not a user of the tenant", userID))
+		}
+		if err != nil {
+			return nil, storageError(log, err, "Failed to fetch membership")
+		}
+		names[userID] = m.UserName
+	}
+	return names, nil
+}
+
+// auditMembers records a grant for every user in after but not before and
+// a revocation for every user in before but not after.
+func (uc *ProvisioningUseCase) auditMembers(ctx context.Context, log *slog.Logger, role *domain.Role, before, after []string) {
+	record := func(eventType domain.AuditEventType, userID string) {
+		recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+			Type:     eventType,
+			TargetID: userID,
+			TenantID: role.TenantID,
+			Metadata: map[string]string{"role_id": role.ID, "role": role.Name},
+		})
+	}
+	for _, userID := range after {
+		if !slices.Contains(before, userID) {
+			record(domain.AuditRoleGranted, userID)
+		}
+	}
+	for _, userID := range before {
+		if !slices.Contains(after, userID) {
+			record(domain.AuditRoleRevoked, userID)
+		}
+	}
+}
+
+func normalizeProvisionGroup(cmd *ProvisionGroupCmd) error {
+	cmd.Name = strings.TrimSpace(cmd.Name)
+	if cmd.Name == "" {
+		return app.NewError(app.ErrCodeValidation, "displayName is required")
+	}
+	members := slices.Clone(cmd.Members)
+	slices.Sort(members)
+	cmd.Members = slices.Compact(members)
+	return nil
+}
+
+// ManageSCIMTokensUseCase issues and checks the bearer tokens tenants'
+// identity providers authenticate to the SCIM API with.
+type ManageSCIMTokensUseCase struct {
+	repo domain.SCIMTokenRepository
+}
+
+func NewManageSCIMTokensUseCase(repo domain.SCIMTokenRepository) *ManageSCIMTokensUseCase {
+	return &ManageSCIMTokensUseCase{repo: repo}
+}
+
+// Create issues a token for the tenant and returns it along with the
+// bearer credential, which is not exposed again afterwards.
+func (uc *ManageSCIMTokensUseCase) Create(ctx context.Context, tenantID, description string) (*domain.SCIMToken, string, error) {
+	if tenantID == "" {
+		return nil, "", app.NewError(app.ErrCodeValidation, "tenant_id is required")
+	}
+	secret, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	secret = scimTokenPrefix + secret
+	token := &domain.SCIMToken{TenantID: tenantID, Description: description, TokenHash: tokenhash.Hash(secret)}
+	if err := uc.repo.Create(ctx, token); err != nil {
+		return nil, "", fmt.Errorf("failed to create scim token: %w", err)
+	}
+	return token, secret, nil
+}
+
+func (uc *ManageSCIMTokensUseCase) List(ctx context.Context, tenantID string) ([]domain.SCIMToken, error) {
+	return uc.repo.List(ctx, tenantID)
+}
+
+func (uc *ManageSCIMTokensUseCase) Delete(ctx context.Context, id string) error {
+	return notFoundAs(uc.repo.Delete(ctx, id), domain.ErrNotFound, "SCIM token not found")
+}
+
+// Authenticate returns the token whose bearer credential is secret.
+func (uc *ManageSCIMTokensUseCase) Authenticate(ctx context.Context, secret string) (*domain.SCIMToken, error) {
+	if !strings.HasPrefix(secret, scimTokenPrefix) {
+		return nil, app.NewError(app.ErrCodeUnauthorized, "Invalid SCIM token")
+	}
+	token, err := uc.repo.FindByHash(ctx, tokenhash.Hash(secret))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeUnauthorized, "Invalid SCIM token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up scim token: %w", err)
+	}
+	return token, nil
+}
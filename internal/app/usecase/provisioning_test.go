@@ -0,0 +1,249 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+)
+
+// provisioning returns the SCIM use case over the fixture's repositories.
+func (f *fixture) provisioning() *ProvisioningUseCase {
+	return NewProvisioningUseCase(f.log, f.tx, testDomains, f.members, f.roles, f.users, f.sessions, f.pats, f.register, f.audit)
+}
+
+func TestProvisioning_CreateUser(t *testing.T) {
+	ctx := context.Background()
+	alice := ProvisionUserCmd{UserName: "alice", Email: "alice@ex.com", Locale: "ru-RU", Active: true}
+	for _, tc := range []struct {
+		name   string
+		before *ProvisionUserCmd // created in acme first, if set
+		tenant string
+		cmd    ProvisionUserCmd
+		// code of the expected error; on success, whether a new account
+		// was registered rather than an existing one taken in
+		wantCode    string
+		wantCreated bool
+	}{
+		// The tenant owns acme.com, so it takes in bob's existing account.
+		{"existing account in the tenant's domain", nil, "acme", ProvisionUserCmd{UserName: "bob@acme.com", Active: true}, "", false},
+		// Accounts outside its domains are not the tenant's to take.
+		{"existing account in no tenant's domain", nil, "acme", ProvisionUserCmd{UserName: "eve@ex.com", Active: true}, app.ErrCodeConflict, false},
+		{"existing account in another tenant's domain", nil, "globex", ProvisionUserCmd{UserName: "bob@acme.com", Active: true}, app.ErrCodeConflict, false},
+		{"new account", nil, "acme", alice, "", true},
+		{"missing email", nil, "acme", ProvisionUserCmd{UserName: "carol"}, app.ErrCodeValidation, false},
+		{"duplicate member", &alice, "acme", ProvisionUserCmd{UserName: "alice2", Email: "alice@ex.com"}, app.ErrCodeConflict, false},
+		// Another tenant cannot take in the account acme created.
+		{"account another tenant created", &alice, "globex", ProvisionUserCmd{UserName: "alice@ex.com", Active: true}, app.ErrCodeConflict, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			uc := f.provisioning()
+			bob, eve := f.user(t, "bob@acme.com", "pw"), f.user(t, "eve@ex.com", "pw")
+			if tc.before != nil {
+				if _, err := uc.CreateUser(ctx, "acme", *tc.before); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := uc.CreateUser(ctx, tc.tenant, tc.cmd)
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				for _, u := range []*domain.User{bob, eve} {
+					if ms, _ := f.members.ListByUser(ctx, u.ID); len(ms) != 0 {
+						t.Fatalf("%s taken in: %+v", u.Email, ms)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			user, err := f.users.FindByID(ctx, got.UserID)
+			if err != nil || got.Version != 1 || got.Email != user.Email || got.CreatedAccount != tc.wantCreated {
+				t.Fatalf("member = %+v, account %+v, %v", got, user, err)
+			}
+			if !tc.wantCreated && user.ID != bob.ID {
+				t.Fatalf("linked %s, want %s", user.ID, bob.ID)
+			}
+			if tc.wantCreated && user.Locale != "ru" {
+				t.Fatalf("registered user = %+v", user)
+			}
+			if last := f.audit.last(); last.Type != domain.AuditUserProvisioned || last.TenantID != tc.tenant || last.TargetID != user.ID {
+				t.Fatalf("audit = %+v", last)
+			}
+		})
+	}
+}
+
+func TestProvisioning_DeactivateRevokesSessions(t *testing.T) {
+	f := newFixture()
+	uc := f.provisioning()
+	ctx := context.Background()
+	u, err := uc.CreateUser(ctx, "acme", ProvisionUserCmd{UserName: "bob@ex.com", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.sessions.Save(ctx, u.UserID, "h1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = uc.ReplaceUser(ctx, "acme", u.UserID, 5, ProvisionUserCmd{UserName: "bob@ex.com"})
+	if !isCode(err, app.ErrCodePreconditionFailed) {
+		t.Fatalf("stale version: %v", err)
+	}
+	got, err := uc.ReplaceUser(ctx, "acme", u.UserID, u.Version, ProvisionUserCmd{UserName: "bob@ex.com", DisplayName: "Bob"})
+	if err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if got.Active || got.DisplayName != "Bob" || got.Version != 2 {
+		t.Fatalf("replaced = %+v", got)
+	}
+	rt, err := f.sessions.FindByHash(ctx, "h1")
+	if err != nil || rt.RevokedAt == nil {
+		t.Fatalf("session = %+v, %v; want revoked", rt, err)
+	}
+	if last := f.audit.last(); last.Type != domain.AuditUserDeprovisioned || last.Metadata["reason"] != "deactivated" {
+		t.Fatalf("audit = %+v", last)
+	}
+
+	if err := uc.DeleteUser(ctx, "acme", u.UserID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := uc.GetUser(ctx, "acme", u.UserID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("get deleted: %v", err)
+	}
+	// The account itself stays.
+	if _, err := f.users.FindByID(ctx, u.UserID); err != nil {
+		t.Fatalf("account: %v", err)
+	}
+}
+
+func TestProvisioning_EmailChange(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name       string
+		email      string
+		takenIn    bool   // the account existed before the tenant provisioned it
+		sharedWith string // another tenant the account is a member of
+		changeBy   string
+		wantCode   string
+	}{
+		{"sole tenant of an account it created", "bob@ex.com", false, "", "acme", ""},
+		{"creator of a shared account", "bob@ex.com", false, "globex", "acme", app.ErrCodeForbidden},
+		{"other member of a shared account", "bob@ex.com", false, "globex", "globex", app.ErrCodeForbidden},
+		// Taking in an account does not let the tenant redirect it.
+		{"account the tenant took in", "bob@acme.com", true, "", "acme", app.ErrCodeForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			uc := f.provisioning()
+			cmd := ProvisionUserCmd{UserName: "bob", Email: tc.email, Active: true}
+			if tc.takenIn {
+				f.user(t, tc.email, "pw")
+				cmd = ProvisionUserCmd{UserName: tc.email, Active: true}
+			}
+			u, err := uc.CreateUser(ctx, "acme", cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.sharedWith != "" {
+				if err := f.members.Create(ctx, &domain.Membership{TenantID: tc.sharedWith, UserID: u.UserID, UserName: "bob", Active: true}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, host, _ := strings.Cut(tc.email, "@")
+			cmd.Email = "robert@" + host
+			got, err := uc.ReplaceUser(ctx, tc.changeBy, u.UserID, 0, cmd)
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				if user, _ := f.users.FindByID(ctx, u.UserID); user.Email != tc.email {
+					t.Fatalf("email changed to %s", user.Email)
+				}
+				return
+			}
+			if err != nil || got.Email != cmd.Email {
+				t.Fatalf("change email = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestProvisioning_Groups(t *testing.T) {
+	f := newFixture()
+	uc := f.provisioning()
+	ctx := context.Background()
+	alice, _ := uc.CreateUser(ctx, "acme", ProvisionUserCmd{UserName: "alice@ex.com", Active: true})
+	bob, _ := uc.CreateUser(ctx, "acme", ProvisionUserCmd{UserName: "bob@ex.com", Active: true})
+	outsider, _ := uc.CreateUser(ctx, "globex", ProvisionUserCmd{UserName: "eve@ex.com", Active: true})
+
+	_, err := uc.CreateGroup(ctx, "acme", ProvisionGroupCmd{Name: "Admins", Members: []string{outsider.UserID}})
+	if !isCode(err, app.ErrCodeValidation) {
+		t.Fatalf("foreign member: %v", err)
+	}
+	g, err := uc.CreateGroup(ctx, "acme", ProvisionGroupCmd{Name: "Admins", Members: []string{alice.UserID}})
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if g.UserNames[alice.UserID] != "alice@ex.com" {
+		t.Fatalf("group = %+v", g)
+	}
+	if _, err := uc.CreateGroup(ctx, "acme", ProvisionGroupCmd{Name: "admins"}); !isCode(err, app.ErrCodeConflict) {
+		t.Fatalf("duplicate name: %v", err)
+	}
+
+	*f.audit = nil
+	g, err = uc.ReplaceGroup(ctx, "acme", g.ID, g.Version, ProvisionGroupCmd{Name: "Admins", Members: []string{bob.UserID}})
+	if err != nil || len(g.Members) != 1 || g.Members[0] != bob.UserID {
+		t.Fatalf("replace group = %+v, %v", g, err)
+	}
+	if len(*f.audit) != 2 || (*f.audit)[0].Type != domain.AuditRoleGranted || (*f.audit)[0].TargetID != bob.UserID ||
+		(*f.audit)[1].Type != domain.AuditRoleRevoked || (*f.audit)[1].TargetID != alice.UserID {
+		t.Fatalf("audit = %+v", *f.audit)
+	}
+
+	u, err := uc.GetUser(ctx, "acme", bob.UserID)
+	if err != nil || len(u.Roles) != 1 || u.Roles[0].Name != "Admins" {
+		t.Fatalf("user roles = %+v, %v", u, err)
+	}
+	if err := uc.DeleteGroup(ctx, "acme", g.ID, 1); !isCode(err, app.ErrCodePreconditionFailed) {
+		t.Fatalf("stale delete: %v", err)
+	}
+	if err := uc.DeleteGroup(ctx, "acme", g.ID, g.Version); err != nil {
+		t.Fatalf("delete group: %v", err)
+	}
+}
+
+func TestSCIMTokens(t *testing.T) {
+	uc := NewManageSCIMTokensUseCase(memory.NewSCIMTokenRepository())
+	ctx := context.Background()
+	token, secret, err := uc.Create(ctx, "acme", "Okta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := uc.Authenticate(ctx, secret)
+	if err != nil || got.ID != token.ID || got.TenantID != "acme" {
+		t.Fatalf("authenticate = %+v, %v", got, err)
+	}
+	if _, err := uc.Authenticate(ctx, secret+"x"); !isCode(err, app.ErrCodeUnauthorized) {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	if err := uc.Delete(ctx, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.Authenticate(ctx, secret); !isCode(err, app.ErrCodeUnauthorized) {
+		t.Fatalf("deleted token: %v", err)
+	}
+	if err := uc.Delete(ctx, token.ID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("delete twice: %v", err)
+	}
+}
@@ -2,21 +2,27 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"go-auth/internal/app"
 	"go-auth/internal/domain"
 	"go-auth/internal/security/tokenhash"
+	"log/slog"
 	"time"
 )
 
 type RefreshCmd struct{ RefreshToken string }
 
 type RefreshUseCase struct {
+	log    *slog.Logger
+	tx     app.TxManager
 	tokens app.TokenService
 	repo   domain.RefreshTokenRepository
+	users  domain.UserRepository
+	audit  app.AuditLog
 }
 
-func NewRefreshUseCase(tokens app.TokenService, repo domain.RefreshTokenRepository) *RefreshUseCase {
-	return &RefreshUseCase{tokens: tokens, repo: repo}
+func NewRefreshUseCase(log *slog.Logger, tx app.TxManager, tokens app.TokenService, repo domain.RefreshTokenRepository, users domain.UserRepository, audit app.AuditLog) *RefreshUseCase {
+	return &RefreshUseCase{log: log, tx: tx, tokens: tokens, repo: repo, users: users, audit: audit}
 }
 
 func (uc *RefreshUseCase) Handle(ctx context.Context, cmd RefreshCmd) (*LoginUserResult, error) {
@@ -26,14 +32,29 @@ func (uc *RefreshUseCase) Handle(ctx context.Context, cmd RefreshCmd) (*LoginUse
 	}
 	h := tokenhash.Hash(cmd.RefreshToken)
 	rec, err := uc.repo.FindByHash(ctx, h)
-	if rec == nil {
+	if errors.Is(err, domain.ErrNotFound) {
 		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid refresh token")
 	}
-	if rec.RevokedAt != nil || time.Now().After(rec.ExpiresAt) {
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "Refresh"), err, "Failed to load refresh token")
+	}
+	if rec.RevokedAt != nil {
+		return nil, uc.reuseDetected(ctx, rec)
+	}
+	if time.Now().After(rec.ExpiresAt) {
 		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid refresh token")
 	}
-
-	_ = uc.repo.RevokeByHash(ctx, h)
+	// Sessions do not outlive the account, nor continue while it is disabled.
+	user, err := uc.users.FindByID(ctx, uid)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid refresh token")
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "Refresh", "user_id", uid), err, "Failed to load user")
+	}
+	if user.Disabled() {
+		return nil, app.NewError(app.ErrCodeAccountDisabled, "Account is disabled")
+	}
 
 	access, err := uc.tokens.GenerateAccessToken(uid)
 	if err != nil {
@@ -43,19 +64,61 @@ func (uc *RefreshUseCase) Handle(ctx context.Context, cmd RefreshCmd) (*LoginUse
 	if err != nil {
 		return nil, app.NewError(app.ErrCodeInternal, "Failed to generate refresh token")
 	}
-	_ = uc.repo.Save(ctx, uid, tokenhash.Hash(newRefresh), time.Now().Add(24*time.Hour*7))
+
+	// Rotate atomically so a failure can neither strand the user without a
+	// valid token nor leave the old one usable next to the new one. The
+	// revoke only succeeds for a token still unrevoked, so when the same
+	// token is refreshed concurrently the loser sees reuse.
+	err = withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		if err := uc.repo.RevokeByHash(ctx, h); err != nil {
+			return err
+		}
+		return uc.repo.Save(ctx, uid, tokenhash.Hash(newRefresh), time.Now().Add(uc.tokens.RefreshTTL()))
+	})
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, uc.reuseDetected(ctx, rec)
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "Refresh", "user_id", uid), err, "Failed to rotate refresh token")
+	}
+
+	recordAudit(ctx, uc.log, uc.audit, domain.AuditEvent{Type: domain.AuditTokenRefreshed, ActorID: uid, TargetID: uid})
 
 	return &LoginUserResult{AccessToken: access, RefreshToken: newRefresh, ExpiresIn: int64(uc.tokens.AccessTTL().Seconds())}, nil
 }
 
+// reuseDetected handles a rotated token that came back: either the client
+// or an attacker holds a stolen copy, so every session of the user ends.
+func (uc *RefreshUseCase) reuseDetected(ctx context.Context, rec *domain.RefreshToken) error {
+	log := uc.log.With("op", "Refresh", "user_id", rec.UserID)
+	log.Warn("refresh token reuse detected")
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditTokenReuseDetected,
+		TargetID: rec.UserID,
+		Metadata: map[string]string{"token_id": rec.ID},
+	})
+	if err := uc.repo.RevokeAllByUser(ctx, rec.UserID); err != nil {
+		log.Error("failed to revoke sessions after reuse", "error", err)
+	}
+	return app.NewError(app.ErrCodeInvalidCredentials, "Invalid refresh token")
+}
+
 type LogoutCmd struct{ UserID string }
 
-type LogoutUseCase struct{ repo domain.RefreshTokenRepository }
+type LogoutUseCase struct {
+	log   *slog.Logger
+	repo  domain.RefreshTokenRepository
+	audit app.AuditLog
+}
 
-func NewLogoutUseCase(repo domain.RefreshTokenRepository) *LogoutUseCase {
-	return &LogoutUseCase{repo: repo}
+func NewLogoutUseCase(log *slog.Logger, repo domain.RefreshTokenRepository, audit app.AuditLog) *LogoutUseCase {
+	return &LogoutUseCase{log: log, repo: repo, audit: audit}
 }
 
 func (uc *LogoutUseCase) Handle(ctx context.Context, cmd LogoutCmd) error {
-	return uc.repo.RevokeAllByUser(ctx, cmd.UserID)
+	if err := uc.repo.RevokeAllByUser(ctx, cmd.UserID); err != nil {
+		return storageError(uc.log.With("op", "Logout", "user_id", cmd.UserID), err, "Failed to revoke sessions")
+	}
+	recordAudit(ctx, uc.log, uc.audit, domain.AuditEvent{Type: domain.AuditLogout, ActorID: cmd.UserID, TargetID: cmd.UserID})
+	return nil
 }
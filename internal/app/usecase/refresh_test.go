@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/security/jwt"
+	"go-auth/internal/security/tokenhash"
+)
+
+// staleRefreshRepo reads every token as unrevoked, as a refresh racing
+// another with the same token does before either has rotated it.
+type staleRefreshRepo struct{ *memory.RefreshRepository }
+
+func (r staleRefreshRepo) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	rec, err := r.RefreshRepository.FindByHash(ctx, tokenHash)
+	if rec != nil {
+		rec.RevokedAt = nil
+	}
+	return rec, err
+}
+
+func TestRefresh_ConcurrentReuseIsDetected(t *testing.T) {
+	ctx := context.Background()
+	users, repo, audit := memory.NewUserRepository(), staleRefreshRepo{memory.NewRefreshRepository()}, &auditTrail{}
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Hour, RefreshTTL: time.Hour})
+	uc := NewRefreshUseCase(slog.New(slog.NewTextHandler(testWriter{}, nil)), nil, tokens, repo, users, audit)
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	refresh, _ := tokens.GenerateRefreshToken(u.ID)
+	if err := repo.Save(ctx, u.ID, tokenhash.Hash(refresh), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := uc.Handle(ctx, RefreshCmd{RefreshToken: refresh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.Handle(ctx, RefreshCmd{RefreshToken: refresh}); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("second refresh with the same token: %v", err)
+	}
+	if last := (*audit)[len(*audit)-1]; last.Type != domain.AuditTokenReuseDetected || last.TargetID != u.ID {
+		t.Fatalf("audit = %+v", last)
+	}
+	if rec, _ := repo.RefreshRepository.FindByHash(ctx, tokenhash.Hash(first.RefreshToken)); rec == nil || rec.RevokedAt == nil {
+		t.Fatalf("winner's token survived reuse: %+v", rec)
+	}
+}
+
+func TestRefresh_RotatedTokenUsesConfiguredTTL(t *testing.T) {
+	ctx := context.Background()
+	users, repo := memory.NewUserRepository(), memory.NewRefreshRepository()
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Minute, RefreshTTL: 2 * time.Hour})
+	uc := NewRefreshUseCase(slog.New(slog.NewTextHandler(testWriter{}, nil)), nil, tokens, repo, users, &auditTrail{})
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	refresh, _ := tokens.GenerateRefreshToken(u.ID)
+	if err := repo.Save(ctx, u.ID, tokenhash.Hash(refresh), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := uc.Handle(ctx, RefreshCmd{RefreshToken: refresh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := repo.FindByHash(ctx, tokenhash.Hash(res.RefreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left := time.Until(rec.ExpiresAt); left > 2*time.Hour || left < 2*time.Hour-time.Minute {
+		t.Fatalf("rotated token expires in %v, want the configured 2h", left)
+	}
+}
@@ -2,62 +2,96 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
 	"go-auth/internal/app"
 	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
 )
 
 type RegisterUserCmd struct {
 	Email    string
 	Password string
+	// Locale is the preferred language; unsupported or empty values fall
+	// back to the locale negotiated for the request.
+	Locale string
+	// TenantID is the tenant the account is created for, if any; it tags
+	// the user.registered event.
+	TenantID string
 }
 
 type RegisterUserUseCase struct {
 	log        *slog.Logger
+	tx         app.TxManager
 	userRepo   domain.UserRepository
+	outbox     domain.OutboxRepository
 	pwdService app.PasswordService
+	audit      app.AuditLog
 }
 
-func NewRegisterUserUseCase(log *slog.Logger, userRepo domain.UserRepository, pwdService app.PasswordService) *RegisterUserUseCase {
+// NewRegisterUserUseCase creates the use case. tx and outbox may be nil; without
+// an outbox no user.registered event is published.
+func NewRegisterUserUseCase(log *slog.Logger, tx app.TxManager, userRepo domain.UserRepository, outbox domain.OutboxRepository, pwdService app.PasswordService, audit app.AuditLog) *RegisterUserUseCase {
 	return &RegisterUserUseCase{
 		log:        log,
+		tx:         tx,
 		userRepo:   userRepo,
+		outbox:     outbox,
 		pwdService: pwdService,
+		audit:      audit,
 	}
 }
 
 func (uc *RegisterUserUseCase) Handle(ctx context.Context, cmd RegisterUserCmd) error {
 	log := uc.log.With("op", "RegisterUser", "email", cmd.Email)
 
-	// 1. Check if user exists
-	// Note: In a real DB impl, FindByEmail might return a specific "NotFound" error.
-	// Here we assume if err == nil and user != nil, then user exists.
-	existing, err := uc.userRepo.FindByEmail(ctx, cmd.Email)
-	if err != nil {
-		// If error is NOT "not found", return it
-		// For now, let's assume we handle this in the repo implementation or specific error types
-		// This is a simplification.
-		return fmt.Errorf("failed to check user existence: %w", err)
-	}
-	if existing != nil {
-		return app.NewError(app.ErrCodeEmailExists, "Email already exists")
-	}
-
-	// 2. Hash password
+	// 1. Hash password up front so the transaction below stays short
 	hash, err := uc.pwdService.Hash(cmd.Password)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	user := domain.NewUser(cmd.Email, hash)
+	// An explicit choice beats the language negotiated for this request.
+	user.Locale = i18n.Negotiate(app.RequestMetaFrom(ctx).Locale, cmd.Locale)
 
-	// 4. Save to repo
-	if err := uc.userRepo.Create(ctx, user); err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+	// 2. Check, insert and publish as one unit of work
+	err = withinTx(ctx, uc.tx, func(ctx context.Context) error {
+		_, err := uc.userRepo.FindByEmail(ctx, cmd.Email)
+		switch {
+		case err == nil:
+			return app.NewError(app.ErrCodeEmailExists, "Email already exists")
+		case !errors.Is(err, domain.ErrNotFound):
+			return storageError(log, err, "Failed to check user existence")
+		}
+
+		if err := uc.userRepo.Create(ctx, user); err != nil {
+			// Lost a race with a concurrent registration of the same email
+			if errors.Is(err, domain.ErrConflict) {
+				return app.NewError(app.ErrCodeEmailExists, "Email already exists")
+			}
+			return storageError(log, err, "Failed to create user")
+		}
+
+		if uc.outbox != nil {
+			if err := uc.outbox.Enqueue(ctx, domain.NewUserEvent(domain.EventUserRegistered, cmd.TenantID, user)); err != nil {
+				return storageError(log, err, "Failed to enqueue user event")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditUserRegistered,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]string{"email": user.Email},
+	})
 	log.Info("user registered successfully", "user_id", user.ID)
 	return nil
 }
@@ -3,7 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
+	"go-auth/internal/app"
 	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
 	"log/slog"
 	"testing"
 )
@@ -18,26 +20,9 @@ func (f *fakePwd) Compare(h, p string) error {
 	return errors.New("mismatch")
 }
 
-type memRepo struct{ users map[string]*domain.User }
-
-func (r *memRepo) Create(ctx context.Context, u *domain.User) error {
-	if r.users == nil {
-		r.users = map[string]*domain.User{}
-	}
-	r.users[u.Email] = u
-	u.ID = "id-1"
-	return nil
-}
-func (r *memRepo) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
-	if r.users == nil {
-		return nil, nil
-	}
-	return r.users[email], nil
-}
-
 func TestRegister_Success(t *testing.T) {
 	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
-	uc := NewRegisterUserUseCase(log, &memRepo{}, &fakePwd{})
+	uc := NewRegisterUserUseCase(log, nil, memory.NewUserRepository(), nil, &fakePwd{}, nil)
 	err := uc.Handle(context.Background(), RegisterUserCmd{Email: "u@ex.com", Password: "p"})
 	if err != nil {
 		t.Fatalf("register failed: %v", err)
@@ -46,15 +31,55 @@ func TestRegister_Success(t *testing.T) {
 
 func TestRegister_Duplicate(t *testing.T) {
 	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
-	repo := &memRepo{}
-	uc := NewRegisterUserUseCase(log, repo, &fakePwd{})
+	repo := memory.NewUserRepository()
+	uc := NewRegisterUserUseCase(log, nil, repo, nil, &fakePwd{}, nil)
 	_ = uc.Handle(context.Background(), RegisterUserCmd{Email: "u@ex.com", Password: "p"})
 	if err := uc.Handle(context.Background(), RegisterUserCmd{Email: "u@ex.com", Password: "p"}); err == nil {
 		t.Fatalf("expected duplicate error")
 	}
 }
 
+func TestRegister_StoresLocale(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
+	repo := memory.NewUserRepository()
+	uc := NewRegisterUserUseCase(log, nil, repo, nil, &fakePwd{}, nil)
+	ctx := app.WithRequestMeta(context.Background(), app.RequestMeta{Locale: "ru"})
+
+	cases := []struct{ email, explicit, want string }{
+		{"negotiated@ex.com", "", "ru"},
+		{"explicit@ex.com", "EN", "en"},
+		{"unsupported@ex.com", "fr", "ru"},
+	}
+	for _, c := range cases {
+		if err := uc.Handle(ctx, RegisterUserCmd{Email: c.email, Password: "p", Locale: c.explicit}); err != nil {
+			t.Fatalf("%s: %v", c.email, err)
+		}
+		u, err := repo.FindByEmail(ctx, c.email)
+		if err != nil || u.Locale != c.want {
+			t.Fatalf("%s: locale %q err %v, want %q", c.email, u.Locale, err, c.want)
+		}
+	}
+}
+
 // testWriter discards logs
 type testWriter struct{}
 
 func (testWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+type failingOutbox struct{}
+
+func (failingOutbox) Enqueue(context.Context, *domain.OutboxEvent) error {
+	return errors.New("outbox down")
+}
+
+func TestRegister_RollsBackWhenEventFails(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
+	users := memory.NewUserRepository()
+	uc := NewRegisterUserUseCase(log, memory.NewTxManager(), users, failingOutbox{}, &fakePwd{}, nil)
+	if err := uc.Handle(context.Background(), RegisterUserCmd{Email: "u@ex.com", Password: "p"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if u, _ := users.FindByEmail(context.Background(), "u@ex.com"); u != nil {
+		t.Fatal("user persisted without its event")
+	}
+}
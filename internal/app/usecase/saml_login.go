@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/security/tokenhash"
+)
+
+// samlProviderPrefix marks SAML logins in the federated login store, which
+// they share with OIDC logins.
+const samlProviderPrefix = "saml:"
+
+const samlNameIDEmail = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+
+// Attribute names tried, in order, when an IdP's attribute map names none:
+// plain names, then the ADFS/Entra claim URIs, then the LDAP OIDs.
+var (
+	samlEmailAttributes = []string{
+		"email", "mail", "emailAddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+	}
+	samlLocaleAttributes = []string{
+		"locale", "preferredLanguage",
+		"urn:oid:2.16.840.1.113730.3.1.39",
+	}
+)
+
+// SAMLLoginConfig configures sign-in through SAML 2.0 identity providers.
+type SAMLLoginConfig struct {
+	IdPs app.SAMLIdPs
+	// TTL bounds the time a user may spend at the IdP. Default 10m.
+	TTL time.Duration
+	// Domains limits the accounts a tenant's own IdPs may link or sign up to
+	// those in the tenant's email domains.
+	Domains app.TenantDomains
+}
+
+// SAMLResponseCmd is what the IdP makes the browser post to the assertion
+// consumer service.
+type SAMLResponseCmd struct {
+	Response   string
+	RelayState string
+	Binding    string
+}
+
+// SAMLLoginUseCase signs users in through SAML identity providers with
+// SP-initiated logins; unsolicited responses are refused.
+type SAMLLoginUseCase struct {
+	accountLinker
+	log    *slog.Logger
+	cfg    SAMLLoginConfig
+	logins domain.FederatedLoginRepository
+	login  *LoginUserUseCase
+	sp     app.SAMLServiceProvider
+	now    func() time.Time
+}
+
+func NewSAMLLoginUseCase(
+	log *slog.Logger,
+	cfg SAMLLoginConfig,
+	tx app.TxManager,
+	logins domain.FederatedLoginRepository,
+	identities domain.IdentityRepository,
+	users domain.UserRepository,
+	register *RegisterUserUseCase,
+	login *LoginUserUseCase,
+	sp app.SAMLServiceProvider,
+	audit app.AuditLog,
+) *SAMLLoginUseCase {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	return &SAMLLoginUseCase{
+		accountLinker: accountLinker{tx: tx, domains: cfg.Domains, identities: identities, users: users, register: register, audit: audit},
+		log:           log,
+		cfg:           cfg,
+		logins:        logins,
+		login:         login,
+		sp:            sp,
+		now:           time.Now,
+	}
+}
+
+// IdPs lists the identity providers users of tenantID can sign in with.
+func (uc *SAMLLoginUseCase) IdPs(tenantID string) []ProviderInfo {
+	idps := uc.cfg.IdPs.For(tenantID)
+	out := make([]ProviderInfo, 0, len(idps))
+	for _, idp := range idps {
+		name := idp.Name
+		if name == "" {
+			name = idp.ID
+		}
+		out = append(out, ProviderInfo{ID: idp.ID, Name: name})
+	}
+	return out
+}
+
+// Metadata returns the SP metadata to register at identity providers.
+func (uc *SAMLLoginUseCase) Metadata() ([]byte, error) {
+	md, err := uc.sp.Metadata()
+	if err != nil {
+		uc.log.Error("failed to build SAML metadata", "error", err)
+		return nil, app.NewError(app.ErrCodeInternal, "Failed to build metadata")
+	}
+	return md, nil
+}
+
+// Start records a new AuthnRequest and returns the IdP URL to send the
+// browser to. The request ID and relay state are fresh for every attempt.
+func (uc *SAMLLoginUseCase) Start(ctx context.Context, cmd StartFederatedLoginCmd) (*StartFederatedLoginResult, error) {
+	log := uc.log.With("op", "StartSAMLLogin", "provider", cmd.Provider, "tenant_id", cmd.TenantID)
+	idp, ok := uc.cfg.IdPs.Find(cmd.TenantID, cmd.Provider)
+	if !ok {
+		return nil, app.NewError(app.ErrCodeNotFound, "Unknown identity provider")
+	}
+
+	var secrets [3]string // relay state, binding, request ID
+	for i := range secrets {
+		token, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = token
+	}
+	// xs:ID values must not start with a digit.
+	relayState, binding, requestID := secrets[0], secrets[1], "_"+secrets[2]
+
+	authURL, err := uc.sp.AuthnRequestURL(idp, app.SAMLAuthnRequest{ID: requestID, RelayState: relayState})
+	if err != nil {
+		log.Error("failed to build SAML AuthnRequest", "error", err)
+		return nil, app.NewError(app.ErrCodeInternal, "Failed to start login")
+	}
+
+	err = uc.logins.Create(ctx, &domain.FederatedLogin{
+		StateHash:   tokenhash.Hash(relayState),
+		BindingHash: tokenhash.Hash(binding),
+		Provider:    samlProviderPrefix + idp.ID,
+		TenantID:    cmd.TenantID,
+		Nonce:       requestID,
+		ExpiresAt:   uc.now().Add(uc.cfg.TTL).UTC(),
+	})
+	if err != nil {
+		return nil, storageError(log, err, "Failed to store login request")
+	}
+	return &StartFederatedLoginResult{AuthURL: authURL, Binding: binding, ExpiresIn: int64(uc.cfg.TTL.Seconds())}, nil
+}
+
+// ACS consumes a response the IdP posted back. The login request it
+// answers is single use, so a captured response cannot be replayed.
+func (uc *SAMLLoginUseCase) ACS(ctx context.Context, cmd SAMLResponseCmd) (*LoginUserResult, error) {
+	log := uc.log.With("op", "SAMLAssertionConsumer")
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid or expired login request")
+	if cmd.Response == "" || cmd.RelayState == "" || cmd.Binding == "" {
+		return nil, invalid
+	}
+	login, err := uc.logins.Consume(ctx, tokenhash.Hash(cmd.RelayState), tokenhash.Hash(cmd.Binding), uc.now())
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Warn("invalid, expired or reused SAML relay state")
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to consume login request")
+	}
+	log = log.With("provider", login.Provider, "tenant_id", login.TenantID)
+	id, isSAML := strings.CutPrefix(login.Provider, samlProviderPrefix)
+	idp, ok := uc.cfg.IdPs.Find(login.TenantID, id)
+	if !isSAML || !ok {
+		log.Warn("relay state does not belong to a configured identity provider")
+		return nil, invalid
+	}
+
+	assertion, err := uc.sp.ParseResponse(idp, cmd.Response, login.Nonce)
+	if errors.Is(err, app.ErrAssertionRejected) {
+		log.Warn("SAML response rejected", "error", err)
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Identity provider rejected the login")
+	}
+	if err != nil {
+		log.Error("failed to process SAML response", "error", err)
+		return nil, app.NewError(app.ErrCodeInternal, "Failed to process login")
+	}
+
+	upstream := samlAccount(idp, login.TenantID, assertion)
+	upstream.Owner = uc.cfg.IdPs.Owner(login.TenantID)
+	if upstream.Subject == "" {
+		log.Warn("SAML assertion lacks the subject attribute", "attribute", idp.Attributes.Subject)
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Identity provider rejected the login")
+	}
+	user, err := uc.resolve(ctx, log.With("subject", upstream.Subject), upstream)
+	if err != nil {
+		return nil, err
+	}
+	return uc.login.IssueTokens(ctx, user, samlProviderPrefix+idp.ID)
+}
+
+// samlAccount maps an assertion onto an upstream account through the IdP's
+// attribute map. SAML carries no email verification flag; an asserted
+// address counts as verified, and TrustEmail together with the owning
+// tenant's domains decides whether to link on it.
+func samlAccount(idp app.SAMLIdP, tenantID string, a *app.SAMLAssertion) upstreamAccount {
+	subject := a.NameID
+	if idp.Attributes.Subject != "" {
+		subject = a.Attribute(idp.Attributes.Subject)
+	}
+	email := samlAttribute(a, idp.Attributes.Email, samlEmailAttributes)
+	if email == "" && a.NameIDFormat == samlNameIDEmail {
+		email = a.NameID
+	}
+	return upstreamAccount{
+		Provider:      idp.ID,
+		Issuer:        a.Issuer,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: email != "",
+		Locale:        samlAttribute(a, idp.Attributes.Locale, samlLocaleAttributes),
+		TenantID:      tenantID,
+		TrustEmail:    idp.TrustEmail,
+		AutoRegister:  idp.AutoRegister,
+	}
+}
+
+// samlAttribute reads the named attribute, or the first of fallbacks
+// present when no name is configured.
+func samlAttribute(a *app.SAMLAssertion, name string, fallbacks []string) string {
+	if name != "" {
+		return a.Attribute(name)
+	}
+	for _, n := range fallbacks {
+		if v := a.Attribute(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/saml"
+	"go-auth/internal/infrastructure/saml/samltest"
+)
+
+// saml returns the SAML login flow with acme's IdP "corp" served by the
+// returned test IdP.
+func (f *fixture) saml(t *testing.T, configure func(idp *app.SAMLIdP)) (*SAMLLoginUseCase, *samltest.IdP) {
+	t.Helper()
+	idp := samltest.NewIdP(t)
+	cfg := idp.Config("corp")
+	if configure != nil {
+		configure(&cfg)
+	}
+	sp := saml.NewServiceProvider(saml.Options{
+		EntityID: "https://auth.example.com/api/v1/auth/saml/metadata",
+		ACSURL:   "https://auth.example.com/api/v1/auth/saml/acs",
+	})
+	uc := NewSAMLLoginUseCase(f.log, SAMLLoginConfig{
+		IdPs:    app.SAMLIdPs{Tenants: map[string][]app.SAMLIdP{"acme": {cfg}}},
+		Domains: testDomains,
+	}, nil, memory.NewFederatedLoginRepository(), f.identities, f.users, f.register, f.login, sp, nil)
+	return uc, idp
+}
+
+// samlSignIn runs an SP-initiated login for user at the IdP.
+func samlSignIn(t *testing.T, uc *SAMLLoginUseCase, idp *samltest.IdP, user samltest.User) (*LoginUserResult, error) {
+	t.Helper()
+	start, err := uc.Start(context.Background(), StartFederatedLoginCmd{TenantID: "acme", Provider: "corp"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	resp, relay := idp.Respond(t, start.AuthURL, user)
+	return uc.ACS(context.Background(), SAMLResponseCmd{Response: resp, RelayState: relay, Binding: start.Binding})
+}
+
+func TestSAMLLogin_FirstLogin(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name         string
+		autoRegister bool
+		email        string
+		wantCode     string
+	}{
+		{"auto-registration", true, "alice@acme.com", ""},
+		{"auto-registration disabled", false, "alice@acme.com", app.ErrCodeForbidden},
+		// The tenant's IdP cannot sign up addresses in another tenant's domain.
+		{"another tenant's domain", true, "ceo@globex.com", app.ErrCodeForbidden},
+		{"domain no tenant owns", true, "alice@ex.com", app.ErrCodeForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			uc, idp := f.saml(t, func(idp *app.SAMLIdP) {
+				idp.AutoRegister = tc.autoRegister
+				idp.Attributes = app.SAMLAttributeMap{Email: "work_email", Locale: "lang"}
+			})
+
+			res, err := samlSignIn(t, uc, idp, samltest.User{
+				NameID:       "emp-1",
+				NameIDFormat: saml.NameIDFormatPersistent,
+				Attributes:   map[string][]string{"work_email": {tc.email}, "lang": {"ru"}},
+			})
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				if _, err := f.users.FindByEmail(ctx, tc.email); err == nil {
+					t.Fatal("account registered")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("first login: %v", err)
+			}
+			user, err := f.users.FindByEmail(ctx, tc.email)
+			if err != nil || res.AccessToken != "acc:"+user.ID || user.Locale != "ru" {
+				t.Fatalf("user = %+v, %v; token %q", user, err, res.AccessToken)
+			}
+			linked, _ := f.identities.ListByUser(ctx, user.ID)
+			if len(linked) != 1 || linked[0].Provider != idp.EntityID || linked[0].Subject != "emp-1" {
+				t.Fatalf("identities = %+v", linked)
+			}
+
+			// The link, not the email, identifies the user on the next login.
+			res, err = samlSignIn(t, uc, idp, samltest.User{NameID: "emp-1", Attributes: map[string][]string{"work_email": {"renamed@acme.com"}}})
+			if err != nil || res.AccessToken != "acc:"+user.ID {
+				t.Fatalf("second login: %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestSAMLLogin_ExistingEmail(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		email    string
+		trust    bool
+		wantCode string
+	}{
+		{"untrusted idp", "bob@acme.com", false, app.ErrCodeEmailExists},
+		{"account in another tenant's domain", "bob@globex.com", true, app.ErrCodeEmailExists},
+		{"account in a domain no tenant owns", "bob@ex.com", true, app.ErrCodeEmailExists},
+		{"trusted idp", "bob@acme.com", true, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			bob := f.user(t, tc.email, "p")
+			uc, idp := f.saml(t, func(idp *app.SAMLIdP) { idp.TrustEmail = tc.trust })
+
+			// With no attribute map the NameID carries the email.
+			res, err := samlSignIn(t, uc, idp, samltest.User{NameID: tc.email, NameIDFormat: saml.NameIDFormatEmail})
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				return
+			}
+			if err != nil || res.AccessToken != "acc:"+bob.ID {
+				t.Fatalf("login = %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestSAMLLogin_Rejections(t *testing.T) {
+	f := newFixture()
+	uc, idp := f.saml(t, func(idp *app.SAMLIdP) { idp.AutoRegister = true })
+	ctx := context.Background()
+	user := samltest.User{NameID: "carol", Attributes: map[string][]string{"email": {"carol@acme.com"}}}
+
+	if _, err := uc.Start(ctx, StartFederatedLoginCmd{TenantID: "globex", Provider: "corp"}); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("idp of another tenant: %v", err)
+	}
+
+	start, err := uc.Start(ctx, StartFederatedLoginCmd{TenantID: "acme", Provider: "corp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, relay := idp.Respond(t, start.AuthURL, user)
+	// A response to one request does not complete another.
+	other, err := uc.Start(ctx, StartFederatedLoginCmd{TenantID: "acme", Provider: "corp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherRelay := idp.Respond(t, other.AuthURL, user)
+
+	// Responses arrive in order; the valid one spends the request.
+	for _, tc := range []struct {
+		name    string
+		cmd     SAMLResponseCmd
+		wantErr bool
+	}{
+		{"wrong binding", SAMLResponseCmd{Response: resp, RelayState: relay, Binding: "other-browser"}, true},
+		{"response to another request", SAMLResponseCmd{Response: resp, RelayState: otherRelay, Binding: other.Binding}, true},
+		{"valid response", SAMLResponseCmd{Response: resp, RelayState: relay, Binding: start.Binding}, false},
+		{"replayed response", SAMLResponseCmd{Response: resp, RelayState: relay, Binding: start.Binding}, true},
+	} {
+		_, err := uc.ACS(ctx, tc.cmd)
+		if tc.wantErr && !isCode(err, app.ErrCodeInvalidCredentials) || !tc.wantErr && err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+	}
+
+	idp.UnsignedAssertion = true
+	if _, err := samlSignIn(t, uc, idp, user); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("unsigned assertion: %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/security/tokenhash"
+)
+
+const (
+	// serviceKeyPrefix marks service account API keys, like
+	// personalTokenPrefix does for personal access tokens.
+	serviceKeyPrefix = "sak_"
+	serviceKeyShown  = len(serviceKeyPrefix) + 6
+	// serviceCredentialTouchEvery limits last-used writes per credential.
+	serviceCredentialTouchEvery = time.Minute
+)
+
+// Grant types service accounts obtain access tokens with.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantJWTBearer         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+type ServiceAccountsConfig struct {
+	// Audience is what JWT assertions must name in "aud": the URL of the
+	// token endpoint.
+	Audience string
+}
+
+type ServiceAccountCmd struct {
+	// TenantID is only read on create; accounts cannot change tenants.
+	TenantID    string
+	Name        string
+	Description string
+	// Roles are IDs of roles in the tenant.
+	Roles    []string
+	Disabled bool
+}
+
+type AddServiceCredentialCmd struct {
+	Type domain.ServiceAccountCredentialType
+	Name string
+	// PublicKey is the PEM key for CredentialPublicKey.
+	PublicKey string
+	// ExpiresAt is optional; nil creates a credential that does not expire.
+	ExpiresAt *time.Time
+}
+
+type ServiceTokenResult struct {
+	AccessToken string
+	ExpiresIn   int64
+}
+
+// ServiceAccountsUseCase manages tenants' service accounts and issues them
+// access tokens for API keys (client credentials) and signed JWT
+// assertions (RFC 7523).
+type ServiceAccountsUseCase struct {
+	log        *slog.Logger
+	cfg        ServiceAccountsConfig
+	repo       domain.ServiceAccountRepository
+	roles      domain.RoleRepository
+	tokens     app.ServiceTokenIssuer
+	assertions app.AssertionVerifier
+	audit      app.AuditLog
+	now        func() time.Time
+}
+
+func NewServiceAccountsUseCase(
+	log *slog.Logger,
+	cfg ServiceAccountsConfig,
+	repo domain.ServiceAccountRepository,
+	roles domain.RoleRepository,
+	tokens app.ServiceTokenIssuer,
+	assertions app.AssertionVerifier,
+	audit app.AuditLog,
+) *ServiceAccountsUseCase {
+	return &ServiceAccountsUseCase{
+		log:        log,
+		cfg:        cfg,
+		repo:       repo,
+		roles:      roles,
+		tokens:     tokens,
+		assertions: assertions,
+		audit:      audit,
+		now:        time.Now,
+	}
+}
+
+func (uc *ServiceAccountsUseCase) Create(ctx context.Context, actorID string, cmd ServiceAccountCmd) (*domain.ServiceAccount, error) {
+	log := uc.log.With("op", "CreateServiceAccount", "tenant_id", cmd.TenantID)
+	if cmd.TenantID == "" {
+		return nil, app.NewError(app.ErrCodeValidation, "tenant_id is required")
+	}
+	now := uc.now().UTC()
+	account := &domain.ServiceAccount{TenantID: cmd.TenantID, CreatedAt: now, UpdatedAt: now}
+	if err := uc.apply(ctx, log, account, cmd, now); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Create(ctx, account); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return nil, app.NewError(app.ErrCodeConflict, "Service account name is taken")
+		}
+		return nil, storageError(log, err, "Failed to create service account")
+	}
+	uc.record(ctx, log, domain.AuditServiceAccountCreated, actorID, account, nil)
+	return account, nil
+}
+
+// apply validates cmd and copies its mutable fields onto account.
+func (uc *ServiceAccountsUseCase) apply(ctx context.Context, log *slog.Logger, account *domain.ServiceAccount, cmd ServiceAccountCmd, now time.Time) error {
+	account.Name, account.Description = strings.TrimSpace(cmd.Name), cmd.Description
+	if account.Name == "" {
+		return app.NewError(app.ErrCodeValidation, "Service account name is required")
+	}
+	roles := slices.Clone(cmd.Roles)
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+	for _, id := range roles {
+		_, err := uc.roles.Get(ctx, account.TenantID, id)
+		if errors.Is(err, domain.ErrNotFound) {
+			return app.NewError(app.ErrCodeValidation, fmt.Sprintf("Unknown role %q", id))
+		}
+		if err != nil {
+			return storageError(log, err, "Failed to load role")
+		}
+	}
+	account.Roles = roles
+	switch {
+	case cmd.Disabled && account.DisabledAt == nil:
+		account.DisabledAt = &now
+	case !cmd.Disabled:
+		account.DisabledAt = nil
+	}
+	return nil
+}
+
+func (uc *ServiceAccountsUseCase) Get(ctx context.Context, id string) (*domain.ServiceAccount, error) {
+	account, err := uc.repo.Get(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeNotFound, "Service account not found")
+	}
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "GetServiceAccount"), err, "Failed to load service account")
+	}
+	return account, nil
+}
+
+// List returns the tenant's accounts, oldest first.
+func (uc *ServiceAccountsUseCase) List(ctx context.Context, tenantID string) ([]domain.ServiceAccount, error) {
+	if tenantID == "" {
+		return nil, app.NewError(app.ErrCodeValidation, "tenant_id is required")
+	}
+	accounts, err := uc.repo.List(ctx, tenantID)
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "ListServiceAccounts"), err, "Failed to list service accounts")
+	}
+	return accounts, nil
+}
+
+// Update replaces the account's name, description, roles and disabled
+// state. Disabling an account stops it from obtaining tokens; tokens
+// already issued stay valid until they expire.
+func (uc *ServiceAccountsUseCase) Update(ctx context.Context, actorID, id string, cmd ServiceAccountCmd) (*domain.ServiceAccount, error) {
+	log := uc.log.With("op", "UpdateServiceAccount", "service_account_id", id)
+	account, err := uc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := uc.now().UTC()
+	if err := uc.apply(ctx, log, account, cmd, now); err != nil {
+		return nil, err
+	}
+	account.UpdatedAt = now
+	if err := uc.repo.Update(ctx, account); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrConflict):
+			return nil, app.NewError(app.ErrCodeConflict, "Service account name is taken")
+		case errors.Is(err, domain.ErrNotFound):
+			return nil, app.NewError(app.ErrCodeNotFound, "Service account not found")
+		}
+		return nil, storageError(log, err, "Failed to update service account")
+	}
+	uc.record(ctx, log, domain.AuditServiceAccountUpdated, actorID, account, map[string]string{
+		"disabled": fmt.Sprint(account.DisabledAt != nil),
+	})
+	return account, nil
+}
+
+// Delete removes the account with its credentials.
+func (uc *ServiceAccountsUseCase) Delete(ctx context.Context, actorID, id string) error {
+	log := uc.log.With("op", "DeleteServiceAccount", "service_account_id", id)
+	account, err := uc.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := notFoundAs(uc.repo.Delete(ctx, id), domain.ErrNotFound, "Service account not found"); err != nil {
+		return err
+	}
+	uc.record(ctx, log, domain.AuditServiceAccountDeleted, actorID, account, nil)
+	return nil
+}
+
+// AddCredential registers an API key or an assertion public key. For API
+// keys it also returns the key, which is not exposed again afterwards.
+func (uc *ServiceAccountsUseCase) AddCredential(ctx context.Context, actorID, accountID string, cmd AddServiceCredentialCmd) (*domain.ServiceAccountCredential, string, error) {
+	log := uc.log.With("op", "AddServiceAccountCredential", "service_account_id", accountID)
+	account, err := uc.Get(ctx, accountID)
+	if err != nil {
+		return nil, "", err
+	}
+	now := uc.now().UTC()
+	cred := &domain.ServiceAccountCredential{
+		ServiceAccountID: account.ID,
+		Type:             cmd.Type,
+		Name:             strings.TrimSpace(cmd.Name),
+		CreatedAt:        now,
+	}
+	if cred.Name == "" {
+		return nil, "", app.NewError(app.ErrCodeValidation, "Credential name is required")
+	}
+	if cmd.ExpiresAt != nil {
+		if !cmd.ExpiresAt.After(now) {
+			return nil, "", app.NewError(app.ErrCodeValidation, "Expiry must be in the future")
+		}
+		expiresAt := cmd.ExpiresAt.UTC()
+		cred.ExpiresAt = &expiresAt
+	}
+
+	var secret string
+	switch cmd.Type {
+	case domain.CredentialAPIKey:
+		if secret, err = randomToken(); err != nil {
+			return nil, "", err
+		}
+		secret = serviceKeyPrefix + secret
+		cred.Prefix, cred.SecretHash = secret[:serviceKeyShown], tokenhash.Hash(secret)
+	case domain.CredentialPublicKey:
+		if err := uc.assertions.CheckPublicKey(cmd.PublicKey); err != nil {
+			return nil, "", app.NewError(app.ErrCodeValidation, "Invalid public key").
+				WithDetails(map[string]any{"reason": err.Error()})
+		}
+		cred.PublicKey = cmd.PublicKey
+	default:
+		return nil, "", app.NewError(app.ErrCodeValidation, fmt.Sprintf("Unknown credential type %q", cmd.Type)).
+			WithDetails(map[string]any{"types": []domain.ServiceAccountCredentialType{domain.CredentialAPIKey, domain.CredentialPublicKey}})
+	}
+	if err := uc.repo.AddCredential(ctx, cred); err != nil {
+		return nil, "", storageError(log, err, "Failed to add credential")
+	}
+	uc.record(ctx, log, domain.AuditServiceAccountCredentialAdded, actorID, account, map[string]string{
+		"credential_id": cred.ID,
+		"type":          string(cred.Type),
+	})
+	return cred, secret, nil
+}
+
+func (uc *ServiceAccountsUseCase) ListCredentials(ctx context.Context, accountID string) ([]domain.ServiceAccountCredential, error) {
+	if _, err := uc.Get(ctx, accountID); err != nil {
+		return nil, err
+	}
+	creds, err := uc.repo.ListCredentials(ctx, accountID)
+	if err != nil {
+		return nil, storageError(uc.log.With("op", "ListServiceAccountCredentials"), err, "Failed to list credentials")
+	}
+	return creds, nil
+}
+
+func (uc *ServiceAccountsUseCase) RevokeCredential(ctx context.Context, actorID, accountID, id string) error {
+	log := uc.log.With("op", "RevokeServiceAccountCredential", "service_account_id", accountID)
+	account, err := uc.Get(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if err := notFoundAs(uc.repo.DeleteCredential(ctx, accountID, id), domain.ErrNotFound, "Credential not found"); err != nil {
+		return err
+	}
+	uc.record(ctx, log, domain.AuditServiceAccountCredentialRevoked, actorID, account, map[string]string{"credential_id": id})
+	return nil
+}
+
+// IsServiceKey reports whether a secret is a service account API key.
+func IsServiceKey(secret string) bool {
+	return strings.HasPrefix(secret, serviceKeyPrefix)
+}
+
+// TokenForKey implements the client credentials grant: it issues an
+// access token for an API key. clientID is optional; if given it must be
+// the key's account.
+func (uc *ServiceAccountsUseCase) TokenForKey(ctx context.Context, clientID, secret string) (*ServiceTokenResult, error) {
+	log := uc.log.With("op", "ServiceTokenForKey", "client_id", clientID)
+	account, cred, err := uc.authenticateKey(ctx, log, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	return uc.issue(ctx, log, account, cred, GrantClientCredentials, uc.now().UTC())
+}
+
+// AuthenticateClient checks client credentials for grants that issue
+// something other than a service token, such as token exchange.
+func (uc *ServiceAccountsUseCase) AuthenticateClient(ctx context.Context, clientID, secret string) (*domain.ServiceAccount, error) {
+	log := uc.log.With("op", "AuthenticateServiceClient", "client_id", clientID)
+	account, cred, err := uc.authenticateKey(ctx, log, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	uc.touch(ctx, log, cred, uc.now().UTC())
+	return account, nil
+}
+
+func (uc *ServiceAccountsUseCase) authenticateKey(ctx context.Context, log *slog.Logger, clientID, secret string) (*domain.ServiceAccount, *domain.ServiceAccountCredential, error) {
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid client credentials")
+	if !IsServiceKey(secret) {
+		return nil, nil, invalid
+	}
+	cred, err := uc.repo.FindCredentialByHash(ctx, tokenhash.Hash(secret))
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Warn("unknown api key")
+		return nil, nil, invalid
+	}
+	if err != nil {
+		return nil, nil, storageError(log, err, "Failed to look up credential")
+	}
+	if cred.Type != domain.CredentialAPIKey || cred.Expired(uc.now().UTC()) || (clientID != "" && clientID != cred.ServiceAccountID) {
+		log.Warn("api key rejected", "credential_id", cred.ID)
+		return nil, nil, invalid
+	}
+	account, err := uc.activeAccount(ctx, log, cred.ServiceAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, cred, nil
+}
+
+// TokenForAssertion implements the JWT bearer grant (RFC 7523): the
+// account signs an assertion with "iss" and "sub" set to its ID and "aud"
+// to the token endpoint, and receives an access token.
+func (uc *ServiceAccountsUseCase) TokenForAssertion(ctx context.Context, assertion string) (*ServiceTokenResult, error) {
+	log := uc.log.With("op", "ServiceTokenForAssertion")
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid assertion")
+	issuer, err := uc.assertions.Issuer(assertion)
+	if err != nil || issuer == "" {
+		return nil, invalid
+	}
+	log = log.With("client_id", issuer)
+	account, err := uc.activeAccount(ctx, log, issuer)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := uc.repo.ListCredentials(ctx, account.ID)
+	if err != nil {
+		return nil, storageError(log, err, "Failed to list credentials")
+	}
+	now := uc.now().UTC()
+	var keys []app.AssertionKey
+	for _, c := range creds {
+		if c.Type == domain.CredentialPublicKey && !c.Expired(now) {
+			keys = append(keys, app.AssertionKey{ID: c.ID, PublicKey: c.PublicKey})
+		}
+	}
+	verified, err := uc.assertions.Verify(assertion, keys, uc.cfg.Audience)
+	if err != nil {
+		log.Warn("assertion rejected", "error", err)
+		return nil, invalid
+	}
+	if verified.Subject != account.ID {
+		log.Warn("assertion subject is not the issuer", "sub", verified.Subject)
+		return nil, invalid
+	}
+	cred := &domain.ServiceAccountCredential{ID: verified.KeyID}
+	for i := range creds {
+		if creds[i].ID == verified.KeyID {
+			cred = &creds[i]
+		}
+	}
+	return uc.issue(ctx, log, account, cred, GrantJWTBearer, now)
+}
+
+// activeAccount loads an account that may obtain tokens.
+func (uc *ServiceAccountsUseCase) activeAccount(ctx context.Context, log *slog.Logger, id string) (*domain.ServiceAccount, error) {
+	account, err := uc.repo.Get(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid client credentials")
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to load service account")
+	}
+	if account.DisabledAt != nil {
+		log.Warn("service account disabled")
+		return nil, app.NewError(app.ErrCodeInvalidCredentials, "Invalid client credentials")
+	}
+	return account, nil
+}
+
+func (uc *ServiceAccountsUseCase) issue(ctx context.Context, log *slog.Logger, account *domain.ServiceAccount, cred *domain.ServiceAccountCredential, grant string, now time.Time) (*ServiceTokenResult, error) {
+	// Roles deleted since they were assigned are skipped.
+	var roles []string
+	for _, id := range account.Roles {
+		role, err := uc.roles.Get(ctx, account.TenantID, id)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, storageError(log, err, "Failed to load role")
+		}
+		roles = append(roles, role.Name)
+	}
+	token, err := uc.tokens.GenerateServiceToken(app.ServiceClaims{AccountID: account.ID, TenantID: account.TenantID, Roles: roles})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	uc.touch(ctx, log, cred, now)
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditServiceAccountTokenIssued,
+		ActorID:  account.ID,
+		TargetID: account.ID,
+		TenantID: account.TenantID,
+		Metadata: map[string]string{
+			"actor_type":    "service_account",
+			"grant_type":    grant,
+			"credential_id": cred.ID,
+		},
+	})
+	return &ServiceTokenResult{AccessToken: token, ExpiresIn: int64(uc.tokens.AccessTTL().Seconds())}, nil
+}
+
+// touch records the use of cred, at most every serviceCredentialTouchEvery.
+func (uc *ServiceAccountsUseCase) touch(ctx context.Context, log *slog.Logger, cred *domain.ServiceAccountCredential, now time.Time) {
+	if cred.LastUsedAt == nil || now.Sub(*cred.LastUsedAt) >= serviceCredentialTouchEvery {
+		if err := uc.repo.TouchCredential(ctx, cred.ID, now); err != nil {
+			log.Warn("failed to record credential use", "credential_id", cred.ID, "error", err)
+		}
+	}
+}
+
+// record audits an admin change to account.
+func (uc *ServiceAccountsUseCase) record(ctx context.Context, log *slog.Logger, typ domain.AuditEventType, actorID string, account *domain.ServiceAccount, metadata map[string]string) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["name"] = account.Name
+	metadata["target_type"] = "service_account"
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     typ,
+		ActorID:  actorID,
+		TargetID: account.ID,
+		TenantID: account.TenantID,
+		Metadata: metadata,
+	})
+}
@@ -0,0 +1,276 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"slices"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/security/jwt"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const testTokenURL = "https://auth.example/api/v1/oauth/token"
+
+type fakeServiceTokens struct{ issued []app.ServiceClaims }
+
+func (f *fakeServiceTokens) GenerateServiceToken(c app.ServiceClaims) (string, error) {
+	f.issued = append(f.issued, c)
+	return "svc:" + c.AccountID, nil
+}
+
+func (f *fakeServiceTokens) AccessTTL() time.Duration { return time.Minute }
+
+// serviceAccounts returns the service-account use case over the fixture's
+// roles, minting tokens with the returned fake.
+func (f *fixture) serviceAccounts() (*ServiceAccountsUseCase, *fakeServiceTokens) {
+	tokens := &fakeServiceTokens{}
+	uc := NewServiceAccountsUseCase(f.log, ServiceAccountsConfig{Audience: testTokenURL},
+		memory.NewServiceAccountRepository(), f.roles, tokens, jwt.NewAssertionVerifier(time.Hour, time.Minute), f.audit)
+	return uc, tokens
+}
+
+func TestServiceAccounts_Create(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		cmd       ServiceAccountCmd
+		roles     []string // role names, resolved into cmd.Roles
+		wantCode  string
+		wantRoles []string
+	}{
+		{"missing tenant", ServiceAccountCmd{Name: "ci"}, nil, app.ErrCodeValidation, nil},
+		{"blank name", ServiceAccountCmd{TenantID: "acme", Name: " "}, nil, app.ErrCodeValidation, nil},
+		{"another tenant's role", ServiceAccountCmd{TenantID: "acme", Name: "ci"}, []string{"admins"}, app.ErrCodeValidation, nil},
+		{"name taken in another case", ServiceAccountCmd{TenantID: "acme", Name: "DEPLOY"}, nil, app.ErrCodeConflict, nil},
+		{"repeated role", ServiceAccountCmd{TenantID: "acme", Name: "ci"}, []string{"deployers", "deployers"}, "", []string{"deployers"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			uc, _ := f.serviceAccounts()
+			ids := map[string]string{}
+			for _, r := range []*domain.Role{{TenantID: "acme", Name: "deployers"}, {TenantID: "globex", Name: "admins"}} {
+				if err := f.roles.Create(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+				ids[r.Name] = r.ID
+			}
+			if _, err := uc.Create(ctx, "admin-1", ServiceAccountCmd{TenantID: "acme", Name: "deploy"}); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := tc.cmd
+			for _, name := range tc.roles {
+				cmd.Roles = append(cmd.Roles, ids[name])
+			}
+			sa, err := uc.Create(ctx, "admin-1", cmd)
+			if tc.wantCode != "" {
+				if !isCode(err, tc.wantCode) {
+					t.Fatalf("got %v, want %s", err, tc.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			var want []string
+			for _, name := range tc.wantRoles {
+				want = append(want, ids[name])
+			}
+			if !slices.Equal(sa.Roles, want) {
+				t.Fatalf("roles = %v, want %v", sa.Roles, want)
+			}
+			if last := f.audit.last(); last.Type != domain.AuditServiceAccountCreated || last.ActorID != "admin-1" ||
+				last.TargetID != sa.ID || last.TenantID != "acme" || last.Metadata["target_type"] != "service_account" {
+				t.Fatalf("audit = %+v", last)
+			}
+		})
+	}
+}
+
+func TestServiceAccounts_Manage(t *testing.T) {
+	f := newFixture()
+	uc, _ := f.serviceAccounts()
+	ctx := context.Background()
+	role := &domain.Role{TenantID: "acme", Name: "deployers"}
+	if err := f.roles.Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	sa, err := uc.Create(ctx, "admin-1", ServiceAccountCmd{TenantID: "acme", Name: "ci", Roles: []string{role.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sa, err = uc.Update(ctx, "admin-1", sa.ID, ServiceAccountCmd{Name: "ci", Disabled: true})
+	if err != nil || sa.DisabledAt == nil || len(sa.Roles) != 0 || sa.TenantID != "acme" {
+		t.Fatalf("disable = %+v, %v", sa, err)
+	}
+	if _, err := uc.Update(ctx, "admin-1", "missing", ServiceAccountCmd{Name: "x"}); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("update missing: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		cmd  AddServiceCredentialCmd
+	}{
+		{"bad public key", AddServiceCredentialCmd{Type: domain.CredentialPublicKey, Name: "k", PublicKey: "junk"}},
+		{"unknown type", AddServiceCredentialCmd{Type: "password", Name: "k"}},
+	} {
+		if _, _, err := uc.AddCredential(ctx, "admin-1", sa.ID, tc.cmd); !isCode(err, app.ErrCodeValidation) {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+	}
+	cred, _, err := uc.AddCredential(ctx, "admin-1", sa.ID, AddServiceCredentialCmd{Type: domain.CredentialAPIKey, Name: "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.RevokeCredential(ctx, "admin-1", sa.ID, cred.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := uc.RevokeCredential(ctx, "admin-1", sa.ID, cred.ID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("revoke twice: %v", err)
+	}
+
+	if err := uc.Delete(ctx, "admin-1", sa.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := uc.ListCredentials(ctx, sa.ID); !isCode(err, app.ErrCodeNotFound) {
+		t.Fatalf("credentials of deleted account: %v", err)
+	}
+	if last := f.audit.last(); last.Type != domain.AuditServiceAccountDeleted || last.TargetID != sa.ID {
+		t.Fatalf("audit = %+v", last)
+	}
+}
+
+func TestServiceAccounts_TokenForKey(t *testing.T) {
+	f := newFixture()
+	uc, tokens := f.serviceAccounts()
+	ctx := context.Background()
+	role := &domain.Role{TenantID: "acme", Name: "deployers"}
+	if err := f.roles.Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	sa, err := uc.Create(ctx, "admin-1", ServiceAccountCmd{TenantID: "acme", Name: "ci", Roles: []string{role.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	uc.now = func() time.Time { return now }
+	expires := now.Add(time.Hour)
+	cred, secret, err := uc.AddCredential(ctx, "admin-1", sa.ID, AddServiceCredentialCmd{Type: domain.CredentialAPIKey, Name: "deploy", ExpiresAt: &expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsServiceKey(secret) || cred.Prefix != secret[:serviceKeyShown] || cred.SecretHash == "" {
+		t.Fatalf("credential = %+v, secret %q", cred, secret)
+	}
+
+	res, err := uc.TokenForKey(ctx, sa.ID, secret)
+	if err != nil || res.AccessToken != "svc:"+sa.ID || res.ExpiresIn != 60 {
+		t.Fatalf("token = %+v, %v", res, err)
+	}
+	if got := tokens.issued[0]; got.TenantID != "acme" || !slices.Equal(got.Roles, []string{"deployers"}) {
+		t.Fatalf("claims = %+v", got)
+	}
+	last := f.audit.last()
+	if last.Type != domain.AuditServiceAccountTokenIssued || last.ActorID != sa.ID || last.TenantID != "acme" ||
+		last.Metadata["actor_type"] != "service_account" || last.Metadata["grant_type"] != GrantClientCredentials {
+		t.Fatalf("audit = %+v", last)
+	}
+	creds, _ := uc.ListCredentials(ctx, sa.ID)
+	if creds[0].LastUsedAt == nil || !creds[0].LastUsedAt.Equal(now) {
+		t.Fatalf("last used = %v", creds[0].LastUsedAt)
+	}
+
+	for _, tc := range []struct{ name, clientID, secret string }{
+		{"wrong secret", sa.ID, secret + "x"},
+		{"not a key", "", "pat_" + secret[4:]},
+		{"other client", "someone-else", secret},
+	} {
+		if _, err := uc.TokenForKey(ctx, tc.clientID, tc.secret); !isCode(err, app.ErrCodeInvalidCredentials) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+	if err := f.roles.Delete(ctx, "acme", role.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.TokenForKey(ctx, "", secret); err != nil || len(tokens.issued[1].Roles) != 0 {
+		t.Fatalf("deleted role: %+v, %v", tokens.issued, err)
+	}
+
+	if _, err := uc.Update(ctx, "admin-1", sa.ID, ServiceAccountCmd{Name: "ci", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.TokenForKey(ctx, "", secret); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("disabled account: %v", err)
+	}
+	if _, err := uc.Update(ctx, "admin-1", sa.ID, ServiceAccountCmd{Name: "ci"}); err != nil {
+		t.Fatal(err)
+	}
+	now = expires
+	if _, err := uc.TokenForKey(ctx, "", secret); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("expired key: %v", err)
+	}
+}
+
+func TestServiceAccounts_TokenForAssertion(t *testing.T) {
+	f := newFixture()
+	uc, _ := f.serviceAccounts()
+	ctx := context.Background()
+	sa, err := uc.Create(ctx, "admin-1", ServiceAccountCmd{TenantID: "acme", Name: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	key, _, err := uc.AddCredential(ctx, "admin-1", sa.ID, AddServiceCredentialCmd{
+		Type:      domain.CredentialPublicKey,
+		Name:      "signer",
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := func(claims gojwt.MapClaims) string {
+		tok := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, claims)
+		tok.Header["kid"] = key.ID
+		s, err := tok.SignedString(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	exp := time.Now().Add(5 * time.Minute).Unix()
+
+	res, err := uc.TokenForAssertion(ctx, assert(gojwt.MapClaims{"iss": sa.ID, "sub": sa.ID, "aud": testTokenURL, "exp": exp}))
+	if err != nil || res.AccessToken != "svc:"+sa.ID {
+		t.Fatalf("token = %+v, %v", res, err)
+	}
+	last := f.audit.last()
+	if last.Metadata["grant_type"] != GrantJWTBearer || last.Metadata["credential_id"] != key.ID {
+		t.Fatalf("audit = %+v", last)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		assertion string
+	}{
+		{"other subject", assert(gojwt.MapClaims{"iss": sa.ID, "sub": "user-1", "aud": testTokenURL, "exp": exp})},
+		{"wrong audience", assert(gojwt.MapClaims{"iss": sa.ID, "sub": sa.ID, "aud": "https://elsewhere", "exp": exp})},
+		{"unknown issuer", assert(gojwt.MapClaims{"iss": "nobody", "sub": "nobody", "aud": testTokenURL, "exp": exp})},
+		{"expired", assert(gojwt.MapClaims{"iss": sa.ID, "sub": sa.ID, "aud": testTokenURL, "exp": time.Now().Add(-5 * time.Minute).Unix()})},
+		{"garbage", "not-a-jwt"},
+	} {
+		if _, err := uc.TokenForAssertion(ctx, tc.assertion); !isCode(err, app.ErrCodeInvalidCredentials) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+const (
+	GrantTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// TokenTypeAccessToken is the only subject token type, and the type of
+	// every issued token.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	// maxDelegationDepth bounds the "act" chain, counting the new actor.
+	maxDelegationDepth = 5
+)
+
+type TokenExchangeCmd struct {
+	SubjectToken     string
+	SubjectTokenType string
+	Audience         string
+	// Scopes is what the client asks for; empty asks for everything the
+	// policy and the subject token allow.
+	Scopes []string
+}
+
+type TokenExchangeResult struct {
+	AccessToken string
+	ExpiresIn   int64
+	Scopes      []string
+}
+
+// TokenExchangeUseCase implements the token exchange grant (RFC 8693): a
+// service account trades a user's access token for a narrower token aimed
+// at another service, within what the policy lets it exchange for.
+type TokenExchangeUseCase struct {
+	log    *slog.Logger
+	policy app.TokenExchangePolicy
+	users  domain.UserRepository
+	tokens app.TokenExchanger
+	audit  app.AuditLog
+	now    func() time.Time
+}
+
+func NewTokenExchangeUseCase(log *slog.Logger, policy app.TokenExchangePolicy, users domain.UserRepository, tokens app.TokenExchanger, audit app.AuditLog) *TokenExchangeUseCase {
+	return &TokenExchangeUseCase{log: log, policy: policy, users: users, tokens: tokens, audit: audit, now: time.Now}
+}
+
+// Exchange issues a token for the user behind cmd.SubjectToken to client,
+// which must already be authenticated. A token from an earlier exchange
+// can only be exchanged again by the client it was issued to. The token
+// never outlives the subject token, and its scopes never exceed those of
+// the subject token. Errors about the audience or scopes name the field, so the token
+// endpoint can answer invalid_target or invalid_scope.
+func (uc *TokenExchangeUseCase) Exchange(ctx context.Context, client *domain.ServiceAccount, cmd TokenExchangeCmd) (*TokenExchangeResult, error) {
+	log := uc.log.With("op", "ExchangeToken", "client_id", client.ID, "audience", cmd.Audience)
+	if cmd.SubjectTokenType != TokenTypeAccessToken {
+		return nil, app.NewError(app.ErrCodeValidation, "Only access tokens can be exchanged").
+			WithFields(app.FieldError{Field: "subject_token_type", Code: "oneof", Param: TokenTypeAccessToken, Message: "unsupported token type"})
+	}
+	rule, ok := uc.policy.Rule(client.ID, cmd.Audience)
+	if !ok {
+		log.Warn("token exchange denied by policy")
+		return nil, app.NewError(app.ErrCodeValidation, "Client may not exchange tokens for this audience").
+			WithFields(app.FieldError{Field: "audience", Code: "invalid_target", Message: "not allowed for this client"})
+	}
+
+	invalid := app.NewError(app.ErrCodeInvalidCredentials, "Invalid subject token")
+	subject, err := uc.tokens.ValidateSubjectToken(cmd.SubjectToken)
+	if err != nil {
+		log.Warn("subject token rejected", "error", err)
+		return nil, invalid
+	}
+	if subject.Audience != nil && !slices.Contains(subject.Audience, client.ID) {
+		log.Warn("subject token issued to another audience", "user_id", subject.UserID, "token_audience", subject.Audience)
+		return nil, invalid
+	}
+	if subject.Actor.Depth()+1 > maxDelegationDepth {
+		log.Warn("delegation chain too long", "user_id", subject.UserID)
+		return nil, invalid
+	}
+	user, err := uc.users.FindByID(ctx, subject.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, storageError(log, err, "Failed to load user")
+	}
+	if user.Disabled() {
+		log.Warn("subject user disabled", "user_id", user.ID)
+		return nil, invalid
+	}
+
+	allowed := rule.Scopes
+	if subject.Scopes != nil {
+		allowed = slices.DeleteFunc(slices.Clone(allowed), func(s string) bool { return !slices.Contains(subject.Scopes, s) })
+	}
+	scopes := allowed
+	if len(cmd.Scopes) > 0 {
+		for _, s := range cmd.Scopes {
+			if !slices.Contains(allowed, s) {
+				return nil, app.NewError(app.ErrCodeValidation, "Requested scope is not allowed").
+					WithFields(app.FieldError{Field: "scope", Code: "invalid_scope", Param: s, Message: "not allowed for this client and subject token"})
+			}
+		}
+		scopes = slices.Compact(slices.Sorted(slices.Values(cmd.Scopes)))
+	}
+
+	now := uc.now()
+	expiresAt := now.Add(uc.tokens.AccessTTL())
+	if subject.ExpiresAt.Before(expiresAt) {
+		expiresAt = subject.ExpiresAt
+	}
+	token, err := uc.tokens.GenerateExchangedToken(app.ExchangeClaims{
+		UserID:    user.ID,
+		ClientID:  client.ID,
+		Audience:  cmd.Audience,
+		Scopes:    scopes,
+		Actor:     subject.Actor,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, app.NewError(app.ErrCodeInternal, "Failed to generate access token")
+	}
+
+	recordAudit(ctx, log, uc.audit, domain.AuditEvent{
+		Type:     domain.AuditTokenExchanged,
+		ActorID:  client.ID,
+		TargetID: user.ID,
+		TenantID: client.TenantID,
+		Metadata: map[string]string{
+			"actor_type":       "service_account",
+			"audience":         cmd.Audience,
+			"scope":            strings.Join(scopes, " "),
+			"delegation_depth": strconv.Itoa(subject.Actor.Depth() + 1),
+		},
+	})
+	return &TokenExchangeResult{AccessToken: token, ExpiresIn: int64(expiresAt.Sub(now).Seconds()), Scopes: scopes}, nil
+}
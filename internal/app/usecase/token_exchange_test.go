@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/security/jwt"
+)
+
+func TestTokenExchange_PolicyScopesAndActorChain(t *testing.T) {
+	ctx := context.Background()
+	users, audit := memory.NewUserRepository(), &auditTrail{}
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Hour})
+	uc := NewTokenExchangeUseCase(slog.New(slog.NewTextHandler(testWriter{}, nil)), app.TokenExchangePolicy{Clients: map[string][]app.TokenExchangeRule{
+		"gateway": {{Audience: "billing", Scopes: []string{"read", "write"}}},
+		"billing": {{Audience: "ledger", Scopes: []string{"read", "delete"}}},
+	}}, users, tokens, audit)
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	userToken, _ := tokens.GenerateAccessToken(u.ID)
+	gateway := &domain.ServiceAccount{ID: "gateway", TenantID: "acme"}
+	billing := &domain.ServiceAccount{ID: "billing", TenantID: "acme"}
+	cmd := func(token, audience string, scopes ...string) TokenExchangeCmd {
+		return TokenExchangeCmd{SubjectToken: token, SubjectTokenType: TokenTypeAccessToken, Audience: audience, Scopes: scopes}
+	}
+	field := func(err error) string {
+		var ae app.AppError
+		if !errors.As(err, &ae) || ae.Code != app.ErrCodeValidation || len(ae.Fields) == 0 {
+			return ""
+		}
+		return ae.Fields[0].Field
+	}
+
+	if _, err := uc.Exchange(ctx, gateway, cmd(userToken, "ledger")); field(err) != "audience" {
+		t.Fatalf("audience outside policy: %v", err)
+	}
+	if _, err := uc.Exchange(ctx, gateway, TokenExchangeCmd{SubjectToken: userToken, SubjectTokenType: "urn:ietf:params:oauth:token-type:id_token", Audience: "billing"}); field(err) != "subject_token_type" {
+		t.Fatalf("id token as subject: %v", err)
+	}
+	if _, err := uc.Exchange(ctx, gateway, cmd("junk", "billing")); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("junk subject token: %v", err)
+	}
+	if _, err := uc.Exchange(ctx, gateway, cmd(userToken, "billing", "read", "admin")); field(err) != "scope" {
+		t.Fatalf("scope outside policy: %v", err)
+	}
+
+	res, err := uc.Exchange(ctx, gateway, cmd(userToken, "billing"))
+	if err != nil || !slices.Equal(res.Scopes, []string{"read", "write"}) || res.ExpiresIn <= 0 || res.ExpiresIn > 3600 {
+		t.Fatalf("exchange = %+v, %v", res, err)
+	}
+	if last := (*audit)[len(*audit)-1]; last.Type != domain.AuditTokenExchanged || last.ActorID != "gateway" || last.TargetID != u.ID ||
+		last.TenantID != "acme" || last.Metadata["audience"] != "billing" || last.Metadata["scope"] != "read write" {
+		t.Fatalf("audit = %+v", last)
+	}
+	if _, err := tokens.ValidateToken(res.AccessToken); err == nil {
+		t.Fatal("exchanged token accepted as a user token")
+	}
+
+	// Only the audience of an exchanged token may exchange it again.
+	if _, err := uc.Exchange(ctx, gateway, cmd(res.AccessToken, "billing")); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("exchanged token replayed by another client: %v", err)
+	}
+
+	// The second hop is limited by both its own rule and the first token.
+	if _, err := uc.Exchange(ctx, billing, cmd(res.AccessToken, "ledger", "delete")); field(err) != "scope" {
+		t.Fatalf("scope beyond subject token: %v", err)
+	}
+	hop, err := uc.Exchange(ctx, billing, cmd(res.AccessToken, "ledger"))
+	if err != nil || !slices.Equal(hop.Scopes, []string{"read"}) {
+		t.Fatalf("second exchange = %+v, %v", hop, err)
+	}
+	subject, err := tokens.ValidateSubjectToken(hop.AccessToken)
+	if err != nil || subject.UserID != u.ID || subject.Actor.Depth() != 2 || subject.Actor.Subject != "billing" || subject.Actor.Actor.Subject != "gateway" {
+		t.Fatalf("second exchanged token = %+v, %v", subject, err)
+	}
+
+	now := time.Now()
+	u.DisabledAt = &now
+	if err := users.UpdateStatus(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.Exchange(ctx, gateway, cmd(userToken, "billing")); !isCode(err, app.ErrCodeInvalidCredentials) {
+		t.Fatalf("disabled user: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package usecase
+
+import (
+	"context"
+
+	"go-auth/internal/app"
+)
+
+// withinTx runs fn in a unit of work of tx, or directly when tx is nil.
+func withinTx(ctx context.Context, tx app.TxManager, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.WithinTx(ctx, fn)
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	mrand "math/rand/v2"
+	"net/url"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+const (
+	webhookBatchSize   = 100
+	webhookLease       = time.Minute
+	webhookMaxAttempts = 12
+	webhookBaseBackoff = 10 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+)
+
+// DispatchWebhooksUseCase moves outbox events to subscribers: it fans new
+// events out into deliveries and attempts every delivery that is due,
+// retrying with exponential backoff until it succeeds or is dead-lettered.
+type DispatchWebhooksUseCase struct {
+	log    *slog.Logger
+	repo   domain.WebhookRepository
+	sender app.WebhookSender
+	now    func() time.Time
+}
+
+func NewDispatchWebhooksUseCase(log *slog.Logger, repo domain.WebhookRepository, sender app.WebhookSender) *DispatchWebhooksUseCase {
+	return &DispatchWebhooksUseCase{log: log, repo: repo, sender: sender, now: time.Now}
+}
+
+// Handle runs one dispatch round and returns the number of attempted deliveries.
+func (uc *DispatchWebhooksUseCase) Handle(ctx context.Context) (int, error) {
+	for {
+		n, err := uc.repo.FanOut(ctx, webhookBatchSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fan out outbox events: %w", err)
+		}
+		if n < webhookBatchSize {
+			break
+		}
+	}
+
+	due, err := uc.repo.ClaimDue(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	for _, d := range due {
+		uc.attempt(ctx, d)
+	}
+	return len(due), nil
+}
+
+func (uc *DispatchWebhooksUseCase) attempt(ctx context.Context, d domain.WebhookDelivery) {
+	log := uc.log.With("op", "DispatchWebhook", "delivery_id", d.ID, "event", d.Event.Type)
+	code, err := uc.sender.Send(ctx, d.URL, d.Secret, d.ID, d.Event)
+	res := domain.DeliveryResult{Status: domain.DeliverySucceeded, StatusCode: code, NextAttempt: uc.now()}
+	if err != nil {
+		res.Error = err.Error()
+		attempts := d.Attempts + 1
+		if attempts >= webhookMaxAttempts {
+			res.Status = domain.DeliveryDead
+			log.Warn("webhook delivery dead-lettered", "attempts", attempts, "error", err)
+		} else {
+			res.Status = domain.DeliveryPending
+			res.NextAttempt = uc.now().Add(webhookBackoff(attempts))
+			log.Info("webhook delivery failed, will retry", "attempts", attempts, "next_attempt_at", res.NextAttempt, "error", err)
+		}
+	}
+	if err := uc.repo.Complete(ctx, d.ID, res); err != nil {
+		log.Error("failed to record webhook delivery result", "error", err)
+	}
+}
+
+// webhookBackoff doubles the delay per attempt with ±20% jitter.
+func webhookBackoff(attempts int) time.Duration {
+	d := webhookMaxBackoff
+	if attempts <= 16 {
+		d = min(webhookBaseBackoff<<(attempts-1), webhookMaxBackoff)
+	}
+	jitter := time.Duration(mrand.Int64N(int64(d)/5*2+1)) - d/5
+	return d + jitter
+}
+
+// Run calls Handle every interval until ctx is cancelled.
+func (uc *DispatchWebhooksUseCase) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := uc.Handle(ctx); err != nil && ctx.Err() == nil {
+			uc.log.Error("webhook dispatch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+type CreateWebhookCmd struct {
+	TenantID   string
+	URL        string
+	EventTypes []string
+}
+
+// ManageWebhooksUseCase backs the admin webhook API.
+type ManageWebhooksUseCase struct {
+	repo domain.WebhookRepository
+}
+
+func NewManageWebhooksUseCase(repo domain.WebhookRepository) *ManageWebhooksUseCase {
+	return &ManageWebhooksUseCase{repo: repo}
+}
+
+// CreateSubscription registers an endpoint and returns it with its signing
+// secret, which is not exposed again afterwards.
+func (uc *ManageWebhooksUseCase) CreateSubscription(ctx context.Context, cmd CreateWebhookCmd) (*domain.WebhookSubscription, error) {
+	u, err := url.Parse(cmd.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, app.NewError(app.ErrCodeValidation, "Webhook URL must be an absolute http(s) URL")
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	sub := &domain.WebhookSubscription{
+		TenantID:   cmd.TenantID,
+		URL:        cmd.URL,
+		Secret:     "whsec_" + hex.EncodeToString(raw),
+		EventTypes: cmd.EventTypes,
+		Active:     true,
+	}
+	if err := uc.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (uc *ManageWebhooksUseCase) ListSubscriptions(ctx context.Context, tenantID string) ([]domain.WebhookSubscription, error) {
+	return uc.repo.ListSubscriptions(ctx, tenantID)
+}
+
+func (uc *ManageWebhooksUseCase) DeleteSubscription(ctx context.Context, id string) error {
+	return notFoundAs(uc.repo.DeleteSubscription(ctx, id), domain.ErrSubscriptionNotFound, "Webhook subscription not found")
+}
+
+func (uc *ManageWebhooksUseCase) ListDeliveries(ctx context.Context, filter domain.DeliveryFilter) ([]domain.WebhookDelivery, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	return uc.repo.ListDeliveries(ctx, filter)
+}
+
+// Replay schedules a delivery, typically a dead-lettered one, for immediate redelivery.
+func (uc *ManageWebhooksUseCase) Replay(ctx context.Context, deliveryID string) error {
+	return notFoundAs(uc.repo.Replay(ctx, deliveryID), domain.ErrDeliveryNotFound, "Webhook delivery not found")
+}
+
+func notFoundAs(err, target error, msg string) error {
+	if errors.Is(err, target) {
+		return app.NewError(app.ErrCodeNotFound, msg)
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+)
+
+type recordingSender struct {
+	fail  bool
+	calls []string
+}
+
+func (s *recordingSender) Send(_ context.Context, url, secret, deliveryID string, e domain.OutboxEvent) (int, error) {
+	s.calls = append(s.calls, url+" "+e.Type)
+	if s.fail {
+		return 500, errors.New("boom")
+	}
+	return 200, nil
+}
+
+func TestDispatchWebhooks_DeliversRetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
+	repo := memory.NewWebhookRepository()
+	manage := NewManageWebhooksUseCase(repo)
+
+	if _, err := manage.CreateSubscription(ctx, CreateWebhookCmd{URL: "ftp://nope"}); err == nil {
+		t.Fatalf("non-http URL must be rejected")
+	}
+	sub, err := manage.CreateSubscription(ctx, CreateWebhookCmd{URL: "https://hooks.example/a", EventTypes: []string{domain.EventUserRegistered}})
+	if err != nil || len(sub.Secret) < 20 {
+		t.Fatalf("create: %v %+v", err, sub)
+	}
+	_, _ = manage.CreateSubscription(ctx, CreateWebhookCmd{TenantID: "other", URL: "https://hooks.example/b"})
+
+	_ = repo.Enqueue(ctx, domain.NewUserEvent(domain.EventUserRegistered, "", &domain.User{ID: "u1", Email: "a@ex.com"}))
+	_ = repo.Enqueue(ctx, domain.NewUserEvent(domain.EventUserDeleted, "", &domain.User{ID: "u2"}))
+
+	sender := &recordingSender{}
+	now := time.Now()
+	uc := NewDispatchWebhooksUseCase(log, repo, sender)
+	uc.now = func() time.Time { return now }
+
+	if n, err := uc.Handle(ctx); err != nil || n != 1 {
+		t.Fatalf("first round: n=%d err=%v", n, err)
+	}
+	if len(sender.calls) != 1 || sender.calls[0] != "https://hooks.example/a user.registered" {
+		t.Fatalf("unexpected calls %v", sender.calls)
+	}
+	if n, _ := uc.Handle(ctx); n != 0 {
+		t.Fatalf("delivered events must not be re-sent, got %d", n)
+	}
+
+	sender.fail = true
+	_ = repo.Enqueue(ctx, domain.NewUserEvent(domain.EventUserRegistered, "", &domain.User{ID: "u3"}))
+	_, _ = uc.Handle(ctx)
+	pending, _ := manage.ListDeliveries(ctx, domain.DeliveryFilter{Status: domain.DeliveryPending})
+	if len(pending) != 1 || pending[0].Attempts != 1 || !pending[0].NextAttemptAt.After(now) {
+		t.Fatalf("failed delivery should be rescheduled: %+v", pending)
+	}
+
+	// Burn through the retry budget so that the next failure dead-letters it.
+	for i := 1; i < webhookMaxAttempts-1; i++ {
+		_ = repo.Complete(ctx, pending[0].ID, domain.DeliveryResult{Status: domain.DeliveryPending, NextAttempt: now})
+	}
+	_, _ = uc.Handle(ctx)
+	dead, _ := manage.ListDeliveries(ctx, domain.DeliveryFilter{Status: domain.DeliveryDead})
+	if len(dead) != 1 {
+		t.Fatalf("expected a dead-lettered delivery")
+	}
+
+	sender.fail = false
+	if err := manage.Replay(ctx, dead[0].ID); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	_, _ = uc.Handle(ctx)
+	ok, _ := manage.ListDeliveries(ctx, domain.DeliveryFilter{Status: domain.DeliverySucceeded})
+	if len(ok) != 2 {
+		t.Fatalf("replayed delivery should succeed, got %d succeeded", len(ok))
+	}
+	if err := manage.Replay(ctx, "missing"); err == nil {
+		t.Fatalf("replay of unknown delivery must fail")
+	}
+}
+
+func TestDispatchWebhooks_TenantSubscriptionsReceiveTheirTenantsEvents(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(testWriter{}, nil))
+	repo := memory.NewWebhookRepository()
+	manage := NewManageWebhooksUseCase(repo)
+	for _, cmd := range []CreateWebhookCmd{
+		{TenantID: "acme", URL: "https://hooks.example/acme"},
+		{TenantID: "other", URL: "https://hooks.example/other"},
+		{URL: "https://hooks.example/global"},
+	} {
+		if _, err := manage.CreateSubscription(ctx, cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	register := NewRegisterUserUseCase(log, nil, memory.NewUserRepository(), repo, &fakePwd{}, nil)
+	if err := register.Handle(ctx, RegisterUserCmd{Email: "a@acme.com", Password: "password123", TenantID: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &recordingSender{}
+	if _, err := NewDispatchWebhooksUseCase(log, repo, sender).Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(sender.calls)
+	if want := []string{"https://hooks.example/acme user.registered", "https://hooks.example/global user.registered"}; !slices.Equal(sender.calls, want) {
+		t.Fatalf("calls = %v, want %v", sender.calls, want)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempts, base := range map[int]time.Duration{1: 10 * time.Second, 3: 40 * time.Second, 30: webhookMaxBackoff} {
+		d := webhookBackoff(attempts)
+		if d < base*8/10 || d > base*12/10 {
+			t.Errorf("attempt %d: backoff %v outside ±20%% of %v", attempts, d, base)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type AuditEventType string
+
+const (
+	AuditUserRegistered                  AuditEventType = "user.registered"
+	AuditLoginSucceeded                  AuditEventType = "auth.login.succeeded"
+	AuditLoginFailed                     AuditEventType = "auth.login.failed"
+	AuditLoginBlocked                    AuditEventType = "auth.login.blocked"
+	AuditMagicLinkRequested              AuditEventType = "auth.magic_link.requested"
+	AuditOTPSent                         AuditEventType = "auth.otp.sent"
+	AuditOTPFailed                       AuditEventType = "auth.otp.failed"
+	AuditTokenRefreshed                  AuditEventType = "auth.token.refreshed"
+	AuditTokenReuseDetected              AuditEventType = "auth.token.reuse_detected"
+	AuditTokenExchanged                  AuditEventType = "auth.token.exchanged"
+	AuditLogout                          AuditEventType = "auth.logout"
+	AuditPasswordChanged                 AuditEventType = "user.password_changed"
+	AuditPhoneVerified                   AuditEventType = "user.phone_verified"
+	AuditPhoneUpdated                    AuditEventType = "user.phone_updated"
+	AuditIdentityLinked                  AuditEventType = "user.identity_linked"
+	AuditRoleGranted                     AuditEventType = "user.role_granted"
+	AuditRoleRevoked                     AuditEventType = "user.role_revoked"
+	AuditUserProvisioned                 AuditEventType = "user.provisioned"
+	AuditUserDeprovisioned               AuditEventType = "user.deprovisioned"
+	AuditPersonalTokenCreated            AuditEventType = "user.personal_token_created"
+	AuditPersonalTokenRevoked            AuditEventType = "user.personal_token_revoked"
+	AuditUserDisabled                    AuditEventType = "user.disabled"
+	AuditUserEnabled                     AuditEventType = "user.enabled"
+	AuditUserSessionsRevoked             AuditEventType = "user.sessions_revoked"
+	AuditUserEmailVerified               AuditEventType = "user.email_verified"
+	AuditUserDeleted                     AuditEventType = "user.deleted"
+	AuditServiceAccountCreated           AuditEventType = "service_account.created"
+	AuditServiceAccountUpdated           AuditEventType = "service_account.updated"
+	AuditServiceAccountDeleted           AuditEventType = "service_account.deleted"
+	AuditServiceAccountCredentialAdded   AuditEventType = "service_account.credential_added"
+	AuditServiceAccountCredentialRevoked AuditEventType = "service_account.credential_revoked"
+	AuditServiceAccountTokenIssued       AuditEventType = "service_account.token_issued"
+	AuditImpersonationStarted            AuditEventType = "impersonation.started"
+	AuditImpersonationEnded              AuditEventType = "impersonation.ended"
+)
+
+// AuditEvent is an append-only record of a security-relevant action.
+// Events form a hash chain: Hash covers the event and PrevHash, the Hash of
+// the event appended before it.
+type AuditEvent struct {
+	ID         string            `json:"id"`
+	Seq        int64             `json:"seq"`
+	Type       AuditEventType    `json:"type"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	TargetID   string            `json:"target_id,omitempty"`
+	TenantID   string            `json:"tenant_id,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	PrevHash   string            `json:"prev_hash"`
+	Hash       string            `json:"hash"`
+}
+
+// ChainHash computes the hash of e linked to prevHash. OccurredAt is taken at
+// microsecond precision so that the value survives a Postgres round trip.
+func (e *AuditEvent) ChainHash(prevHash string) string {
+	canonical, _ := json.Marshal(struct {
+		Prev       string            `json:"prev"`
+		Type       AuditEventType    `json:"type"`
+		ActorID    string            `json:"actor"`
+		TargetID   string            `json:"target"`
+		TenantID   string            `json:"tenant"`
+		IP         string            `json:"ip"`
+		UserAgent  string            `json:"ua"`
+		RequestID  string            `json:"rid"`
+		Metadata   map[string]string `json:"meta"`
+		OccurredAt int64             `json:"at"`
+	}{prevHash, e.Type, e.ActorID, e.TargetID, e.TenantID, e.IP, e.UserAgent, e.RequestID, e.Metadata, e.OccurredAt.UnixMicro()})
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks events given in append order and returns the index
+// of the first event whose hash or link does not match, or -1 if intact.
+func VerifyAuditChain(prevHash string, events []AuditEvent) int {
+	for i := range events {
+		if events[i].PrevHash != prevHash || events[i].ChainHash(prevHash) != events[i].Hash {
+			return i
+		}
+		prevHash = events[i].Hash
+	}
+	return -1
+}
+
+// AuditFilter selects events for AuditRepository.List. Results are ordered
+// newest first; BeforeSeq is the keyset cursor from the previous page.
+type AuditFilter struct {
+	Type      AuditEventType
+	ActorID   string
+	TargetID  string
+	TenantID  string
+	From      time.Time
+	To        time.Time
+	BeforeSeq int64
+	Limit     int
+}
+
+type AuditRepository interface {
+	// Append assigns ID, Seq, PrevHash and Hash and stores the event.
+	Append(ctx context.Context, event *AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+}
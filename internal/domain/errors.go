@@ -0,0 +1,14 @@
+package domain
+
+import "errors"
+
+// Repositories wrap these errors so that callers can tell expected outcomes
+// from infrastructure failures with errors.Is.
+var (
+	// ErrNotFound: the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict: the write would violate a uniqueness constraint.
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable: the storage could not be reached; retrying later may succeed.
+	ErrUnavailable = errors.New("storage unavailable")
+)
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Identity links a user to an account at an upstream identity provider.
+// Provider and Subject together identify the upstream account.
+type Identity struct {
+	ID     string
+	UserID string
+	// Provider is the upstream issuer URL, so that tenants configuring the
+	// same provider share links.
+	Provider string
+	Subject  string
+	// Email is the address the provider asserted when the link was made.
+	Email     string
+	CreatedAt time.Time
+}
+
+type IdentityRepository interface {
+	// Create assigns identity.ID. It fails with ErrConflict if the upstream
+	// account is already linked.
+	Create(ctx context.Context, identity *Identity) error
+	// FindBySubject fails with ErrNotFound if the upstream account is not
+	// linked to any user.
+	FindBySubject(ctx context.Context, provider, subject string) (*Identity, error)
+	// ListByUser returns the user's identities, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]Identity, error)
+}
+
+// FederatedLogin is an authorization request in flight to an upstream
+// provider. It is looked up by the hash of the OAuth state parameter, or of
+// the SAML RelayState, and bound to the browser that started it by
+// BindingHash.
+type FederatedLogin struct {
+	ID          string
+	StateHash   string
+	BindingHash string
+	// Provider is the OIDC provider ID, or "saml:" and the SAML IdP ID.
+	Provider string
+	TenantID string
+	// CodeVerifier is the PKCE secret sent with the code exchange.
+	CodeVerifier string
+	// Nonce must come back in the ID token; for SAML it is the AuthnRequest
+	// ID the response must answer.
+	Nonce      string
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type FederatedLoginRepository interface {
+	Create(ctx context.Context, login *FederatedLogin) error
+	// Consume marks the login with stateHash and bindingHash used and returns
+	// it. It fails with ErrNotFound unless such a login exists, is unexpired
+	// at now and has not been consumed.
+	Consume(ctx context.Context, stateHash, bindingHash string, now time.Time) (*FederatedLogin, error)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Impersonation is an admin acting as a user through a short-lived access
+// token that names the admin in its "act" claim (RFC 8693). It ends when
+// the token expires or an admin ends it early.
+type Impersonation struct {
+	ID      string `json:"id"`
+	ActorID string `json:"actor_id"` // the admin
+	UserID  string `json:"user_id"`
+	Reason  string `json:"reason,omitempty"`
+	// EndReason is "ended" or "expired" once EndedAt is set.
+	EndReason string     `json:"end_reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// Active reports whether the impersonation token is still usable at now.
+func (i *Impersonation) Active(now time.Time) bool {
+	return i.EndedAt == nil && now.Before(i.ExpiresAt)
+}
+
+type ImpersonationRepository interface {
+	// Create assigns ID.
+	Create(ctx context.Context, imp *Impersonation) error
+	// FindByID fails with ErrNotFound for unknown IDs.
+	FindByID(ctx context.Context, id string) (*Impersonation, error)
+	// End sets EndedAt and EndReason. It fails with ErrNotFound unless an
+	// impersonation with id exists and has not ended, so only one caller
+	// ends each.
+	End(ctx context.Context, id string, at time.Time, reason string) error
+	// ListExpired returns up to limit impersonations that expired before now
+	// without being ended, oldest first.
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]Impersonation, error)
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MagicLink is a single-use passwordless login link. Only hashes of the
+// emailed token and of the browser nonce are stored.
+type MagicLink struct {
+	ID        string
+	Email     string
+	TenantID  string
+	TokenHash string
+	NonceHash string
+	// AutoRegister allows consuming the link to create an unknown user.
+	AutoRegister bool
+	ExpiresAt    time.Time
+	ConsumedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, link *MagicLink) error
+	// Consume marks the link with tokenHash and nonceHash used and returns it.
+	// It fails with ErrNotFound unless such a link exists, is unexpired at
+	// now and has not been consumed, so a link can succeed only once.
+	Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (*MagicLink, error)
+}
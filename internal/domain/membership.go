@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Membership places a user in a tenant. SCIM provisioning creates and
+// maintains memberships; the profile fields are what the tenant's identity
+// provider says about the user, kept apart from the user's own account.
+type Membership struct {
+	TenantID string
+	UserID   string
+	// UserName is unique within the tenant, ignoring case.
+	UserName    string
+	ExternalID  string
+	GivenName   string
+	FamilyName  string
+	DisplayName string
+	// Active is false once the tenant deprovisioned the user.
+	Active bool
+	// CreatedAccount is set when provisioning into the tenant created the
+	// user's account. It is fixed at creation.
+	CreatedAccount bool
+	// Version starts at 1 and increments on every update.
+	Version   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type MembershipRepository interface {
+	// Create sets m.Version to 1. It fails with ErrConflict if the user is
+	// already a member or the user name is taken in the tenant.
+	Create(ctx context.Context, m *Membership) error
+	// Get fails with ErrNotFound if the user is not a member of the tenant.
+	Get(ctx context.Context, tenantID, userID string) (*Membership, error)
+	// List returns the tenant's memberships, oldest first.
+	List(ctx context.Context, tenantID string) ([]Membership, error)
+	// ListByUser returns the user's memberships in every tenant, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]Membership, error)
+	// Update stores the profile fields, Active and UpdatedAt and increments
+	// m.Version. It fails with ErrNotFound unless the membership exists at
+	// m.Version, and with ErrConflict if the user name is taken.
+	Update(ctx context.Context, m *Membership) error
+	// Delete removes the membership along with the user's roles in the
+	// tenant. It fails with ErrNotFound if there is no such membership.
+	Delete(ctx context.Context, tenantID, userID string) error
+}
+
+// Role is a named set of tenant members; SCIM groups map onto roles.
+type Role struct {
+	ID         string
+	TenantID   string
+	Name       string
+	ExternalID string
+	// Members are user IDs, each a member of the tenant.
+	Members []string
+	// Version starts at 1 and increments on every update.
+	Version   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type RoleRepository interface {
+	// Create assigns role.ID and sets role.Version to 1. It fails with
+	// ErrConflict if the name is taken in the tenant.
+	Create(ctx context.Context, role *Role) error
+	// Get fails with ErrNotFound if the tenant has no such role.
+	Get(ctx context.Context, tenantID, id string) (*Role, error)
+	// List returns the tenant's roles with their members, oldest first.
+	List(ctx context.Context, tenantID string) ([]Role, error)
+	// ListByMember returns the roles the user holds in the tenant, oldest
+	// first, without their members.
+	ListByMember(ctx context.Context, tenantID, userID string) ([]Role, error)
+	// Update stores Name, ExternalID, Members and UpdatedAt and increments
+	// role.Version. It fails with ErrNotFound unless the role exists at
+	// role.Version, and with ErrConflict if the name is taken.
+	Update(ctx context.Context, role *Role) error
+	// Delete fails with ErrNotFound if the tenant has no such role.
+	Delete(ctx context.Context, tenantID, id string) error
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OTP purposes. A code issued for one purpose is never accepted for another.
+const (
+	OTPVerifyPhone  = "verify_phone"
+	OTPPhoneLogin   = "phone_login"
+	OTPSecondFactor = "second_factor"
+)
+
+// OTP is a one-time code sent by SMS. Only a hash of the code is stored;
+// clients refer to it by ID.
+type OTP struct {
+	ID         string
+	Purpose    string
+	UserID     string
+	Phone      string
+	CodeHash   string
+	Attempts   int
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type OTPRepository interface {
+	// Create assigns otp.ID.
+	Create(ctx context.Context, otp *OTP) error
+	// RecordAttempt counts one verification attempt and returns the OTP with
+	// the new count. Counting before the code is checked bounds guesses even
+	// under concurrent requests. It fails with ErrNotFound for unknown IDs.
+	RecordAttempt(ctx context.Context, id string) (*OTP, error)
+	// Consume marks the OTP used. It fails with ErrNotFound unless the OTP
+	// exists, is unexpired at now and has not been consumed.
+	Consume(ctx context.Context, id string, now time.Time) error
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Identity event types published to other services.
+const (
+	EventUserRegistered    = "user.registered"
+	EventUserEmailVerified = "user.email_verified"
+	EventUserDeleted       = "user.deleted"
+)
+
+// OutboxEvent is written in the same transaction as the change it describes
+// and later fanned out to webhook subscriptions.
+type OutboxEvent struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	TenantID    string          `json:"tenant_id,omitempty"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// NewUserEvent builds an outbox event describing u. tenantID is the tenant
+// the change happened in, or "" for changes outside any tenant.
+func NewUserEvent(eventType, tenantID string, u *User) *OutboxEvent {
+	payload, _ := json.Marshal(map[string]any{
+		"user_id":     u.ID,
+		"email":       u.Email,
+		"is_verified": u.IsVerified,
+	})
+	return &OutboxEvent{
+		Type:        eventType,
+		TenantID:    tenantID,
+		AggregateID: u.ID,
+		Payload:     payload,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, event *OutboxEvent) error
+}
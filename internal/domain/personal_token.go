@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Scopes a personal access token can carry. A write scope implies the read
+// scope of the same area.
+const (
+	ScopeUserRead   = "user:read"
+	ScopeUserWrite  = "user:write"
+	ScopeAdminRead  = "admin:read"
+	ScopeAdminWrite = "admin:write"
+)
+
+// PersonalAccessToken is a long-lived bearer credential a user creates for
+// scripts. Only the hash of the token is stored; Prefix is its public
+// beginning, enough to tell tokens apart in listings.
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	TokenHash  string     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil: never expires
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Expired reports whether the token is past its expiry at now.
+func (t *PersonalAccessToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+type PersonalAccessTokenRepository interface {
+	// Create assigns ID and fails with ErrConflict if the hash is taken.
+	Create(ctx context.Context, token *PersonalAccessToken) error
+	// FindByHash fails with ErrNotFound for unknown hashes. Expired tokens
+	// are returned; callers check Expired.
+	FindByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error)
+	// ListByUser returns the user's tokens, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]PersonalAccessToken, error)
+	// Delete fails with ErrNotFound unless the user has a token with id.
+	Delete(ctx context.Context, userID, id string) error
+	// DeleteAllByUser removes every token of the user.
+	DeleteAllByUser(ctx context.Context, userID string) error
+	// Touch sets LastUsedAt and fails with ErrNotFound for unknown IDs.
+	Touch(ctx context.Context, id string, at time.Time) error
+}
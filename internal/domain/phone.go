@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizePhone returns raw in E.164 form ("+" and up to 15 digits),
+// dropping the spaces, dashes, dots and parentheses people type. ok is false
+// if the result is not a valid E.164 number.
+func NormalizePhone(raw string) (phone string, ok bool) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+	return phone, e164.MatchString(phone)
+}
+
+// MaskPhone hides all but the last two digits, for showing which number a
+// code was sent to.
+func MaskPhone(phone string) string {
+	if len(phone) < 4 {
+		return phone
+	}
+	return phone[:2] + strings.Repeat("*", len(phone)-4) + phone[len(phone)-2:]
+}
@@ -15,8 +15,13 @@ type RefreshToken struct {
 }
 
 type RefreshTokenRepository interface {
+	// Save fails with ErrConflict if tokenHash is already stored.
 	Save(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	// FindByHash fails with ErrNotFound for unknown hashes. Expired and
+	// revoked tokens are returned; callers check ExpiresAt and RevokedAt.
 	FindByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// RevokeByHash fails with ErrNotFound unless the token is stored and
+	// not revoked yet, so that of two concurrent rotations only one wins.
 	RevokeByHash(ctx context.Context, tokenHash string) error
 	RevokeAllByUser(ctx context.Context, userID string) error
 }
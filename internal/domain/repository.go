@@ -4,6 +4,29 @@ import "context"
 
 // UserRepository defines the interface for user persistence.
 type UserRepository interface {
+	// Create assigns user.ID. It fails with ErrConflict if the email is taken.
 	Create(ctx context.Context, user *User) error
+	// FindByEmail fails with ErrNotFound if no user has the email.
 	FindByEmail(ctx context.Context, email string) (*User, error)
+	// FindByID fails with ErrNotFound if no user has the ID.
+	FindByID(ctx context.Context, id string) (*User, error)
+	// FindByPhone fails with ErrNotFound if no user has the verified phone.
+	FindByPhone(ctx context.Context, phone string) (*User, error)
+	// UpdatePhone stores user.Phone, PhoneVerifiedAt, SMSSecondFactor and
+	// UpdatedAt. It fails with ErrNotFound if the user does not exist and
+	// with ErrConflict if another user has the phone.
+	UpdatePhone(ctx context.Context, user *User) error
+	// UpdateProfile stores user.Email, Locale and UpdatedAt. It fails with
+	// ErrNotFound if the user does not exist and with ErrConflict if another
+	// user has the email.
+	UpdateProfile(ctx context.Context, user *User) error
+	// UpdateStatus stores user.IsVerified, DisabledAt and UpdatedAt. It
+	// fails with ErrNotFound if the user does not exist.
+	UpdateStatus(ctx context.Context, user *User) error
+	// Search returns the users matching filter.
+	Search(ctx context.Context, filter UserFilter) ([]User, error)
+	// Delete removes the user; SQL backends cascade to the user's sessions,
+	// identities, memberships and tokens. It fails with ErrNotFound if the
+	// user does not exist.
+	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SCIMToken authenticates a tenant's identity provider to the SCIM API.
+// Only the hash of the bearer token is stored.
+type SCIMToken struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Description string    `json:"description,omitempty"`
+	TokenHash   string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SCIMTokenRepository interface {
+	// Create assigns token.ID. It fails with ErrConflict if the hash is
+	// already stored.
+	Create(ctx context.Context, token *SCIMToken) error
+	// FindByHash fails with ErrNotFound for unknown hashes.
+	FindByHash(ctx context.Context, tokenHash string) (*SCIMToken, error)
+	// List returns the tenant's tokens, oldest first.
+	List(ctx context.Context, tenantID string) ([]SCIMToken, error)
+	// Delete fails with ErrNotFound if there is no such token.
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ServiceAccount is a non-human principal owned by a tenant. It has no
+// password; it obtains access tokens with its credentials.
+type ServiceAccount struct {
+	ID          string `json:"id"`
+	TenantID    string `json:"tenant_id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Roles are IDs of roles in the tenant.
+	Roles      []string   `json:"roles"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type ServiceAccountCredentialType string
+
+const (
+	// CredentialAPIKey is a secret the account presents as client_secret.
+	// Only its hash is stored.
+	CredentialAPIKey ServiceAccountCredentialType = "api_key"
+	// CredentialPublicKey verifies JWT assertions the account signs with
+	// the matching private key (RFC 7523).
+	CredentialPublicKey ServiceAccountCredentialType = "public_key"
+)
+
+// ServiceAccountCredential is an API key or an assertion key of a service
+// account. Prefix and SecretHash are set for API keys, PublicKey (PEM) for
+// assertion keys, whose ID is the "kid" assertions may name.
+type ServiceAccountCredential struct {
+	ID               string                       `json:"id"`
+	ServiceAccountID string                       `json:"service_account_id"`
+	Type             ServiceAccountCredentialType `json:"type"`
+	Name             string                       `json:"name"`
+	Prefix           string                       `json:"prefix,omitempty"`
+	SecretHash       string                       `json:"-"`
+	PublicKey        string                       `json:"public_key,omitempty"`
+	ExpiresAt        *time.Time                   `json:"expires_at,omitempty"` // nil: never expires
+	LastUsedAt       *time.Time                   `json:"last_used_at,omitempty"`
+	CreatedAt        time.Time                    `json:"created_at"`
+}
+
+// Expired reports whether the credential is past its expiry at now.
+func (c *ServiceAccountCredential) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
+
+type ServiceAccountRepository interface {
+	// Create assigns account.ID. It fails with ErrConflict if the name is
+	// taken in the tenant, ignoring case.
+	Create(ctx context.Context, account *ServiceAccount) error
+	// Get fails with ErrNotFound for unknown IDs.
+	Get(ctx context.Context, id string) (*ServiceAccount, error)
+	// List returns the tenant's accounts, oldest first.
+	List(ctx context.Context, tenantID string) ([]ServiceAccount, error)
+	// Update stores Name, Description, Roles, DisabledAt and UpdatedAt. It
+	// fails with ErrNotFound for unknown IDs and with ErrConflict if the
+	// name is taken.
+	Update(ctx context.Context, account *ServiceAccount) error
+	// Delete removes the account along with its credentials. It fails with
+	// ErrNotFound for unknown IDs.
+	Delete(ctx context.Context, id string) error
+
+	// AddCredential assigns cred.ID and fails with ErrConflict if the
+	// secret hash is taken.
+	AddCredential(ctx context.Context, cred *ServiceAccountCredential) error
+	// ListCredentials returns the account's credentials, oldest first.
+	ListCredentials(ctx context.Context, accountID string) ([]ServiceAccountCredential, error)
+	// FindCredentialByHash finds an API key. It fails with ErrNotFound for
+	// unknown hashes; expired keys are returned.
+	FindCredentialByHash(ctx context.Context, secretHash string) (*ServiceAccountCredential, error)
+	// DeleteCredential fails with ErrNotFound unless the account has a
+	// credential with id.
+	DeleteCredential(ctx context.Context, accountID, id string) error
+	// TouchCredential sets LastUsedAt and fails with ErrNotFound for
+	// unknown IDs.
+	TouchCredential(ctx context.Context, id string, at time.Time) error
+}
@@ -6,12 +6,24 @@ import (
 
 // User represents a registered user in the system.
 type User struct {
-	ID         string    `json:"id"`
-	Email      string    `json:"email"`
-	Password   string    `json:"-"` // Never return password hash
-	IsVerified bool      `json:"is_verified"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         string `json:"id"`
+	Email      string `json:"email"`
+	Password   string `json:"-"` // Never return password hash
+	IsVerified bool   `json:"is_verified"`
+	// Locale is the user's preferred language for email and API messages;
+	// empty means negotiate per request.
+	Locale string `json:"locale,omitempty"`
+	// Phone is a verified E.164 number, or empty. It is only stored once the
+	// user proved they receive SMS on it.
+	Phone           string     `json:"phone,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
+	// SMSSecondFactor requires an SMS code after the password on login.
+	SMSSecondFactor bool `json:"sms_second_factor"`
+	// DisabledAt is set while an administrator has disabled the account;
+	// disabled users can neither log in nor refresh sessions.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
 // NewUser creates a new user instance with default values.
@@ -26,3 +38,28 @@ func NewUser(email, passwordHash string) *User {
 		UpdatedAt:  now,
 	}
 }
+
+// Disabled reports whether the account is disabled.
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// UserFilter selects users for UserRepository.Search. Results are ordered
+// newest first by CreatedAt, then ID; Before is the keyset cursor, the last
+// user of the previous page.
+type UserFilter struct {
+	// EmailPrefix matches the start of the email, ignoring case.
+	EmailPrefix string
+	// Verified, when set, selects users by IsVerified.
+	Verified    *bool
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Before      *UserCursor
+	Limit       int
+}
+
+// UserCursor is a position in the UserFilter ordering.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Both wrap ErrNotFound.
+var (
+	ErrSubscriptionNotFound = fmt.Errorf("webhook subscription %w", ErrNotFound)
+	ErrDeliveryNotFound     = fmt.Errorf("webhook delivery %w", ErrNotFound)
+)
+
+// WebhookSubscription delivers a tenant's events to URL, signed with Secret.
+// An empty EventTypes list subscribes to every event type.
+type WebhookSubscription struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Matches reports whether e should be delivered to s. A subscription
+// without a tenant receives the events of every tenant.
+func (s *WebhookSubscription) Matches(e *OutboxEvent) bool {
+	if !s.Active || (s.TenantID != "" && s.TenantID != e.TenantID) {
+		return false
+	}
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event bound for one subscription.
+type WebhookDelivery struct {
+	ID             string         `json:"id"`
+	SubscriptionID string         `json:"subscription_id"`
+	Event          OutboxEvent    `json:"event"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	LastError      string         `json:"last_error,omitempty"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	// Populated by ClaimDue for the dispatcher.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// DeliveryResult records the outcome of one attempt.
+type DeliveryResult struct {
+	Status      DeliveryStatus // DeliveryPending to retry at NextAttemptAt
+	StatusCode  int
+	Error       string
+	NextAttempt time.Time
+}
+
+type DeliveryFilter struct {
+	TenantID       string
+	SubscriptionID string
+	Status         DeliveryStatus
+	Limit          int
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *WebhookSubscription) error
+	ListSubscriptions(ctx context.Context, tenantID string) ([]WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+
+	// FanOut turns up to limit unprocessed outbox events into deliveries for
+	// every matching subscription and returns the number of events processed.
+	FanOut(ctx context.Context, limit int) (int, error)
+	// ClaimDue leases up to limit pending deliveries whose next attempt is due,
+	// pushing their next attempt lease into the future so that concurrent
+	// dispatchers skip them.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	Complete(ctx context.Context, id string, result DeliveryResult) error
+
+	ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]WebhookDelivery, error)
+	// Replay resets a delivery to pending with a fresh attempt budget.
+	Replay(ctx context.Context, id string) error
+}
@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RenderEmail renders the subject and plain-text body of email template id
+// in locale, falling back to Default for missing entries.
+func RenderEmail(locale, id string, data any) (subject, body string, err error) {
+	subject, ok := Lookup(locale, "email."+id+".subject")
+	if !ok {
+		return "", "", fmt.Errorf("i18n: unknown email template %q", id)
+	}
+	src, ok := Lookup(locale, "email."+id+".body")
+	if !ok {
+		return "", "", fmt.Errorf("i18n: email template %q has no body", id)
+	}
+	body, err = execute("email", id, src, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// RenderSMS renders text message template id in locale, falling back to
+// Default for missing entries.
+func RenderSMS(locale, id string, data any) (string, error) {
+	src, ok := Lookup(locale, "sms."+id)
+	if !ok {
+		return "", fmt.Errorf("i18n: unknown sms template %q", id)
+	}
+	return execute("sms", id, src, data)
+}
+
+func execute(kind, id, src string, data any) (string, error) {
+	tmpl, err := template.New(id).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("i18n: parse %s template %q: %w", kind, id, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("i18n: render %s template %q: %w", kind, id, err)
+	}
+	return b.String(), nil
+}
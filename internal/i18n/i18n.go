@@ -0,0 +1,140 @@
+// Package i18n holds the message catalogs for API errors and outgoing email
+// and negotiates which locale a request or user is served in.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the locale used when nothing better can be negotiated. Its
+// catalog is complete; other catalogs fall back to it key by key.
+const Default = "en"
+
+// Email template IDs. Each has "email.<id>.subject" and "email.<id>.body"
+// entries in every catalog; bodies are text/template sources.
+const (
+	EmailVerify        = "verify_email"
+	EmailPasswordReset = "password_reset"
+	EmailMagicLink     = "magic_link"
+)
+
+// SMS template IDs, one "sms.<id>" entry each. They match the OTP purposes
+// in package domain.
+const (
+	SMSVerifyPhone  = "verify_phone"
+	SMSPhoneLogin   = "phone_login"
+	SMSSecondFactor = "second_factor"
+)
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+var catalogs = mustLoad()
+
+func mustLoad() map[string]map[string]string {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]map[string]string, len(entries))
+	for _, e := range entries {
+		raw, err := localeFS.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(raw, &msgs); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", e.Name(), err))
+		}
+		out[strings.TrimSuffix(e.Name(), ".json")] = msgs
+	}
+	if _, ok := out[Default]; !ok {
+		panic("i18n: no catalog for default locale " + Default)
+	}
+	return out
+}
+
+// Locales lists the locales a catalog ships for, sorted.
+func Locales() []string {
+	out := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Supported reports whether locale has a catalog of its own.
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Lookup returns the message for key in locale, falling back to Default.
+func Lookup(locale, key string) (string, bool) {
+	if msg, ok := catalogs[locale][key]; ok {
+		return msg, true
+	}
+	msg, ok := catalogs[Default][key]
+	return msg, ok
+}
+
+// Message returns the message for key in locale with every "{param}"
+// placeholder replaced by param, or fallback when no catalog has the key.
+func Message(locale, key, param, fallback string) string {
+	msg, ok := Lookup(locale, key)
+	if !ok {
+		return fallback
+	}
+	return strings.ReplaceAll(msg, "{param}", param)
+}
+
+// Negotiate picks the locale to serve. A supported preferred locale (for
+// example one stored on the user's profile) wins; otherwise the highest
+// weighted Accept-Language entry that matches a catalog, by full tag or by
+// primary subtag, is used; otherwise Default.
+func Negotiate(acceptLanguage, preferred string) string {
+	if l := normalize(preferred); Supported(l) {
+		return l
+	}
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q <= bestQ {
+			continue
+		}
+		if l := match(tag); l != "" {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+func match(tag string) string {
+	tag = normalize(tag)
+	if Supported(tag) {
+		return tag
+	}
+	if primary, _, ok := strings.Cut(tag, "-"); ok && Supported(primary) {
+		return primary
+	}
+	return ""
+}
+
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
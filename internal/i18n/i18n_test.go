@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for _, l := range Locales() {
+		for key := range catalogs[Default] {
+			if _, ok := catalogs[l][key]; !ok {
+				t.Errorf("%s: missing %q", l, key)
+			}
+		}
+		for key := range catalogs[l] {
+			if _, ok := catalogs[Default][key]; !ok {
+				t.Errorf("%s: %q is not in the %s catalog", l, key, Default)
+			}
+		}
+	}
+	if !Supported("ru") {
+		t.Fatal("ru catalog missing")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		header, preferred, want string
+	}{
+		{"", "", "en"},
+		{"ru-RU,ru;q=0.9,en;q=0.8", "", "ru"},
+		{"de-DE,de;q=0.9,en;q=0.5", "", "en"},
+		{"en;q=0.4, ru;q=0.7", "", "ru"},
+		{"ru;q=0", "", "en"},
+		{"*", "", "en"},
+		{"en", "ru", "ru"},
+		{"ru", "fr", "ru"},
+		{"RU_ru", "", "ru"},
+	}
+	for _, c := range cases {
+		if got := Negotiate(c.header, c.preferred); got != c.want {
+			t.Errorf("Negotiate(%q, %q) = %q, want %q", c.header, c.preferred, got, c.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message("ru", "field.min", "8", ""); got != "должно содержать не менее 8 символов" {
+		t.Fatalf("ru field.min = %q", got)
+	}
+	if got := Message("fr", "NOT_FOUND", "", ""); got != "Not found" {
+		t.Fatalf("unsupported locale should fall back to en, got %q", got)
+	}
+	if got := Message("ru", "NO_SUCH_KEY", "", "fallback"); got != "fallback" {
+		t.Fatalf("missing key = %q", got)
+	}
+}
+
+func TestRenderEmail(t *testing.T) {
+	data := map[string]any{"Link": "https://example.com/v?t=1", "ExpiresIn": "24h", "Minutes": 15, "Email": "a@ex.com"}
+	for _, l := range Locales() {
+		for _, id := range []string{EmailVerify, EmailPasswordReset, EmailMagicLink} {
+			subject, body, err := RenderEmail(l, id, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", l, id, err)
+			}
+			if subject == "" || !strings.Contains(body, "https://example.com/v?t=1") {
+				t.Fatalf("%s/%s: subject %q body %q", l, id, subject, body)
+			}
+		}
+	}
+	if _, _, err := RenderEmail("en", EmailVerify, map[string]any{}); err == nil {
+		t.Fatal("missing template data accepted")
+	}
+	if _, _, err := RenderEmail("en", "nope", nil); err == nil {
+		t.Fatal("unknown template accepted")
+	}
+}
+
+func TestRenderSMS(t *testing.T) {
+	data := map[string]any{"Code": "123456", "Minutes": 5}
+	for _, l := range Locales() {
+		for _, id := range []string{SMSVerifyPhone, SMSPhoneLogin, SMSSecondFactor} {
+			text, err := RenderSMS(l, id, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", l, id, err)
+			}
+			if !strings.HasPrefix(text, "123456") || len([]rune(text)) > 160 {
+				t.Fatalf("%s/%s: %q does not fit one SMS", l, id, text)
+			}
+		}
+	}
+	if _, err := RenderSMS("en", "nope", data); err == nil {
+		t.Fatal("unknown template accepted")
+	}
+}
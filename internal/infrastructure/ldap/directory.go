@@ -0,0 +1,151 @@
+// Package ldap is an app.Directory backed by an LDAP server or Active
+// Directory. It finds the user's entry with a service account search and
+// verifies the password by binding as that entry.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// Config describes one directory.
+type Config struct {
+	// URL is ldap://host:389 or ldaps://host:636.
+	URL                string `json:"url"`
+	StartTLS           bool   `json:"start_tls"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	// BindDN and BindPassword are the service account that searches for
+	// users; leave both empty for an anonymous search.
+	BindDN       string `json:"bind_dn"`
+	BindPassword string `json:"bind_password"`
+	BaseDN       string `json:"base_dn"`
+	// Filter selects the user's entry. {login} is replaced by the login as
+	// typed and {username} by its part before "@", both escaped. Default
+	// "(mail={login})"; Active Directory typically uses
+	// "(&(objectClass=user)(sAMAccountName={username}))".
+	Filter     string     `json:"filter"`
+	Attributes Attributes `json:"attributes"`
+}
+
+// Attributes maps directory attributes onto user fields.
+type Attributes struct {
+	Email  string `json:"email"`  // default "mail"
+	Locale string `json:"locale"` // optional, e.g. "preferredLanguage"
+}
+
+// Directory implements app.Directory. It opens a connection per login.
+type Directory struct {
+	cfg     Config
+	timeout time.Duration
+}
+
+func NewDirectory(cfg Config) *Directory {
+	if cfg.Filter == "" {
+		cfg.Filter = "(mail={login})"
+	}
+	if cfg.Attributes.Email == "" {
+		cfg.Attributes.Email = "mail"
+	}
+	return &Directory{cfg: cfg, timeout: 10 * time.Second}
+}
+
+func (d *Directory) Bind(ctx context.Context, login, password string) (*app.DirectoryEntry, error) {
+	// An empty password would be an unauthenticated bind, which servers
+	// accept for any DN.
+	if login == "" || password == "" {
+		return nil, &app.CredentialsError{Reason: "empty_credentials"}
+	}
+	conn, err := d.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service account bind: %w", unavailable(err))
+		}
+	}
+	attrs := []string{d.cfg.Attributes.Email}
+	if d.cfg.Attributes.Locale != "" {
+		attrs = append(attrs, d.cfg.Attributes.Locale)
+	}
+	res, err := conn.Search(goldap.NewSearchRequest(
+		d.cfg.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, 0, false,
+		d.filter(login), attrs, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap: search: %w", unavailable(err))
+	}
+	switch len(res.Entries) {
+	case 0:
+		return nil, &app.CredentialsError{Reason: "unknown_email"}
+	case 1:
+	default:
+		return nil, &app.CredentialsError{Reason: "ambiguous_entry"}
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, &app.CredentialsError{Reason: "invalid_password"}
+		}
+		return nil, fmt.Errorf("ldap: user bind: %w", unavailable(err))
+	}
+	out := &app.DirectoryEntry{DN: entry.DN, Email: entry.GetAttributeValue(d.cfg.Attributes.Email)}
+	if d.cfg.Attributes.Locale != "" {
+		out.Locale = entry.GetAttributeValue(d.cfg.Attributes.Locale)
+	}
+	return out, nil
+}
+
+func (d *Directory) dial(ctx context.Context) (*goldap.Conn, error) {
+	timeout := d.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	u, err := url.Parse(d.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: url: %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: d.cfg.InsecureSkipVerify}
+	conn, err := goldap.DialURL(d.cfg.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		goldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial: %w", unavailable(err))
+	}
+	conn.SetTimeout(timeout)
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: start tls: %w", unavailable(err))
+		}
+	}
+	return conn, nil
+}
+
+func (d *Directory) filter(login string) string {
+	username, _, _ := strings.Cut(login, "@")
+	return strings.NewReplacer(
+		"{login}", goldap.EscapeFilter(login),
+		"{username}", goldap.EscapeFilter(username),
+	).Replace(d.cfg.Filter)
+}
+
+// unavailable marks a directory failure as domain.ErrUnavailable, so login
+// answers 503 instead of 500.
+func unavailable(err error) error {
+	return fmt.Errorf("%w: %v", domain.ErrUnavailable, err)
+}
@@ -0,0 +1,89 @@
+package ldap_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/ldap"
+	"go-auth/internal/infrastructure/ldap/ldaptest"
+)
+
+const base = "ou=people,dc=example,dc=com"
+
+func newServer(t *testing.T) *ldaptest.Server {
+	return ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=svc,dc=example,dc=com", Password: "svc-secret"},
+		ldaptest.Entry{DN: "uid=alice," + base, Password: "alice-pw", Attributes: map[string][]string{
+			"objectClass":       {"inetOrgPerson"},
+			"uid":               {"alice"},
+			"mail":              {"Alice@Example.com"},
+			"preferredLanguage": {"ru"},
+		}},
+		ldaptest.Entry{DN: "uid=bob,ou=other,dc=example,dc=com", Password: "bob-pw", Attributes: map[string][]string{
+			"uid":  {"bob"},
+			"mail": {"bob@example.com"},
+		}},
+	)
+}
+
+func TestDirectory_Bind(t *testing.T) {
+	srv := newServer(t)
+	dir := ldap.NewDirectory(ldap.Config{
+		URL:          srv.URL,
+		BindDN:       "cn=svc,dc=example,dc=com",
+		BindPassword: "svc-secret",
+		BaseDN:       base,
+		Filter:       "(&(objectClass=inetOrgPerson)(uid={username}))",
+		Attributes:   ldap.Attributes{Locale: "preferredLanguage"},
+	})
+	ctx := context.Background()
+
+	entry, err := dir.Bind(ctx, "alice@example.com", "alice-pw")
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if entry.DN != "uid=alice,"+base || entry.Email != "Alice@Example.com" || entry.Locale != "ru" {
+		t.Fatalf("entry = %+v", entry)
+	}
+
+	for _, tc := range []struct {
+		login, password, reason string
+	}{
+		{"alice@example.com", "wrong", "invalid_password"},
+		{"alice@example.com", "", "empty_credentials"},
+		{"bob@example.com", "bob-pw", "unknown_email"}, // outside the base DN
+		{"*", "alice-pw", "unknown_email"},             // escaped, not a wildcard
+	} {
+		_, err := dir.Bind(ctx, tc.login, tc.password)
+		var ce *app.CredentialsError
+		if !errors.As(err, &ce) || ce.Reason != tc.reason {
+			t.Errorf("Bind(%q, %q) = %v, want reason %s", tc.login, tc.password, err, tc.reason)
+		}
+	}
+}
+
+func TestDirectory_ServiceAccountAndOutage(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	wrongSvc := ldap.NewDirectory(ldap.Config{URL: srv.URL, BindDN: "cn=svc,dc=example,dc=com", BindPassword: "nope", BaseDN: base})
+	if _, err := wrongSvc.Bind(ctx, "alice@example.com", "alice-pw"); !errors.Is(err, domain.ErrUnavailable) {
+		t.Fatalf("bad service account: %v", err)
+	}
+	anonymous := ldap.NewDirectory(ldap.Config{URL: srv.URL, BaseDN: base})
+	if _, err := anonymous.Bind(ctx, "alice@example.com", "alice-pw"); !errors.Is(err, domain.ErrUnavailable) {
+		t.Fatalf("anonymous search on a server that forbids it: %v", err)
+	}
+	srv.AllowAnonymousSearch = true
+	if _, err := anonymous.Bind(ctx, "alice@example.com", "alice-pw"); err != nil {
+		t.Fatalf("anonymous search: %v", err)
+	}
+
+	down := ldap.NewDirectory(ldap.Config{URL: "ldap://127.0.0.1:1", BaseDN: base})
+	if _, err := down.Bind(ctx, "alice@example.com", "alice-pw"); !errors.Is(err, domain.ErrUnavailable) {
+		t.Fatalf("unreachable server: %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Package ldaptest runs an in-process LDAP server for tests. It speaks the
+// subset of LDAPv3 a login needs: simple bind, subtree search with
+// and/or/not/equality/presence filters, and unbind.
+package ldaptest
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP protocol operations, tagged [APPLICATION n].
+const (
+	appBindRequest       ber.Tag = 0
+	appBindResponse      ber.Tag = 1
+	appUnbindRequest     ber.Tag = 2
+	appSearchRequest     ber.Tag = 3
+	appSearchResultEntry ber.Tag = 4
+	appSearchResultDone  ber.Tag = 5
+)
+
+// LDAP result codes used by the server.
+const (
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+)
+
+// Entry is a directory object. Password is the one simple bind accepts
+// for DN; entries without one cannot bind.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a fake LDAP server listening on a local TCP port.
+type Server struct {
+	// URL is ldap://127.0.0.1:<port>.
+	URL string
+	// AllowAnonymousSearch lets unbound connections search.
+	AllowAnonymousSearch bool
+
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries []Entry
+	binds   []string
+}
+
+// NewServer starts a server holding entries that is closed when the test
+// ends.
+func NewServer(t testing.TB, entries ...Entry) *Server {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ldaptest: listen: %v", err)
+	}
+	s := &Server{URL: "ldap://" + l.Addr().String(), listener: l, entries: entries}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+// Add stores another entry.
+func (s *Server) Add(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+}
+
+// Binds returns the DNs of successful binds, oldest first.
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case appBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess
+			write(conn, response(id, appBindResponse, code))
+		case appUnbindRequest:
+			return
+		case appSearchRequest:
+			if !bound && !s.AllowAnonymousSearch {
+				write(conn, response(id, appSearchResultDone, resultInsufficientAccess))
+				continue
+			}
+			for _, e := range s.search(op) {
+				write(conn, searchEntry(id, e))
+			}
+			write(conn, response(id, appSearchResultDone, resultSuccess))
+		default:
+			// ExtendedRequest (StartTLS) and anything else.
+			write(conn, response(id, op.Tag+1, resultProtocolError))
+		}
+	}
+}
+
+// bind checks a simple BindRequest: version, name, [0] password.
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 || op.Children[2].Tag != 0 {
+		return resultProtocolError
+	}
+	dn, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+	if password == "" {
+		return resultInvalidCredentials
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if strings.EqualFold(e.DN, dn) && e.Password != "" && e.Password == password {
+			s.binds = append(s.binds, e.DN)
+			return resultSuccess
+		}
+	}
+	return resultInvalidCredentials
+}
+
+// search runs a SearchRequest: base, scope, deref, size limit, time limit,
+// types only, filter, attributes. Every scope is treated as a subtree.
+func (s *Server) search(op *ber.Packet) []Entry {
+	if len(op.Children) < 8 {
+		return nil
+	}
+	base, _ := op.Children[0].Value.(string)
+	limit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	var wanted []string
+	for _, a := range op.Children[7].Children {
+		if name, ok := a.Value.(string); ok {
+			wanted = append(wanted, name)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Entry
+	for _, e := range s.entries {
+		if !underBase(e.DN, base) || !match(filter, e) {
+			continue
+		}
+		out = append(out, project(e, wanted))
+		if limit > 0 && int64(len(out)) >= limit {
+			break
+		}
+	}
+	return out
+}
+
+func underBase(dn, base string) bool {
+	dn, base = strings.ToLower(dn), strings.ToLower(base)
+	return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+// match evaluates the filter choices login filters use.
+func match(f *ber.Packet, e Entry) bool {
+	switch f.Tag {
+	case 0: // and
+		for _, c := range f.Children {
+			if !match(c, e) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, c := range f.Children {
+			if match(c, e) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return len(f.Children) == 1 && !match(f.Children[0], e)
+	case 3: // equalityMatch
+		if len(f.Children) != 2 {
+			return false
+		}
+		for _, v := range values(e, f.Children[0].Data.String()) {
+			if strings.EqualFold(v, f.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case 7: // present
+		return len(values(e, f.Data.String())) > 0
+	}
+	return false
+}
+
+func values(e Entry, attr string) []string {
+	if strings.EqualFold(attr, "objectClass") && len(e.Attributes["objectClass"]) == 0 {
+		return []string{"top"}
+	}
+	for name, vals := range e.Attributes {
+		if strings.EqualFold(name, attr) {
+			return vals
+		}
+	}
+	return nil
+}
+
+func project(e Entry, wanted []string) Entry {
+	if len(wanted) == 0 {
+		return e
+	}
+	out := Entry{DN: e.DN, Attributes: make(map[string][]string)}
+	for _, name := range wanted {
+		if vals := values(e, name); len(vals) > 0 {
+			out.Attributes[name] = vals
+		}
+	}
+	return out
+}
+
+func envelope(id int64) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	return p
+}
+
+func response(id int64, tag ber.Tag, code int) *ber.Packet {
+	p := envelope(id)
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	p.AppendChild(op)
+	return p
+}
+
+func searchEntry(id int64, e Entry) *ber.Packet {
+	p := envelope(id)
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, appSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, vals := range e.Attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range vals {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	p.AppendChild(op)
+	return p
+}
+
+func write(w io.Writer, p *ber.Packet) {
+	_, _ = w.Write(p.Bytes())
+}
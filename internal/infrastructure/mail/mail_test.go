@@ -0,0 +1,277 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+var testEmail = app.Email{
+	From:    "Go Auth <no-reply@example.com>",
+	To:      "user@example.com",
+	Subject: "Подтвердите адрес",
+	Text:    "Здравствуйте!\n\nhttps://example.com/v?t=1\n",
+	HTML:    "<p>Здравствуйте!</p>",
+}
+
+func TestBuild_MultipartAlternative(t *testing.T) {
+	raw, err := Build(testEmail, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != testEmail.Subject {
+		t.Fatalf("subject %q %v", subject, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q %v", mediaType, err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(quotedprintable.NewReader(p))
+		bodies = append(bodies, p.Header.Get("Content-Type")+"|"+string(b))
+	}
+	if len(bodies) != 2 || !strings.HasPrefix(bodies[0], "text/plain") || !strings.Contains(bodies[0], "https://example.com/v?t=1") ||
+		!strings.HasPrefix(bodies[1], "text/html") || !strings.Contains(bodies[1], "<p>Здравствуйте!</p>") {
+		t.Fatalf("parts: %q", bodies)
+	}
+
+	plain := testEmail
+	plain.HTML = ""
+	raw, _ = Build(plain, time.Now())
+	if msg, _ := mail.ReadMessage(strings.NewReader(string(raw))); !strings.HasPrefix(msg.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("text-only content type %q", msg.Header.Get("Content-Type"))
+	}
+
+	if _, err := Build(app.Email{From: "nobody", To: "user@example.com"}, time.Now()); err == nil {
+		t.Fatal("invalid from accepted")
+	}
+}
+
+func TestMaildir_WritesIntoNew(t *testing.T) {
+	dir := t.TempDir()
+	md, err := NewMaildir(dir, "no-reply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := testEmail
+	e.From = ""
+	if err := md.Send(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "new", "*"))
+	tmp, _ := filepath.Glob(filepath.Join(dir, "tmp", "*"))
+	if len(files) != 1 || len(tmp) != 0 {
+		t.Fatalf("new=%v tmp=%v", files, tmp)
+	}
+	raw, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(raw), "From: <no-reply@example.com>") {
+		t.Fatalf("default sender not applied:\n%s", raw)
+	}
+}
+
+// fakeSMTP is a minimal SMTP server: it records the envelope and data of
+// each message and optionally advertises STARTTLS.
+type fakeSMTP struct {
+	addr     string
+	startTLS bool
+
+	mu   sync.Mutex
+	rcpt []string
+	data []string
+}
+
+func startFakeSMTP(t *testing.T, startTLS bool) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeSMTP{addr: ln.Addr().String(), startTLS: startTLS}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			if s.startTLS {
+				reply("250-fake")
+				reply("250 STARTTLS")
+			} else {
+				reply("250 fake")
+			}
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, strings.TrimSpace(line[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = append(s.data, b.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	srv := startFakeSMTP(t, false)
+	host, port, _ := net.SplitHostPort(srv.addr)
+	m := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "no-reply@example.com", Timeout: 5 * time.Second})
+	if err := m.Send(context.Background(), testEmail); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.rcpt) != 1 || srv.rcpt[0] != "<user@example.com>" || len(srv.data) != 1 || !strings.Contains(srv.data[0], "multipart/alternative") {
+		t.Fatalf("rcpt=%v data=%q", srv.rcpt, srv.data)
+	}
+}
+
+func TestSMTPMailer_RequiresStartTLS(t *testing.T) {
+	srv := startFakeSMTP(t, false)
+	host, port, _ := net.SplitHostPort(srv.addr)
+	m := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "no-reply@example.com", StartTLS: true, Timeout: 5 * time.Second})
+	if err := m.Send(context.Background(), testEmail); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("plain-text server accepted with StartTLS required: %v", err)
+	}
+	m = NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "no-reply@example.com", Username: "u", Password: "p", Timeout: 5 * time.Second})
+	if err := m.Send(context.Background(), testEmail); err == nil {
+		t.Fatal("credentials offered to a server without AUTH")
+	}
+}
+
+type flakyMailer struct {
+	failures int32
+	calls    atomic.Int32
+	sent     chan app.Email
+}
+
+func (f *flakyMailer) Send(_ context.Context, e app.Email) error {
+	if f.calls.Add(1) <= f.failures {
+		return errors.New("421 try again later")
+	}
+	f.sent <- e
+	return nil
+}
+
+func TestQueue_RetriesAndDrains(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := &flakyMailer{failures: 2, sent: make(chan app.Email, 1)}
+	q := NewQueue(next, log, QueueOptions{Workers: 1, Backoff: time.Millisecond})
+	if err := q.Send(context.Background(), testEmail); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-next.sent:
+		if e.To != testEmail.To {
+			t.Fatalf("delivered %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+	}
+	if n := next.calls.Load(); n != 3 {
+		t.Fatalf("%d attempts, want 3", n)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Send(context.Background(), testEmail); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("send after close: %v", err)
+	}
+}
+
+func TestQueue_FullDoesNotBlock(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	block := make(chan struct{})
+	q := NewQueue(blockingMailer(block), log, QueueOptions{Size: 1, Workers: 1})
+	defer func() {
+		close(block)
+		_ = q.Close(context.Background())
+	}()
+
+	var full bool
+	for i := 0; i < 3; i++ {
+		if err := q.Send(context.Background(), testEmail); errors.Is(err, ErrQueueFull) {
+			full = true
+		}
+	}
+	if !full {
+		t.Fatal("queue never reported full")
+	}
+}
+
+type blockingMailer chan struct{}
+
+func (b blockingMailer) Send(context.Context, app.Email) error {
+	<-b
+	return nil
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+// Maildir drops every message into a Maildir (tmp/, new/, cur/) for local
+// development; any mail client or `cat` can read it.
+type Maildir struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+// NewMaildir creates the Maildir layout under dir if needed.
+func NewMaildir(dir, from string) (*Maildir, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("maildir: %w", err)
+		}
+	}
+	return &Maildir{dir: dir, from: from, now: time.Now}, nil
+}
+
+// Send writes to tmp/ and renames into new/, so readers never see a
+// partial message.
+func (m *Maildir) Send(_ context.Context, email app.Email) error {
+	if email.From == "" {
+		email.From = m.from
+	}
+	now := m.now()
+	msg, err := Build(email, now)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	host, _ := os.Hostname()
+	name := strconv.FormatInt(now.UnixNano(), 10) + "." + hex.EncodeToString(b) + "." + host
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, msg, 0o600); err != nil {
+		return fmt.Errorf("maildir: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(m.dir, "new", name)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("maildir: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+var (
+	ErrQueueFull   = errors.New("mail: send queue is full")
+	ErrQueueClosed = errors.New("mail: send queue is closed")
+)
+
+// QueueOptions tunes Queue. Zero values pick the defaults noted per field.
+type QueueOptions struct {
+	Size        int           // buffered messages, default 256
+	Workers     int           // concurrent senders, default 2
+	MaxAttempts int           // per message, default 5
+	Backoff     time.Duration // first retry delay, doubled per attempt, default 2s
+	Timeout     time.Duration // per attempt, default 30s
+}
+
+// Queue is an app.Mailer that accepts messages immediately and delivers
+// them from background workers, retrying transient failures with
+// exponential backoff. Messages that exhaust their attempts are logged and
+// dropped.
+type Queue struct {
+	next app.Mailer
+	log  *slog.Logger
+	opts QueueOptions
+
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan app.Email
+	stop   chan struct{}
+	halt   sync.Once
+	wg     sync.WaitGroup
+}
+
+func NewQueue(next app.Mailer, log *slog.Logger, opts QueueOptions) *Queue {
+	if opts.Size <= 0 {
+		opts.Size = 256
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 2 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	q := &Queue{
+		next: next,
+		log:  log,
+		opts: opts,
+		jobs: make(chan app.Email, opts.Size),
+		stop: make(chan struct{}),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Send enqueues email without waiting for delivery.
+func (q *Queue) Send(_ context.Context, email app.Email) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- email:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits until queued ones have been
+// attempted or ctx expires; pending retries are abandoned on expiry.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.halt.Do(func() { close(q.stop) })
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for email := range q.jobs {
+		q.deliver(email)
+	}
+}
+
+func (q *Queue) deliver(email app.Email) {
+	delay := q.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), q.opts.Timeout)
+		err := q.next.Send(ctx, email)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= q.opts.MaxAttempts {
+			q.log.Error("mail delivery failed, dropping message", "to", email.To, "subject", email.Subject, "attempts", attempt, "error", err)
+			return
+		}
+		q.log.Warn("mail delivery failed, retrying", "to", email.To, "attempt", attempt, "retry_in", delay, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-q.stop:
+			q.log.Error("mail queue closed before delivery", "to", email.To, "subject", email.Subject)
+			return
+		}
+		delay *= 2
+	}
+}
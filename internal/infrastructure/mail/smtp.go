@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+// SMTPConfig configures SMTPMailer. With StartTLS set the connection is
+// upgraded before authenticating and servers that cannot do so are refused;
+// credentials are never sent in clear text to a remote host.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	StartTLS bool
+	Timeout  time.Duration
+	// TLSConfig overrides the default verification settings (tests).
+	TLSConfig *tls.Config
+}
+
+// SMTPMailer sends each message over a new SMTP connection.
+type SMTPMailer struct {
+	cfg SMTPConfig
+	now func() time.Time
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTPMailer{cfg: cfg, now: time.Now}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, email app.Email) error {
+	if email.From == "" {
+		email.From = m.cfg.From
+	}
+	msg, err := Build(email, m.now())
+	if err != nil {
+		return err
+	}
+	from, _ := mail.ParseAddress(email.From)
+	to, _ := mail.ParseAddress(email.To)
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(m.cfg.Host, m.cfg.Port))
+	if err != nil {
+		return fmt.Errorf("smtp: dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp: greeting: %w", err)
+	}
+	defer c.Close()
+
+	if m.cfg.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server does not support STARTTLS")
+		}
+		tlsCfg := m.cfg.TLSConfig
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{ServerName: m.cfg.Host, MinVersion: tls.VersionTLS12}
+		}
+		if err := c.StartTLS(tlsCfg); err != nil {
+			return fmt.Errorf("smtp: starttls: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server does not support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("smtp: auth: %w", err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp: mail from: %w", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp: rcpt to: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp: write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: end data: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type attemptWindow struct {
+	count   int
+	expires time.Time
+}
+
+// AttemptCounter is an in-memory implementation of app.AttemptCounter.
+type AttemptCounter struct {
+	mu      sync.Mutex
+	windows map[string]attemptWindow
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewAttemptCounter creates a counter and starts a janitor that drops expired
+// windows every sweepEvery. Call Close to stop it.
+func NewAttemptCounter(sweepEvery time.Duration) *AttemptCounter {
+	c := &AttemptCounter{
+		windows: make(map[string]attemptWindow),
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+	if sweepEvery > 0 {
+		go c.janitor(sweepEvery)
+	}
+	return c
+}
+
+func (c *AttemptCounter) Incr(_ context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.windows[key]
+	if !ok || !w.expires.After(now) {
+		w = attemptWindow{expires: now.Add(window)}
+	}
+	w.count++
+	c.windows[key] = w
+	return w.count, w.expires.Sub(now), nil
+}
+
+func (c *AttemptCounter) Get(_ context.Context, key string) (int, time.Duration, error) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.windows[key]
+	if !ok || !w.expires.After(now) {
+		return 0, 0, nil
+	}
+	return w.count, w.expires.Sub(now), nil
+}
+
+func (c *AttemptCounter) Reset(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.windows, key)
+	return nil
+}
+
+// Close stops the background janitor.
+func (c *AttemptCounter) Close() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+func (c *AttemptCounter) janitor(every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+			now := c.now()
+			c.mu.Lock()
+			for k, w := range c.windows {
+				if !w.expires.After(now) {
+					delete(c.windows, k)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
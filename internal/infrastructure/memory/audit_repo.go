@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// AuditRepository is an in-memory implementation of domain.AuditRepository.
+type AuditRepository struct {
+	mu     sync.RWMutex
+	events []domain.AuditEvent
+}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+func (r *AuditRepository) Append(_ context.Context, e *domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev := ""
+	if n := len(r.events); n > 0 {
+		prev = r.events[n-1].Hash
+	}
+	e.ID = uuid.NewString()
+	e.Seq = int64(len(r.events) + 1)
+	e.PrevHash = prev
+	e.Hash = e.ChainHash(prev)
+	r.events = append(r.events, *e)
+	return nil
+}
+
+func (r *AuditRepository) List(_ context.Context, f domain.AuditFilter) ([]domain.AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.AuditEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		e := r.events[i]
+		if f.BeforeSeq > 0 && e.Seq >= f.BeforeSeq {
+			continue
+		}
+		if !matchAudit(e, f) {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func matchAudit(e domain.AuditEvent, f domain.AuditFilter) bool {
+	switch {
+	case f.Type != "" && e.Type != f.Type,
+		f.ActorID != "" && e.ActorID != f.ActorID,
+		f.TargetID != "" && e.TargetID != f.TargetID,
+		f.TenantID != "" && e.TenantID != f.TenantID,
+		!f.From.IsZero() && e.OccurredAt.Before(f.From),
+		!f.To.IsZero() && !e.OccurredAt.Before(f.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+func TestAuditRepository_ChainAndFilter(t *testing.T) {
+	ctx := context.Background()
+	r := NewAuditRepository()
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, typ := range []domain.AuditEventType{domain.AuditUserRegistered, domain.AuditLoginFailed, domain.AuditLoginSucceeded} {
+		e := &domain.AuditEvent{Type: typ, ActorID: "u1", OccurredAt: at.Add(time.Duration(i) * time.Minute)}
+		if err := r.Append(ctx, e); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	all, _ := r.List(ctx, domain.AuditFilter{})
+	if len(all) != 3 || all[0].Type != domain.AuditLoginSucceeded {
+		t.Fatalf("expected newest first, got %+v", all)
+	}
+	chain := []domain.AuditEvent{all[2], all[1], all[0]}
+	if i := domain.VerifyAuditChain("", chain); i != -1 {
+		t.Fatalf("chain broken at %d", i)
+	}
+	chain[1].ActorID = "someone-else"
+	if i := domain.VerifyAuditChain("", chain); i != 1 {
+		t.Fatalf("tampering not detected, got %d", i)
+	}
+
+	page, _ := r.List(ctx, domain.AuditFilter{Limit: 2})
+	next, _ := r.List(ctx, domain.AuditFilter{Limit: 2, BeforeSeq: page[1].Seq})
+	if len(page) != 2 || len(next) != 1 || next[0].Type != domain.AuditUserRegistered {
+		t.Fatalf("pagination: page=%d next=%+v", len(page), next)
+	}
+
+	failed, _ := r.List(ctx, domain.AuditFilter{Type: domain.AuditLoginFailed})
+	if len(failed) != 1 {
+		t.Fatalf("type filter: %+v", failed)
+	}
+	ranged, _ := r.List(ctx, domain.AuditFilter{From: at.Add(time.Minute), To: at.Add(2 * time.Minute)})
+	if len(ranged) != 1 || ranged[0].Type != domain.AuditLoginFailed {
+		t.Fatalf("time filter: %+v", ranged)
+	}
+}
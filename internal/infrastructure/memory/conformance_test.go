@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"testing"
+
+	"go-auth/internal/infrastructure/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(*testing.T) repotest.Store {
+		webhooks := NewWebhookRepository()
+		memberships, roles := NewTenantRepositories()
+		return repotest.Store{
+			Tx:              NewTxManager(),
+			Users:           NewUserRepository(),
+			RefreshTokens:   NewRefreshRepository(),
+			Audit:           NewAuditRepository(),
+			Outbox:          webhooks,
+			Webhooks:        webhooks,
+			MagicLinks:      NewMagicLinkRepository(),
+			OTPs:            NewOTPRepository(),
+			Identities:      NewIdentityRepository(),
+			FederatedLogins: NewFederatedLoginRepository(),
+			Memberships:     memberships,
+			Roles:           roles,
+			SCIMTokens:      NewSCIMTokenRepository(),
+			PersonalTokens:  NewPersonalAccessTokenRepository(),
+			ServiceAccounts: NewServiceAccountRepository(),
+			Impersonations:  NewImpersonationRepository(),
+		}
+	})
+}
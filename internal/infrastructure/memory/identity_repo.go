@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// IdentityRepository is an in-memory implementation of
+// domain.IdentityRepository.
+type IdentityRepository struct {
+	mu         sync.RWMutex
+	identities map[string]*domain.Identity // key: provider + "\x00" + subject
+}
+
+func NewIdentityRepository() *IdentityRepository {
+	return &IdentityRepository{identities: make(map[string]*domain.Identity)}
+}
+
+func identityKey(provider, subject string) string { return provider + "\x00" + subject }
+
+func (r *IdentityRepository) Create(ctx context.Context, identity *domain.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := identityKey(identity.Provider, identity.Subject)
+	if _, ok := r.identities[key]; ok {
+		return fmt.Errorf("memory: insert identity: %w", domain.ErrConflict)
+	}
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now().UTC()
+	}
+	identity.ID = uuid.NewString()
+	cp := *identity
+	r.identities[key] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.identities, key)
+	})
+	return nil
+}
+
+func (r *IdentityRepository) FindBySubject(_ context.Context, provider, subject string) (*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if identity, ok := r.identities[identityKey(provider, subject)]; ok {
+		cp := *identity
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("memory: find identity: %w", domain.ErrNotFound)
+}
+
+func (r *IdentityRepository) ListByUser(_ context.Context, userID string) ([]domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			out = append(out, *identity)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// FederatedLoginRepository is an in-memory implementation of
+// domain.FederatedLoginRepository.
+type FederatedLoginRepository struct {
+	mu     sync.Mutex
+	logins map[string]*domain.FederatedLogin // key: state hash
+}
+
+func NewFederatedLoginRepository() *FederatedLoginRepository {
+	return &FederatedLoginRepository{logins: make(map[string]*domain.FederatedLogin)}
+}
+
+func (r *FederatedLoginRepository) Create(ctx context.Context, login *domain.FederatedLogin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.logins[login.StateHash]; ok {
+		return fmt.Errorf("memory: insert federated login: %w", domain.ErrConflict)
+	}
+	if login.CreatedAt.IsZero() {
+		login.CreatedAt = time.Now().UTC()
+	}
+	login.ID = uuid.NewString()
+	cp := *login
+	r.logins[login.StateHash] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.logins, login.StateHash)
+	})
+	return nil
+}
+
+func (r *FederatedLoginRepository) Consume(ctx context.Context, stateHash, bindingHash string, now time.Time) (*domain.FederatedLogin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	login, ok := r.logins[stateHash]
+	if !ok || login.BindingHash != bindingHash || login.ConsumedAt != nil || !login.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("memory: consume federated login: %w", domain.ErrNotFound)
+	}
+	at := now
+	login.ConsumedAt = &at
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		login.ConsumedAt = nil
+	})
+	cp := *login
+	return &cp, nil
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// ImpersonationRepository is an in-memory implementation of
+// domain.ImpersonationRepository.
+type ImpersonationRepository struct {
+	mu   sync.RWMutex
+	imps map[string]*domain.Impersonation // key: ID
+}
+
+func NewImpersonationRepository() *ImpersonationRepository {
+	return &ImpersonationRepository{imps: make(map[string]*domain.Impersonation)}
+}
+
+func copyImpersonation(i *domain.Impersonation) domain.Impersonation {
+	cp := *i
+	if i.EndedAt != nil {
+		at := *i.EndedAt
+		cp.EndedAt = &at
+	}
+	return cp
+}
+
+func (r *ImpersonationRepository) Create(ctx context.Context, imp *domain.Impersonation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if imp.CreatedAt.IsZero() {
+		imp.CreatedAt = time.Now().UTC()
+	}
+	imp.ID = uuid.NewString()
+	cp := copyImpersonation(imp)
+	r.imps[imp.ID] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.imps, cp.ID)
+	})
+	return nil
+}
+
+func (r *ImpersonationRepository) FindByID(_ context.Context, id string) (*domain.Impersonation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.imps[id]
+	if !ok {
+		return nil, fmt.Errorf("memory: find impersonation: %w", domain.ErrNotFound)
+	}
+	cp := copyImpersonation(i)
+	return &cp, nil
+}
+
+func (r *ImpersonationRepository) End(ctx context.Context, id string, at time.Time, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.imps[id]
+	if !ok || i.EndedAt != nil {
+		return fmt.Errorf("memory: end impersonation: %w", domain.ErrNotFound)
+	}
+	at = at.UTC()
+	i.EndedAt, i.EndReason = &at, reason
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		i.EndedAt, i.EndReason = nil, ""
+	})
+	return nil
+}
+
+func (r *ImpersonationRepository) ListExpired(_ context.Context, now time.Time, limit int) ([]domain.Impersonation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.Impersonation
+	for _, i := range r.imps {
+		if i.EndedAt == nil && i.ExpiresAt.Before(now) {
+			out = append(out, copyImpersonation(i))
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if !out[a].ExpiresAt.Equal(out[b].ExpiresAt) {
+			return out[a].ExpiresAt.Before(out[b].ExpiresAt)
+		}
+		return out[a].ID < out[b].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// MagicLinkRepository is an in-memory implementation of
+// domain.MagicLinkRepository.
+type MagicLinkRepository struct {
+	mu    sync.Mutex
+	links map[string]*domain.MagicLink // key: token hash
+}
+
+func NewMagicLinkRepository() *MagicLinkRepository {
+	return &MagicLinkRepository{links: make(map[string]*domain.MagicLink)}
+}
+
+func (r *MagicLinkRepository) Create(ctx context.Context, link *domain.MagicLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.links[link.TokenHash]; ok {
+		return fmt.Errorf("memory: insert magic link: %w", domain.ErrConflict)
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+	link.ID = uuid.NewString()
+	cp := *link
+	r.links[link.TokenHash] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.links, link.TokenHash)
+	})
+	return nil
+}
+
+func (r *MagicLinkRepository) Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (*domain.MagicLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link, ok := r.links[tokenHash]
+	if !ok || link.NonceHash != nonceHash || link.ConsumedAt != nil || !link.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("memory: consume magic link: %w", domain.ErrNotFound)
+	}
+	at := now
+	link.ConsumedAt = &at
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		link.ConsumedAt = nil
+	})
+	cp := *link
+	return &cp, nil
+}
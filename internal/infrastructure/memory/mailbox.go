@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"go-auth/internal/app"
+)
+
+// Mailbox is an app.Mailer that keeps every message in memory, for tests
+// and the all-in-memory demo mode.
+type Mailbox struct {
+	mu   sync.Mutex
+	sent []app.Email
+}
+
+func NewMailbox() *Mailbox {
+	return &Mailbox{}
+}
+
+func (m *Mailbox) Send(_ context.Context, email app.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far, oldest first.
+func (m *Mailbox) Messages() []app.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]app.Email(nil), m.sent...)
+}
+
+// Last returns the newest message sent to addr.
+func (m *Mailbox) Last(addr string) (app.Email, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To == addr {
+			return m.sent[i], true
+		}
+	}
+	return app.Email{}, false
+}
@@ -0,0 +1,305 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// tenantDirectory holds memberships and roles, which refer to each other:
+// role members must be tenant members, and removing a membership removes
+// the user from the tenant's roles.
+type tenantDirectory struct {
+	mu      sync.Mutex
+	members map[string]*domain.Membership // key: tenant ID + "\x00" + user ID
+	roles   map[string]*domain.Role       // key: role ID
+}
+
+// MembershipRepository is an in-memory implementation of
+// domain.MembershipRepository.
+type MembershipRepository struct{ dir *tenantDirectory }
+
+// RoleRepository is an in-memory implementation of domain.RoleRepository.
+type RoleRepository struct{ dir *tenantDirectory }
+
+// NewTenantRepositories returns membership and role repositories sharing
+// one store.
+func NewTenantRepositories() (*MembershipRepository, *RoleRepository) {
+	dir := &tenantDirectory{
+		members: make(map[string]*domain.Membership),
+		roles:   make(map[string]*domain.Role),
+	}
+	return &MembershipRepository{dir: dir}, &RoleRepository{dir: dir}
+}
+
+func membershipKey(tenantID, userID string) string { return tenantID + "\x00" + userID }
+
+// userNameTaken reports whether another member of the tenant has the name.
+func (d *tenantDirectory) userNameTaken(m *domain.Membership) bool {
+	for _, other := range d.members {
+		if other.TenantID == m.TenantID && other.UserID != m.UserID && strings.EqualFold(other.UserName, m.UserName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *tenantDirectory) roleNameTaken(role *domain.Role) bool {
+	for _, other := range d.roles {
+		if other.TenantID == role.TenantID && other.ID != role.ID && strings.EqualFold(other.Name, role.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *tenantDirectory) checkMembers(op string, role *domain.Role) error {
+	for _, userID := range role.Members {
+		if _, ok := d.members[membershipKey(role.TenantID, userID)]; !ok {
+			return fmt.Errorf("memory: %s: user %s is not a member of the tenant", op, userID)
+		}
+	}
+	return nil
+}
+
+func (r *MembershipRepository) Create(ctx context.Context, m *domain.Membership) error {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := membershipKey(m.TenantID, m.UserID)
+	if _, ok := d.members[key]; ok || d.userNameTaken(m) {
+		return fmt.Errorf("memory: insert membership: %w", domain.ErrConflict)
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = m.CreatedAt
+	}
+	m.Version = 1
+	cp := *m
+	d.members[key] = &cp
+	onRollback(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.members, key)
+	})
+	return nil
+}
+
+func (r *MembershipRepository) Get(_ context.Context, tenantID, userID string) (*domain.Membership, error) {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if m, ok := d.members[membershipKey(tenantID, userID)]; ok {
+		cp := *m
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("memory: find membership: %w", domain.ErrNotFound)
+}
+
+func (r *MembershipRepository) List(_ context.Context, tenantID string) ([]domain.Membership, error) {
+	return r.dir.listMembers(func(m *domain.Membership) bool { return m.TenantID == tenantID }), nil
+}
+
+func (r *MembershipRepository) ListByUser(_ context.Context, userID string) ([]domain.Membership, error) {
+	return r.dir.listMembers(func(m *domain.Membership) bool { return m.UserID == userID }), nil
+}
+
+func (d *tenantDirectory) listMembers(match func(*domain.Membership) bool) []domain.Membership {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []domain.Membership
+	for _, m := range d.members {
+		if match(m) {
+			out = append(out, *m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].TenantID+out[i].UserID < out[j].TenantID+out[j].UserID
+	})
+	return out
+}
+
+func (r *MembershipRepository) Update(ctx context.Context, m *domain.Membership) error {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target, ok := d.members[membershipKey(m.TenantID, m.UserID)]
+	if !ok || target.Version != m.Version {
+		return fmt.Errorf("memory: update membership: %w", domain.ErrNotFound)
+	}
+	if d.userNameTaken(m) {
+		return fmt.Errorf("memory: update membership: %w", domain.ErrConflict)
+	}
+	prev := *target
+	m.Version++
+	*target = *m
+	target.CreatedAt, target.CreatedAccount = prev.CreatedAt, prev.CreatedAccount
+	onRollback(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		*target = prev
+	})
+	return nil
+}
+
+func (r *MembershipRepository) Delete(ctx context.Context, tenantID, userID string) error {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := membershipKey(tenantID, userID)
+	m, ok := d.members[key]
+	if !ok {
+		return fmt.Errorf("memory: delete membership: %w", domain.ErrNotFound)
+	}
+	delete(d.members, key)
+	held := map[*domain.Role][]string{}
+	for _, role := range d.roles {
+		if role.TenantID == tenantID && slices.Contains(role.Members, userID) {
+			held[role] = role.Members
+			role.Members = slices.DeleteFunc(slices.Clone(role.Members), func(id string) bool { return id == userID })
+		}
+	}
+	onRollback(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.members[key] = m
+		for role, members := range held {
+			role.Members = members
+		}
+	})
+	return nil
+}
+
+func (r *RoleRepository) Create(ctx context.Context, role *domain.Role) error {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.roleNameTaken(role) {
+		return fmt.Errorf("memory: insert role: %w", domain.ErrConflict)
+	}
+	if err := d.checkMembers("insert role", role); err != nil {
+		return err
+	}
+	if role.CreatedAt.IsZero() {
+		role.CreatedAt = time.Now().UTC()
+	}
+	if role.UpdatedAt.IsZero() {
+		role.UpdatedAt = role.CreatedAt
+	}
+	role.ID = uuid.NewString()
+	role.Version = 1
+	cp := copyRole(role)
+	d.roles[role.ID] = cp
+	onRollback(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.roles, cp.ID)
+	})
+	return nil
+}
+
+func (r *RoleRepository) Get(_ context.Context, tenantID, id string) (*domain.Role, error) {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if role, ok := d.roles[id]; ok && role.TenantID == tenantID {
+		return copyRole(role), nil
+	}
+	return nil, fmt.Errorf("memory: find role: %w", domain.ErrNotFound)
+}
+
+func (r *RoleRepository) List(_ context.Context, tenantID string) ([]domain.Role, error) {
+	return r.dir.listRoles(func(role *domain.Role) bool { return role.TenantID == tenantID }, true), nil
+}
+
+func (r *RoleRepository) ListByMember(_ context.Context, tenantID, userID string) ([]domain.Role, error) {
+	return r.dir.listRoles(func(role *domain.Role) bool {
+		return role.TenantID == tenantID && slices.Contains(role.Members, userID)
+	}, false), nil
+}
+
+func (d *tenantDirectory) listRoles(match func(*domain.Role) bool, withMembers bool) []domain.Role {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []domain.Role
+	for _, role := range d.roles {
+		if match(role) {
+			cp := copyRole(role)
+			if !withMembers {
+				cp.Members = nil
+			}
+			out = append(out, *cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (r *RoleRepository) Update(ctx context.Context, role *domain.Role) error {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target, ok := d.roles[role.ID]
+	if !ok || target.TenantID != role.TenantID || target.Version != role.Version {
+		return fmt.Errorf("memory: update role: %w", domain.ErrNotFound)
+	}
+	if d.roleNameTaken(role) {
+		return fmt.Errorf("memory: update role: %w", domain.ErrConflict)
+	}
+	if err := d.checkMembers("update role", role); err != nil {
+		return err
+	}
+	prev := *target
+	role.Version++
+	*target = *copyRole(role)
+	target.CreatedAt = prev.CreatedAt
+	onRollback(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		*target = prev
+	})
+	return nil
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, tenantID, id string) error {
+	d := r.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	role, ok := d.roles[id]
+	if !ok || role.TenantID != tenantID {
+		return fmt.Errorf("memory: delete role: %w", domain.ErrNotFound)
+	}
+	delete(d.roles, id)
+	onRollback(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.roles[id] = role
+	})
+	return nil
+}
+
+// copyRole returns a copy of role with its own, sorted member slice.
+func copyRole(role *domain.Role) *domain.Role {
+	cp := *role
+	cp.Members = slices.Clone(role.Members)
+	slices.Sort(cp.Members)
+	return &cp
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// OTPRepository is an in-memory implementation of domain.OTPRepository.
+type OTPRepository struct {
+	mu   sync.Mutex
+	otps map[string]*domain.OTP // key: ID
+}
+
+func NewOTPRepository() *OTPRepository {
+	return &OTPRepository{otps: make(map[string]*domain.OTP)}
+}
+
+func (r *OTPRepository) Create(ctx context.Context, otp *domain.OTP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if otp.CreatedAt.IsZero() {
+		otp.CreatedAt = time.Now().UTC()
+	}
+	otp.ID = uuid.NewString()
+	cp := *otp
+	r.otps[otp.ID] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.otps, otp.ID)
+	})
+	return nil
+}
+
+func (r *OTPRepository) RecordAttempt(ctx context.Context, id string) (*domain.OTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	otp, ok := r.otps[id]
+	if !ok {
+		return nil, fmt.Errorf("memory: record otp attempt: %w", domain.ErrNotFound)
+	}
+	otp.Attempts++
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		otp.Attempts--
+	})
+	cp := *otp
+	return &cp, nil
+}
+
+func (r *OTPRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	otp, ok := r.otps[id]
+	if !ok || otp.ConsumedAt != nil || !otp.ExpiresAt.After(now) {
+		return fmt.Errorf("memory: consume otp: %w", domain.ErrNotFound)
+	}
+	at := now
+	otp.ConsumedAt = &at
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		otp.ConsumedAt = nil
+	})
+	return nil
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// PersonalAccessTokenRepository is an in-memory implementation of
+// domain.PersonalAccessTokenRepository.
+type PersonalAccessTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]*domain.PersonalAccessToken // key: ID
+}
+
+func NewPersonalAccessTokenRepository() *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{tokens: make(map[string]*domain.PersonalAccessToken)}
+}
+
+func copyPersonalToken(t *domain.PersonalAccessToken) domain.PersonalAccessToken {
+	cp := *t
+	cp.Scopes = slices.Clone(t.Scopes)
+	if t.ExpiresAt != nil {
+		at := *t.ExpiresAt
+		cp.ExpiresAt = &at
+	}
+	if t.LastUsedAt != nil {
+		at := *t.LastUsedAt
+		cp.LastUsedAt = &at
+	}
+	return cp
+}
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t.TokenHash == token.TokenHash {
+			return fmt.Errorf("memory: insert personal access token: %w", domain.ErrConflict)
+		}
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	token.ID = uuid.NewString()
+	cp := copyPersonalToken(token)
+	r.tokens[token.ID] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.tokens, cp.ID)
+	})
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) FindByHash(_ context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			cp := copyPersonalToken(t)
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("memory: find personal access token: %w", domain.ErrNotFound)
+}
+
+func (r *PersonalAccessTokenRepository) ListByUser(_ context.Context, userID string) ([]domain.PersonalAccessToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.PersonalAccessToken
+	for _, t := range r.tokens {
+		if t.UserID == userID {
+			out = append(out, copyPersonalToken(t))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tokens[id]
+	if !ok || t.UserID != userID {
+		return fmt.Errorf("memory: delete personal access token: %w", domain.ErrNotFound)
+	}
+	delete(r.tokens, id)
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.tokens[id] = t
+	})
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) DeleteAllByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var removed []*domain.PersonalAccessToken
+	for id, t := range r.tokens {
+		if t.UserID == userID {
+			removed = append(removed, t)
+			delete(r.tokens, id)
+		}
+	}
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, t := range removed {
+			r.tokens[t.ID] = t
+		}
+	})
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tokens[id]
+	if !ok {
+		return fmt.Errorf("memory: touch personal access token: %w", domain.ErrNotFound)
+	}
+	prev := t.LastUsedAt
+	at = at.UTC()
+	t.LastUsedAt = &at
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		t.LastUsedAt = prev
+	})
+	return nil
+}
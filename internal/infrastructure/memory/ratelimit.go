@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+const (
+	rateLimitShards        = 64
+	defaultMaxKeysPerShard = 16384
+)
+
+type rateLimitShard struct {
+	mu   sync.Mutex
+	tats map[string]time.Time // theoretical arrival time per key
+}
+
+// RateLimitStore is a sharded in-memory GCRA implementation of app.RateLimitStore.
+// Keys are dropped once their budget is fully restored. A shard that reaches its
+// capacity drops its expired keys, which hold no state; when none have expired it
+// rejects new keys rather than forget a live budget.
+type RateLimitStore struct {
+	shards  [rateLimitShards]rateLimitShard
+	maxKeys int
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewRateLimitStore creates a store and starts a janitor that sweeps stale keys
+// every sweepEvery. Call Close to stop it.
+func NewRateLimitStore(sweepEvery time.Duration) *RateLimitStore {
+	s := &RateLimitStore{
+		maxKeys: defaultMaxKeysPerShard,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i].tats = make(map[string]time.Time)
+	}
+	if sweepEvery > 0 {
+		go s.janitor(sweepEvery)
+	}
+	return s
+}
+
+func (s *RateLimitStore) Allow(_ context.Context, key string, policy app.RateLimitPolicy) (app.RateLimitResult, error) {
+	sh := s.shard(key)
+	now := s.now()
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	tat, ok := sh.tats[key]
+	if !ok && len(sh.tats) >= s.maxKeys {
+		if earliest := sh.evictExpired(now); len(sh.tats) >= s.maxKeys {
+			return app.RateLimitResult{Limit: policy.Limit, RetryAfter: earliest.Sub(now), ResetAfter: earliest.Sub(now)}, nil
+		}
+	}
+	newTat, res := gcra(tat, now, policy)
+	if res.Allowed {
+		sh.tats[key] = newTat
+	}
+	return res, nil
+}
+
+func (s *RateLimitStore) Refund(_ context.Context, key string, policy app.RateLimitPolicy) error {
+	if policy.Limit <= 0 || policy.Window <= 0 {
+		return nil
+	}
+	sh := s.shard(key)
+	now := s.now()
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	tat, ok := sh.tats[key]
+	if !ok {
+		return nil
+	}
+	if tat = tat.Add(-policy.Window / time.Duration(policy.Limit)); tat.After(now) {
+		sh.tats[key] = tat
+	} else {
+		delete(sh.tats, key)
+	}
+	return nil
+}
+
+// Close stops the background janitor.
+func (s *RateLimitStore) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Len reports the number of tracked keys.
+func (s *RateLimitStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		n += len(sh.tats)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+func (s *RateLimitStore) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &s.shards[h.Sum32()%rateLimitShards]
+}
+
+func (s *RateLimitStore) janitor(every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			s.sweep()
+		}
+	}
+}
+
+func (s *RateLimitStore) sweep() {
+	now := s.now()
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		sh.evictExpired(now)
+		sh.mu.Unlock()
+	}
+}
+
+// evictExpired drops keys whose budget is fully restored and returns when
+// the earliest remaining key will be.
+func (sh *rateLimitShard) evictExpired(now time.Time) time.Time {
+	var earliest time.Time
+	for k, tat := range sh.tats {
+		if !tat.After(now) {
+			delete(sh.tats, k)
+		} else if earliest.IsZero() || tat.Before(earliest) {
+			earliest = tat
+		}
+	}
+	return earliest
+}
+
+// gcra applies the generic cell rate algorithm: each request pushes the
+// theoretical arrival time (TAT) forward by window/limit, and a request is
+// rejected when that would put TAT more than one window ahead of now.
+func gcra(tat, now time.Time, p app.RateLimitPolicy) (time.Time, app.RateLimitResult) {
+	res := app.RateLimitResult{Limit: p.Limit}
+	if p.Limit <= 0 || p.Window <= 0 {
+		return tat, res
+	}
+	interval := p.Window / time.Duration(p.Limit)
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-p.Window)
+	if now.Before(allowAt) {
+		res.RetryAfter = allowAt.Sub(now)
+		res.ResetAfter = tat.Sub(now)
+		return tat, res
+	}
+	res.Allowed = true
+	res.ResetAfter = newTat.Sub(now)
+	res.Remaining = int((p.Window - res.ResetAfter) / interval)
+	return newTat, res
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+func TestRateLimitStore_GCRA(t *testing.T) {
+	s := NewRateLimitStore(0)
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+	p := app.RateLimitPolicy{Name: "test", Limit: 3, Window: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		res, _ := s.Allow(context.Background(), "k", p)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+	}
+	res, _ := s.Allow(context.Background(), "k", p)
+	if res.Allowed || res.RetryAfter != 20*time.Second {
+		t.Fatalf("expected rejection with 20s retry, got %+v", res)
+	}
+
+	now = now.Add(20 * time.Second)
+	if res, _ := s.Allow(context.Background(), "k", p); !res.Allowed {
+		t.Fatalf("budget should refill after one interval: %+v", res)
+	}
+
+	_ = s.Refund(context.Background(), "k", p)
+	if res, _ := s.Allow(context.Background(), "k", p); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("refunded request should be available again: %+v", res)
+	}
+	for i := 0; i < 3; i++ {
+		_ = s.Refund(context.Background(), "k", p)
+	}
+	if n := s.Len(); n != 0 {
+		t.Fatalf("fully refunded key kept: %d keys", n)
+	}
+}
+
+func TestRateLimitStore_Eviction(t *testing.T) {
+	s := NewRateLimitStore(0)
+	s.maxKeys = 2
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+	p := app.RateLimitPolicy{Name: "test", Limit: 10, Window: time.Second}
+
+	for i := 0; i < 10; i++ {
+		_, _ = s.Allow(context.Background(), "victim", p)
+	}
+	rejected := 0
+	for i := 0; i < 1000; i++ {
+		if res, _ := s.Allow(context.Background(), fmt.Sprintf("ip-%d", i), p); !res.Allowed {
+			rejected++
+		}
+	}
+	if n := s.Len(); n > 2*rateLimitShards {
+		t.Fatalf("store grew past its capacity: %d keys", n)
+	}
+	// Spraying new keys fails closed instead of resetting live budgets.
+	if rejected == 0 {
+		t.Fatal("full store accepted every new key")
+	}
+	if res, _ := s.Allow(context.Background(), "victim", p); res.Allowed {
+		t.Fatalf("victim's budget was reset by eviction: %+v", res)
+	}
+
+	now = now.Add(time.Second)
+	s.sweep()
+	if n := s.Len(); n != 0 {
+		t.Fatalf("expired keys not swept: %d", n)
+	}
+}
+
+func TestRateLimitStore_Concurrent(t *testing.T) {
+	s := NewRateLimitStore(time.Millisecond)
+	defer s.Close()
+	p := app.RateLimitPolicy{Name: "test", Limit: 50, Window: time.Hour}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, _ := s.Allow(context.Background(), "shared", p)
+			if res.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 50 {
+		t.Fatalf("allowed=%d, want 50", allowed)
+	}
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// RefreshRepository is an in-memory implementation of
+// domain.RefreshTokenRepository. As in Postgres, expired tokens are still
+// returned by FindByHash; callers check ExpiresAt.
+type RefreshRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]*domain.RefreshToken // key: token hash
+	now    func() time.Time
+}
+
+func NewRefreshRepository() *RefreshRepository {
+	return &RefreshRepository{
+		tokens: make(map[string]*domain.RefreshToken),
+		now:    time.Now,
+	}
+}
+
+func (r *RefreshRepository) Save(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tokens[tokenHash]; ok {
+		return fmt.Errorf("memory: save refresh: %w", domain.ErrConflict)
+	}
+	r.tokens[tokenHash] = &domain.RefreshToken{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: r.now().UTC(),
+	}
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.tokens, tokenHash)
+	})
+	return nil
+}
+
+func (r *RefreshRepository) FindByHash(_ context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rt, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, fmt.Errorf("memory: find refresh: %w", domain.ErrNotFound)
+	}
+	cp := *rt
+	if rt.RevokedAt != nil {
+		at := *rt.RevokedAt
+		cp.RevokedAt = &at
+	}
+	return &cp, nil
+}
+
+func (r *RefreshRepository) RevokeByHash(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt, ok := r.tokens[tokenHash]
+	if !ok || rt.RevokedAt != nil {
+		return fmt.Errorf("memory: revoke refresh: %w", domain.ErrNotFound)
+	}
+	r.revoke(ctx, rt)
+	return nil
+}
+
+func (r *RefreshRepository) RevokeAllByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rt := range r.tokens {
+		if rt.UserID == userID && rt.RevokedAt == nil {
+			r.revoke(ctx, rt)
+		}
+	}
+	return nil
+}
+
+// revoke must be called with r.mu held.
+func (r *RefreshRepository) revoke(ctx context.Context, rt *domain.RefreshToken) {
+	prev := rt.RevokedAt
+	now := r.now().UTC()
+	rt.RevokedAt = &now
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		rt.RevokedAt = prev
+	})
+}
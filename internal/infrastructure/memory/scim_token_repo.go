@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// SCIMTokenRepository is an in-memory implementation of
+// domain.SCIMTokenRepository.
+type SCIMTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]*domain.SCIMToken // key: ID
+}
+
+func NewSCIMTokenRepository() *SCIMTokenRepository {
+	return &SCIMTokenRepository{tokens: make(map[string]*domain.SCIMToken)}
+}
+
+func (r *SCIMTokenRepository) Create(ctx context.Context, token *domain.SCIMToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t.TokenHash == token.TokenHash {
+			return fmt.Errorf("memory: insert scim token: %w", domain.ErrConflict)
+		}
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	token.ID = uuid.NewString()
+	cp := *token
+	r.tokens[token.ID] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.tokens, cp.ID)
+	})
+	return nil
+}
+
+func (r *SCIMTokenRepository) FindByHash(_ context.Context, tokenHash string) (*domain.SCIMToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("memory: find scim token: %w", domain.ErrNotFound)
+}
+
+func (r *SCIMTokenRepository) List(_ context.Context, tenantID string) ([]domain.SCIMToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.SCIMToken
+	for _, t := range r.tokens {
+		if t.TenantID == tenantID {
+			out = append(out, *t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *SCIMTokenRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tokens[id]
+	if !ok {
+		return fmt.Errorf("memory: delete scim token: %w", domain.ErrNotFound)
+	}
+	delete(r.tokens, id)
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.tokens[id] = t
+	})
+	return nil
+}
@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+// ServiceAccountRepository is an in-memory implementation of
+// domain.ServiceAccountRepository.
+type ServiceAccountRepository struct {
+	mu       sync.RWMutex
+	accounts map[string]*domain.ServiceAccount           // key: ID
+	creds    map[string]*domain.ServiceAccountCredential // key: ID
+}
+
+func NewServiceAccountRepository() *ServiceAccountRepository {
+	return &ServiceAccountRepository{
+		accounts: make(map[string]*domain.ServiceAccount),
+		creds:    make(map[string]*domain.ServiceAccountCredential),
+	}
+}
+
+func copyServiceAccount(a *domain.ServiceAccount) domain.ServiceAccount {
+	cp := *a
+	cp.Roles = slices.Clone(a.Roles)
+	if cp.Roles == nil {
+		cp.Roles = []string{}
+	}
+	if a.DisabledAt != nil {
+		at := *a.DisabledAt
+		cp.DisabledAt = &at
+	}
+	return cp
+}
+
+func copyServiceAccountCredential(c *domain.ServiceAccountCredential) domain.ServiceAccountCredential {
+	cp := *c
+	if c.ExpiresAt != nil {
+		at := *c.ExpiresAt
+		cp.ExpiresAt = &at
+	}
+	if c.LastUsedAt != nil {
+		at := *c.LastUsedAt
+		cp.LastUsedAt = &at
+	}
+	return cp
+}
+
+func (r *ServiceAccountRepository) nameTaken(a *domain.ServiceAccount) bool {
+	for _, other := range r.accounts {
+		if other.TenantID == a.TenantID && other.ID != a.ID && strings.EqualFold(other.Name, a.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ServiceAccountRepository) Create(ctx context.Context, account *domain.ServiceAccount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nameTaken(account) {
+		return fmt.Errorf("memory: insert service account: %w", domain.ErrConflict)
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+	if account.UpdatedAt.IsZero() {
+		account.UpdatedAt = account.CreatedAt
+	}
+	account.ID = uuid.NewString()
+	cp := copyServiceAccount(account)
+	r.accounts[account.ID] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.accounts, cp.ID)
+	})
+	return nil
+}
+
+func (r *ServiceAccountRepository) Get(_ context.Context, id string) (*domain.ServiceAccount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("memory: find service account: %w", domain.ErrNotFound)
+	}
+	cp := copyServiceAccount(a)
+	return &cp, nil
+}
+
+func (r *ServiceAccountRepository) List(_ context.Context, tenantID string) ([]domain.ServiceAccount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.ServiceAccount
+	for _, a := range r.accounts {
+		if a.TenantID == tenantID {
+			out = append(out, copyServiceAccount(a))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *ServiceAccountRepository) Update(ctx context.Context, account *domain.ServiceAccount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.accounts[account.ID]
+	if !ok {
+		return fmt.Errorf("memory: update service account: %w", domain.ErrNotFound)
+	}
+	if r.nameTaken(&domain.ServiceAccount{ID: stored.ID, TenantID: stored.TenantID, Name: account.Name}) {
+		return fmt.Errorf("memory: update service account: %w", domain.ErrConflict)
+	}
+	prev := *stored
+	updated := copyServiceAccount(account)
+	stored.Name, stored.Description, stored.Roles = updated.Name, updated.Description, updated.Roles
+	stored.DisabledAt, stored.UpdatedAt = updated.DisabledAt, updated.UpdatedAt
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		*stored = prev
+	})
+	return nil
+}
+
+func (r *ServiceAccountRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.accounts[id]
+	if !ok {
+		return fmt.Errorf("memory: delete service account: %w", domain.ErrNotFound)
+	}
+	delete(r.accounts, id)
+	var removed []*domain.ServiceAccountCredential
+	for credID, c := range r.creds {
+		if c.ServiceAccountID == id {
+			removed = append(removed, c)
+			delete(r.creds, credID)
+		}
+	}
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.accounts[id] = a
+		for _, c := range removed {
+			r.creds[c.ID] = c
+		}
+	})
+	return nil
+}
+
+func (r *ServiceAccountRepository) AddCredential(ctx context.Context, cred *domain.ServiceAccountCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.accounts[cred.ServiceAccountID]; !ok {
+		return fmt.Errorf("memory: insert service account credential: unknown account %s", cred.ServiceAccountID)
+	}
+	if cred.SecretHash != "" {
+		for _, c := range r.creds {
+			if c.SecretHash == cred.SecretHash {
+				return fmt.Errorf("memory: insert service account credential: %w", domain.ErrConflict)
+			}
+		}
+	}
+	if cred.CreatedAt.IsZero() {
+		cred.CreatedAt = time.Now().UTC()
+	}
+	cred.ID = uuid.NewString()
+	cp := copyServiceAccountCredential(cred)
+	r.creds[cred.ID] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.creds, cp.ID)
+	})
+	return nil
+}
+
+func (r *ServiceAccountRepository) ListCredentials(_ context.Context, accountID string) ([]domain.ServiceAccountCredential, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []domain.ServiceAccountCredential
+	for _, c := range r.creds {
+		if c.ServiceAccountID == accountID {
+			out = append(out, copyServiceAccountCredential(c))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *ServiceAccountRepository) FindCredentialByHash(_ context.Context, secretHash string) (*domain.ServiceAccountCredential, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.creds {
+		if c.SecretHash != "" && c.SecretHash == secretHash {
+			cp := copyServiceAccountCredential(c)
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("memory: find service account credential: %w", domain.ErrNotFound)
+}
+
+func (r *ServiceAccountRepository) DeleteCredential(ctx context.Context, accountID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.creds[id]
+	if !ok || c.ServiceAccountID != accountID {
+		return fmt.Errorf("memory: delete service account credential: %w", domain.ErrNotFound)
+	}
+	delete(r.creds, id)
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.creds[id] = c
+	})
+	return nil
+}
+
+func (r *ServiceAccountRepository) TouchCredential(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.creds[id]
+	if !ok {
+		return fmt.Errorf("memory: touch service account credential: %w", domain.ErrNotFound)
+	}
+	prev := c.LastUsedAt
+	at = at.UTC()
+	c.LastUsedAt = &at
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		c.LastUsedAt = prev
+	})
+	return nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"go-auth/internal/app"
+)
+
+// SMSOutbox is an app.SMSSender that keeps every message in memory, for
+// tests and the all-in-memory demo mode.
+type SMSOutbox struct {
+	mu   sync.Mutex
+	sent []app.SMS
+}
+
+func NewSMSOutbox() *SMSOutbox {
+	return &SMSOutbox{}
+}
+
+func (o *SMSOutbox) Send(_ context.Context, sms app.SMS) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, sms)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far, oldest first.
+func (o *SMSOutbox) Messages() []app.SMS {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]app.SMS(nil), o.sent...)
+}
+
+// Last returns the newest message sent to phone.
+func (o *SMSOutbox) Last(phone string) (app.SMS, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.sent) - 1; i >= 0; i-- {
+		if o.sent[i].To == phone {
+			return o.sent[i], true
+		}
+	}
+	return app.SMS{}, false
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+type txKey struct{}
+
+// journal collects the compensating actions of one unit of work.
+type journal struct {
+	undo []func()
+}
+
+// TxManager implements app.TxManager for the in-memory repositories.
+// Units of work are serialized; a failed one rolls back by replaying the
+// undo actions its repositories recorded, in reverse order.
+type TxManager struct {
+	mu sync.Mutex
+}
+
+func NewTxManager() *TxManager {
+	return &TxManager{}
+}
+
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*journal); ok {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := &journal{}
+	defer func() {
+		if p := recover(); p != nil {
+			j.rollback()
+			panic(p)
+		}
+		if err != nil {
+			j.rollback()
+		}
+	}()
+	return fn(context.WithValue(ctx, txKey{}, j))
+}
+
+func (j *journal) rollback() {
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		j.undo[i]()
+	}
+}
+
+// onRollback registers undo to run if the unit of work in ctx fails.
+// Outside a unit of work it does nothing.
+func onRollback(ctx context.Context, undo func()) {
+	if j, ok := ctx.Value(txKey{}).(*journal); ok {
+		j.undo = append(j.undo, undo)
+	}
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+func TestTxManager_RollsBackAcrossRepositories(t *testing.T) {
+	ctx := context.Background()
+	tx := NewTxManager()
+	users := NewUserRepository()
+	tokens := NewRefreshRepository()
+	outbox := NewWebhookRepository()
+
+	keep := domain.NewUser("keep@ex.com", "hash")
+	if err := users.Create(ctx, keep); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.Save(ctx, keep.ID, "h1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	boom := errors.New("boom")
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := users.Create(ctx, domain.NewUser("new@ex.com", "hash")); err != nil {
+			return err
+		}
+		if err := tokens.RevokeAllByUser(ctx, keep.ID); err != nil {
+			return err
+		}
+		return tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := outbox.Enqueue(ctx, &domain.OutboxEvent{Type: domain.EventUserRegistered}); err != nil {
+				return err
+			}
+			return boom
+		})
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+
+	if u, _ := users.FindByEmail(ctx, "new@ex.com"); u != nil {
+		t.Fatal("created user survived rollback")
+	}
+	if u, _ := users.FindByEmail(ctx, "keep@ex.com"); u == nil || u.ID != keep.ID {
+		t.Fatal("existing user lost")
+	}
+	if rt, _ := tokens.FindByHash(ctx, "h1"); rt == nil || rt.RevokedAt != nil {
+		t.Fatalf("revocation survived rollback: %+v", rt)
+	}
+	if len(outbox.outbox) != 0 {
+		t.Fatalf("outbox event survived rollback: %d", len(outbox.outbox))
+	}
+
+	err = tx.WithinTx(ctx, func(ctx context.Context) error {
+		return outbox.Enqueue(ctx, &domain.OutboxEvent{Type: domain.EventUserRegistered})
+	})
+	if err != nil || len(outbox.outbox) != 1 {
+		t.Fatalf("commit: err=%v events=%d", err, len(outbox.outbox))
+	}
+}
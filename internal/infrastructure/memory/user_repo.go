@@ -1,12 +1,22 @@
 package memory
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
 	"go-auth/internal/domain"
 )
 
 // UserRepository is an in-memory implementation of domain.UserRepository.
+// Like the SQL backends it assigns IDs, keeps emails unique and hands out
+// copies, so callers never share state with the store.
 type UserRepository struct {
 	mu    sync.RWMutex
 	users map[string]*domain.User // key: email
@@ -21,15 +31,197 @@ func NewUserRepository() *UserRepository {
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.users[user.Email] = user
+	if _, ok := r.users[user.Email]; ok {
+		return fmt.Errorf("memory: insert user: %w", domain.ErrConflict)
+	}
+	user.ID = uuid.NewString()
+	cp := *user
+	r.users[user.Email] = &cp
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.users, user.Email)
+	})
 	return nil
 }
 
-func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+func (r *UserRepository) FindByEmail(_ context.Context, email string) (*domain.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	if u, ok := r.users[email]; ok {
-		return u, nil
+		cp := *u
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("memory: find user: %w", domain.ErrNotFound)
+}
+
+func (r *UserRepository) FindByID(_ context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, u := range r.users {
+		if u.ID == id {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("memory: find user: %w", domain.ErrNotFound)
+}
+
+func (r *UserRepository) FindByPhone(_ context.Context, phone string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, u := range r.users {
+		if phone != "" && u.Phone == phone {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("memory: find user: %w", domain.ErrNotFound)
+}
+
+func (r *UserRepository) UpdatePhone(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var target *domain.User
+	for _, u := range r.users {
+		if u.ID == user.ID {
+			target = u
+		} else if user.Phone != "" && u.Phone == user.Phone {
+			return fmt.Errorf("memory: update phone: %w", domain.ErrConflict)
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("memory: update phone: %w", domain.ErrNotFound)
+	}
+	prev := *target
+	target.Phone, target.SMSSecondFactor, target.UpdatedAt = user.Phone, user.SMSSecondFactor, user.UpdatedAt
+	target.PhoneVerifiedAt = nil
+	if user.PhoneVerifiedAt != nil {
+		at := *user.PhoneVerifiedAt
+		target.PhoneVerifiedAt = &at
+	}
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		*target = prev
+	})
+	return nil
+}
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var target *domain.User
+	for _, u := range r.users {
+		if u.ID == user.ID {
+			target = u
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("memory: update profile: %w", domain.ErrNotFound)
+	}
+	if other, ok := r.users[user.Email]; ok && other != target {
+		return fmt.Errorf("memory: update profile: %w", domain.ErrConflict)
 	}
-	return nil, nil
+	prev := *target
+	delete(r.users, prev.Email)
+	target.Email, target.Locale, target.UpdatedAt = user.Email, user.Locale, user.UpdatedAt
+	r.users[target.Email] = target
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.users, target.Email)
+		*target = prev
+		r.users[prev.Email] = target
+	})
+	return nil
+}
+
+func (r *UserRepository) UpdateStatus(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	target := r.byID(user.ID)
+	if target == nil {
+		return fmt.Errorf("memory: update status: %w", domain.ErrNotFound)
+	}
+	prev := *target
+	target.IsVerified, target.UpdatedAt = user.IsVerified, user.UpdatedAt
+	target.DisabledAt = nil
+	if user.DisabledAt != nil {
+		at := *user.DisabledAt
+		target.DisabledAt = &at
+	}
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		*target = prev
+	})
+	return nil
+}
+
+func (r *UserRepository) Search(_ context.Context, f domain.UserFilter) ([]domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	prefix := strings.ToLower(f.EmailPrefix)
+	// Compare at the microsecond precision the SQL backends keep, so
+	// cursors behave the same everywhere.
+	var before *domain.UserCursor
+	if f.Before != nil {
+		before = &domain.UserCursor{CreatedAt: f.Before.CreatedAt.Truncate(time.Microsecond), ID: f.Before.ID}
+	}
+	var out []domain.User
+	for _, u := range r.users {
+		created := u.CreatedAt.Truncate(time.Microsecond)
+		switch {
+		case !strings.HasPrefix(strings.ToLower(u.Email), prefix),
+			f.Verified != nil && u.IsVerified != *f.Verified,
+			!f.CreatedFrom.IsZero() && created.Before(f.CreatedFrom),
+			!f.CreatedTo.IsZero() && !created.Before(f.CreatedTo),
+			before != nil && userCursorCompare(created, u.ID, *before) >= 0:
+			continue
+		}
+		out = append(out, *u)
+	}
+	slices.SortFunc(out, func(a, b domain.User) int {
+		return -userCursorCompare(a.CreatedAt.Truncate(time.Microsecond), a.ID, domain.UserCursor{
+			CreatedAt: b.CreatedAt.Truncate(time.Microsecond), ID: b.ID,
+		})
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func userCursorCompare(createdAt time.Time, id string, c domain.UserCursor) int {
+	if n := createdAt.Compare(c.CreatedAt); n != 0 {
+		return n
+	}
+	return cmp.Compare(id, c.ID)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	target := r.byID(id)
+	if target == nil {
+		return fmt.Errorf("memory: delete user: %w", domain.ErrNotFound)
+	}
+	delete(r.users, target.Email)
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.users[target.Email] = target
+	})
+	return nil
+}
+
+// byID must be called with mu held.
+func (r *UserRepository) byID(id string) *domain.User {
+	for _, u := range r.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
 }
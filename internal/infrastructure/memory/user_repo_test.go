@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+func TestUserRepository_IDsUniquenessAndCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository()
+
+	u := domain.NewUser("a@ex.com", "hash")
+	if err := repo.Create(ctx, u); err != nil || u.ID == "" {
+		t.Fatalf("create: id=%q err=%v", u.ID, err)
+	}
+	if err := repo.Create(ctx, domain.NewUser("a@ex.com", "other")); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate email: %v", err)
+	}
+
+	u.Password = "mutated"
+	got, _ := repo.FindByEmail(ctx, "a@ex.com")
+	if got == nil || got.Password != "hash" {
+		t.Fatalf("store shares state with caller: %+v", got)
+	}
+	got.Password = "mutated"
+	if again, _ := repo.FindByEmail(ctx, "a@ex.com"); again.Password != "hash" {
+		t.Fatal("returned user shares state with store")
+	}
+}
+
+func TestRefreshRepository_RevokeAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRefreshRepository()
+
+	if err := repo.Save(ctx, "u1", "expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(ctx, "u1", "live", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(ctx, "u1", "live", time.Now().Add(time.Hour)); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate hash: %v", err)
+	}
+
+	// Expired tokens are still found; the caller decides what to do with them.
+	if rt, _ := repo.FindByHash(ctx, "expired"); rt == nil || rt.ExpiresAt.After(time.Now()) {
+		t.Fatalf("expired token: %+v", rt)
+	}
+	if err := repo.RevokeAllByUser(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{"expired", "live"} {
+		if rt, _ := repo.FindByHash(ctx, h); rt == nil || rt.RevokedAt == nil {
+			t.Fatalf("%s not revoked: %+v", h, rt)
+		}
+	}
+	if rt, err := repo.FindByHash(ctx, "missing"); rt != nil || !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("missing: %+v %v", rt, err)
+	}
+}
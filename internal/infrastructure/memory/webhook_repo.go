@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type outboxEntry struct {
+	event     domain.OutboxEvent
+	processed bool
+}
+
+// WebhookRepository is an in-memory implementation of both
+// domain.OutboxRepository and domain.WebhookRepository.
+type WebhookRepository struct {
+	mu         sync.Mutex
+	outbox     []*outboxEntry
+	subs       map[string]*domain.WebhookSubscription
+	deliveries map[string]*domain.WebhookDelivery
+	now        func() time.Time
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		subs:       make(map[string]*domain.WebhookSubscription),
+		deliveries: make(map[string]*domain.WebhookDelivery),
+		now:        time.Now,
+	}
+}
+
+func (r *WebhookRepository) Enqueue(ctx context.Context, e *domain.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.ID = uuid.NewString()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = r.now().UTC()
+	}
+	entry := &outboxEntry{event: *e}
+	r.outbox = append(r.outbox, entry)
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, o := range r.outbox {
+			if o == entry {
+				r.outbox = append(r.outbox[:i], r.outbox[i+1:]...)
+				break
+			}
+		}
+	})
+	return nil
+}
+
+func (r *WebhookRepository) CreateSubscription(_ context.Context, sub *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub.ID = uuid.NewString()
+	sub.CreatedAt = r.now().UTC()
+	cp := *sub
+	r.subs[sub.ID] = &cp
+	return nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(_ context.Context, tenantID string) ([]domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.WebhookSubscription
+	for _, s := range r.subs {
+		if s.TenantID == tenantID {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *WebhookRepository) DeleteSubscription(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subs[id]; !ok {
+		return domain.ErrSubscriptionNotFound
+	}
+	delete(r.subs, id)
+	for did, d := range r.deliveries {
+		if d.SubscriptionID == id {
+			delete(r.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (r *WebhookRepository) FanOut(_ context.Context, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	n := 0
+	for _, entry := range r.outbox {
+		if n == limit {
+			break
+		}
+		if entry.processed {
+			continue
+		}
+		for _, s := range r.subs {
+			if !s.Matches(&entry.event) {
+				continue
+			}
+			id := uuid.NewString()
+			r.deliveries[id] = &domain.WebhookDelivery{
+				ID:             id,
+				SubscriptionID: s.ID,
+				Event:          entry.event,
+				Status:         domain.DeliveryPending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+		}
+		entry.processed = true
+		n++
+	}
+	return n, nil
+}
+
+func (r *WebhookRepository) ClaimDue(_ context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	var due []*domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	out := make([]domain.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		cp := *d
+		if s := r.subs[d.SubscriptionID]; s != nil {
+			cp.URL, cp.Secret = s.URL, s.Secret
+		}
+		out = append(out, cp)
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Complete(_ context.Context, id string, res domain.DeliveryResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.deliveries[id]
+	if !ok {
+		return domain.ErrDeliveryNotFound
+	}
+	d.Status = res.Status
+	d.Attempts++
+	d.NextAttemptAt = res.NextAttempt
+	d.LastError = res.Error
+	d.LastStatusCode = res.StatusCode
+	d.UpdatedAt = r.now()
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(_ context.Context, f domain.DeliveryFilter) ([]domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		s := r.subs[d.SubscriptionID]
+		switch {
+		case f.TenantID != "" && (s == nil || s.TenantID != f.TenantID),
+			f.SubscriptionID != "" && d.SubscriptionID != f.SubscriptionID,
+			f.Status != "" && d.Status != f.Status:
+			continue
+		}
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Replay(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.deliveries[id]
+	if !ok {
+		return domain.ErrDeliveryNotFound
+	}
+	d.Status = domain.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = r.now()
+	d.UpdatedAt = d.NextAttemptAt
+	return nil
+}
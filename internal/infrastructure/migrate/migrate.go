@@ -0,0 +1,228 @@
+// Package migrate applies versioned SQL migrations through a database-specific Driver.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNoDown         = errors.New("migrate: migration has no down script")
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+)
+
+// Migration is one schema change. Down is empty when it cannot be reverted.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration file and whether it has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Driver runs migrations against one database.
+type Driver interface {
+	// Lock blocks until the caller holds the migration lock across all
+	// processes sharing the database; unlock releases it.
+	Lock(ctx context.Context) (unlock func(), err error)
+	// Applied creates the version table if needed and returns the applied
+	// versions with the time they were applied.
+	Applied(ctx context.Context) (map[int64]time.Time, error)
+	// Apply runs m.Up (or m.Down) and records (or removes) m.Version atomically.
+	Apply(ctx context.Context, m Migration, up bool) error
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads NNNNNN_name.up.sql / NNNNNN_name.down.sql files from the root of
+// fsys, ignoring anything else, and returns them ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read migrations: %w", err)
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || parts == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: bad version: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: read %s: %w", entry.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migrate: version %d used by %q and %q", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up script", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Runner moves a database between migration versions.
+type Runner struct {
+	driver     Driver
+	migrations []Migration
+	log        *slog.Logger
+}
+
+// New loads the migrations in fsys; see Load.
+func New(driver Driver, fsys fs.FS, log *slog.Logger) (*Runner, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{driver: driver, migrations: migrations, log: log}, nil
+}
+
+// Latest returns the highest known version, or 0 without migrations.
+func (r *Runner) Latest() int64 {
+	if len(r.migrations) == 0 {
+		return 0
+	}
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Up applies every pending migration and returns how many ran.
+func (r *Runner) Up(ctx context.Context) (int, error) {
+	return r.Goto(ctx, r.Latest())
+}
+
+// Down reverts the last steps applied migrations.
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	unlock, err := r.driver.Lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := r.driver.Applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := len(r.migrations) - 1; i >= 0 && n < steps; i-- {
+		m := r.migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := r.apply(ctx, m, false); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Goto applies pending migrations up to and including version and reverts
+// applied ones above it. Version 0 reverts everything.
+func (r *Runner) Goto(ctx context.Context, version int64) (int, error) {
+	if version != 0 && r.find(version) < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	unlock, err := r.driver.Lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := r.driver.Applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := len(r.migrations) - 1; i >= 0; i-- {
+		m := r.migrations[i]
+		if _, ok := applied[m.Version]; ok && m.Version > version {
+			if err := r.apply(ctx, m, false); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	for _, m := range r.migrations {
+		if _, ok := applied[m.Version]; !ok && m.Version <= version {
+			if err := r.apply(ctx, m, true); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Status lists every known migration in version order.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		at, ok := applied[m.Version]
+		out = append(out, Status{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+func (r *Runner) apply(ctx context.Context, m Migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+		if m.Down == "" {
+			return fmt.Errorf("%w: %d (%s)", ErrNoDown, m.Version, m.Name)
+		}
+	}
+	start := time.Now()
+	if err := r.driver.Apply(ctx, m, up); err != nil {
+		return fmt.Errorf("migrate: %d_%s %s: %w", m.Version, m.Name, direction, err)
+	}
+	r.log.Info("migration applied", "version", m.Version, "name", m.Name, "direction", direction, "took", time.Since(start))
+	return nil
+}
+
+func (r *Runner) find(version int64) int {
+	for i, m := range r.migrations {
+		if m.Version == version {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,142 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type fakeDriver struct {
+	applied map[int64]time.Time
+	log     []string
+	locked  bool
+}
+
+func (d *fakeDriver) Lock(context.Context) (func(), error) {
+	if d.locked {
+		return nil, errors.New("already locked")
+	}
+	d.locked = true
+	return func() { d.locked = false }, nil
+}
+
+func (d *fakeDriver) Applied(context.Context) (map[int64]time.Time, error) {
+	if d.applied == nil {
+		d.applied = map[int64]time.Time{}
+	}
+	out := make(map[int64]time.Time, len(d.applied))
+	for k, v := range d.applied {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (d *fakeDriver) Apply(_ context.Context, m Migration, up bool) error {
+	if up {
+		d.applied[m.Version] = time.Now()
+		d.log = append(d.log, m.Up)
+	} else {
+		delete(d.applied, m.Version)
+		d.log = append(d.log, m.Down)
+	}
+	return nil
+}
+
+var testFS = fstest.MapFS{
+	"000001_users.up.sql":     {Data: []byte("u1")},
+	"000001_users.down.sql":   {Data: []byte("d1")},
+	"000002_tokens.up.sql":    {Data: []byte("u2")},
+	"000002_tokens.down.sql":  {Data: []byte("d2")},
+	"000010_audit.up.sql":     {Data: []byte("u10")},
+	"README.md":               {Data: []byte("ignored")},
+	"migrations.go":           {Data: []byte("package migrations")},
+	"000003_notes.sql":        {Data: []byte("ignored, no direction")},
+	"000004_broken.up.sql.gz": {Data: []byte("ignored")},
+}
+
+func newRunner(t *testing.T, d Driver) *Runner {
+	t.Helper()
+	r, err := New(d, testFS, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestLoad(t *testing.T) {
+	ms, err := Load(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Migration{
+		{Version: 1, Name: "users", Up: "u1", Down: "d1"},
+		{Version: 2, Name: "tokens", Up: "u2", Down: "d2"},
+		{Version: 10, Name: "audit", Up: "u10"},
+	}
+	if !reflect.DeepEqual(ms, want) {
+		t.Fatalf("got %+v", ms)
+	}
+
+	if _, err := Load(fstest.MapFS{"000001_a.down.sql": {Data: []byte("x")}}); err == nil {
+		t.Fatal("expected error for missing up script")
+	}
+	if _, err := Load(fstest.MapFS{"000001_a.up.sql": {}, "000001_b.up.sql": {}}); err == nil {
+		t.Fatal("expected error for duplicate version")
+	}
+}
+
+func TestRunner_UpGotoDown(t *testing.T) {
+	ctx := context.Background()
+	d := &fakeDriver{}
+	r := newRunner(t, d)
+
+	if n, err := r.Goto(ctx, 2); err != nil || n != 2 {
+		t.Fatalf("goto 2: n=%d err=%v", n, err)
+	}
+	if n, err := r.Up(ctx); err != nil || n != 1 {
+		t.Fatalf("up: n=%d err=%v", n, err)
+	}
+	if n, err := r.Up(ctx); err != nil || n != 0 {
+		t.Fatalf("second up: n=%d err=%v", n, err)
+	}
+
+	// Version 10 has no down script.
+	if _, err := r.Down(ctx, 1); !errors.Is(err, ErrNoDown) {
+		t.Fatalf("expected ErrNoDown, got %v", err)
+	}
+	delete(d.applied, 10)
+
+	if n, err := r.Goto(ctx, 0); err != nil || n != 2 {
+		t.Fatalf("goto 0: n=%d err=%v", n, err)
+	}
+	if want := []string{"u1", "u2", "u10", "d2", "d1"}; !reflect.DeepEqual(d.log, want) {
+		t.Fatalf("order: got %v want %v", d.log, want)
+	}
+	if d.locked {
+		t.Fatal("lock not released")
+	}
+	if _, err := r.Goto(ctx, 5); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestRunner_Status(t *testing.T) {
+	ctx := context.Background()
+	d := &fakeDriver{}
+	r := newRunner(t, d)
+	if _, err := r.Goto(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	st, err := r.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st) != 3 || !st[0].Applied || st[1].Applied || st[2].Applied || st[2].Version != 10 {
+		t.Fatalf("status: %+v", st)
+	}
+}
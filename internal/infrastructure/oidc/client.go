@@ -0,0 +1,320 @@
+// Package oidc is an OpenID Connect relying party: it discovers upstream
+// providers, builds authorization-code + PKCE requests and validates the ID
+// tokens they return against the provider's JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-auth/internal/app"
+)
+
+// Options tunes a Client. Zero values pick the defaults.
+type Options struct {
+	HTTPClient *http.Client
+	// CacheTTL is how long discovery documents and key sets are reused.
+	// Default 1h; an unknown key ID always triggers a refetch.
+	CacheTTL time.Duration
+	// Leeway is the allowed clock skew for exp/iat/nbf. Default 1m.
+	Leeway time.Duration
+}
+
+// Client implements app.OIDCClient.
+type Client struct {
+	http   *http.Client
+	ttl    time.Duration
+	leeway time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	discovery map[string]cached[*metadata] // by issuer
+	keys      map[string]cached[keySet]    // by jwks_uri
+}
+
+type cached[T any] struct {
+	value     T
+	fetchedAt time.Time
+}
+
+// metadata is the part of the discovery document the client uses.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewClient(opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = time.Hour
+	}
+	if opts.Leeway <= 0 {
+		opts.Leeway = time.Minute
+	}
+	return &Client{
+		http:      opts.HTTPClient,
+		ttl:       opts.CacheTTL,
+		leeway:    opts.Leeway,
+		now:       time.Now,
+		discovery: make(map[string]cached[*metadata]),
+		keys:      make(map[string]cached[keySet]),
+	}
+}
+
+func (c *Client) AuthCodeURL(ctx context.Context, p app.OIDCProvider, req app.OIDCAuthRequest) (string, error) {
+	md, err := c.metadata(ctx, p.Issuer)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(md.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: authorization endpoint: %w", err)
+	}
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", req.RedirectURL)
+	q.Set("scope", scopeString(scopes))
+	q.Set("state", req.State)
+	q.Set("nonce", req.Nonce)
+	q.Set("code_challenge", codeChallenge(req.CodeVerifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (c *Client) Exchange(ctx context.Context, p app.OIDCProvider, req app.OIDCExchange) (*app.UpstreamIdentity, error) {
+	md, err := c.metadata(ctx, p.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := c.redeem(ctx, md, p, req)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := c.verify(ctx, md, p, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != req.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", app.ErrUpstreamRejected)
+	}
+	return &app.UpstreamIdentity{
+		Issuer:        md.Issuer,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// redeem posts the code to the token endpoint and returns the ID token.
+func (c *Client) redeem(ctx context.Context, md *metadata, p app.OIDCProvider, req app.OIDCExchange) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {req.Code},
+		"redirect_uri":  {req.RedirectURL},
+		"code_verifier": {req.CodeVerifier},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("oidc: build token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("oidc: read token response: %w", err)
+	}
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(body, &e)
+		return "", fmt.Errorf("%w: token endpoint: status %d %s", app.ErrUpstreamRejected, resp.StatusCode, e.Error)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("oidc: token endpoint: unexpected status %d", resp.StatusCode)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("oidc: decode token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token in token response", app.ErrUpstreamRejected)
+	}
+	return tokens.IDToken, nil
+}
+
+type idClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string   `json:"nonce"`
+	AuthorizedBy  string   `json:"azp"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// flexBool accepts both true and "true"; some providers send strings.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = flexBool(s == "true")
+	return nil
+}
+
+func (c *Client) verify(ctx context.Context, md *metadata, p app.OIDCProvider, raw string) (*idClaims, error) {
+	var claims idClaims
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256"}),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(c.leeway),
+		jwt.WithTimeFunc(c.now),
+	)
+	_, err := parser.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.key(ctx, md.JWKSURI, kid, t.Method.Alg())
+	})
+	if err != nil {
+		var unavailable *fetchError
+		if errors.As(err, &unavailable) {
+			return nil, unavailable
+		}
+		return nil, fmt.Errorf("%w: id token: %v", app.ErrUpstreamRejected, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: id token has no subject", app.ErrUpstreamRejected)
+	}
+	// With several audiences the token must have been issued to us.
+	if len(claims.Audience) > 1 && claims.AuthorizedBy != p.ClientID {
+		return nil, fmt.Errorf("%w: id token azp %q", app.ErrUpstreamRejected, claims.AuthorizedBy)
+	}
+	return &claims, nil
+}
+
+// fetchError marks failures to reach the provider so they are not reported
+// as rejected tokens.
+type fetchError struct{ err error }
+
+func (e *fetchError) Error() string { return e.err.Error() }
+func (e *fetchError) Unwrap() error { return e.err }
+
+func (c *Client) metadata(ctx context.Context, issuer string) (*metadata, error) {
+	c.mu.Lock()
+	entry, ok := c.discovery[issuer]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.fetchedAt) < c.ttl {
+		return entry.value, nil
+	}
+
+	var md metadata
+	if err := c.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &md); err != nil {
+		return nil, err
+	}
+	if md.Issuer != issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", md.Issuer, issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery document for %s is incomplete", issuer)
+	}
+	c.mu.Lock()
+	c.discovery[issuer] = cached[*metadata]{value: &md, fetchedAt: c.now()}
+	c.mu.Unlock()
+	return &md, nil
+}
+
+// key returns the verification key kid from the key set at jwksURI. An
+// unknown kid refetches the set once, so upstream key rotation is picked up
+// without waiting for the cache to expire.
+func (c *Client) key(ctx context.Context, jwksURI, kid, alg string) (any, error) {
+	c.mu.Lock()
+	entry, ok := c.keys[jwksURI]
+	c.mu.Unlock()
+	fresh := ok && c.now().Sub(entry.fetchedAt) < c.ttl
+	if fresh {
+		if key, found := entry.value.find(kid, alg); found {
+			return key, nil
+		}
+	}
+
+	var doc jwksDocument
+	if err := c.getJSON(ctx, jwksURI, &doc); err != nil {
+		return nil, &fetchError{err: err}
+	}
+	set := doc.parse()
+	c.mu.Lock()
+	c.keys[jwksURI] = cached[keySet]{value: set, fetchedAt: c.now()}
+	c.mu.Unlock()
+	if key, found := set.find(kid, alg); found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key %q for %s", kid, alg)
+}
+
+func (c *Client) getJSON(ctx context.Context, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("oidc: build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: get %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: get %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("oidc: decode %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+func scopeString(scopes []string) string {
+	for _, s := range scopes {
+		if s == "openid" {
+			return strings.Join(scopes, " ")
+		}
+	}
+	return strings.Join(append([]string{"openid"}, scopes...), " ")
+}
+
+// codeChallenge is the RFC 7636 S256 transform of verifier.
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
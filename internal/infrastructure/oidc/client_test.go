@@ -0,0 +1,121 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/oidc"
+	"go-auth/internal/infrastructure/oidc/oidctest"
+)
+
+const redirectURL = "https://app.example.com/oidc/callback"
+
+func login(t *testing.T, c *oidc.Client, provider *oidctest.Provider, nonce string) (*app.UpstreamIdentity, error) {
+	t.Helper()
+	ctx := context.Background()
+	cfg := provider.Config("fake")
+	authURL, err := c.AuthCodeURL(ctx, cfg, app.OIDCAuthRequest{
+		RedirectURL:  redirectURL,
+		State:        "state-1",
+		Nonce:        "nonce-1",
+		CodeVerifier: "verifier-verifier-verifier-verifier-verifier",
+	})
+	if err != nil {
+		t.Fatalf("auth url: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	if q := u.Query(); q.Get("scope") != "openid email profile" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("auth url query = %v", q)
+	}
+	code, state := provider.Authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+	return c.Exchange(ctx, cfg, app.OIDCExchange{
+		Code:         code,
+		RedirectURL:  redirectURL,
+		CodeVerifier: "verifier-verifier-verifier-verifier-verifier",
+		Nonce:        nonce,
+	})
+}
+
+func TestClient_Login(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	provider.SignIn(oidctest.User{Subject: "alice", Email: "Alice@Example.com", EmailVerified: true, Name: "Alice"})
+	c := oidc.NewClient(oidc.Options{})
+
+	id, err := login(t, c, provider, "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if id.Issuer != provider.Issuer || id.Subject != "alice" || id.Email != "alice@example.com" || !id.EmailVerified || id.Name != "Alice" {
+		t.Fatalf("identity = %+v", id)
+	}
+
+	// A rotated key has an unknown kid and must be fetched despite the cache.
+	provider.RotateKey()
+	if _, err := login(t, c, provider, "nonce-1"); err != nil {
+		t.Fatalf("after key rotation: %v", err)
+	}
+}
+
+func TestClient_Rejects(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	c := oidc.NewClient(oidc.Options{})
+
+	if _, err := login(t, c, provider, "other-nonce"); !errors.Is(err, app.ErrUpstreamRejected) {
+		t.Fatalf("nonce mismatch: %v", err)
+	}
+
+	cases := map[string]func(jwt.MapClaims){
+		"audience": func(c jwt.MapClaims) { c["aud"] = "someone-else" },
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"azp":      func(c jwt.MapClaims) { c["aud"] = []string{provider.ClientID, "other"}; c["azp"] = "other" },
+	}
+	for name, modify := range cases {
+		t.Run(name, func(t *testing.T) {
+			provider.ModifyClaims = modify
+			defer func() { provider.ModifyClaims = nil }()
+			if _, err := login(t, c, provider, "nonce-1"); !errors.Is(err, app.ErrUpstreamRejected) {
+				t.Fatalf("got %v, want ErrUpstreamRejected", err)
+			}
+		})
+	}
+}
+
+func TestClient_PKCEAndCodeReuse(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	c := oidc.NewClient(oidc.Options{})
+	ctx := context.Background()
+	cfg := provider.Config("fake")
+
+	authURL, err := c.AuthCodeURL(ctx, cfg, app.OIDCAuthRequest{RedirectURL: redirectURL, State: "s", Nonce: "n", CodeVerifier: "right-verifier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := provider.Authorize(t, authURL)
+	_, err = c.Exchange(ctx, cfg, app.OIDCExchange{Code: code, RedirectURL: redirectURL, CodeVerifier: "wrong-verifier", Nonce: "n"})
+	if !errors.Is(err, app.ErrUpstreamRejected) {
+		t.Fatalf("wrong verifier: %v", err)
+	}
+	_, err = c.Exchange(ctx, cfg, app.OIDCExchange{Code: code, RedirectURL: redirectURL, CodeVerifier: "right-verifier", Nonce: "n"})
+	if !errors.Is(err, app.ErrUpstreamRejected) {
+		t.Fatalf("reused code: %v", err)
+	}
+}
+
+func TestClient_UnreachableIsNotRejection(t *testing.T) {
+	c := oidc.NewClient(oidc.Options{})
+	cfg := app.OIDCProvider{ID: "down", Issuer: "http://127.0.0.1:1", ClientID: "x"}
+	_, err := c.Exchange(context.Background(), cfg, app.OIDCExchange{Code: "c"})
+	if err == nil || errors.Is(err, app.ErrUpstreamRejected) {
+		t.Fatalf("got %v, want a non-rejection error", err)
+	}
+}
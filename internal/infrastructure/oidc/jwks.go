@@ -0,0 +1,120 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"strings"
+)
+
+type jwksDocument struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk is a JSON Web Key; only the RSA and EC public members are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	kid string
+	alg string // empty if the key does not restrict it
+	key any    // *rsa.PublicKey or *ecdsa.PublicKey
+}
+
+type keySet []publicKey
+
+// parse keeps the signing keys it understands and skips the rest, so one
+// exotic key does not break a provider.
+func (d jwksDocument) parse() keySet {
+	var set keySet
+	for _, k := range d.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key any
+		switch k.Kty {
+		case "RSA":
+			n, errN := decodeInt(k.N)
+			e, errE := decodeInt(k.E)
+			if errN != nil || errE != nil || !e.IsInt64() {
+				continue
+			}
+			key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			curve := curveFor(k.Crv)
+			x, errX := decodeInt(k.X)
+			y, errY := decodeInt(k.Y)
+			if curve == nil || errX != nil || errY != nil {
+				continue
+			}
+			key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		default:
+			continue
+		}
+		set = append(set, publicKey{kid: k.Kid, alg: k.Alg, key: key})
+	}
+	return set
+}
+
+// find returns the key with kid usable for alg. A token without kid matches
+// the only key of the right type.
+func (s keySet) find(kid, alg string) (any, bool) {
+	var match []any
+	for _, k := range s {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		if !keyFits(k.key, alg) {
+			continue
+		}
+		match = append(match, k.key)
+	}
+	if len(match) != 1 {
+		return nil, false
+	}
+	return match[0], true
+}
+
+func keyFits(key any, alg string) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	}
+	return false
+}
+
+func curveFor(crv string) elliptic.Curve {
+	switch crv {
+	case "P-256":
+		return elliptic.P256()
+	case "P-384":
+		return elliptic.P384()
+	case "P-521":
+		return elliptic.P521()
+	}
+	return nil
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+// auditChainLock serializes appends so that every event links to its predecessor.
+const auditChainLock = 0x61756469 // "audi"
+
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, e *domain.AuditEvent) error {
+	meta, err := json.Marshal(e.Metadata)
+	if err != nil {
+		return fmt.Errorf("postgres: encode audit metadata: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return wrapErr("begin audit append", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return wrapErr("lock audit chain", err)
+	}
+	prev := ""
+	err = tx.QueryRow(ctx, `SELECT hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&prev)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return wrapErr("read audit chain head", err)
+	}
+	e.PrevHash = prev
+	e.Hash = e.ChainHash(prev)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO audit_events (type, actor_id, target_id, tenant_id, ip, user_agent, request_id, metadata, occurred_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, seq
+	`, e.Type, e.ActorID, e.TargetID, e.TenantID, e.IP, e.UserAgent, e.RequestID, meta, e.OccurredAt, e.PrevHash, e.Hash).Scan(&e.ID, &e.Seq)
+	if err != nil {
+		return wrapErr("insert audit event", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return wrapErr("commit audit append", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, f domain.AuditFilter) ([]domain.AuditEvent, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Type != "" {
+		add("type = $%d", f.Type)
+	}
+	if f.ActorID != "" {
+		add("actor_id = $%d", f.ActorID)
+	}
+	if f.TargetID != "" {
+		add("target_id = $%d", f.TargetID)
+	}
+	if f.TenantID != "" {
+		add("tenant_id = $%d", f.TenantID)
+	}
+	if !f.From.IsZero() {
+		add("occurred_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("occurred_at < $%d", f.To)
+	}
+	if f.BeforeSeq > 0 {
+		add("seq < $%d", f.BeforeSeq)
+	}
+
+	query := `SELECT seq, id, type, actor_id, target_id, tenant_id, ip, user_agent, request_id, metadata, occurred_at, prev_hash, hash FROM audit_events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY seq DESC"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr("list audit events", err)
+	}
+	defer rows.Close()
+
+	var out []domain.AuditEvent
+	for rows.Next() {
+		var (
+			e    domain.AuditEvent
+			meta []byte
+		)
+		if err := rows.Scan(&e.Seq, &e.ID, &e.Type, &e.ActorID, &e.TargetID, &e.TenantID, &e.IP, &e.UserAgent, &e.RequestID, &meta, &e.OccurredAt, &e.PrevHash, &e.Hash); err != nil {
+			return nil, wrapErr("scan audit event", err)
+		}
+		if err := json.Unmarshal(meta, &e.Metadata); err != nil {
+			return nil, fmt.Errorf("postgres: decode audit metadata: %w", err)
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list audit events", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+func TestAuditRepository_AppendOnlyChain(t *testing.T) {
+	ctx, pool := openTestDB(t)
+
+	repo := NewAuditRepository(pool)
+	actor := "it-" + time.Now().Format("150405.000000")
+	for _, typ := range []domain.AuditEventType{domain.AuditLoginFailed, domain.AuditLoginSucceeded} {
+		e := &domain.AuditEvent{Type: typ, ActorID: actor, Metadata: map[string]string{"k": "v"}, OccurredAt: time.Now().UTC().Truncate(time.Microsecond)}
+		if err := repo.Append(ctx, e); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	events, err := repo.List(ctx, domain.AuditFilter{ActorID: actor})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("list: %v %+v", err, events)
+	}
+	if events[0].PrevHash != events[1].Hash || events[0].ChainHash(events[0].PrevHash) != events[0].Hash {
+		t.Fatalf("hash chain does not survive a round trip")
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE audit_events SET actor_id = 'x' WHERE seq = $1`, events[0].Seq); err == nil {
+		t.Fatalf("update of audit event must be rejected")
+	}
+}
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"testing"
+
+	"go-auth/internal/infrastructure/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Store {
+		_, pool := openTestDB(t)
+		return repotest.Store{
+			Tx:              NewTxManager(pool),
+			Users:           NewUserRepository(pool),
+			RefreshTokens:   NewRefreshRepository(pool),
+			Audit:           NewAuditRepository(pool),
+			Outbox:          NewOutboxRepository(pool),
+			Webhooks:        NewWebhookRepository(pool),
+			MagicLinks:      NewMagicLinkRepository(pool),
+			OTPs:            NewOTPRepository(pool),
+			Identities:      NewIdentityRepository(pool),
+			FederatedLogins: NewFederatedLoginRepository(pool),
+			Memberships:     NewMembershipRepository(pool),
+			Roles:           NewRoleRepository(pool),
+			SCIMTokens:      NewSCIMTokenRepository(pool),
+			PersonalTokens:  NewPersonalAccessTokenRepository(pool),
+			ServiceAccounts: NewServiceAccountRepository(pool),
+			Impersonations:  NewImpersonationRepository(pool),
+		}
+	})
+}
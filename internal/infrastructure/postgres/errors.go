@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-auth/internal/domain"
+)
+
+const uniqueViolation = "23505"
+
+// wrapErr annotates err with op and the matching domain error: unique
+// violations become domain.ErrConflict and failures to reach the server
+// domain.ErrUnavailable.
+func wrapErr(op string, err error) error {
+	var (
+		pgErr   *pgconn.PgError
+		connErr *pgconn.ConnectError
+		netErr  net.Error
+	)
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation:
+		return fmt.Errorf("postgres: %s: %w: %w", op, domain.ErrConflict, err)
+	case errors.As(err, &connErr), errors.As(err, &netErr), pgconn.Timeout(err),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("postgres: %s: %w: %w", op, domain.ErrUnavailable, err)
+	default:
+		return fmt.Errorf("postgres: %s: %w", op, err)
+	}
+}
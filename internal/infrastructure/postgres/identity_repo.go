@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type IdentityRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdentityRepository(pool *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{pool: pool}
+}
+
+func (r *IdentityRepository) Create(ctx context.Context, identity *domain.Identity) error {
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt).Scan(&identity.ID)
+	if err != nil {
+		return wrapErr("insert identity", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepository) FindBySubject(ctx context.Context, provider, subject string) (*domain.Identity, error) {
+	var identity domain.Identity
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities WHERE provider = $1 AND subject = $2
+	`, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find identity: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find identity", err)
+	}
+	return &identity, nil
+}
+
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID string) ([]domain.Identity, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, nil
+	}
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, wrapErr("list identities", err)
+	}
+	defer rows.Close()
+	var out []domain.Identity
+	for rows.Next() {
+		var identity domain.Identity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, wrapErr("scan identity", err)
+		}
+		out = append(out, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list identities", err)
+	}
+	return out, nil
+}
+
+type FederatedLoginRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewFederatedLoginRepository(pool *pgxpool.Pool) *FederatedLoginRepository {
+	return &FederatedLoginRepository{pool: pool}
+}
+
+func (r *FederatedLoginRepository) Create(ctx context.Context, login *domain.FederatedLogin) error {
+	if login.CreatedAt.IsZero() {
+		login.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO federated_logins (state_hash, binding_hash, provider, tenant_id, code_verifier, nonce, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, login.StateHash, login.BindingHash, login.Provider, login.TenantID, login.CodeVerifier, login.Nonce, login.ExpiresAt, login.CreatedAt).Scan(&login.ID)
+	if err != nil {
+		return wrapErr("insert federated login", err)
+	}
+	return nil
+}
+
+func (r *FederatedLoginRepository) Consume(ctx context.Context, stateHash, bindingHash string, now time.Time) (*domain.FederatedLogin, error) {
+	var login domain.FederatedLogin
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		UPDATE federated_logins SET consumed_at = $3
+		WHERE state_hash = $1 AND binding_hash = $2 AND consumed_at IS NULL AND expires_at > $3
+		RETURNING id, state_hash, binding_hash, provider, tenant_id, code_verifier, nonce, expires_at, consumed_at, created_at
+	`, stateHash, bindingHash, now).Scan(
+		&login.ID, &login.StateHash, &login.BindingHash, &login.Provider, &login.TenantID,
+		&login.CodeVerifier, &login.Nonce, &login.ExpiresAt, &login.ConsumedAt, &login.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: consume federated login: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("consume federated login", err)
+	}
+	return &login, nil
+}
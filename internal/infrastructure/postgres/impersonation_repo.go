@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type ImpersonationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewImpersonationRepository(pool *pgxpool.Pool) *ImpersonationRepository {
+	return &ImpersonationRepository{pool: pool}
+}
+
+const impersonationColumns = `id, actor_id, user_id, reason, end_reason, created_at, expires_at, ended_at`
+
+func scanImpersonation(row pgx.Row) (*domain.Impersonation, error) {
+	var i domain.Impersonation
+	if err := row.Scan(&i.ID, &i.ActorID, &i.UserID, &i.Reason, &i.EndReason, &i.CreatedAt, &i.ExpiresAt, &i.EndedAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *ImpersonationRepository) Create(ctx context.Context, imp *domain.Impersonation) error {
+	if imp.CreatedAt.IsZero() {
+		imp.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO impersonations (actor_id, user_id, reason, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, imp.ActorID, imp.UserID, imp.Reason, imp.CreatedAt, imp.ExpiresAt).Scan(&imp.ID)
+	if err != nil {
+		return wrapErr("insert impersonation", err)
+	}
+	return nil
+}
+
+func (r *ImpersonationRepository) FindByID(ctx context.Context, id string) (*domain.Impersonation, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("postgres: find impersonation: %w", domain.ErrNotFound)
+	}
+	i, err := scanImpersonation(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+impersonationColumns+` FROM impersonations WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find impersonation: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find impersonation", err)
+	}
+	return i, nil
+}
+
+func (r *ImpersonationRepository) End(ctx context.Context, id string, at time.Time, reason string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: end impersonation: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE impersonations SET ended_at = $1, end_reason = $2 WHERE id = $3 AND ended_at IS NULL`, at, reason, id)
+	if err != nil {
+		return wrapErr("end impersonation", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: end impersonation: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *ImpersonationRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.Impersonation, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT `+impersonationColumns+` FROM impersonations
+		WHERE ended_at IS NULL AND expires_at < $1
+		ORDER BY expires_at, id
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, wrapErr("list expired impersonations", err)
+	}
+	defer rows.Close()
+	var out []domain.Impersonation
+	for rows.Next() {
+		i, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, wrapErr("scan impersonation", err)
+		}
+		out = append(out, *i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list expired impersonations", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type MagicLinkRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewMagicLinkRepository(pool *pgxpool.Pool) *MagicLinkRepository {
+	return &MagicLinkRepository{pool: pool}
+}
+
+func (r *MagicLinkRepository) Create(ctx context.Context, link *domain.MagicLink) error {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO magic_links (email, tenant_id, token_hash, nonce_hash, auto_register, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, link.Email, link.TenantID, link.TokenHash, link.NonceHash, link.AutoRegister, link.ExpiresAt, link.CreatedAt).Scan(&link.ID)
+	if err != nil {
+		return wrapErr("insert magic link", err)
+	}
+	return nil
+}
+
+func (r *MagicLinkRepository) Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (*domain.MagicLink, error) {
+	var link domain.MagicLink
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		UPDATE magic_links SET consumed_at = $3
+		WHERE token_hash = $1 AND nonce_hash = $2 AND consumed_at IS NULL AND expires_at > $3
+		RETURNING id, email, tenant_id, token_hash, nonce_hash, auto_register, expires_at, consumed_at, created_at
+	`, tokenHash, nonceHash, now).Scan(
+		&link.ID, &link.Email, &link.TenantID, &link.TokenHash, &link.NonceHash,
+		&link.AutoRegister, &link.ExpiresAt, &link.ConsumedAt, &link.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: consume magic link: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("consume magic link", err)
+	}
+	return &link, nil
+}
@@ -0,0 +1,309 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type MembershipRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewMembershipRepository(pool *pgxpool.Pool) *MembershipRepository {
+	return &MembershipRepository{pool: pool}
+}
+
+const membershipColumns = `tenant_id, user_id, user_name, external_id, given_name, family_name, display_name, active, created_account, version, created_at, updated_at`
+
+func scanMembership(row pgx.Row) (*domain.Membership, error) {
+	var m domain.Membership
+	if err := row.Scan(&m.TenantID, &m.UserID, &m.UserName, &m.ExternalID, &m.GivenName, &m.FamilyName, &m.DisplayName,
+		&m.Active, &m.CreatedAccount, &m.Version, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *MembershipRepository) Create(ctx context.Context, m *domain.Membership) error {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = m.CreatedAt
+	}
+	_, err := conn(ctx, r.pool).Exec(ctx, `
+		INSERT INTO tenant_memberships (`+membershipColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1, $10, $11)
+	`, m.TenantID, m.UserID, m.UserName, m.ExternalID, m.GivenName, m.FamilyName, m.DisplayName, m.Active, m.CreatedAccount, m.CreatedAt, m.UpdatedAt)
+	if err != nil {
+		return wrapErr("insert membership", err)
+	}
+	m.Version = 1
+	return nil
+}
+
+func (r *MembershipRepository) Get(ctx context.Context, tenantID, userID string) (*domain.Membership, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("postgres: find membership: %w", domain.ErrNotFound)
+	}
+	m, err := scanMembership(conn(ctx, r.pool).QueryRow(ctx, `
+		SELECT `+membershipColumns+` FROM tenant_memberships WHERE tenant_id = $1 AND user_id = $2
+	`, tenantID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find membership: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find membership", err)
+	}
+	return m, nil
+}
+
+func (r *MembershipRepository) List(ctx context.Context, tenantID string) ([]domain.Membership, error) {
+	return r.list(ctx, `WHERE tenant_id = $1`, tenantID)
+}
+
+func (r *MembershipRepository) ListByUser(ctx context.Context, userID string) ([]domain.Membership, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, nil
+	}
+	return r.list(ctx, `WHERE user_id = $1`, userID)
+}
+
+func (r *MembershipRepository) list(ctx context.Context, where string, arg string) ([]domain.Membership, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT `+membershipColumns+` FROM tenant_memberships `+where+`
+		ORDER BY created_at, tenant_id, user_id
+	`, arg)
+	if err != nil {
+		return nil, wrapErr("list memberships", err)
+	}
+	defer rows.Close()
+	var out []domain.Membership
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, wrapErr("scan membership", err)
+		}
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list memberships", err)
+	}
+	return out, nil
+}
+
+func (r *MembershipRepository) Update(ctx context.Context, m *domain.Membership) error {
+	if _, err := uuid.Parse(m.UserID); err != nil {
+		return fmt.Errorf("postgres: update membership: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `
+		UPDATE tenant_memberships
+		SET user_name = $4, external_id = $5, given_name = $6, family_name = $7, display_name = $8, active = $9,
+			version = version + 1, updated_at = $10
+		WHERE tenant_id = $1 AND user_id = $2 AND version = $3
+	`, m.TenantID, m.UserID, m.Version, m.UserName, m.ExternalID, m.GivenName, m.FamilyName, m.DisplayName, m.Active, m.UpdatedAt)
+	if err != nil {
+		return wrapErr("update membership", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: update membership: %w", domain.ErrNotFound)
+	}
+	m.Version++
+	return nil
+}
+
+func (r *MembershipRepository) Delete(ctx context.Context, tenantID, userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return fmt.Errorf("postgres: delete membership: %w", domain.ErrNotFound)
+	}
+	// Role memberships go with it through the foreign key.
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM tenant_memberships WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID)
+	if err != nil {
+		return wrapErr("delete membership", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete membership: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+type RoleRepository struct {
+	pool *pgxpool.Pool
+	tx   *TxManager
+}
+
+func NewRoleRepository(pool *pgxpool.Pool) *RoleRepository {
+	return &RoleRepository{pool: pool, tx: NewTxManager(pool)}
+}
+
+const roleColumns = `id, tenant_id, name, external_id, version, created_at, updated_at`
+
+func (r *RoleRepository) Create(ctx context.Context, role *domain.Role) error {
+	if role.CreatedAt.IsZero() {
+		role.CreatedAt = time.Now().UTC()
+	}
+	if role.UpdatedAt.IsZero() {
+		role.UpdatedAt = role.CreatedAt
+	}
+	var id string
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := conn(ctx, r.pool).QueryRow(ctx, `
+			INSERT INTO tenant_roles (tenant_id, name, external_id, version, created_at, updated_at)
+			VALUES ($1, $2, $3, 1, $4, $5)
+			RETURNING id
+		`, role.TenantID, role.Name, role.ExternalID, role.CreatedAt, role.UpdatedAt).Scan(&id)
+		if err != nil {
+			return wrapErr("insert role", err)
+		}
+		return r.insertMembers(ctx, id, role.TenantID, role.Members)
+	})
+	if err != nil {
+		return err
+	}
+	role.ID, role.Version = id, 1
+	return nil
+}
+
+func (r *RoleRepository) insertMembers(ctx context.Context, roleID, tenantID string, members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, r.pool).Exec(ctx, `
+		INSERT INTO tenant_role_members (role_id, tenant_id, user_id)
+		SELECT $1, $2, m FROM unnest($3::uuid[]) AS m
+		ON CONFLICT DO NOTHING
+	`, roleID, tenantID, members)
+	if err != nil {
+		return wrapErr("insert role members", err)
+	}
+	return nil
+}
+
+func (r *RoleRepository) Get(ctx context.Context, tenantID, id string) (*domain.Role, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("postgres: find role: %w", domain.ErrNotFound)
+	}
+	roles, err := r.list(ctx, `WHERE tenant_id = $1 AND id = $2`, true, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("postgres: find role: %w", domain.ErrNotFound)
+	}
+	return &roles[0], nil
+}
+
+func (r *RoleRepository) List(ctx context.Context, tenantID string) ([]domain.Role, error) {
+	return r.list(ctx, `WHERE tenant_id = $1`, true, tenantID)
+}
+
+func (r *RoleRepository) ListByMember(ctx context.Context, tenantID, userID string) ([]domain.Role, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, nil
+	}
+	return r.list(ctx, `WHERE tenant_id = $1 AND id IN (SELECT role_id FROM tenant_role_members WHERE tenant_id = $1 AND user_id = $2)`,
+		false, tenantID, userID)
+}
+
+func (r *RoleRepository) list(ctx context.Context, where string, withMembers bool, args ...any) ([]domain.Role, error) {
+	db := conn(ctx, r.pool)
+	rows, err := db.Query(ctx, `
+		SELECT `+roleColumns+` FROM tenant_roles `+where+`
+		ORDER BY created_at, id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list roles", err)
+	}
+	var (
+		out   []domain.Role
+		index = map[string]int{}
+	)
+	for rows.Next() {
+		var role domain.Role
+		if err := rows.Scan(&role.ID, &role.TenantID, &role.Name, &role.ExternalID, &role.Version, &role.CreatedAt, &role.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, wrapErr("scan role", err)
+		}
+		index[role.ID] = len(out)
+		out = append(out, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list roles", err)
+	}
+	if !withMembers || len(out) == 0 {
+		return out, nil
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT role_id, user_id FROM tenant_role_members
+		WHERE role_id IN (SELECT id FROM tenant_roles `+where+`)
+		ORDER BY user_id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list role members", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var roleID, userID string
+		if err := rows.Scan(&roleID, &userID); err != nil {
+			return nil, wrapErr("scan role member", err)
+		}
+		i := index[roleID]
+		out[i].Members = append(out[i].Members, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list role members", err)
+	}
+	return out, nil
+}
+
+func (r *RoleRepository) Update(ctx context.Context, role *domain.Role) error {
+	if _, err := uuid.Parse(role.ID); err != nil {
+		return fmt.Errorf("postgres: update role: %w", domain.ErrNotFound)
+	}
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.pool)
+		tag, err := db.Exec(ctx, `
+			UPDATE tenant_roles SET name = $4, external_id = $5, version = version + 1, updated_at = $6
+			WHERE tenant_id = $1 AND id = $2 AND version = $3
+		`, role.TenantID, role.ID, role.Version, role.Name, role.ExternalID, role.UpdatedAt)
+		if err != nil {
+			return wrapErr("update role", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("postgres: update role: %w", domain.ErrNotFound)
+		}
+		if _, err := db.Exec(ctx, `DELETE FROM tenant_role_members WHERE role_id = $1`, role.ID); err != nil {
+			return wrapErr("replace role members", err)
+		}
+		return r.insertMembers(ctx, role.ID, role.TenantID, role.Members)
+	})
+	if err != nil {
+		return err
+	}
+	role.Version++
+	return nil
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, tenantID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: delete role: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM tenant_roles WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return wrapErr("delete role", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete role: %w", domain.ErrNotFound)
+	}
+	return nil
+}
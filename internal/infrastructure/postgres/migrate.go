@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/infrastructure/migrate"
+)
+
+// migrationLock keeps concurrently starting replicas from migrating at once.
+const migrationLock = 0x6d696772 // "migr"
+
+// MigrationDriver implements migrate.Driver for Postgres. Applied versions
+// are recorded in the schema_versions table.
+type MigrationDriver struct {
+	pool *pgxpool.Pool
+}
+
+func NewMigrationDriver(pool *pgxpool.Pool) *MigrationDriver {
+	return &MigrationDriver{pool: pool}
+}
+
+// Lock holds a session-level advisory lock on a dedicated connection, so
+// that each migration can still run in its own transaction.
+func (d *MigrationDriver) Lock(ctx context.Context) (func(), error) {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: acquire migration conn: %w", err)
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("postgres: lock migrations: %w", err)
+	}
+	return func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+		conn.Release()
+	}, nil
+}
+
+func (d *MigrationDriver) Applied(ctx context.Context) (map[int64]time.Time, error) {
+	_, err := d.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_versions (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: create schema_versions: %w", err)
+	}
+
+	rows, err := d.pool.Query(ctx, `SELECT version, applied_at FROM schema_versions`)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: read schema_versions: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			v  int64
+			at time.Time
+		)
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("postgres: scan schema_versions: %w", err)
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+func (d *MigrationDriver) Apply(ctx context.Context, m migrate.Migration, up bool) error {
+	return pgx.BeginFunc(ctx, d.pool, func(tx pgx.Tx) error {
+		script := m.Up
+		if !up {
+			script = m.Down
+		}
+		// Without arguments pgx uses the simple protocol, which accepts
+		// several statements per call.
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+		if up {
+			_, err := tx.Exec(ctx, `INSERT INTO schema_versions (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_versions WHERE version = $1`, m.Version)
+		return err
+	})
+}
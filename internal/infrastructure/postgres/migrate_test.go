@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"log/slog"
+	"testing"
+
+	"go-auth/internal/infrastructure/migrate"
+	"go-auth/migrations"
+)
+
+func TestMigrations_DownAndUpAgain(t *testing.T) {
+	ctx, pool := openTestDB(t)
+
+	runner, err := migrate.New(NewMigrationDriver(pool), migrations.FS, slog.New(slog.NewTextHandler(testWriter{}, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Goto(ctx, 0); err != nil {
+		t.Fatalf("down to 0: %v", err)
+	}
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists); err != nil || exists {
+		t.Fatalf("users table left after full rollback: exists=%v err=%v", exists, err)
+	}
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("up again: %v", err)
+	}
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Fatalf("migration %d not applied after up", s.Version)
+		}
+	}
+}
+
+func TestEmbeddedMigrations_Reversible(t *testing.T) {
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for _, m := range ms {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type OTPRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOTPRepository(pool *pgxpool.Pool) *OTPRepository {
+	return &OTPRepository{pool: pool}
+}
+
+func (r *OTPRepository) Create(ctx context.Context, otp *domain.OTP) error {
+	if otp.CreatedAt.IsZero() {
+		otp.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO otp_codes (purpose, user_id, phone, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, otp.Purpose, otp.UserID, otp.Phone, otp.CodeHash, otp.Attempts, otp.ExpiresAt, otp.CreatedAt).Scan(&otp.ID)
+	if err != nil {
+		return wrapErr("insert otp", err)
+	}
+	return nil
+}
+
+func (r *OTPRepository) RecordAttempt(ctx context.Context, id string) (*domain.OTP, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("postgres: record otp attempt: %w", domain.ErrNotFound)
+	}
+	var otp domain.OTP
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		UPDATE otp_codes SET attempts = attempts + 1
+		WHERE id = $1
+		RETURNING id, purpose, user_id, phone, code_hash, attempts, expires_at, consumed_at, created_at
+	`, id).Scan(
+		&otp.ID, &otp.Purpose, &otp.UserID, &otp.Phone, &otp.CodeHash,
+		&otp.Attempts, &otp.ExpiresAt, &otp.ConsumedAt, &otp.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: record otp attempt: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("record otp attempt", err)
+	}
+	return &otp, nil
+}
+
+func (r *OTPRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: consume otp: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `
+		UPDATE otp_codes SET consumed_at = $2
+		WHERE id = $1 AND consumed_at IS NULL AND expires_at > $2
+	`, id, now)
+	if err != nil {
+		return wrapErr("consume otp", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: consume otp: %w", domain.ErrNotFound)
+	}
+	return nil
+}
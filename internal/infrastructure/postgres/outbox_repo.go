@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type OutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepository(pool *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{pool: pool}
+}
+
+// Enqueue writes e. Call it within the unit of work of the change it describes
+// so that both commit or roll back together.
+func (r *OutboxRepository) Enqueue(ctx context.Context, e *domain.OutboxEvent) error {
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO outbox_events (type, tenant_id, aggregate_id, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, e.Type, e.TenantID, e.AggregateID, []byte(e.Payload), e.CreatedAt).Scan(&e.ID)
+	if err != nil {
+		return wrapErr("insert outbox event", err)
+	}
+	return nil
+}
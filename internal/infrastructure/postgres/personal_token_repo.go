@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type PersonalAccessTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPersonalAccessTokenRepository(pool *pgxpool.Pool) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{pool: pool}
+}
+
+const personalTokenColumns = `id, user_id, name, prefix, scopes, token_hash, expires_at, last_used_at, created_at`
+
+func scanPersonalToken(row pgx.Row) (*domain.PersonalAccessToken, error) {
+	var t domain.PersonalAccessToken
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scopes, &t.TokenHash, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) error {
+	if token.Scopes == nil {
+		token.Scopes = []string{}
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO personal_access_tokens (user_id, name, prefix, scopes, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, token.UserID, token.Name, token.Prefix, token.Scopes, token.TokenHash, token.ExpiresAt, token.CreatedAt).Scan(&token.ID)
+	if err != nil {
+		return wrapErr("insert personal access token", err)
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	t, err := scanPersonalToken(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+personalTokenColumns+` FROM personal_access_tokens WHERE token_hash = $1`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find personal access token: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find personal access token", err)
+	}
+	return t, nil
+}
+
+func (r *PersonalAccessTokenRepository) ListByUser(ctx context.Context, userID string) ([]domain.PersonalAccessToken, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, nil
+	}
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT `+personalTokenColumns+` FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, wrapErr("list personal access tokens", err)
+	}
+	defer rows.Close()
+	var out []domain.PersonalAccessToken
+	for rows.Next() {
+		t, err := scanPersonalToken(rows)
+		if err != nil {
+			return nil, wrapErr("scan personal access token", err)
+		}
+		out = append(out, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list personal access tokens", err)
+	}
+	return out, nil
+}
+
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, userID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: delete personal access token: %w", domain.ErrNotFound)
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return fmt.Errorf("postgres: delete personal access token: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return wrapErr("delete personal access token", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete personal access token: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) DeleteAllByUser(ctx context.Context, userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil
+	}
+	if _, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM personal_access_tokens WHERE user_id = $1`, userID); err != nil {
+		return wrapErr("delete personal access tokens", err)
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: touch personal access token: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2`, at, id)
+	if err != nil {
+		return wrapErr("touch personal access token", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: touch personal access token: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go-auth/internal/domain"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,30 +20,42 @@ func NewRefreshRepository(pool *pgxpool.Pool) *RefreshRepository {
 }
 
 func (r *RefreshRepository) Save(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO refresh_tokens(user_id, token_hash, expires_at) VALUES($1,$2,$3)`, userID, tokenHash, expiresAt)
+	_, err := conn(ctx, r.pool).Exec(ctx, `INSERT INTO refresh_tokens(user_id, token_hash, expires_at) VALUES($1,$2,$3)`, userID, tokenHash, expiresAt)
 	if err != nil {
-		return fmt.Errorf("postgres: save refresh: %w", err)
+		return wrapErr("save refresh", err)
 	}
 	return nil
 }
 
 func (r *RefreshRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
 	var rt domain.RefreshToken
-	err := r.pool.QueryRow(ctx, `SELECT id, user_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash=$1`, tokenHash).Scan(
+	err := conn(ctx, r.pool).QueryRow(ctx, `SELECT id, user_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash=$1`, tokenHash).Scan(
 		&rt.ID, &rt.UserID, &rt.TokenHash, &rt.ExpiresAt, &rt.RevokedAt, &rt.CreatedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find refresh: %w", domain.ErrNotFound)
+	}
 	if err != nil {
-		return nil, nil
+		return nil, wrapErr("find refresh", err)
 	}
 	return &rt, nil
 }
 
 func (r *RefreshRepository) RevokeByHash(ctx context.Context, tokenHash string) error {
-	_, err := r.pool.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=NOW() WHERE token_hash=$1`, tokenHash)
-	return err
+	tag, err := conn(ctx, r.pool).Exec(ctx, `UPDATE refresh_tokens SET revoked_at=NOW() WHERE token_hash=$1 AND revoked_at IS NULL`, tokenHash)
+	if err != nil {
+		return wrapErr("revoke refresh", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: revoke refresh: %w", domain.ErrNotFound)
+	}
+	return nil
 }
 
 func (r *RefreshRepository) RevokeAllByUser(ctx context.Context, userID string) error {
-	_, err := r.pool.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`, userID)
-	return err
+	_, err := conn(ctx, r.pool).Exec(ctx, `UPDATE refresh_tokens SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return wrapErr("revoke user refresh tokens", err)
+	}
+	return nil
 }
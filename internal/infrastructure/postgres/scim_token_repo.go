@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type SCIMTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSCIMTokenRepository(pool *pgxpool.Pool) *SCIMTokenRepository {
+	return &SCIMTokenRepository{pool: pool}
+}
+
+func (r *SCIMTokenRepository) Create(ctx context.Context, token *domain.SCIMToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO scim_tokens (tenant_id, description, token_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, token.TenantID, token.Description, token.TokenHash, token.CreatedAt).Scan(&token.ID)
+	if err != nil {
+		return wrapErr("insert scim token", err)
+	}
+	return nil
+}
+
+func (r *SCIMTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.SCIMToken, error) {
+	var token domain.SCIMToken
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		SELECT id, tenant_id, description, token_hash, created_at FROM scim_tokens WHERE token_hash = $1
+	`, tokenHash).Scan(&token.ID, &token.TenantID, &token.Description, &token.TokenHash, &token.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find scim token: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find scim token", err)
+	}
+	return &token, nil
+}
+
+func (r *SCIMTokenRepository) List(ctx context.Context, tenantID string) ([]domain.SCIMToken, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT id, tenant_id, description, token_hash, created_at FROM scim_tokens
+		WHERE tenant_id = $1
+		ORDER BY created_at, id
+	`, tenantID)
+	if err != nil {
+		return nil, wrapErr("list scim tokens", err)
+	}
+	defer rows.Close()
+	var out []domain.SCIMToken
+	for rows.Next() {
+		var token domain.SCIMToken
+		if err := rows.Scan(&token.ID, &token.TenantID, &token.Description, &token.TokenHash, &token.CreatedAt); err != nil {
+			return nil, wrapErr("scan scim token", err)
+		}
+		out = append(out, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list scim tokens", err)
+	}
+	return out, nil
+}
+
+func (r *SCIMTokenRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: delete scim token: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM scim_tokens WHERE id = $1`, id)
+	if err != nil {
+		return wrapErr("delete scim token", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete scim token: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type ServiceAccountRepository struct {
+	pool *pgxpool.Pool
+	tx   *TxManager
+}
+
+func NewServiceAccountRepository(pool *pgxpool.Pool) *ServiceAccountRepository {
+	return &ServiceAccountRepository{pool: pool, tx: NewTxManager(pool)}
+}
+
+const (
+	serviceAccountColumns    = `id, tenant_id, name, description, disabled_at, created_at, updated_at`
+	serviceCredentialColumns = `id, service_account_id, type, name, prefix, coalesce(secret_hash, ''), public_key, expires_at, last_used_at, created_at`
+)
+
+func (r *ServiceAccountRepository) Create(ctx context.Context, account *domain.ServiceAccount) error {
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+	if account.UpdatedAt.IsZero() {
+		account.UpdatedAt = account.CreatedAt
+	}
+	var id string
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := conn(ctx, r.pool).QueryRow(ctx, `
+			INSERT INTO service_accounts (tenant_id, name, description, disabled_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, account.TenantID, account.Name, account.Description, account.DisabledAt, account.CreatedAt, account.UpdatedAt).Scan(&id)
+		if err != nil {
+			return wrapErr("insert service account", err)
+		}
+		return r.insertRoles(ctx, id, account.Roles)
+	})
+	if err != nil {
+		return err
+	}
+	account.ID = id
+	return nil
+}
+
+func (r *ServiceAccountRepository) insertRoles(ctx context.Context, accountID string, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, r.pool).Exec(ctx, `
+		INSERT INTO service_account_roles (service_account_id, role_id)
+		SELECT $1, role FROM unnest($2::uuid[]) AS role
+		ON CONFLICT DO NOTHING
+	`, accountID, roles)
+	if err != nil {
+		return wrapErr("insert service account roles", err)
+	}
+	return nil
+}
+
+func (r *ServiceAccountRepository) Get(ctx context.Context, id string) (*domain.ServiceAccount, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("postgres: find service account: %w", domain.ErrNotFound)
+	}
+	accounts, err := r.list(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("postgres: find service account: %w", domain.ErrNotFound)
+	}
+	return &accounts[0], nil
+}
+
+func (r *ServiceAccountRepository) List(ctx context.Context, tenantID string) ([]domain.ServiceAccount, error) {
+	return r.list(ctx, `WHERE tenant_id = $1`, tenantID)
+}
+
+func (r *ServiceAccountRepository) list(ctx context.Context, where string, args ...any) ([]domain.ServiceAccount, error) {
+	db := conn(ctx, r.pool)
+	rows, err := db.Query(ctx, `
+		SELECT `+serviceAccountColumns+` FROM service_accounts `+where+`
+		ORDER BY created_at, id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list service accounts", err)
+	}
+	var (
+		out   []domain.ServiceAccount
+		index = map[string]int{}
+	)
+	for rows.Next() {
+		var a domain.ServiceAccount
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.Name, &a.Description, &a.DisabledAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, wrapErr("scan service account", err)
+		}
+		a.Roles = []string{}
+		index[a.ID] = len(out)
+		out = append(out, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list service accounts", err)
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT service_account_id, role_id FROM service_account_roles
+		WHERE service_account_id IN (SELECT id FROM service_accounts `+where+`)
+		ORDER BY role_id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list service account roles", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var accountID, roleID string
+		if err := rows.Scan(&accountID, &roleID); err != nil {
+			return nil, wrapErr("scan service account role", err)
+		}
+		i := index[accountID]
+		out[i].Roles = append(out[i].Roles, roleID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list service account roles", err)
+	}
+	return out, nil
+}
+
+func (r *ServiceAccountRepository) Update(ctx context.Context, account *domain.ServiceAccount) error {
+	if _, err := uuid.Parse(account.ID); err != nil {
+		return fmt.Errorf("postgres: update service account: %w", domain.ErrNotFound)
+	}
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.pool)
+		tag, err := db.Exec(ctx, `
+			UPDATE service_accounts SET name = $2, description = $3, disabled_at = $4, updated_at = $5
+			WHERE id = $1
+		`, account.ID, account.Name, account.Description, account.DisabledAt, account.UpdatedAt)
+		if err != nil {
+			return wrapErr("update service account", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("postgres: update service account: %w", domain.ErrNotFound)
+		}
+		if _, err := db.Exec(ctx, `DELETE FROM service_account_roles WHERE service_account_id = $1`, account.ID); err != nil {
+			return wrapErr("replace service account roles", err)
+		}
+		return r.insertRoles(ctx, account.ID, account.Roles)
+	})
+}
+
+func (r *ServiceAccountRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: delete service account: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM service_accounts WHERE id = $1`, id)
+	if err != nil {
+		return wrapErr("delete service account", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete service account: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func scanServiceCredential(row pgx.Row) (*domain.ServiceAccountCredential, error) {
+	var c domain.ServiceAccountCredential
+	if err := row.Scan(&c.ID, &c.ServiceAccountID, &c.Type, &c.Name, &c.Prefix, &c.SecretHash, &c.PublicKey,
+		&c.ExpiresAt, &c.LastUsedAt, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *ServiceAccountRepository) AddCredential(ctx context.Context, cred *domain.ServiceAccountCredential) error {
+	if _, err := uuid.Parse(cred.ServiceAccountID); err != nil {
+		return fmt.Errorf("postgres: insert service account credential: unknown account %s", cred.ServiceAccountID)
+	}
+	if cred.CreatedAt.IsZero() {
+		cred.CreatedAt = time.Now().UTC()
+	}
+	// Assertion keys have no secret; NULLs keep them out of the unique index.
+	var secretHash *string
+	if cred.SecretHash != "" {
+		secretHash = &cred.SecretHash
+	}
+	err := conn(ctx, r.pool).QueryRow(ctx, `
+		INSERT INTO service_account_credentials (service_account_id, type, name, prefix, secret_hash, public_key, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, cred.ServiceAccountID, cred.Type, cred.Name, cred.Prefix, secretHash, cred.PublicKey, cred.ExpiresAt, cred.CreatedAt).Scan(&cred.ID)
+	if err != nil {
+		return wrapErr("insert service account credential", err)
+	}
+	return nil
+}
+
+func (r *ServiceAccountRepository) ListCredentials(ctx context.Context, accountID string) ([]domain.ServiceAccountCredential, error) {
+	if _, err := uuid.Parse(accountID); err != nil {
+		return nil, nil
+	}
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT `+serviceCredentialColumns+` FROM service_account_credentials
+		WHERE service_account_id = $1
+		ORDER BY created_at, id
+	`, accountID)
+	if err != nil {
+		return nil, wrapErr("list service account credentials", err)
+	}
+	defer rows.Close()
+	var out []domain.ServiceAccountCredential
+	for rows.Next() {
+		c, err := scanServiceCredential(rows)
+		if err != nil {
+			return nil, wrapErr("scan service account credential", err)
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list service account credentials", err)
+	}
+	return out, nil
+}
+
+func (r *ServiceAccountRepository) FindCredentialByHash(ctx context.Context, secretHash string) (*domain.ServiceAccountCredential, error) {
+	c, err := scanServiceCredential(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+serviceCredentialColumns+` FROM service_account_credentials WHERE secret_hash = $1`, secretHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("postgres: find service account credential: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find service account credential", err)
+	}
+	return c, nil
+}
+
+func (r *ServiceAccountRepository) DeleteCredential(ctx context.Context, accountID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: delete service account credential: %w", domain.ErrNotFound)
+	}
+	if _, err := uuid.Parse(accountID); err != nil {
+		return fmt.Errorf("postgres: delete service account credential: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx,
+		`DELETE FROM service_account_credentials WHERE id = $1 AND service_account_id = $2`, id, accountID)
+	if err != nil {
+		return wrapErr("delete service account credential", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete service account credential: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *ServiceAccountRepository) TouchCredential(ctx context.Context, id string, at time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: touch service account credential: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `UPDATE service_account_credentials SET last_used_at = $1 WHERE id = $2`, at, id)
+	if err != nil {
+		return wrapErr("touch service account credential", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: touch service account credential: %w", domain.ErrNotFound)
+	}
+	return nil
+}
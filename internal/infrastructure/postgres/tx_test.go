@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+func TestTxManager_CommitsAndRollsBackTogether(t *testing.T) {
+	ctx, pool := openTestDB(t)
+
+	tx := NewTxManager(pool)
+	users := NewUserRepository(pool)
+	outbox := NewOutboxRepository(pool)
+	countEvents := func(userID string) int {
+		var n int
+		if err := pool.QueryRow(ctx, `SELECT count(*) FROM outbox_events WHERE aggregate_id = $1`, userID).Scan(&n); err != nil {
+			t.Fatalf("count events: %v", err)
+		}
+		return n
+	}
+	createWithEvent := func(ctx context.Context, u *domain.User) error {
+		if err := users.Create(ctx, u); err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, domain.NewUserEvent(domain.EventUserRegistered, "", u))
+	}
+
+	committed := domain.NewUser(fmt.Sprintf("tx-ok-%d@ex.com", time.Now().UnixNano()), "hash")
+	if err := tx.WithinTx(ctx, func(ctx context.Context) error { return createWithEvent(ctx, committed) }); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if got, _ := users.FindByEmail(ctx, committed.Email); got == nil || countEvents(committed.ID) != 1 {
+		t.Fatalf("committed unit of work not visible")
+	}
+
+	boom := errors.New("boom")
+	rolledBack := domain.NewUser(fmt.Sprintf("tx-fail-%d@ex.com", time.Now().UnixNano()), "hash")
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := createWithEvent(ctx, rolledBack); err != nil {
+			return err
+		}
+		// Nested units of work join the outer transaction.
+		if err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			got, err := users.FindByEmail(ctx, rolledBack.Email)
+			if got == nil {
+				return fmt.Errorf("insert not visible inside tx: %v", err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if got, _ := users.FindByEmail(ctx, rolledBack.Email); got != nil {
+		t.Fatalf("user survived rollback")
+	}
+	if n := countEvents(rolledBack.ID); n != 0 {
+		t.Fatalf("outbox event survived rollback: %d", n)
+	}
+}
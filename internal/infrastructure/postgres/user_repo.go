@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go-auth/internal/domain"
@@ -23,51 +25,214 @@ func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
 
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, is_verified, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (email, password_hash, is_verified, locale, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
-	
-	err := r.pool.QueryRow(ctx, query,
+
+	err := conn(ctx, r.pool).QueryRow(ctx, query,
 		user.Email,
 		user.Password, // Stores hash
 		user.IsVerified,
+		user.Locale,
 		user.CreatedAt,
 		user.UpdatedAt,
 	).Scan(&user.ID)
 
 	if err != nil {
-		return fmt.Errorf("postgres: failed to insert user: %w", err)
+		return wrapErr("insert user", err)
 	}
 
 	return nil
 }
 
-func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `
-		SELECT id, email, password_hash, is_verified, created_at, updated_at
-		FROM users
-		WHERE email = $1
-	`
+// userColumns are the columns scanUser reads, in order.
+const userColumns = `id, email, password_hash, is_verified, locale, COALESCE(phone, ''), phone_verified_at, sms_second_factor, disabled_at, created_at, updated_at`
 
+func scanUser(row pgx.Row) (*domain.User, error) {
 	var user domain.User
-	err := r.pool.QueryRow(ctx, query, email).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
 		&user.IsVerified,
+		&user.Locale,
+		&user.Phone,
+		&user.PhoneVerifiedAt,
+		&user.SMSSecondFactor,
+		&user.DisabledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) findBy(ctx context.Context, column, value string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + column + ` = $1`
 
+	user, err := scanUser(conn(ctx, r.pool).QueryRow(ctx, query, value))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil // Not found is not an error in our contract
+			return nil, fmt.Errorf("postgres: find user: %w", domain.ErrNotFound)
 		}
-		return nil, fmt.Errorf("postgres: failed to find user: %w", err)
+		return nil, wrapErr("find user by "+column, err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.findBy(ctx, "email", email)
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("postgres: find user: %w", domain.ErrNotFound)
+	}
+	return r.findBy(ctx, "id", id)
+}
+
+func (r *UserRepository) FindByPhone(ctx context.Context, phone string) (*domain.User, error) {
+	return r.findBy(ctx, "phone", phone)
+}
+
+func (r *UserRepository) UpdatePhone(ctx context.Context, user *domain.User) error {
+	query := `
+		UPDATE users
+		SET phone = NULLIF($2, ''), phone_verified_at = $3, sms_second_factor = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	if _, err := uuid.Parse(user.ID); err != nil {
+		return fmt.Errorf("postgres: update phone: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, query, user.ID, user.Phone, user.PhoneVerifiedAt, user.SMSSecondFactor, user.UpdatedAt)
+	if err != nil {
+		return wrapErr("update phone", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: update phone: %w", domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *domain.User) error {
+	query := `
+		UPDATE users SET email = $2, locale = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	if _, err := uuid.Parse(user.ID); err != nil {
+		return fmt.Errorf("postgres: update profile: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, query, user.ID, user.Email, user.Locale, user.UpdatedAt)
+	if err != nil {
+		return wrapErr("update profile", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: update profile: %w", domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateStatus(ctx context.Context, user *domain.User) error {
+	query := `
+		UPDATE users SET is_verified = $2, disabled_at = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	if _, err := uuid.Parse(user.ID); err != nil {
+		return fmt.Errorf("postgres: update status: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, query, user.ID, user.IsVerified, user.DisabledAt, user.UpdatedAt)
+	if err != nil {
+		return wrapErr("update status", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: update status: %w", domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+// likeEscaper escapes LIKE wildcards; queries declare ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *UserRepository) Search(ctx context.Context, f domain.UserFilter) ([]domain.User, error) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.EmailPrefix != "" {
+		where = append(where, `lower(email) LIKE `+arg(likeEscaper.Replace(strings.ToLower(f.EmailPrefix))+"%")+` ESCAPE '\'`)
+	}
+	if f.Verified != nil {
+		where = append(where, "is_verified = "+arg(*f.Verified))
+	}
+	if !f.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= "+arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		where = append(where, "created_at < "+arg(f.CreatedTo))
+	}
+	if f.Before != nil {
+		id := f.Before.ID
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, nil
+		}
+		where = append(where, "(created_at, id) < ("+arg(f.Before.CreatedAt)+", "+arg(id)+"::uuid)")
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT " + arg(f.Limit)
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr("search users", err)
+	}
+	defer rows.Close()
+	var out []domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, wrapErr("scan user", err)
+		}
+		out = append(out, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("search users", err)
+	}
+	return out, nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("postgres: delete user: %w", domain.ErrNotFound)
+	}
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return wrapErr("delete user", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: delete user: %w", domain.ErrNotFound)
+	}
+
+	return nil
 }
 
 // InitPool initializes a connection pool to Postgres.
@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-auth/internal/domain"
+)
+
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (tenant_id, url, secret, event_types, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, sub.TenantID, sub.URL, sub.Secret, sub.EventTypes, sub.Active).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return wrapErr("insert webhook subscription", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, tenantID string) ([]domain.WebhookSubscription, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, tenant_id, url, secret, event_types, active, created_at
+		FROM webhook_subscriptions
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, wrapErr("list webhook subscriptions", err)
+	}
+	defer rows.Close()
+
+	var out []domain.WebhookSubscription
+	for rows.Next() {
+		var s domain.WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.URL, &s.Secret, &s.EventTypes, &s.Active, &s.CreatedAt); err != nil {
+			return nil, wrapErr("scan webhook subscription", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return wrapErr("delete webhook subscription", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) FanOut(ctx context.Context, limit int) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, wrapErr("begin fan-out", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM outbox_events
+		WHERE processed_at IS NULL
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, wrapErr("claim outbox events", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, wrapErr("claim outbox events", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id)
+		SELECT s.id, e.id
+		FROM outbox_events e
+		JOIN webhook_subscriptions s
+		  ON (s.tenant_id = '' OR s.tenant_id = e.tenant_id)
+		 AND s.active
+		 AND (cardinality(s.event_types) = 0 OR e.type = ANY(s.event_types))
+		WHERE e.id = ANY($1::uuid[])
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`, ids)
+	if err != nil {
+		return 0, wrapErr("create webhook deliveries", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE outbox_events SET processed_at = NOW() WHERE id = ANY($1::uuid[])`, ids); err != nil {
+		return 0, wrapErr("mark outbox events processed", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, wrapErr("commit fan-out", err)
+	}
+	return len(ids), nil
+}
+
+const deliveryColumns = `
+	d.id, d.subscription_id, d.status, d.attempts, d.next_attempt_at, d.last_error, d.last_status_code, d.created_at, d.updated_at,
+	e.id, e.type, e.tenant_id, e.aggregate_id, e.payload, e.created_at,
+	s.url, s.secret`
+
+func scanDelivery(row pgx.Row) (domain.WebhookDelivery, error) {
+	var (
+		d       domain.WebhookDelivery
+		payload []byte
+	)
+	err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.LastStatusCode, &d.CreatedAt, &d.UpdatedAt,
+		&d.Event.ID, &d.Event.Type, &d.Event.TenantID, &d.Event.AggregateID, &payload, &d.Event.CreatedAt,
+		&d.URL, &d.Secret,
+	)
+	d.Event.Payload = payload
+	return d, err
+}
+
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), leased AS (
+			UPDATE webhook_deliveries w
+			SET next_attempt_at = NOW() + $2::interval
+			FROM due
+			WHERE w.id = due.id
+			RETURNING w.*
+		)
+		SELECT `+deliveryColumns+`
+		FROM leased d
+		JOIN outbox_events e ON e.id = d.event_id
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	`, limit, lease)
+	if err != nil {
+		return nil, wrapErr("claim webhook deliveries", err)
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.WebhookDelivery, error) { return scanDelivery(row) })
+	if err != nil {
+		return nil, wrapErr("claim webhook deliveries", err)
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Complete(ctx context.Context, id string, res domain.DeliveryResult) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, next_attempt_at = $3,
+		    last_error = $4, last_status_code = $5, updated_at = NOW()
+		WHERE id = $1
+	`, id, res.Status, res.NextAttempt, res.Error, res.StatusCode)
+	if err != nil {
+		return wrapErr("complete webhook delivery", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, f domain.DeliveryFilter) ([]domain.WebhookDelivery, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.TenantID != "" {
+		add("s.tenant_id = $%d", f.TenantID)
+	}
+	if f.SubscriptionID != "" {
+		add("d.subscription_id = $%d", f.SubscriptionID)
+	}
+	if f.Status != "" {
+		add("d.status = $%d", f.Status)
+	}
+	query := `SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY d.created_at DESC"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr("list webhook deliveries", err)
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.WebhookDelivery, error) { return scanDelivery(row) })
+	if err != nil {
+		return nil, wrapErr("list webhook deliveries", err)
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Replay(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return wrapErr("replay webhook delivery", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var incrScript = goredis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {n, redis.call('PTTL', KEYS[1])}
+`)
+
+// AttemptCounter is a Redis implementation of app.AttemptCounter.
+type AttemptCounter struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+func NewAttemptCounter(client goredis.UniversalClient) *AttemptCounter {
+	return &AttemptCounter{client: client, prefix: "attempts:"}
+}
+
+func (c *AttemptCounter) Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	vals, err := incrScript.Run(ctx, c.client, []string{c.prefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("redis: incr attempts: %w", err)
+	}
+	return int(vals[0]), time.Duration(vals[1]) * time.Millisecond, nil
+}
+
+func (c *AttemptCounter) Get(ctx context.Context, key string) (int, time.Duration, error) {
+	pipe := c.client.Pipeline()
+	get := pipe.Get(ctx, c.prefix+key)
+	ttl := pipe.PTTL(ctx, c.prefix+key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		return 0, 0, fmt.Errorf("redis: get attempts: %w", err)
+	}
+	n, err := get.Int()
+	if errors.Is(err, goredis.Nil) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("redis: get attempts: %w", err)
+	}
+	return n, ttl.Val(), nil
+}
+
+func (c *AttemptCounter) Reset(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		return fmt.Errorf("redis: reset attempts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// InitClient connects to Redis at addr and verifies the connection.
+func InitClient(ctx context.Context, addr string, log *slog.Logger) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{Addr: addr})
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+	log.Info("connected to redis")
+	return client, nil
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"go-auth/internal/app"
+)
+
+func TestRateLimitStore_GCRA(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	s := NewRateLimitStore(client)
+	p := app.RateLimitPolicy{Name: "test", Limit: 3, Window: time.Minute}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		res, err := s.Allow(ctx, "k", p)
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d: res=%+v err=%v", i, res, err)
+		}
+		if res.Remaining != 2-i {
+			t.Fatalf("request %d: remaining=%d", i, res.Remaining)
+		}
+	}
+	res, err := s.Allow(ctx, "k", p)
+	if err != nil || res.Allowed {
+		t.Fatalf("expected rejection, res=%+v err=%v", res, err)
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > 20*time.Second {
+		t.Fatalf("retry after=%v", res.RetryAfter)
+	}
+	if ttl := srv.TTL("ratelimit:k"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("ttl=%v", ttl)
+	}
+
+	if res, _ := s.Allow(ctx, "other", p); !res.Allowed {
+		t.Fatalf("keys must not share budgets")
+	}
+
+	if err := s.Refund(ctx, "k", p); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := s.Allow(ctx, "k", p); err != nil || !res.Allowed {
+		t.Fatalf("refunded request should be available again: res=%+v err=%v", res, err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.Refund(ctx, "k", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if srv.Exists("ratelimit:k") {
+		t.Fatal("fully refunded key kept")
+	}
+}
@@ -0,0 +1,1561 @@
+// Package repotest is a conformance suite for implementations of the domain
+// repository interfaces. Every storage backend runs it against itself so that
+// backends stay interchangeable:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repotest.Store { ... })
+//	}
+//
+// The suite only creates records with unique keys, so it can run against a
+// shared database.
+package repotest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+)
+
+// Store is the set of repositories under test. Nil members are skipped.
+// Outbox and Webhooks must share storage, since fan-out reads the outbox.
+type Store struct {
+	Tx            app.TxManager
+	Users         domain.UserRepository
+	RefreshTokens domain.RefreshTokenRepository
+	Audit         domain.AuditRepository
+	Outbox        domain.OutboxRepository
+	Webhooks      domain.WebhookRepository
+	MagicLinks    domain.MagicLinkRepository
+	OTPs          domain.OTPRepository
+	Identities    domain.IdentityRepository
+	// FederatedLogins is tested on its own; it has no foreign keys.
+	FederatedLogins domain.FederatedLoginRepository
+	// Memberships and Roles must share storage, since roles hold members.
+	Memberships domain.MembershipRepository
+	Roles       domain.RoleRepository
+	SCIMTokens  domain.SCIMTokenRepository
+	// PersonalTokens references Users.
+	PersonalTokens domain.PersonalAccessTokenRepository
+	// ServiceAccounts references Roles.
+	ServiceAccounts domain.ServiceAccountRepository
+	// Impersonations references Users.
+	Impersonations domain.ImpersonationRepository
+}
+
+// Run runs every applicable test. newStore is called once per test.
+func Run(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Run("Users", func(t *testing.T) {
+		users := newStore(t).Users
+		if users == nil {
+			t.Skip("no UserRepository")
+		}
+		testUsers(t, users)
+	})
+	t.Run("RefreshTokens", func(t *testing.T) {
+		s := newStore(t)
+		if s.Users == nil || s.RefreshTokens == nil {
+			t.Skip("no RefreshTokenRepository")
+		}
+		testRefreshTokens(t, s.Users, s.RefreshTokens)
+	})
+	t.Run("Tx", func(t *testing.T) {
+		s := newStore(t)
+		if s.Tx == nil || s.Users == nil || s.RefreshTokens == nil {
+			t.Skip("no TxManager")
+		}
+		testTx(t, s)
+	})
+	t.Run("Audit", func(t *testing.T) {
+		audit := newStore(t).Audit
+		if audit == nil {
+			t.Skip("no AuditRepository")
+		}
+		testAudit(t, audit)
+	})
+	t.Run("Webhooks", func(t *testing.T) {
+		s := newStore(t)
+		if s.Outbox == nil || s.Webhooks == nil {
+			t.Skip("no OutboxRepository/WebhookRepository")
+		}
+		testWebhooks(t, s.Outbox, s.Webhooks)
+	})
+	t.Run("MagicLinks", func(t *testing.T) {
+		links := newStore(t).MagicLinks
+		if links == nil {
+			t.Skip("no MagicLinkRepository")
+		}
+		testMagicLinks(t, links)
+	})
+	t.Run("OTPs", func(t *testing.T) {
+		s := newStore(t)
+		if s.Users == nil || s.OTPs == nil {
+			t.Skip("no OTPRepository")
+		}
+		testOTPs(t, s.Users, s.OTPs)
+	})
+	t.Run("Identities", func(t *testing.T) {
+		s := newStore(t)
+		if s.Users == nil || s.Identities == nil {
+			t.Skip("no IdentityRepository")
+		}
+		testIdentities(t, s.Users, s.Identities)
+	})
+	t.Run("FederatedLogins", func(t *testing.T) {
+		logins := newStore(t).FederatedLogins
+		if logins == nil {
+			t.Skip("no FederatedLoginRepository")
+		}
+		testFederatedLogins(t, logins)
+	})
+	t.Run("Memberships", func(t *testing.T) {
+		s := newStore(t)
+		if s.Users == nil || s.Memberships == nil || s.Roles == nil {
+			t.Skip("no MembershipRepository/RoleRepository")
+		}
+		testMemberships(t, s.Users, s.Memberships, s.Roles)
+	})
+	t.Run("SCIMTokens", func(t *testing.T) {
+		tokens := newStore(t).SCIMTokens
+		if tokens == nil {
+			t.Skip("no SCIMTokenRepository")
+		}
+		testSCIMTokens(t, tokens)
+	})
+	t.Run("PersonalTokens", func(t *testing.T) {
+		s := newStore(t)
+		if s.Users == nil || s.PersonalTokens == nil {
+			t.Skip("no PersonalAccessTokenRepository")
+		}
+		testPersonalTokens(t, s.Users, s.PersonalTokens)
+	})
+	t.Run("ServiceAccounts", func(t *testing.T) {
+		s := newStore(t)
+		if s.Roles == nil || s.ServiceAccounts == nil {
+			t.Skip("no ServiceAccountRepository")
+		}
+		testServiceAccounts(t, s.Roles, s.ServiceAccounts)
+	})
+	t.Run("Impersonations", func(t *testing.T) {
+		s := newStore(t)
+		if s.Users == nil || s.Impersonations == nil {
+			t.Skip("no ImpersonationRepository")
+		}
+		testImpersonations(t, s.Users, s.Impersonations)
+	})
+}
+
+// Backends store timestamps with at least microsecond precision.
+const timeTolerance = time.Millisecond
+
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -timeTolerance && d < timeTolerance
+}
+
+func unique(prefix string) string {
+	return prefix + "-" + uuid.NewString()
+}
+
+// uniquePhone returns a valid E.164 number unlikely to be used by another
+// test run against the same database.
+func uniquePhone() string {
+	id := uuid.New()
+	return fmt.Sprintf("+1%010d", binary.BigEndian.Uint64(id[:8])%1e10)
+}
+
+func createUser(t *testing.T, ctx context.Context, users domain.UserRepository) *domain.User {
+	t.Helper()
+	u := domain.NewUser(unique("user")+"@example.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return u
+}
+
+func testUsers(t *testing.T, users domain.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("CreateAssignsIDAndRoundTrips", func(t *testing.T) {
+		u := domain.NewUser(unique("user")+"@example.com", "hash")
+		u.IsVerified = true
+		u.Locale = "ru"
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if u.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+		got, err := users.FindByEmail(ctx, u.Email)
+		if err != nil || got == nil {
+			t.Fatalf("find: %+v %v", got, err)
+		}
+		if got.ID != u.ID || got.Email != u.Email || got.Password != u.Password || got.IsVerified != u.IsVerified || got.Locale != u.Locale {
+			t.Fatalf("round trip: got %+v want %+v", got, u)
+		}
+		if !sameTime(got.CreatedAt, u.CreatedAt) || !sameTime(got.UpdatedAt, u.UpdatedAt) {
+			t.Fatalf("timestamps: got %v/%v want %v/%v", got.CreatedAt, got.UpdatedAt, u.CreatedAt, u.UpdatedAt)
+		}
+	})
+
+	t.Run("DistinctIDs", func(t *testing.T) {
+		a, b := createUser(t, ctx, users), createUser(t, ctx, users)
+		if a.ID == b.ID {
+			t.Fatalf("two users share ID %s", a.ID)
+		}
+	})
+
+	t.Run("DuplicateEmailRejected", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		if err := users.Create(ctx, domain.NewUser(u.Email, "other")); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("duplicate email: got %v, want ErrConflict", err)
+		}
+		got, err := users.FindByEmail(ctx, u.Email)
+		if err != nil || got == nil || got.ID != u.ID || got.Password != "hash" {
+			t.Fatalf("original user changed by rejected duplicate: %+v %v", got, err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		got, err := users.FindByEmail(ctx, unique("missing")+"@example.com")
+		if got != nil || !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("missing user: got %+v err %v, want ErrNotFound", got, err)
+		}
+	})
+
+	t.Run("FindByID", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		got, err := users.FindByID(ctx, u.ID)
+		if err != nil || got.Email != u.Email {
+			t.Fatalf("find by id: %+v %v", got, err)
+		}
+		for _, id := range []string{uuid.NewString(), "not-a-uuid"} {
+			if _, err := users.FindByID(ctx, id); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("missing id %q: got %v, want ErrNotFound", id, err)
+			}
+		}
+	})
+
+	t.Run("Phone", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		phone := uniquePhone()
+		verifiedAt := time.Now().UTC()
+		u.Phone, u.PhoneVerifiedAt, u.SMSSecondFactor, u.UpdatedAt = phone, &verifiedAt, true, verifiedAt
+		if err := users.UpdatePhone(ctx, u); err != nil {
+			t.Fatalf("update phone: %v", err)
+		}
+		got, err := users.FindByPhone(ctx, phone)
+		if err != nil || got.ID != u.ID || !got.SMSSecondFactor || got.PhoneVerifiedAt == nil || !sameTime(*got.PhoneVerifiedAt, verifiedAt) {
+			t.Fatalf("find by phone: %+v %v", got, err)
+		}
+
+		other := createUser(t, ctx, users)
+		other.Phone = phone
+		if err := users.UpdatePhone(ctx, other); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("taken phone: got %v, want ErrConflict", err)
+		}
+
+		u.Phone, u.PhoneVerifiedAt, u.SMSSecondFactor = "", nil, false
+		if err := users.UpdatePhone(ctx, u); err != nil {
+			t.Fatalf("clear phone: %v", err)
+		}
+		if _, err := users.FindByPhone(ctx, phone); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("cleared phone still found: %v", err)
+		}
+		got, err = users.FindByEmail(ctx, u.Email)
+		if err != nil || got.Phone != "" || got.PhoneVerifiedAt != nil || got.SMSSecondFactor {
+			t.Fatalf("cleared phone: %+v %v", got, err)
+		}
+
+		ghost := domain.NewUser("ghost@example.com", "hash")
+		ghost.ID = uuid.NewString()
+		if err := users.UpdatePhone(ctx, ghost); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("unknown user: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Profile", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		oldEmail := u.Email
+		u.Email, u.Locale, u.UpdatedAt = unique("renamed")+"@example.com", "de", time.Now().UTC()
+		if err := users.UpdateProfile(ctx, u); err != nil {
+			t.Fatalf("update profile: %v", err)
+		}
+		got, err := users.FindByEmail(ctx, u.Email)
+		if err != nil || got.ID != u.ID || got.Locale != "de" || got.Password != "hash" || !sameTime(got.UpdatedAt, u.UpdatedAt) {
+			t.Fatalf("find by new email: %+v %v", got, err)
+		}
+		if _, err := users.FindByEmail(ctx, oldEmail); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("old email still found: %v", err)
+		}
+
+		other := createUser(t, ctx, users)
+		other.Email = u.Email
+		if err := users.UpdateProfile(ctx, other); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("taken email: got %v, want ErrConflict", err)
+		}
+
+		ghost := domain.NewUser(unique("ghost")+"@example.com", "hash")
+		ghost.ID = uuid.NewString()
+		if err := users.UpdateProfile(ctx, ghost); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("unknown user: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		disabledAt := time.Now().UTC()
+		u.IsVerified, u.DisabledAt, u.UpdatedAt = true, &disabledAt, disabledAt
+		if err := users.UpdateStatus(ctx, u); err != nil {
+			t.Fatalf("update status: %v", err)
+		}
+		got, err := users.FindByID(ctx, u.ID)
+		if err != nil || !got.IsVerified || got.DisabledAt == nil || !sameTime(*got.DisabledAt, disabledAt) || got.Password != "hash" {
+			t.Fatalf("disabled user: %+v %v", got, err)
+		}
+		u.DisabledAt = nil
+		if err := users.UpdateStatus(ctx, u); err != nil {
+			t.Fatalf("enable: %v", err)
+		}
+		if got, err := users.FindByEmail(ctx, u.Email); err != nil || got.DisabledAt != nil {
+			t.Fatalf("enabled user: %+v %v", got, err)
+		}
+
+		ghost := domain.NewUser(unique("ghost")+"@example.com", "hash")
+		ghost.ID = uuid.NewString()
+		if err := users.UpdateStatus(ctx, ghost); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("unknown user: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		prefix := unique("Search")
+		base := time.Now().UTC().Truncate(time.Second)
+		var created []*domain.User
+		for i, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+			u := domain.NewUser(prefix+"-"+email, "hash")
+			u.IsVerified = i%2 == 0
+			u.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			if i == 3 {
+				// Ties on CreatedAt are broken by ID.
+				u.CreatedAt = created[2].CreatedAt
+			}
+			if err := users.Create(ctx, u); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			created = append(created, u)
+		}
+		if err := users.Create(ctx, domain.NewUser(unique("other")+"_x@example.com", "hash")); err != nil {
+			t.Fatal(err)
+		}
+		ids := func(us []domain.User) []string {
+			var out []string
+			for _, u := range us {
+				out = append(out, u.ID)
+			}
+			return out
+		}
+
+		all, err := users.Search(ctx, domain.UserFilter{EmailPrefix: strings.ToUpper(prefix)})
+		if err != nil || len(all) != 4 {
+			t.Fatalf("by prefix: %v %v", ids(all), err)
+		}
+		for i := 1; i < len(all); i++ {
+			prev, cur := all[i-1], all[i]
+			if cur.CreatedAt.After(prev.CreatedAt) || cur.CreatedAt.Equal(prev.CreatedAt) && cur.ID > prev.ID {
+				t.Fatalf("not newest first: %+v", all)
+			}
+		}
+
+		var paged []domain.User
+		filter := domain.UserFilter{EmailPrefix: prefix, Limit: 3}
+		for {
+			page, err := users.Search(ctx, filter)
+			if err != nil {
+				t.Fatalf("page: %v", err)
+			}
+			paged = append(paged, page...)
+			if len(page) < filter.Limit {
+				break
+			}
+			last := page[len(page)-1]
+			filter.Before = &domain.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		if !slices.Equal(ids(paged), ids(all)) {
+			t.Fatalf("pages %v, want %v", ids(paged), ids(all))
+		}
+
+		verified := true
+		got, err := users.Search(ctx, domain.UserFilter{EmailPrefix: prefix, Verified: &verified})
+		if err != nil || len(got) != 2 || !got[0].IsVerified || !got[1].IsVerified {
+			t.Fatalf("verified: %v %v", ids(got), err)
+		}
+		got, err = users.Search(ctx, domain.UserFilter{EmailPrefix: prefix, CreatedFrom: base.Add(time.Minute), CreatedTo: base.Add(2 * time.Minute)})
+		if err != nil || len(got) != 1 || got[0].ID != created[1].ID {
+			t.Fatalf("created range: %v %v", ids(got), err)
+		}
+		if got, err := users.Search(ctx, domain.UserFilter{EmailPrefix: prefix + "%"}); err != nil || len(got) != 0 {
+			t.Fatalf("wildcard in prefix matched: %v %v", ids(got), err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		if err := users.Delete(ctx, u.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := users.FindByID(ctx, u.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("deleted user found: %v", err)
+		}
+		if err := users.Delete(ctx, u.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("delete twice: got %v, want ErrNotFound", err)
+		}
+		if err := users.Create(ctx, domain.NewUser(u.Email, "hash")); err != nil {
+			t.Fatalf("email of deleted user not reusable: %v", err)
+		}
+	})
+
+	t.Run("ConcurrentDuplicateCreates", func(t *testing.T) {
+		email := unique("race") + "@example.com"
+		var (
+			wg             sync.WaitGroup
+			won, conflicts atomic.Int32
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				switch err := users.Create(ctx, domain.NewUser(email, "hash")); {
+				case err == nil:
+					won.Add(1)
+				case errors.Is(err, domain.ErrConflict):
+					conflicts.Add(1)
+				default:
+					t.Errorf("create: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if won.Load() != 1 || conflicts.Load() != 7 {
+			t.Fatalf("concurrent creates of one email: %d succeeded, %d conflicted; want 1 and 7", won.Load(), conflicts.Load())
+		}
+	})
+}
+
+func testRefreshTokens(t *testing.T, users domain.UserRepository, tokens domain.RefreshTokenRepository) {
+	ctx := context.Background()
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		hash, expires := unique("hash"), time.Now().Add(time.Hour)
+		if err := tokens.Save(ctx, u.ID, hash, expires); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		got, err := tokens.FindByHash(ctx, hash)
+		if err != nil || got == nil {
+			t.Fatalf("find: %+v %v", got, err)
+		}
+		if got.ID == "" || got.UserID != u.ID || got.TokenHash != hash || got.RevokedAt != nil || !sameTime(got.ExpiresAt, expires) {
+			t.Fatalf("round trip: %+v", got)
+		}
+	})
+
+	t.Run("DuplicateHashRejected", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		hash := unique("hash")
+		if err := tokens.Save(ctx, u.ID, hash, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tokens.Save(ctx, u.ID, hash, time.Now().Add(time.Hour)); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("duplicate token hash: got %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		got, err := tokens.FindByHash(ctx, unique("missing"))
+		if got != nil || !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("missing token: got %+v err %v, want ErrNotFound", got, err)
+		}
+		if err := tokens.RevokeByHash(ctx, unique("missing")); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("revoking a missing token: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ExpiredTokensAreReturned", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		hash, expires := unique("hash"), time.Now().Add(-time.Hour)
+		if err := tokens.Save(ctx, u.ID, hash, expires); err != nil {
+			t.Fatal(err)
+		}
+		got, err := tokens.FindByHash(ctx, hash)
+		if err != nil || got == nil || !sameTime(got.ExpiresAt, expires) {
+			t.Fatalf("expired token: %+v %v", got, err)
+		}
+	})
+
+	t.Run("Revocation", func(t *testing.T) {
+		u, other := createUser(t, ctx, users), createUser(t, ctx, users)
+		h1, h2, h3 := unique("hash"), unique("hash"), unique("hash")
+		for _, rec := range []struct{ user, hash string }{{u.ID, h1}, {u.ID, h2}, {other.ID, h3}} {
+			if err := tokens.Save(ctx, rec.user, rec.hash, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		before := time.Now()
+		if err := tokens.RevokeByHash(ctx, h1); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := tokens.FindByHash(ctx, h1)
+		if got == nil || got.RevokedAt == nil || got.RevokedAt.Before(before.Add(-time.Second)) {
+			t.Fatalf("RevokeByHash: %+v", got)
+		}
+		if got, _ := tokens.FindByHash(ctx, h2); got == nil || got.RevokedAt != nil {
+			t.Fatalf("RevokeByHash touched another token: %+v", got)
+		}
+		// Only one of two concurrent rotations may revoke the token.
+		if err := tokens.RevokeByHash(ctx, h1); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("revoking a revoked token: got %v, want ErrNotFound", err)
+		}
+
+		if err := tokens.RevokeAllByUser(ctx, u.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := tokens.FindByHash(ctx, h2); got == nil || got.RevokedAt == nil {
+			t.Fatalf("RevokeAllByUser missed a token: %+v", got)
+		}
+		if got, _ := tokens.FindByHash(ctx, h3); got == nil || got.RevokedAt != nil {
+			t.Fatalf("RevokeAllByUser revoked another user's token: %+v", got)
+		}
+	})
+
+	t.Run("ConcurrentSaves", func(t *testing.T) {
+		u := createUser(t, ctx, users)
+		hashes := make([]string, 8)
+		errs := make([]error, len(hashes))
+		var wg sync.WaitGroup
+		for i := range hashes {
+			hashes[i] = unique("hash")
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = tokens.Save(ctx, u.ID, hashes[i], time.Now().Add(time.Hour))
+			}(i)
+		}
+		wg.Wait()
+		for i, h := range hashes {
+			if errs[i] != nil {
+				t.Fatalf("save %d: %v", i, errs[i])
+			}
+			if got, _ := tokens.FindByHash(ctx, h); got == nil {
+				t.Fatalf("token %d lost", i)
+			}
+		}
+	})
+}
+
+func testTx(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	t.Run("Commit", func(t *testing.T) {
+		var u *domain.User
+		hash := unique("hash")
+		err := s.Tx.WithinTx(ctx, func(ctx context.Context) error {
+			u = domain.NewUser(unique("user")+"@example.com", "hash")
+			if err := s.Users.Create(ctx, u); err != nil {
+				return err
+			}
+			return s.RefreshTokens.Save(ctx, u.ID, hash, time.Now().Add(time.Hour))
+		})
+		if err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+		if got, _ := s.Users.FindByEmail(ctx, u.Email); got == nil {
+			t.Fatal("committed user missing")
+		}
+		if got, _ := s.RefreshTokens.FindByHash(ctx, hash); got == nil {
+			t.Fatal("committed token missing")
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		existing := createUser(t, ctx, s.Users)
+		kept := unique("hash")
+		if err := s.RefreshTokens.Save(ctx, existing.ID, kept, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		var u *domain.User
+		hash := unique("hash")
+		boom := errors.New("boom")
+		err := s.Tx.WithinTx(ctx, func(ctx context.Context) error {
+			u = domain.NewUser(unique("user")+"@example.com", "hash")
+			if err := s.Users.Create(ctx, u); err != nil {
+				return err
+			}
+			if err := s.RefreshTokens.Save(ctx, u.ID, hash, time.Now().Add(time.Hour)); err != nil {
+				return err
+			}
+			if err := s.RefreshTokens.RevokeAllByUser(ctx, existing.ID); err != nil {
+				return err
+			}
+			// Nested units of work join the outer one.
+			return s.Tx.WithinTx(ctx, func(context.Context) error { return boom })
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("WithinTx returned %v, want the callback error", err)
+		}
+		if _, err := s.Users.FindByEmail(ctx, u.Email); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("user survived rollback: %v", err)
+		}
+		if _, err := s.RefreshTokens.FindByHash(ctx, hash); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("token survived rollback: %v", err)
+		}
+		if got, _ := s.RefreshTokens.FindByHash(ctx, kept); got == nil || got.RevokedAt != nil {
+			t.Fatalf("revocation survived rollback: %+v", got)
+		}
+	})
+}
+
+func testAudit(t *testing.T, audit domain.AuditRepository) {
+	ctx := context.Background()
+
+	t.Run("AppendChainsAndFilters", func(t *testing.T) {
+		actor := unique("actor")
+		types := []domain.AuditEventType{domain.AuditLoginFailed, domain.AuditLoginFailed, domain.AuditLoginSucceeded}
+		for i, typ := range types {
+			e := &domain.AuditEvent{
+				Type:       typ,
+				ActorID:    actor,
+				Metadata:   map[string]string{"i": fmt.Sprint(i)},
+				OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+			}
+			if err := audit.Append(ctx, e); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			if e.ID == "" || e.Seq == 0 || e.Hash != e.ChainHash(e.PrevHash) {
+				t.Fatalf("append did not fill ID/Seq/Hash: %+v", e)
+			}
+		}
+
+		events, err := audit.List(ctx, domain.AuditFilter{ActorID: actor})
+		if err != nil || len(events) != len(types) {
+			t.Fatalf("list: %d events, err %v", len(events), err)
+		}
+		for i := range events {
+			if events[i].Hash != events[i].ChainHash(events[i].PrevHash) {
+				t.Fatalf("event %d hash does not verify", i)
+			}
+			if i > 0 && events[i-1].Seq <= events[i].Seq {
+				t.Fatalf("events not newest first: %d then %d", events[i-1].Seq, events[i].Seq)
+			}
+		}
+		if events[0].Metadata["i"] != "2" {
+			t.Fatalf("metadata: %+v", events[0].Metadata)
+		}
+
+		failed, _ := audit.List(ctx, domain.AuditFilter{ActorID: actor, Type: domain.AuditLoginFailed})
+		if len(failed) != 2 {
+			t.Fatalf("type filter: %d events", len(failed))
+		}
+		page, _ := audit.List(ctx, domain.AuditFilter{ActorID: actor, BeforeSeq: events[0].Seq, Limit: 1})
+		if len(page) != 1 || page[0].Seq != events[1].Seq {
+			t.Fatalf("cursor page: %+v", page)
+		}
+	})
+
+	t.Run("ConcurrentAppendsKeepChain", func(t *testing.T) {
+		actor := unique("actor")
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e := &domain.AuditEvent{Type: domain.AuditLogout, ActorID: actor, OccurredAt: time.Now().UTC().Truncate(time.Microsecond)}
+				if err := audit.Append(ctx, e); err != nil {
+					t.Errorf("append: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		all, err := audit.List(ctx, domain.AuditFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+			all[i], all[j] = all[j], all[i]
+		}
+		if len(all) == 0 {
+			t.Fatal("no events")
+		}
+		if bad := domain.VerifyAuditChain(all[0].PrevHash, all); bad >= 0 {
+			t.Fatalf("chain broken at event seq %d", all[bad].Seq)
+		}
+	})
+}
+
+func testWebhooks(t *testing.T, outbox domain.OutboxRepository, webhooks domain.WebhookRepository) {
+	ctx := context.Background()
+	tenant := unique("tenant")
+
+	all := &domain.WebhookSubscription{TenantID: tenant, URL: "https://all.example", Secret: "s1", Active: true}
+	deleted := &domain.WebhookSubscription{TenantID: tenant, URL: "https://deleted.example", Secret: "s2", EventTypes: []string{domain.EventUserDeleted}, Active: true}
+	inactive := &domain.WebhookSubscription{TenantID: tenant, URL: "https://inactive.example", Secret: "s3", Active: false}
+	other := &domain.WebhookSubscription{TenantID: unique("tenant"), URL: "https://other.example", Secret: "s4", Active: true}
+	global := &domain.WebhookSubscription{URL: "https://global.example", Secret: "s5", EventTypes: []string{domain.EventUserRegistered}, Active: true}
+	for _, s := range []*domain.WebhookSubscription{all, deleted, inactive, other, global} {
+		if err := webhooks.CreateSubscription(ctx, s); err != nil {
+			t.Fatalf("create subscription: %v", err)
+		}
+		if s.ID == "" || s.CreatedAt.IsZero() {
+			t.Fatalf("subscription not filled: %+v", s)
+		}
+	}
+	subs, err := webhooks.ListSubscriptions(ctx, tenant)
+	if err != nil || len(subs) != 3 {
+		t.Fatalf("list subscriptions: %d %v", len(subs), err)
+	}
+
+	event := &domain.OutboxEvent{Type: domain.EventUserRegistered, TenantID: tenant, AggregateID: unique("user"), Payload: []byte(`{"ok":true}`), CreatedAt: time.Now().UTC()}
+	if err := outbox.Enqueue(ctx, event); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if event.ID == "" {
+		t.Fatal("Enqueue did not assign an ID")
+	}
+	for {
+		n, err := webhooks.FanOut(ctx, 100)
+		if err != nil {
+			t.Fatalf("fan-out: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	var mine []domain.WebhookDelivery
+	claimed, err := webhooks.ClaimDue(ctx, 1000, time.Minute)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	for _, d := range claimed {
+		if d.Event.TenantID == tenant {
+			mine = append(mine, d)
+		}
+	}
+	// The tenant's catch-all subscription and the global one match; a
+	// global subscription receives the events of every tenant.
+	if len(mine) != 2 {
+		t.Fatalf("claimed %d deliveries for tenant, want 2", len(mine))
+	}
+	slices.SortFunc(mine, func(a, b domain.WebhookDelivery) int { return strings.Compare(a.URL, b.URL) })
+	if mine[1].SubscriptionID != global.ID {
+		t.Fatalf("global subscription delivery: %+v", mine[1])
+	}
+	if err := webhooks.DeleteSubscription(ctx, global.ID); err != nil {
+		t.Fatalf("delete global subscription: %v", err)
+	}
+	d := mine[0]
+	if d.SubscriptionID != all.ID || d.URL != all.URL || d.Secret != all.Secret || d.Event.ID != event.ID || string(d.Event.Payload) != `{"ok":true}` {
+		t.Fatalf("claimed delivery: %+v", d)
+	}
+	again, _ := webhooks.ClaimDue(ctx, 1000, time.Minute)
+	for _, a := range again {
+		if a.ID == d.ID {
+			t.Fatal("leased delivery claimed twice")
+		}
+	}
+
+	if err := webhooks.Complete(ctx, d.ID, domain.DeliveryResult{Status: domain.DeliveryDead, StatusCode: 500, Error: "boom", NextAttempt: time.Now()}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	dead, err := webhooks.ListDeliveries(ctx, domain.DeliveryFilter{TenantID: tenant, Status: domain.DeliveryDead})
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 1 || dead[0].LastStatusCode != 500 || dead[0].LastError != "boom" {
+		t.Fatalf("dead deliveries: %+v %v", dead, err)
+	}
+	if err := webhooks.Replay(ctx, d.ID); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	pending, _ := webhooks.ListDeliveries(ctx, domain.DeliveryFilter{SubscriptionID: all.ID})
+	if len(pending) != 1 || pending[0].Status != domain.DeliveryPending || pending[0].Attempts != 0 {
+		t.Fatalf("replayed delivery: %+v", pending)
+	}
+
+	if err := webhooks.DeleteSubscription(ctx, all.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := webhooks.DeleteSubscription(ctx, other.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if left, _ := webhooks.ListDeliveries(ctx, domain.DeliveryFilter{TenantID: tenant}); len(left) != 0 {
+		t.Fatalf("deliveries outlived their subscription: %d", len(left))
+	}
+	if err := webhooks.DeleteSubscription(ctx, all.ID); !errors.Is(err, domain.ErrSubscriptionNotFound) {
+		t.Fatalf("delete missing subscription: %v", err)
+	}
+	if err := webhooks.Replay(ctx, d.ID); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Fatalf("replay missing delivery: %v", err)
+	}
+}
+
+func testMagicLinks(t *testing.T, links domain.MagicLinkRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	create := func(t *testing.T, expiresAt time.Time) *domain.MagicLink {
+		t.Helper()
+		link := &domain.MagicLink{
+			Email:        unique("magic") + "@example.com",
+			TenantID:     "t1",
+			TokenHash:    unique("token"),
+			NonceHash:    unique("nonce"),
+			AutoRegister: true,
+			ExpiresAt:    expiresAt,
+		}
+		if err := links.Create(ctx, link); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if link.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+		return link
+	}
+
+	t.Run("ConsumeOnce", func(t *testing.T) {
+		link := create(t, now.Add(10*time.Minute))
+		got, err := links.Consume(ctx, link.TokenHash, link.NonceHash, now)
+		if err != nil {
+			t.Fatalf("consume: %v", err)
+		}
+		if got.ID != link.ID || got.Email != link.Email || got.TenantID != "t1" || !got.AutoRegister ||
+			got.ConsumedAt == nil || !sameTime(got.ExpiresAt, link.ExpiresAt) {
+			t.Fatalf("consumed link: %+v want %+v", got, link)
+		}
+		if _, err := links.Consume(ctx, link.TokenHash, link.NonceHash, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("second consume: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DuplicateTokenRejected", func(t *testing.T) {
+		link := create(t, now.Add(10*time.Minute))
+		dup := *link
+		if err := links.Create(ctx, &dup); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("duplicate token hash: got %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("WrongNonceDoesNotConsume", func(t *testing.T) {
+		link := create(t, now.Add(10*time.Minute))
+		if _, err := links.Consume(ctx, link.TokenHash, "other-nonce", now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("wrong nonce: got %v, want ErrNotFound", err)
+		}
+		if _, err := links.Consume(ctx, link.TokenHash, link.NonceHash, now); err != nil {
+			t.Fatalf("consume after wrong nonce: %v", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		link := create(t, now.Add(-time.Second))
+		if _, err := links.Consume(ctx, link.TokenHash, link.NonceHash, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expired: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ConcurrentConsume", func(t *testing.T) {
+		link := create(t, now.Add(10*time.Minute))
+		var (
+			wg  sync.WaitGroup
+			won atomic.Int32
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				switch _, err := links.Consume(ctx, link.TokenHash, link.NonceHash, now); {
+				case err == nil:
+					won.Add(1)
+				case !errors.Is(err, domain.ErrNotFound):
+					t.Errorf("consume: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := won.Load(); n != 1 {
+			t.Fatalf("%d concurrent consumes succeeded, want 1", n)
+		}
+	})
+}
+
+func testOTPs(t *testing.T, users domain.UserRepository, otps domain.OTPRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	user := createUser(t, ctx, users)
+	create := func(t *testing.T, expiresAt time.Time) *domain.OTP {
+		t.Helper()
+		otp := &domain.OTP{
+			Purpose:   domain.OTPPhoneLogin,
+			UserID:    user.ID,
+			Phone:     uniquePhone(),
+			CodeHash:  unique("code"),
+			ExpiresAt: expiresAt,
+		}
+		if err := otps.Create(ctx, otp); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if otp.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+		return otp
+	}
+
+	t.Run("RecordAttemptCountsAndRoundTrips", func(t *testing.T) {
+		otp := create(t, now.Add(5*time.Minute))
+		for want := 1; want <= 3; want++ {
+			got, err := otps.RecordAttempt(ctx, otp.ID)
+			if err != nil {
+				t.Fatalf("record attempt: %v", err)
+			}
+			if got.Attempts != want || got.Purpose != otp.Purpose || got.UserID != user.ID || got.Phone != otp.Phone ||
+				got.CodeHash != otp.CodeHash || got.ConsumedAt != nil || !sameTime(got.ExpiresAt, otp.ExpiresAt) {
+				t.Fatalf("attempt %d: got %+v want %+v", want, got, otp)
+			}
+		}
+		for _, id := range []string{uuid.NewString(), "not-a-uuid"} {
+			if _, err := otps.RecordAttempt(ctx, id); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("missing otp %q: got %v, want ErrNotFound", id, err)
+			}
+		}
+	})
+
+	t.Run("ConsumeOnce", func(t *testing.T) {
+		otp := create(t, now.Add(5*time.Minute))
+		if err := otps.Consume(ctx, otp.ID, now); err != nil {
+			t.Fatalf("consume: %v", err)
+		}
+		if err := otps.Consume(ctx, otp.ID, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("second consume: got %v, want ErrNotFound", err)
+		}
+		got, err := otps.RecordAttempt(ctx, otp.ID)
+		if err != nil || got.ConsumedAt == nil {
+			t.Fatalf("consumed otp: %+v %v", got, err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		otp := create(t, now.Add(-time.Second))
+		if err := otps.Consume(ctx, otp.ID, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expired: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ConcurrentAttempts", func(t *testing.T) {
+		otp := create(t, now.Add(5*time.Minute))
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			seen = make(map[int]bool)
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := otps.RecordAttempt(ctx, otp.ID)
+				if err != nil {
+					t.Errorf("record attempt: %v", err)
+					return
+				}
+				mu.Lock()
+				seen[got.Attempts] = true
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		if len(seen) != 8 {
+			t.Fatalf("concurrent attempts observed counts %v, want 1..8 each once", seen)
+		}
+	})
+}
+
+func testIdentities(t *testing.T, users domain.UserRepository, identities domain.IdentityRepository) {
+	ctx := context.Background()
+
+	t.Run("CreateFindAndList", func(t *testing.T) {
+		user := createUser(t, ctx, users)
+		first := &domain.Identity{UserID: user.ID, Provider: unique("google"), Subject: unique("sub"), Email: user.Email}
+		if err := identities.Create(ctx, first); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if first.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+		second := &domain.Identity{UserID: user.ID, Provider: unique("gitlab"), Subject: first.Subject, CreatedAt: first.CreatedAt.Add(time.Second)}
+		if err := identities.Create(ctx, second); err != nil {
+			t.Fatalf("same subject at another provider: %v", err)
+		}
+
+		got, err := identities.FindBySubject(ctx, first.Provider, first.Subject)
+		if err != nil || got.ID != first.ID || got.UserID != user.ID || got.Email != user.Email || !sameTime(got.CreatedAt, first.CreatedAt) {
+			t.Fatalf("find: %+v %v", got, err)
+		}
+		list, err := identities.ListByUser(ctx, user.ID)
+		if err != nil || len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Fatalf("list: %+v %v", list, err)
+		}
+		for _, id := range []string{uuid.NewString(), "not-a-uuid"} {
+			if list, err := identities.ListByUser(ctx, id); err != nil || len(list) != 0 {
+				t.Fatalf("list for unknown user %q: %+v %v", id, list, err)
+			}
+		}
+	})
+
+	t.Run("DuplicateSubjectRejected", func(t *testing.T) {
+		identity := &domain.Identity{UserID: createUser(t, ctx, users).ID, Provider: unique("google"), Subject: unique("sub")}
+		if err := identities.Create(ctx, identity); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		dup := &domain.Identity{UserID: createUser(t, ctx, users).ID, Provider: identity.Provider, Subject: identity.Subject}
+		if err := identities.Create(ctx, dup); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("duplicate subject: got %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, err := identities.FindBySubject(ctx, unique("google"), unique("sub")); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("missing identity: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func testFederatedLogins(t *testing.T, logins domain.FederatedLoginRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	create := func(t *testing.T, expiresAt time.Time) *domain.FederatedLogin {
+		t.Helper()
+		login := &domain.FederatedLogin{
+			StateHash:    unique("state"),
+			BindingHash:  unique("binding"),
+			Provider:     "google",
+			TenantID:     "t1",
+			CodeVerifier: unique("verifier"),
+			Nonce:        unique("nonce"),
+			ExpiresAt:    expiresAt,
+		}
+		if err := logins.Create(ctx, login); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if login.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+		return login
+	}
+
+	t.Run("ConsumeOnce", func(t *testing.T) {
+		login := create(t, now.Add(10*time.Minute))
+		got, err := logins.Consume(ctx, login.StateHash, login.BindingHash, now)
+		if err != nil {
+			t.Fatalf("consume: %v", err)
+		}
+		if got.ID != login.ID || got.Provider != "google" || got.TenantID != "t1" || got.CodeVerifier != login.CodeVerifier ||
+			got.Nonce != login.Nonce || got.ConsumedAt == nil || !sameTime(got.ExpiresAt, login.ExpiresAt) {
+			t.Fatalf("consumed login: %+v want %+v", got, login)
+		}
+		if _, err := logins.Consume(ctx, login.StateHash, login.BindingHash, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("second consume: got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DuplicateStateRejected", func(t *testing.T) {
+		login := create(t, now.Add(10*time.Minute))
+		dup := *login
+		if err := logins.Create(ctx, &dup); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("duplicate state hash: got %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("WrongBindingDoesNotConsume", func(t *testing.T) {
+		login := create(t, now.Add(10*time.Minute))
+		if _, err := logins.Consume(ctx, login.StateHash, "other-browser", now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("wrong binding: got %v, want ErrNotFound", err)
+		}
+		if _, err := logins.Consume(ctx, login.StateHash, login.BindingHash, now); err != nil {
+			t.Fatalf("consume after wrong binding: %v", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		login := create(t, now.Add(-time.Second))
+		if _, err := logins.Consume(ctx, login.StateHash, login.BindingHash, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expired: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func testMemberships(t *testing.T, users domain.UserRepository, memberships domain.MembershipRepository, roles domain.RoleRepository) {
+	ctx := context.Background()
+	join := func(t *testing.T, tenantID string, user *domain.User) *domain.Membership {
+		t.Helper()
+		m := &domain.Membership{TenantID: tenantID, UserID: user.ID, UserName: user.Email, Active: true}
+		if err := memberships.Create(ctx, m); err != nil {
+			t.Fatalf("create membership: %v", err)
+		}
+		return m
+	}
+
+	t.Run("CreateGetAndList", func(t *testing.T) {
+		tenant := unique("tenant")
+		user := createUser(t, ctx, users)
+		m := &domain.Membership{
+			TenantID:       tenant,
+			UserID:         user.ID,
+			UserName:       "Jane.Doe",
+			ExternalID:     unique("ext"),
+			GivenName:      "Jane",
+			FamilyName:     "Doe",
+			DisplayName:    "Jane Doe",
+			Active:         true,
+			CreatedAccount: true,
+		}
+		if err := memberships.Create(ctx, m); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if m.Version != 1 {
+			t.Fatalf("version after create: %d, want 1", m.Version)
+		}
+		got, err := memberships.Get(ctx, tenant, user.ID)
+		if err != nil || got.UserName != "Jane.Doe" || got.ExternalID != m.ExternalID || got.GivenName != "Jane" || got.FamilyName != "Doe" ||
+			got.DisplayName != "Jane Doe" || !got.Active || !got.CreatedAccount || got.Version != 1 || !sameTime(got.CreatedAt, m.CreatedAt) {
+			t.Fatalf("get: %+v %v", got, err)
+		}
+
+		second := createUser(t, ctx, users)
+		join(t, tenant, second)
+		elsewhere := join(t, unique("tenant"), user)
+		list, err := memberships.List(ctx, tenant)
+		if err != nil || len(list) != 2 || list[0].UserID != user.ID || list[1].UserID != second.ID {
+			t.Fatalf("list: %+v %v", list, err)
+		}
+		byUser, err := memberships.ListByUser(ctx, user.ID)
+		if err != nil || len(byUser) != 2 || byUser[0].TenantID != tenant || byUser[1].TenantID != elsewhere.TenantID {
+			t.Fatalf("list by user: %+v %v", byUser, err)
+		}
+		for _, id := range []string{uuid.NewString(), "not-a-uuid"} {
+			if _, err := memberships.Get(ctx, tenant, id); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("missing member %q: got %v, want ErrNotFound", id, err)
+			}
+		}
+	})
+
+	t.Run("UserNameUniquePerTenant", func(t *testing.T) {
+		tenant := unique("tenant")
+		first := &domain.Membership{TenantID: tenant, UserID: createUser(t, ctx, users).ID, UserName: "Shared@Example.com"}
+		if err := memberships.Create(ctx, first); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		dup := &domain.Membership{TenantID: tenant, UserID: createUser(t, ctx, users).ID, UserName: "shared@example.com"}
+		if err := memberships.Create(ctx, dup); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("user name differing in case: got %v, want ErrConflict", err)
+		}
+		dup.TenantID = unique("tenant")
+		if err := memberships.Create(ctx, dup); err != nil {
+			t.Fatalf("same user name in another tenant: %v", err)
+		}
+		again := &domain.Membership{TenantID: tenant, UserID: first.UserID, UserName: "other"}
+		if err := memberships.Create(ctx, again); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("second membership in one tenant: got %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("UpdateChecksVersion", func(t *testing.T) {
+		tenant := unique("tenant")
+		m := join(t, tenant, createUser(t, ctx, users))
+		taken := join(t, tenant, createUser(t, ctx, users))
+
+		// CreatedAccount is fixed at creation.
+		m.DisplayName, m.Active, m.CreatedAccount, m.UpdatedAt = "Renamed", false, true, time.Now().UTC()
+		if err := memberships.Update(ctx, m); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if m.Version != 2 {
+			t.Fatalf("version after update: %d, want 2", m.Version)
+		}
+		got, err := memberships.Get(ctx, tenant, m.UserID)
+		if err != nil || got.DisplayName != "Renamed" || got.Active || got.CreatedAccount || got.Version != 2 || !sameTime(got.UpdatedAt, m.UpdatedAt) {
+			t.Fatalf("after update: %+v %v", got, err)
+		}
+
+		stale := *got
+		stale.Version = 1
+		if err := memberships.Update(ctx, &stale); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("stale version: got %v, want ErrNotFound", err)
+		}
+		got.UserName = strings.ToUpper(taken.UserName)
+		if err := memberships.Update(ctx, got); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("taken user name: got %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		tenant := unique("tenant")
+		alice, bob := join(t, tenant, createUser(t, ctx, users)), join(t, tenant, createUser(t, ctx, users))
+		role := &domain.Role{TenantID: tenant, Name: "Admins", ExternalID: unique("ext"), Members: []string{alice.UserID}}
+		if err := roles.Create(ctx, role); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if role.ID == "" || role.Version != 1 {
+			t.Fatalf("create did not assign ID and version: %+v", role)
+		}
+		if err := roles.Create(ctx, &domain.Role{TenantID: tenant, Name: "admins"}); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("duplicate name: got %v, want ErrConflict", err)
+		}
+		if err := roles.Create(ctx, &domain.Role{TenantID: unique("tenant"), Name: "Admins"}); err != nil {
+			t.Fatalf("same name in another tenant: %v", err)
+		}
+
+		got, err := roles.Get(ctx, tenant, role.ID)
+		if err != nil || got.Name != "Admins" || got.ExternalID != role.ExternalID || !slices.Equal(got.Members, []string{alice.UserID}) {
+			t.Fatalf("get: %+v %v", got, err)
+		}
+		if _, err := roles.Get(ctx, unique("tenant"), role.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("role of another tenant: got %v, want ErrNotFound", err)
+		}
+
+		got.Name, got.Members, got.UpdatedAt = "Owners", []string{alice.UserID, bob.UserID}, time.Now().UTC()
+		if err := roles.Update(ctx, got); err != nil || got.Version != 2 {
+			t.Fatalf("update: %v, version %d", err, got.Version)
+		}
+		stale := *got
+		stale.Version = 1
+		if err := roles.Update(ctx, &stale); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("stale version: got %v, want ErrNotFound", err)
+		}
+
+		list, err := roles.List(ctx, tenant)
+		want := []string{alice.UserID, bob.UserID}
+		slices.Sort(want)
+		if err != nil || len(list) != 1 || list[0].Name != "Owners" || !slices.Equal(list[0].Members, want) {
+			t.Fatalf("list: %+v %v", list, err)
+		}
+		held, err := roles.ListByMember(ctx, tenant, bob.UserID)
+		if err != nil || len(held) != 1 || held[0].ID != role.ID {
+			t.Fatalf("list by member: %+v %v", held, err)
+		}
+
+		// Leaving the tenant drops the user from its roles.
+		if err := memberships.Delete(ctx, tenant, bob.UserID); err != nil {
+			t.Fatalf("delete membership: %v", err)
+		}
+		if err := memberships.Delete(ctx, tenant, bob.UserID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("second delete: got %v, want ErrNotFound", err)
+		}
+		got, err = roles.Get(ctx, tenant, role.ID)
+		if err != nil || !slices.Equal(got.Members, []string{alice.UserID}) {
+			t.Fatalf("members after leaving: %+v %v", got, err)
+		}
+
+		if err := roles.Delete(ctx, tenant, role.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if err := roles.Delete(ctx, tenant, role.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("second delete: got %v, want ErrNotFound", err)
+		}
+		if held, err := roles.ListByMember(ctx, tenant, alice.UserID); err != nil || len(held) != 0 {
+			t.Fatalf("roles after delete: %+v %v", held, err)
+		}
+	})
+}
+
+func testSCIMTokens(t *testing.T, tokens domain.SCIMTokenRepository) {
+	ctx := context.Background()
+	tenant := unique("tenant")
+	first := &domain.SCIMToken{TenantID: tenant, Description: "Okta", TokenHash: unique("hash")}
+	if err := tokens.Create(ctx, first); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if first.ID == "" {
+		t.Fatal("Create did not assign an ID")
+	}
+	second := &domain.SCIMToken{TenantID: tenant, TokenHash: unique("hash"), CreatedAt: first.CreatedAt.Add(time.Second)}
+	if err := tokens.Create(ctx, second); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := tokens.Create(ctx, &domain.SCIMToken{TenantID: tenant, TokenHash: first.TokenHash}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate hash: got %v, want ErrConflict", err)
+	}
+
+	got, err := tokens.FindByHash(ctx, first.TokenHash)
+	if err != nil || got.ID != first.ID || got.TenantID != tenant || got.Description != "Okta" || !sameTime(got.CreatedAt, first.CreatedAt) {
+		t.Fatalf("find: %+v %v", got, err)
+	}
+	list, err := tokens.List(ctx, tenant)
+	if err != nil || len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+
+	if err := tokens.Delete(ctx, first.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := tokens.FindByHash(ctx, first.TokenHash); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("deleted token: got %v, want ErrNotFound", err)
+	}
+	for _, id := range []string{first.ID, "not-a-uuid"} {
+		if err := tokens.Delete(ctx, id); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("delete missing %q: got %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func testPersonalTokens(t *testing.T, users domain.UserRepository, tokens domain.PersonalAccessTokenRepository) {
+	ctx := context.Background()
+	owner, other := createUser(t, ctx, users), createUser(t, ctx, users)
+	expires := time.Now().UTC().Add(time.Hour)
+	first := &domain.PersonalAccessToken{
+		UserID:    owner.ID,
+		Name:      "ci",
+		Prefix:    "pat_abcd",
+		Scopes:    []string{domain.ScopeUserRead, domain.ScopeAdminRead},
+		TokenHash: unique("hash"),
+		ExpiresAt: &expires,
+	}
+	if err := tokens.Create(ctx, first); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if first.ID == "" {
+		t.Fatal("Create did not assign an ID")
+	}
+	second := &domain.PersonalAccessToken{UserID: owner.ID, Name: "backup", TokenHash: unique("hash"), CreatedAt: first.CreatedAt.Add(time.Second)}
+	if err := tokens.Create(ctx, second); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := tokens.Create(ctx, &domain.PersonalAccessToken{UserID: other.ID, Name: "dup", TokenHash: first.TokenHash}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate hash: got %v, want ErrConflict", err)
+	}
+
+	got, err := tokens.FindByHash(ctx, first.TokenHash)
+	if err != nil || got.ID != first.ID || got.UserID != owner.ID || got.Name != "ci" || got.Prefix != "pat_abcd" ||
+		!slices.Equal(got.Scopes, first.Scopes) || got.ExpiresAt == nil || !sameTime(*got.ExpiresAt, expires) || got.LastUsedAt != nil {
+		t.Fatalf("find: %+v %v", got, err)
+	}
+	if _, err := tokens.FindByHash(ctx, unique("hash")); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("unknown hash: got %v, want ErrNotFound", err)
+	}
+
+	usedAt := time.Now().UTC()
+	if err := tokens.Touch(ctx, first.ID, usedAt); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	list, err := tokens.ListByUser(ctx, owner.ID)
+	if err != nil || len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID ||
+		list[0].LastUsedAt == nil || !sameTime(*list[0].LastUsedAt, usedAt) || list[1].ExpiresAt != nil || len(list[1].Scopes) != 0 {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+
+	if err := tokens.Delete(ctx, other.ID, first.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete another user's token: got %v, want ErrNotFound", err)
+	}
+	if err := tokens.Delete(ctx, owner.ID, first.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, id := range []string{first.ID, "not-a-uuid"} {
+		if err := tokens.Delete(ctx, owner.ID, id); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("delete missing %q: got %v, want ErrNotFound", id, err)
+		}
+		if err := tokens.Touch(ctx, id, usedAt); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("touch missing %q: got %v, want ErrNotFound", id, err)
+		}
+	}
+
+	if err := tokens.DeleteAllByUser(ctx, owner.ID); err != nil {
+		t.Fatalf("delete all: %v", err)
+	}
+	if list, err := tokens.ListByUser(ctx, owner.ID); err != nil || len(list) != 0 {
+		t.Fatalf("list after delete all: %+v %v", list, err)
+	}
+}
+
+func testServiceAccounts(t *testing.T, roles domain.RoleRepository, accounts domain.ServiceAccountRepository) {
+	ctx := context.Background()
+	tenant := unique("tenant")
+	role := &domain.Role{TenantID: tenant, Name: "deployers"}
+	if err := roles.Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+
+	ci := &domain.ServiceAccount{TenantID: tenant, Name: "ci", Description: "Build pipeline", Roles: []string{role.ID}}
+	if err := accounts.Create(ctx, ci); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if ci.ID == "" {
+		t.Fatal("Create did not assign an ID")
+	}
+	if err := accounts.Create(ctx, &domain.ServiceAccount{TenantID: tenant, Name: "CI"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate name: got %v, want ErrConflict", err)
+	}
+	if err := accounts.Create(ctx, &domain.ServiceAccount{TenantID: unique("tenant"), Name: "ci"}); err != nil {
+		t.Fatalf("same name in another tenant: %v", err)
+	}
+	bot := &domain.ServiceAccount{TenantID: tenant, Name: "bot", CreatedAt: ci.CreatedAt.Add(time.Second)}
+	if err := accounts.Create(ctx, bot); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := accounts.Get(ctx, ci.ID)
+	if err != nil || got.TenantID != tenant || got.Name != "ci" || got.Description != "Build pipeline" ||
+		!slices.Equal(got.Roles, []string{role.ID}) || got.DisabledAt != nil || !sameTime(got.CreatedAt, ci.CreatedAt) {
+		t.Fatalf("get: %+v %v", got, err)
+	}
+	list, err := accounts.List(ctx, tenant)
+	if err != nil || len(list) != 2 || list[0].ID != ci.ID || list[1].ID != bot.ID || len(list[1].Roles) != 0 {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+
+	disabledAt := time.Now().UTC()
+	got.Name, got.Roles, got.DisabledAt, got.UpdatedAt = "ci-runner", nil, &disabledAt, disabledAt
+	if err := accounts.Update(ctx, got); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err = accounts.Get(ctx, ci.ID)
+	if err != nil || got.Name != "ci-runner" || len(got.Roles) != 0 || got.DisabledAt == nil || !sameTime(*got.DisabledAt, disabledAt) {
+		t.Fatalf("get updated: %+v %v", got, err)
+	}
+	got.Name = "BOT"
+	if err := accounts.Update(ctx, got); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("rename to taken name: got %v, want ErrConflict", err)
+	}
+
+	expires := time.Now().UTC().Add(time.Hour)
+	key := &domain.ServiceAccountCredential{ServiceAccountID: ci.ID, Type: domain.CredentialAPIKey, Name: "deploy",
+		Prefix: "sak_abcd", SecretHash: unique("hash"), ExpiresAt: &expires}
+	if err := accounts.AddCredential(ctx, key); err != nil {
+		t.Fatalf("add api key: %v", err)
+	}
+	pub := &domain.ServiceAccountCredential{ServiceAccountID: ci.ID, Type: domain.CredentialPublicKey, Name: "signer",
+		PublicKey: "-----BEGIN PUBLIC KEY-----", CreatedAt: key.CreatedAt.Add(time.Second)}
+	if err := accounts.AddCredential(ctx, pub); err != nil {
+		t.Fatalf("add public key: %v", err)
+	}
+	if err := accounts.AddCredential(ctx, &domain.ServiceAccountCredential{ServiceAccountID: bot.ID, Type: domain.CredentialPublicKey, Name: "other"}); err != nil {
+		t.Fatalf("second key without secret: %v", err)
+	}
+	if err := accounts.AddCredential(ctx, &domain.ServiceAccountCredential{ServiceAccountID: bot.ID, Type: domain.CredentialAPIKey,
+		Name: "dup", SecretHash: key.SecretHash}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate hash: got %v, want ErrConflict", err)
+	}
+
+	found, err := accounts.FindCredentialByHash(ctx, key.SecretHash)
+	if err != nil || found.ID != key.ID || found.ServiceAccountID != ci.ID || found.Type != domain.CredentialAPIKey ||
+		found.Prefix != "sak_abcd" || found.ExpiresAt == nil || !sameTime(*found.ExpiresAt, expires) {
+		t.Fatalf("find: %+v %v", found, err)
+	}
+	if _, err := accounts.FindCredentialByHash(ctx, unique("hash")); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("unknown hash: got %v, want ErrNotFound", err)
+	}
+	usedAt := time.Now().UTC()
+	if err := accounts.TouchCredential(ctx, pub.ID, usedAt); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	creds, err := accounts.ListCredentials(ctx, ci.ID)
+	if err != nil || len(creds) != 2 || creds[0].ID != key.ID || creds[1].ID != pub.ID || creds[1].SecretHash != "" ||
+		creds[1].PublicKey != pub.PublicKey || creds[1].LastUsedAt == nil || !sameTime(*creds[1].LastUsedAt, usedAt) {
+		t.Fatalf("list credentials: %+v %v", creds, err)
+	}
+
+	if err := accounts.DeleteCredential(ctx, bot.ID, key.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete another account's credential: got %v, want ErrNotFound", err)
+	}
+	if err := accounts.DeleteCredential(ctx, ci.ID, key.ID); err != nil {
+		t.Fatalf("delete credential: %v", err)
+	}
+	for _, id := range []string{key.ID, "not-a-uuid"} {
+		if err := accounts.DeleteCredential(ctx, ci.ID, id); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("delete missing credential %q: got %v, want ErrNotFound", id, err)
+		}
+		if err := accounts.TouchCredential(ctx, id, usedAt); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("touch missing %q: got %v, want ErrNotFound", id, err)
+		}
+	}
+
+	if err := accounts.Delete(ctx, ci.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if creds, err := accounts.ListCredentials(ctx, ci.ID); err != nil || len(creds) != 0 {
+		t.Fatalf("credentials after delete: %+v %v", creds, err)
+	}
+	for _, id := range []string{ci.ID, "not-a-uuid"} {
+		if _, err := accounts.Get(ctx, id); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("get missing %q: got %v, want ErrNotFound", id, err)
+		}
+		if err := accounts.Delete(ctx, id); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("delete missing %q: got %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func testImpersonations(t *testing.T, users domain.UserRepository, imps domain.ImpersonationRepository) {
+	ctx := context.Background()
+	admin, user := createUser(t, ctx, users), createUser(t, ctx, users)
+	now := time.Now().UTC()
+	imp := &domain.Impersonation{ActorID: admin.ID, UserID: user.ID, Reason: "ticket 42", ExpiresAt: now.Add(time.Hour)}
+	if err := imps.Create(ctx, imp); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if imp.ID == "" || imp.CreatedAt.IsZero() {
+		t.Fatalf("Create did not assign ID and CreatedAt: %+v", imp)
+	}
+	got, err := imps.FindByID(ctx, imp.ID)
+	if err != nil || got.ActorID != admin.ID || got.UserID != user.ID || got.Reason != "ticket 42" ||
+		!sameTime(got.ExpiresAt, imp.ExpiresAt) || !sameTime(got.CreatedAt, imp.CreatedAt) || got.EndedAt != nil || !got.Active(now) {
+		t.Fatalf("find: %+v %v", got, err)
+	}
+	if _, err := imps.FindByID(ctx, "not-a-uuid"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("find missing: got %v, want ErrNotFound", err)
+	}
+
+	// Expiry far in the past keeps other runs' records out of the way.
+	long := now.Add(-100 * 365 * 24 * time.Hour)
+	expired := []*domain.Impersonation{
+		{ActorID: admin.ID, UserID: user.ID, ExpiresAt: long.Add(time.Second)},
+		{ActorID: admin.ID, UserID: user.ID, ExpiresAt: long},
+	}
+	for _, e := range expired {
+		if err := imps.Create(ctx, e); err != nil {
+			t.Fatalf("create expired: %v", err)
+		}
+	}
+	list, err := imps.ListExpired(ctx, long.Add(time.Minute), 1)
+	if err != nil || len(list) != 1 || list[0].ID != expired[1].ID {
+		t.Fatalf("list expired: %+v %v", list, err)
+	}
+
+	endedAt := now.Add(time.Minute)
+	if err := imps.End(ctx, imp.ID, endedAt, "ended"); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	if got, err := imps.FindByID(ctx, imp.ID); err != nil || got.EndedAt == nil || !sameTime(*got.EndedAt, endedAt) ||
+		got.EndReason != "ended" || got.Active(now) {
+		t.Fatalf("find ended: %+v %v", got, err)
+	}
+	for _, id := range []string{imp.ID, uuid.NewString(), "not-a-uuid"} {
+		if err := imps.End(ctx, id, endedAt, "ended"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("end %q again or missing: got %v, want ErrNotFound", id, err)
+		}
+	}
+	for _, e := range expired {
+		if err := imps.End(ctx, e.ID, endedAt, "expired"); err != nil {
+			t.Fatalf("end expired: %v", err)
+		}
+	}
+	if list, err := imps.ListExpired(ctx, long.Add(time.Minute), 10); err != nil || len(list) != 0 {
+		t.Fatalf("list after ending: %+v %v", list, err)
+	}
+}
@@ -0,0 +1,145 @@
+package saml
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/beevik/etree"
+
+	"go-auth/internal/app"
+)
+
+// maxMetadataSize bounds metadata documents; aggregate federation feeds are
+// not supported.
+const maxMetadataSize = 1 << 20
+
+// LoadMetadata imports the metadata of idp from its MetadataFile or
+// MetadataURL.
+func LoadMetadata(ctx context.Context, client *http.Client, idp app.SAMLIdP) (app.SAMLIdPMetadata, error) {
+	var raw []byte
+	switch {
+	case idp.MetadataFile != "":
+		b, err := os.ReadFile(idp.MetadataFile)
+		if err != nil {
+			return app.SAMLIdPMetadata{}, fmt.Errorf("saml: read idp metadata: %w", err)
+		}
+		raw = b
+	case idp.MetadataURL != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, idp.MetadataURL, nil)
+		if err != nil {
+			return app.SAMLIdPMetadata{}, fmt.Errorf("saml: fetch idp metadata: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return app.SAMLIdPMetadata{}, fmt.Errorf("saml: fetch idp metadata: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return app.SAMLIdPMetadata{}, fmt.Errorf("saml: fetch idp metadata: status %d", resp.StatusCode)
+		}
+		if raw, err = io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize)); err != nil {
+			return app.SAMLIdPMetadata{}, fmt.Errorf("saml: fetch idp metadata: %w", err)
+		}
+	default:
+		return app.SAMLIdPMetadata{}, fmt.Errorf("saml: idp %q has neither metadata_file nor metadata_url", idp.ID)
+	}
+	return ParseIdPMetadata(raw, idp.EntityID)
+}
+
+// ParseIdPMetadata reads an EntityDescriptor, or an EntitiesDescriptor from
+// which entityID picks the IdP. It needs an HTTP-Redirect SSO endpoint and
+// at least one signing certificate.
+func ParseIdPMetadata(raw []byte, entityID string) (app.SAMLIdPMetadata, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return app.SAMLIdPMetadata{}, fmt.Errorf("saml: parse idp metadata: %w", err)
+	}
+	if doc.Root() == nil {
+		return app.SAMLIdPMetadata{}, fmt.Errorf("saml: parse idp metadata: empty document")
+	}
+
+	var found []*etree.Element
+	for _, entity := range entityDescriptors(doc.Root()) {
+		if child(entity, nsMetadata, "IDPSSODescriptor") == nil {
+			continue
+		}
+		if entityID == "" || entity.SelectAttrValue("entityID", "") == entityID {
+			found = append(found, entity)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return app.SAMLIdPMetadata{}, fmt.Errorf("saml: idp metadata describes no matching identity provider")
+	case len(found) > 1:
+		return app.SAMLIdPMetadata{}, fmt.Errorf("saml: idp metadata describes %d identity providers, set entity_id", len(found))
+	}
+	entity := found[0]
+
+	md := app.SAMLIdPMetadata{EntityID: entity.SelectAttrValue("entityID", "")}
+	desc := child(entity, nsMetadata, "IDPSSODescriptor")
+	for _, sso := range children(desc, nsMetadata, "SingleSignOnService") {
+		if sso.SelectAttrValue("Binding", "") == bindingRedirect {
+			md.SSOURL = sso.SelectAttrValue("Location", "")
+			break
+		}
+	}
+	for _, key := range children(desc, nsMetadata, "KeyDescriptor") {
+		if use := key.SelectAttrValue("use", "signing"); use != "signing" {
+			continue
+		}
+		info := child(key, nsDSig, "KeyInfo")
+		if info == nil {
+			continue
+		}
+		for _, data := range children(info, nsDSig, "X509Data") {
+			for _, c := range children(data, nsDSig, "X509Certificate") {
+				der, err := base64.StdEncoding.DecodeString(stripSpace(c.Text()))
+				if err != nil {
+					return app.SAMLIdPMetadata{}, fmt.Errorf("saml: idp certificate: %w", err)
+				}
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return app.SAMLIdPMetadata{}, fmt.Errorf("saml: idp certificate: %w", err)
+				}
+				md.Certificates = append(md.Certificates, cert)
+			}
+		}
+	}
+
+	if md.EntityID == "" || md.SSOURL == "" || len(md.Certificates) == 0 {
+		return app.SAMLIdPMetadata{}, fmt.Errorf("saml: idp metadata needs an entityID, an HTTP-Redirect SSO endpoint and a signing certificate")
+	}
+	return md, nil
+}
+
+func entityDescriptors(el *etree.Element) []*etree.Element {
+	if el.NamespaceURI() != nsMetadata {
+		return nil
+	}
+	switch el.Tag {
+	case "EntityDescriptor":
+		return []*etree.Element{el}
+	case "EntitiesDescriptor":
+		var out []*etree.Element
+		for _, c := range el.ChildElements() {
+			out = append(out, entityDescriptors(c)...)
+		}
+		return out
+	}
+	return nil
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+}
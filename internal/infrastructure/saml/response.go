@@ -0,0 +1,241 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+
+	"go-auth/internal/app"
+)
+
+func rejectf(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{app.ErrAssertionRejected}, args...)...)
+}
+
+// ParseResponse only reads elements covered by a verified signature: either
+// the whole Response or its single Assertion must be signed by one of the
+// IdP's certificates. Encrypted assertions are not supported.
+func (sp *ServiceProvider) ParseResponse(idp app.SAMLIdP, encoded, requestID string) (*app.SAMLAssertion, error) {
+	raw, err := base64.StdEncoding.DecodeString(stripSpace(encoded))
+	if err != nil {
+		return nil, rejectf("decode response: %v", err)
+	}
+	// Documents that change meaning when re-serialised defeat signature
+	// checks; see the validator's advisory.
+	if err := xrv.Validate(bytes.NewReader(raw)); err != nil {
+		return nil, rejectf("malformed response: %v", err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, rejectf("parse response: %v", err)
+	}
+	resp := doc.Root()
+	if resp == nil || resp.Tag != "Response" || resp.NamespaceURI() != nsProtocol {
+		return nil, rejectf("not a SAML response")
+	}
+
+	now := sp.now()
+	validator := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: idp.Metadata.Certificates})
+	validator.Clock = dsig.NewFakeClockAt(now)
+
+	signed := false
+	if child(resp, nsDSig, "Signature") != nil {
+		if resp, err = validator.Validate(resp); err != nil {
+			return nil, rejectf("response signature: %v", err)
+		}
+		signed = true
+	}
+	if err := sp.checkResponse(idp, resp, requestID); err != nil {
+		return nil, err
+	}
+
+	if child(resp, nsAssertion, "EncryptedAssertion") != nil {
+		return nil, rejectf("encrypted assertions are not supported")
+	}
+	assertions := children(resp, nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, rejectf("want one assertion, got %d", len(assertions))
+	}
+	assertion := assertions[0]
+	if child(assertion, nsDSig, "Signature") != nil {
+		// Carry the namespaces declared on the response into the assertion
+		// so it canonicalises the way the IdP signed it.
+		nsCtx, err := etreeutils.NSBuildParentContext(assertion)
+		if err != nil {
+			return nil, rejectf("assertion namespaces: %v", err)
+		}
+		detached, err := etreeutils.NSDetatch(nsCtx, assertion)
+		if err != nil {
+			return nil, rejectf("assertion namespaces: %v", err)
+		}
+		if assertion, err = validator.Validate(detached); err != nil {
+			return nil, rejectf("assertion signature: %v", err)
+		}
+		signed = true
+	}
+	if !signed {
+		return nil, rejectf("neither the response nor the assertion is signed")
+	}
+	return sp.checkAssertion(idp, assertion, requestID, now)
+}
+
+func (sp *ServiceProvider) checkResponse(idp app.SAMLIdP, resp *etree.Element, requestID string) error {
+	if dest := resp.SelectAttrValue("Destination", ""); dest != "" && dest != sp.acsURL {
+		return rejectf("destination %q is not this service", dest)
+	}
+	if irt := resp.SelectAttrValue("InResponseTo", ""); irt != "" && irt != requestID {
+		return rejectf("response answers another request")
+	}
+	if issuer := child(resp, nsAssertion, "Issuer"); issuer != nil && strings.TrimSpace(issuer.Text()) != idp.Metadata.EntityID {
+		return rejectf("response issuer %q is not the idp", strings.TrimSpace(issuer.Text()))
+	}
+	status := child(resp, nsProtocol, "Status")
+	if status == nil {
+		return rejectf("response has no status")
+	}
+	code := child(status, nsProtocol, "StatusCode")
+	if code == nil {
+		return rejectf("response has no status code")
+	}
+	if value := code.SelectAttrValue("Value", ""); value != statusSuccess {
+		// The second-level code says why, e.g. AuthnFailed.
+		if sub := child(code, nsProtocol, "StatusCode"); sub != nil {
+			value += " / " + sub.SelectAttrValue("Value", "")
+		}
+		return rejectf("idp returned status %s", value)
+	}
+	return nil
+}
+
+func (sp *ServiceProvider) checkAssertion(idp app.SAMLIdP, assertion *etree.Element, requestID string, now time.Time) (*app.SAMLAssertion, error) {
+	issuer := child(assertion, nsAssertion, "Issuer")
+	if issuer == nil || strings.TrimSpace(issuer.Text()) != idp.Metadata.EntityID {
+		return nil, rejectf("assertion is not issued by the idp")
+	}
+
+	subject := child(assertion, nsAssertion, "Subject")
+	if subject == nil {
+		return nil, rejectf("assertion has no subject")
+	}
+	nameID := child(subject, nsAssertion, "NameID")
+	if nameID == nil || strings.TrimSpace(nameID.Text()) == "" {
+		return nil, rejectf("assertion has no name id")
+	}
+	if err := sp.checkConfirmation(subject, requestID, now); err != nil {
+		return nil, err
+	}
+	if err := sp.checkConditions(child(assertion, nsAssertion, "Conditions"), now); err != nil {
+		return nil, err
+	}
+
+	out := &app.SAMLAssertion{
+		Issuer:       idp.Metadata.EntityID,
+		NameID:       strings.TrimSpace(nameID.Text()),
+		NameIDFormat: nameID.SelectAttrValue("Format", ""),
+		Attributes:   make(map[string][]string),
+	}
+	if authn := child(assertion, nsAssertion, "AuthnStatement"); authn != nil {
+		out.SessionIndex = authn.SelectAttrValue("SessionIndex", "")
+	}
+	for _, statement := range children(assertion, nsAssertion, "AttributeStatement") {
+		for _, attr := range children(statement, nsAssertion, "Attribute") {
+			name := attr.SelectAttrValue("Name", "")
+			for _, v := range children(attr, nsAssertion, "AttributeValue") {
+				out.Attributes[name] = append(out.Attributes[name], strings.TrimSpace(v.Text()))
+			}
+		}
+	}
+	return out, nil
+}
+
+// checkConfirmation requires a bearer confirmation addressed to this ACS,
+// answering requestID and still within its validity window.
+func (sp *ServiceProvider) checkConfirmation(subject *etree.Element, requestID string, now time.Time) error {
+	var reason error = rejectf("assertion has no bearer subject confirmation")
+	for _, sc := range children(subject, nsAssertion, "SubjectConfirmation") {
+		if sc.SelectAttrValue("Method", "") != methodBearer {
+			continue
+		}
+		data := child(sc, nsAssertion, "SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		switch {
+		case data.SelectAttrValue("Recipient", "") != sp.acsURL:
+			reason = rejectf("subject confirmation is for another recipient")
+		case data.SelectAttrValue("InResponseTo", "") != requestID:
+			reason = rejectf("subject confirmation answers another request")
+		default:
+			if err := sp.checkWindow(data, now, true); err != nil {
+				reason = err
+				continue
+			}
+			return nil
+		}
+	}
+	return reason
+}
+
+// checkConditions enforces the validity window and that every audience
+// restriction names this SP.
+func (sp *ServiceProvider) checkConditions(conditions *etree.Element, now time.Time) error {
+	if conditions == nil {
+		return rejectf("assertion has no conditions")
+	}
+	if err := sp.checkWindow(conditions, now, false); err != nil {
+		return err
+	}
+	restrictions := children(conditions, nsAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return rejectf("assertion has no audience restriction")
+	}
+	for _, r := range restrictions {
+		ok := false
+		for _, audience := range children(r, nsAssertion, "Audience") {
+			if strings.TrimSpace(audience.Text()) == sp.entityID {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return rejectf("assertion is not meant for this service")
+		}
+	}
+	return nil
+}
+
+// checkWindow checks the NotBefore and NotOnOrAfter attributes of el,
+// allowing for clock skew. requireEnd makes NotOnOrAfter mandatory.
+func (sp *ServiceProvider) checkWindow(el *etree.Element, now time.Time, requireEnd bool) error {
+	if v := el.SelectAttrValue("NotBefore", ""); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return rejectf("%s NotBefore: %v", el.Tag, err)
+		}
+		if now.Add(sp.skew).Before(t) {
+			return rejectf("%s is not yet valid", el.Tag)
+		}
+	}
+	v := el.SelectAttrValue("NotOnOrAfter", "")
+	if v == "" {
+		if requireEnd {
+			return rejectf("%s has no NotOnOrAfter", el.Tag)
+		}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return rejectf("%s NotOnOrAfter: %v", el.Tag, err)
+	}
+	if !now.Add(-sp.skew).Before(t) {
+		return rejectf("%s has expired", el.Tag)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Package samltest is a fake SAML 2.0 identity provider for tests. It signs
+// with a keypair generated per test, answers AuthnRequests taken from the
+// redirect URL the SP built, and lets tests tamper with the response.
+package samltest
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"go-auth/internal/app"
+)
+
+const (
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+
+	timeFormat = "2006-01-02T15:04:05Z"
+)
+
+// User is the account the IdP asserts.
+type User struct {
+	NameID       string
+	NameIDFormat string
+	Attributes   map[string][]string
+}
+
+// IdP is a fake identity provider. Its zero signing options sign the
+// assertion only, like most real IdPs.
+type IdP struct {
+	EntityID string
+	SSOURL   string
+	// SignResponse additionally signs the whole response;
+	// UnsignedAssertion leaves the assertion unsigned.
+	SignResponse      bool
+	UnsignedAssertion bool
+	// Now is the IdP clock. Default time.Now.
+	Now func() time.Time
+	// ModifyAssertion edits the assertion before it is signed;
+	// ModifyResponse edits the response after signing, to test how the SP
+	// handles bad or tampered responses.
+	ModifyAssertion func(assertion *etree.Element)
+	ModifyResponse  func(response *etree.Element)
+
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// NewIdP creates an IdP with a fresh RSA key and self-signed certificate.
+func NewIdP(t testing.TB) *IdP {
+	t.Helper()
+	key, cert, err := keyPair("Test IdP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &IdP{
+		EntityID: "https://idp.example.test/metadata",
+		SSOURL:   "https://idp.example.test/sso",
+		Now:      time.Now,
+		key:      key,
+		cert:     cert,
+	}
+}
+
+// Certificate is the certificate the IdP signs with.
+func (p *IdP) Certificate() *x509.Certificate { return p.cert }
+
+// Config returns an app.SAMLIdP for this IdP named id, with its metadata
+// already imported.
+func (p *IdP) Config(id string) app.SAMLIdP {
+	return app.SAMLIdP{
+		ID:   id,
+		Name: "Test IdP",
+		Metadata: app.SAMLIdPMetadata{
+			EntityID:     p.EntityID,
+			SSOURL:       p.SSOURL,
+			Certificates: []*x509.Certificate{p.cert},
+		},
+	}
+}
+
+// Metadata returns the IdP metadata document.
+func (p *IdP) Metadata() []byte {
+	doc := etree.NewDocument()
+	entity := doc.CreateElement("md:EntityDescriptor")
+	entity.CreateAttr("xmlns:md", nsMetadata)
+	entity.CreateAttr("xmlns:ds", nsDSig)
+	entity.CreateAttr("entityID", p.EntityID)
+	desc := entity.CreateElement("md:IDPSSODescriptor")
+	desc.CreateAttr("protocolSupportEnumeration", nsProtocol)
+	key := desc.CreateElement("md:KeyDescriptor")
+	key.CreateAttr("use", "signing")
+	key.CreateElement("ds:KeyInfo").CreateElement("ds:X509Data").CreateElement("ds:X509Certificate").
+		SetText(base64.StdEncoding.EncodeToString(p.cert.Raw))
+	for binding, location := range map[string]string{
+		"urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST":     p.SSOURL + "/post",
+		"urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect": p.SSOURL,
+	} {
+		sso := desc.CreateElement("md:SingleSignOnService")
+		sso.CreateAttr("Binding", binding)
+		sso.CreateAttr("Location", location)
+	}
+	doc.Indent(2)
+	raw, _ := doc.WriteToBytes()
+	return raw
+}
+
+// Request is an AuthnRequest decoded from an SP redirect URL.
+type Request struct {
+	ID         string
+	Issuer     string
+	ACSURL     string
+	RelayState string
+}
+
+// ParseRequest decodes the AuthnRequest carried by redirectURL.
+func (p *IdP) ParseRequest(t testing.TB, redirectURL string) Request {
+	t.Helper()
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deflated, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	if err != nil {
+		t.Fatalf("SAMLRequest: %v", err)
+	}
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		t.Fatalf("SAMLRequest: %v", err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		t.Fatalf("SAMLRequest: %v", err)
+	}
+	root := doc.Root()
+	req := Request{
+		ID:         root.SelectAttrValue("ID", ""),
+		ACSURL:     root.SelectAttrValue("AssertionConsumerServiceURL", ""),
+		RelayState: u.Query().Get("RelayState"),
+	}
+	if issuer := root.SelectElement("Issuer"); issuer != nil {
+		req.Issuer = issuer.Text()
+	}
+	if req.ID == "" || req.ACSURL == "" || req.Issuer == "" {
+		t.Fatalf("incomplete AuthnRequest: %s", raw)
+	}
+	return req
+}
+
+// Respond signs user in for the AuthnRequest in redirectURL and returns
+// what the browser would post to the SP: the base64 response and the relay
+// state.
+func (p *IdP) Respond(t testing.TB, redirectURL string, user User) (response, relayState string) {
+	t.Helper()
+	req := p.ParseRequest(t, redirectURL)
+	raw, err := p.response(req, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw), req.RelayState
+}
+
+func (p *IdP) response(req Request, user User) ([]byte, error) {
+	now := p.Now().UTC()
+	assertion, err := p.assertion(req, user, now)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := etree.NewElement("samlp:Response")
+	resp.CreateAttr("xmlns:samlp", nsProtocol)
+	resp.CreateAttr("xmlns:saml", nsAssertion)
+	resp.CreateAttr("ID", randomID())
+	resp.CreateAttr("Version", "2.0")
+	resp.CreateAttr("IssueInstant", now.Format(timeFormat))
+	resp.CreateAttr("Destination", req.ACSURL)
+	resp.CreateAttr("InResponseTo", req.ID)
+	resp.CreateElement("saml:Issuer").SetText(p.EntityID)
+	resp.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").
+		CreateAttr("Value", "urn:oasis:names:tc:SAML:2.0:status:Success")
+	resp.AddChild(assertion)
+	if p.SignResponse {
+		if resp, err = p.sign(resp); err != nil {
+			return nil, err
+		}
+	}
+	if p.ModifyResponse != nil {
+		p.ModifyResponse(resp)
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(resp)
+	return doc.WriteToBytes()
+}
+
+// assertion builds the assertion as a standalone element, declaring its own
+// namespace the way most IdPs do, and signs it.
+func (p *IdP) assertion(req Request, user User, now time.Time) (*etree.Element, error) {
+	expires := now.Add(5 * time.Minute).Format(timeFormat)
+	a := etree.NewElement("saml:Assertion")
+	a.CreateAttr("xmlns:saml", nsAssertion)
+	a.CreateAttr("ID", randomID())
+	a.CreateAttr("Version", "2.0")
+	a.CreateAttr("IssueInstant", now.Format(timeFormat))
+	a.CreateElement("saml:Issuer").SetText(p.EntityID)
+
+	subject := a.CreateElement("saml:Subject")
+	nameID := subject.CreateElement("saml:NameID")
+	if user.NameIDFormat != "" {
+		nameID.CreateAttr("Format", user.NameIDFormat)
+	}
+	nameID.SetText(user.NameID)
+	confirmation := subject.CreateElement("saml:SubjectConfirmation")
+	confirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
+	data := confirmation.CreateElement("saml:SubjectConfirmationData")
+	data.CreateAttr("InResponseTo", req.ID)
+	data.CreateAttr("Recipient", req.ACSURL)
+	data.CreateAttr("NotOnOrAfter", expires)
+
+	conditions := a.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", now.Add(-time.Minute).Format(timeFormat))
+	conditions.CreateAttr("NotOnOrAfter", expires)
+	conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(req.Issuer)
+
+	authn := a.CreateElement("saml:AuthnStatement")
+	authn.CreateAttr("AuthnInstant", now.Format(timeFormat))
+	authn.CreateAttr("SessionIndex", randomID())
+	authn.CreateElement("saml:AuthnContext").CreateElement("saml:AuthnContextClassRef").
+		SetText("urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport")
+
+	if len(user.Attributes) > 0 {
+		statement := a.CreateElement("saml:AttributeStatement")
+		for name, values := range user.Attributes {
+			attr := statement.CreateElement("saml:Attribute")
+			attr.CreateAttr("Name", name)
+			for _, v := range values {
+				attr.CreateElement("saml:AttributeValue").SetText(v)
+			}
+		}
+	}
+
+	if p.ModifyAssertion != nil {
+		p.ModifyAssertion(a)
+	}
+	if p.UnsignedAssertion {
+		return a, nil
+	}
+	return p.sign(a)
+}
+
+// sign adds an enveloped signature, placed after the Issuer as the SAML
+// schema requires.
+func (p *IdP) sign(el *etree.Element) (*etree.Element, error) {
+	ctx, err := dsig.NewSigningContext(p.key, [][]byte{p.cert.Raw})
+	if err != nil {
+		return nil, err
+	}
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := ctx.SignEnveloped(el)
+	if err != nil {
+		return nil, err
+	}
+	// SignEnveloped appends the signature without indexing it, so detach
+	// it by hand.
+	sig := signed.Child[len(signed.Child)-1]
+	signed.Child = signed.Child[:len(signed.Child)-1]
+	signed.InsertChildAt(signed.SelectElement("Issuer").Index()+1, sig)
+	return signed, nil
+}
+
+func keyPair(name string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-24 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	return key, cert, err
+}
+
+// randomID returns an xs:ID, which must not start with a digit.
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "_" + hex.EncodeToString(b)
+}
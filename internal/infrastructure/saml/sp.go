@@ -0,0 +1,154 @@
+// Package saml is a SAML 2.0 service provider: it imports IdP metadata,
+// publishes SP metadata, sends AuthnRequests over the HTTP-Redirect binding
+// and validates the signed responses IdPs post back.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/beevik/etree"
+
+	"go-auth/internal/app"
+)
+
+const (
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	statusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer    = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	NameIDFormatEmail      = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	NameIDFormatPersistent = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+
+	// timeFormat is xs:dateTime in UTC as SAML requires.
+	timeFormat = "2006-01-02T15:04:05Z"
+)
+
+// Options configures a ServiceProvider.
+type Options struct {
+	// EntityID identifies this SP to IdPs; by convention it is the URL the
+	// SP metadata is served from.
+	EntityID string
+	// ACSURL is the assertion consumer service IdPs post responses to.
+	ACSURL string
+	// ClockSkew is the allowed difference between IdP and SP clocks.
+	// Default 2m.
+	ClockSkew time.Duration
+}
+
+// ServiceProvider implements app.SAMLServiceProvider.
+type ServiceProvider struct {
+	entityID string
+	acsURL   string
+	skew     time.Duration
+	now      func() time.Time
+}
+
+func NewServiceProvider(opts Options) *ServiceProvider {
+	if opts.ClockSkew <= 0 {
+		opts.ClockSkew = 2 * time.Minute
+	}
+	return &ServiceProvider{entityID: opts.EntityID, acsURL: opts.ACSURL, skew: opts.ClockSkew, now: time.Now}
+}
+
+// Metadata describes the SP: its entity ID, the POST binding ACS and that
+// assertions must be signed. AuthnRequests are sent unsigned.
+func (sp *ServiceProvider) Metadata() ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	entity := doc.CreateElement("md:EntityDescriptor")
+	entity.CreateAttr("xmlns:md", nsMetadata)
+	entity.CreateAttr("entityID", sp.entityID)
+
+	desc := entity.CreateElement("md:SPSSODescriptor")
+	desc.CreateAttr("AuthnRequestsSigned", "false")
+	desc.CreateAttr("WantAssertionsSigned", "true")
+	desc.CreateAttr("protocolSupportEnumeration", nsProtocol)
+	for _, format := range []string{NameIDFormatPersistent, NameIDFormatEmail} {
+		desc.CreateElement("md:NameIDFormat").SetText(format)
+	}
+	acs := desc.CreateElement("md:AssertionConsumerService")
+	acs.CreateAttr("Binding", bindingPOST)
+	acs.CreateAttr("Location", sp.acsURL)
+	acs.CreateAttr("index", "0")
+	acs.CreateAttr("isDefault", "true")
+
+	doc.Indent(2)
+	return doc.WriteToBytes()
+}
+
+func (sp *ServiceProvider) AuthnRequestURL(idp app.SAMLIdP, req app.SAMLAuthnRequest) (string, error) {
+	if idp.Metadata.SSOURL == "" {
+		return "", fmt.Errorf("saml: idp %q has no HTTP-Redirect SSO endpoint", idp.ID)
+	}
+	doc := etree.NewDocument()
+	authn := doc.CreateElement("samlp:AuthnRequest")
+	authn.CreateAttr("xmlns:samlp", nsProtocol)
+	authn.CreateAttr("xmlns:saml", nsAssertion)
+	authn.CreateAttr("ID", req.ID)
+	authn.CreateAttr("Version", "2.0")
+	authn.CreateAttr("IssueInstant", sp.now().UTC().Format(timeFormat))
+	authn.CreateAttr("Destination", idp.Metadata.SSOURL)
+	authn.CreateAttr("AssertionConsumerServiceURL", sp.acsURL)
+	authn.CreateAttr("ProtocolBinding", bindingPOST)
+	authn.CreateElement("saml:Issuer").SetText(sp.entityID)
+	authn.CreateElement("samlp:NameIDPolicy").CreateAttr("AllowCreate", "true")
+	raw, err := doc.WriteToBytes()
+	if err != nil {
+		return "", fmt.Errorf("saml: encode authn request: %w", err)
+	}
+
+	// The redirect binding carries the request raw-deflated and base64'd.
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(idp.Metadata.SSOURL)
+	if err != nil {
+		return "", fmt.Errorf("saml: idp %q sso url: %w", idp.ID, err)
+	}
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if req.RelayState != "" {
+		q.Set("RelayState", req.RelayState)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// children returns el's child elements named tag in namespace ns.
+func children(el *etree.Element, ns, tag string) []*etree.Element {
+	var out []*etree.Element
+	for _, c := range el.ChildElements() {
+		if c.Tag == tag && c.NamespaceURI() == ns {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// child returns el's first child element named tag in namespace ns, or nil.
+func child(el *etree.Element, ns, tag string) *etree.Element {
+	if c := children(el, ns, tag); len(c) > 0 {
+		return c[0]
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package saml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/saml/samltest"
+)
+
+const (
+	testEntityID = "https://auth.example.com/api/v1/auth/saml/metadata"
+	testACSURL   = "https://auth.example.com/api/v1/auth/saml/acs"
+)
+
+func newTestSP() *ServiceProvider {
+	return NewServiceProvider(Options{EntityID: testEntityID, ACSURL: testACSURL})
+}
+
+var alice = samltest.User{
+	NameID:       "alice-42",
+	NameIDFormat: NameIDFormatPersistent,
+	Attributes:   map[string][]string{"email": {"alice@ex.com"}, "groups": {"dev", "ops"}},
+}
+
+// login runs an SP-initiated login for user at idp, trusting the IdP
+// configured by trusted, and parses the response.
+func login(t *testing.T, sp *ServiceProvider, idp *samltest.IdP, trusted app.SAMLIdP, user samltest.User) (*app.SAMLAssertion, error) {
+	t.Helper()
+	redirect, err := sp.AuthnRequestURL(trusted, app.SAMLAuthnRequest{ID: "_req1", RelayState: "relay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, relay := idp.Respond(t, redirect, user)
+	if relay != "relay" {
+		t.Fatalf("relay state = %q", relay)
+	}
+	return sp.ParseResponse(trusted, resp, "_req1")
+}
+
+func TestParseIdPMetadata(t *testing.T) {
+	idp := samltest.NewIdP(t)
+	md, err := ParseIdPMetadata(idp.Metadata(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.EntityID != idp.EntityID || md.SSOURL != idp.SSOURL || len(md.Certificates) != 1 || !md.Certificates[0].Equal(idp.Certificate()) {
+		t.Fatalf("metadata = %+v", md)
+	}
+
+	other := samltest.NewIdP(t)
+	other.EntityID = "https://other.example.test"
+	both := `<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata">` +
+		strings.TrimSpace(string(idp.Metadata())) + strings.TrimSpace(string(other.Metadata())) + `</md:EntitiesDescriptor>`
+	if _, err := ParseIdPMetadata([]byte(both), ""); err == nil {
+		t.Fatal("ambiguous aggregate accepted")
+	}
+	if md, err := ParseIdPMetadata([]byte(both), other.EntityID); err != nil || md.EntityID != other.EntityID {
+		t.Fatalf("select by entity id: %+v, %v", md, err)
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	idp := samltest.NewIdP(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(idp.Metadata()) }))
+	defer srv.Close()
+
+	md, err := LoadMetadata(context.Background(), srv.Client(), app.SAMLIdP{ID: "corp", MetadataURL: srv.URL})
+	if err != nil || md.EntityID != idp.EntityID {
+		t.Fatalf("metadata = %+v, %v", md, err)
+	}
+	if _, err := LoadMetadata(context.Background(), srv.Client(), app.SAMLIdP{ID: "corp"}); err == nil {
+		t.Fatal("idp without a metadata source accepted")
+	}
+}
+
+func TestServiceProvider_Metadata(t *testing.T) {
+	raw, err := newTestSP().Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		t.Fatal(err)
+	}
+	root := doc.Root()
+	acs := root.FindElement("./SPSSODescriptor/AssertionConsumerService")
+	if root.SelectAttrValue("entityID", "") != testEntityID || acs == nil || acs.SelectAttrValue("Location", "") != testACSURL {
+		t.Fatalf("metadata:\n%s", raw)
+	}
+}
+
+func TestServiceProvider_Login(t *testing.T) {
+	idp := samltest.NewIdP(t)
+	a, err := login(t, newTestSP(), idp, idp.Config("test"), alice)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if a.Issuer != idp.EntityID || a.NameID != "alice-42" || a.NameIDFormat != NameIDFormatPersistent ||
+		a.Attribute("email") != "alice@ex.com" || len(a.Attributes["groups"]) != 2 || a.SessionIndex == "" {
+		t.Fatalf("assertion = %+v", a)
+	}
+
+	// A signed response covers an unsigned assertion.
+	idp.SignResponse, idp.UnsignedAssertion = true, true
+	if _, err := login(t, newTestSP(), idp, idp.Config("test"), alice); err != nil {
+		t.Fatalf("signed response: %v", err)
+	}
+}
+
+func TestServiceProvider_ClockSkew(t *testing.T) {
+	idp := samltest.NewIdP(t)
+	idp.Now = func() time.Time { return time.Now().Add(-6 * time.Minute) }
+	if _, err := login(t, newTestSP(), idp, idp.Config("test"), alice); err != nil {
+		t.Fatalf("expired within skew: %v", err)
+	}
+	idp.Now = func() time.Time { return time.Now().Add(-8 * time.Minute) }
+	if _, err := login(t, newTestSP(), idp, idp.Config("test"), alice); !errors.Is(err, app.ErrAssertionRejected) {
+		t.Fatalf("expired beyond skew: %v", err)
+	}
+	idp.Now = func() time.Time { return time.Now().Add(4 * time.Minute) }
+	if _, err := login(t, newTestSP(), idp, idp.Config("test"), alice); !errors.Is(err, app.ErrAssertionRejected) {
+		t.Fatalf("not yet valid: %v", err)
+	}
+}
+
+func TestServiceProvider_Rejects(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(idp *samltest.IdP)
+	}{
+		{"unsigned", func(idp *samltest.IdP) { idp.UnsignedAssertion = true }},
+		{"untrusted key", func(idp *samltest.IdP) { *idp = *samltest.NewIdP(t) }},
+		{"tampered name id", func(idp *samltest.IdP) {
+			idp.ModifyResponse = func(r *etree.Element) { r.FindElement("./Assertion/Subject/NameID").SetText("admin") }
+		}},
+		{"wrapped assertion", func(idp *samltest.IdP) {
+			idp.ModifyResponse = func(r *etree.Element) {
+				forged := r.SelectElement("Assertion").Copy()
+				forged.FindElement("./Subject/NameID").SetText("admin")
+				r.InsertChildAt(0, forged)
+			}
+		}},
+		{"wrong audience", func(idp *samltest.IdP) {
+			idp.ModifyAssertion = func(a *etree.Element) {
+				a.FindElement("./Conditions/AudienceRestriction/Audience").SetText("https://evil.example")
+			}
+		}},
+		{"wrong recipient", func(idp *samltest.IdP) {
+			idp.ModifyAssertion = func(a *etree.Element) {
+				a.FindElement("./Subject/SubjectConfirmation/SubjectConfirmationData").CreateAttr("Recipient", "https://evil.example/acs")
+			}
+		}},
+		{"wrong issuer", func(idp *samltest.IdP) {
+			idp.ModifyAssertion = func(a *etree.Element) { a.SelectElement("Issuer").SetText("https://evil.example") }
+		}},
+		{"other request", func(idp *samltest.IdP) {
+			idp.ModifyAssertion = func(a *etree.Element) {
+				a.FindElement("./Subject/SubjectConfirmation/SubjectConfirmationData").CreateAttr("InResponseTo", "_other")
+			}
+		}},
+		{"failed status", func(idp *samltest.IdP) {
+			idp.ModifyResponse = func(r *etree.Element) {
+				r.FindElement("./Status/StatusCode").CreateAttr("Value", "urn:oasis:names:tc:SAML:2.0:status:Responder")
+			}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			idp := samltest.NewIdP(t)
+			trusted := idp.Config("test")
+			tc.setup(idp)
+			if _, err := login(t, newTestSP(), idp, trusted, alice); !errors.Is(err, app.ErrAssertionRejected) {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
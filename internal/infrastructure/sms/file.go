@@ -0,0 +1,53 @@
+// Package sms implements app.SMSSender: a log file for local development
+// and a generic HTTP gateway for real delivery.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+// FileSender appends every message to a file as one JSON object per line,
+// so codes can be read with `tail -f` during development.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+	now  func() time.Time
+}
+
+// NewFileSender creates the file's directory if needed.
+func NewFileSender(path string) (*FileSender, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("sms file: %w", err)
+	}
+	return &FileSender{path: path, now: time.Now}, nil
+}
+
+func (s *FileSender) Send(_ context.Context, sms app.SMS) error {
+	line, err := json.Marshal(map[string]string{
+		"time": s.now().UTC().Format(time.RFC3339),
+		"to":   sms.To,
+		"text": sms.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("sms file: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("sms file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("sms file: %w", err)
+	}
+	return f.Close()
+}
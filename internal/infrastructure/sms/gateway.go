@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+// GatewayConfig configures Gateway. Most SMS providers, or a small proxy in
+// front of them, can accept its request shape.
+type GatewayConfig struct {
+	URL string
+	// Token, if set, is sent as "Authorization: Bearer <token>".
+	Token   string
+	From    string
+	Timeout time.Duration
+}
+
+// Gateway posts each message as JSON {"from", "to", "text"} to an HTTP
+// endpoint. Any 2xx response counts as accepted.
+type Gateway struct {
+	cfg    GatewayConfig
+	client *http.Client
+}
+
+func NewGateway(cfg GatewayConfig) *Gateway {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Gateway{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (g *Gateway) Send(ctx context.Context, sms app.SMS) error {
+	body, err := json.Marshal(map[string]string{"from": g.cfg.From, "to": sms.To, "text": sms.Text})
+	if err != nil {
+		return fmt.Errorf("sms gateway: encode: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.Token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway: status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
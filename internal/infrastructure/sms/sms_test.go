@@ -0,0 +1,76 @@
+package sms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-auth/internal/app"
+)
+
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "var", "sms.log")
+	s, err := NewFileSender(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"first", "second"} {
+		if err := s.Send(context.Background(), app.SMS{To: "+15550100", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []map[string]string
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var line map[string]string
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, line)
+	}
+	if len(got) != 2 || got[1]["to"] != "+15550100" || got[1]["text"] != "second" || got[0]["time"] == "" {
+		t.Fatalf("log = %v", got)
+	}
+}
+
+func TestGateway(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		if got["to"] == "+15550199" {
+			http.Error(w, "unroutable", http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	g := NewGateway(GatewayConfig{URL: srv.URL, Token: "secret", From: "Acme"})
+	if err := g.Send(context.Background(), app.SMS{To: "+15550100", Text: "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["from"] != "Acme" || got["to"] != "+15550100" || got["text"] != "123456" {
+		t.Fatalf("request body = %v", got)
+	}
+	if err := g.Send(context.Background(), app.SMS{To: "+15550199", Text: "x"}); err == nil {
+		t.Fatal("gateway rejection not reported")
+	}
+	if err := NewGateway(GatewayConfig{URL: srv.URL}).Send(context.Background(), app.SMS{To: "+15550100"}); err == nil {
+		t.Fatal("unauthorized not reported")
+	}
+}
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Append links e to the chain head. The immediate transaction holds the
+// database write lock, so no other append can interleave.
+func (r *AuditRepository) Append(ctx context.Context, e *domain.AuditEvent) error {
+	meta, err := json.Marshal(e.Metadata)
+	if err != nil {
+		return fmt.Errorf("sqlite: encode audit metadata: %w", err)
+	}
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		prev := ""
+		err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&prev)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return wrapErr("read audit chain head", err)
+		}
+		id := uuid.NewString()
+		hash := e.ChainHash(prev)
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO audit_events (id, type, actor_id, target_id, tenant_id, ip, user_agent, request_id, metadata, occurred_at, prev_hash, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, e.Type, e.ActorID, e.TargetID, e.TenantID, e.IP, e.UserAgent, e.RequestID, string(meta), toMicros(e.OccurredAt), prev, hash)
+		if err != nil {
+			return wrapErr("insert audit event", err)
+		}
+		seq, err := res.LastInsertId()
+		if err != nil {
+			return wrapErr("insert audit event", err)
+		}
+		e.ID, e.Seq, e.PrevHash, e.Hash = id, seq, prev, hash
+		return nil
+	})
+}
+
+func (r *AuditRepository) List(ctx context.Context, f domain.AuditFilter) ([]domain.AuditEvent, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		where = append(where, cond)
+		args = append(args, v)
+	}
+	if f.Type != "" {
+		add("type = ?", f.Type)
+	}
+	if f.ActorID != "" {
+		add("actor_id = ?", f.ActorID)
+	}
+	if f.TargetID != "" {
+		add("target_id = ?", f.TargetID)
+	}
+	if f.TenantID != "" {
+		add("tenant_id = ?", f.TenantID)
+	}
+	if !f.From.IsZero() {
+		add("occurred_at >= ?", toMicros(f.From))
+	}
+	if !f.To.IsZero() {
+		add("occurred_at < ?", toMicros(f.To))
+	}
+	if f.BeforeSeq > 0 {
+		add("seq < ?", f.BeforeSeq)
+	}
+
+	query := `SELECT seq, id, type, actor_id, target_id, tenant_id, ip, user_agent, request_id, metadata, occurred_at, prev_hash, hash FROM audit_events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY seq DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr("list audit events", err)
+	}
+	defer rows.Close()
+
+	var out []domain.AuditEvent
+	for rows.Next() {
+		var (
+			e          domain.AuditEvent
+			meta       string
+			occurredAt int64
+		)
+		if err := rows.Scan(&e.Seq, &e.ID, &e.Type, &e.ActorID, &e.TargetID, &e.TenantID, &e.IP, &e.UserAgent, &e.RequestID, &meta, &occurredAt, &e.PrevHash, &e.Hash); err != nil {
+			return nil, wrapErr("scan audit event", err)
+		}
+		if err := json.Unmarshal([]byte(meta), &e.Metadata); err != nil {
+			return nil, fmt.Errorf("sqlite: decode audit metadata: %w", err)
+		}
+		e.OccurredAt = fromMicros(occurredAt)
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list audit events", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"testing"
+
+	"go-auth/internal/infrastructure/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Store {
+		db := openTestDB(t)
+		return repotest.Store{
+			Tx:              NewTxManager(db),
+			Users:           NewUserRepository(db),
+			RefreshTokens:   NewRefreshRepository(db),
+			Audit:           NewAuditRepository(db),
+			Outbox:          NewOutboxRepository(db),
+			Webhooks:        NewWebhookRepository(db),
+			MagicLinks:      NewMagicLinkRepository(db),
+			OTPs:            NewOTPRepository(db),
+			Identities:      NewIdentityRepository(db),
+			FederatedLogins: NewFederatedLoginRepository(db),
+			Memberships:     NewMembershipRepository(db),
+			Roles:           NewRoleRepository(db),
+			SCIMTokens:      NewSCIMTokenRepository(db),
+			PersonalTokens:  NewPersonalAccessTokenRepository(db),
+			ServiceAccounts: NewServiceAccountRepository(db),
+			Impersonations:  NewImpersonationRepository(db),
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// Package sqlite stores everything in a single SQLite database file using a
+// pure-Go driver, for single-node installs and tests without Docker.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// Scheme prefixes DATABASE_URL values handled by this package,
+// e.g. sqlite://data/auth.db or sqlite://:memory:.
+const Scheme = "sqlite://"
+
+// Open connects to the database named by dsn (see Scheme). SQLite allows a
+// single writer, so the pool is limited to one connection; this also keeps
+// :memory: databases alive for the lifetime of the pool.
+func Open(ctx context.Context, dsn string, log *slog.Logger) (*sql.DB, error) {
+	path, query, _ := strings.Cut(strings.TrimPrefix(dsn, Scheme), "?")
+	if path == "" {
+		return nil, fmt.Errorf("sqlite: empty database path in %q", dsn)
+	}
+	params := []string{
+		"_pragma=foreign_keys(1)",
+		"_pragma=busy_timeout(5000)",
+		"_pragma=journal_mode(WAL)",
+		"_txlock=immediate",
+	}
+	if query != "" {
+		params = append(params, query)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+strings.Join(params, "&"))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: open: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("sqlite: ping: %w", err)
+	}
+
+	log.Info("opened sqlite database", "path", path)
+	return db, nil
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// TxManager implements app.TxManager by carrying a *sql.Tx in the context.
+type TxManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	return withTx(ctx, m.db, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// withTx runs fn in the transaction active in ctx, or in a new one.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapErr("begin", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return wrapErr("commit", err)
+	}
+	return nil
+}
+
+// conn returns the transaction active in ctx, or db outside a unit of work.
+func conn(ctx context.Context, db *sql.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// Timestamps are stored as Unix microseconds, matching Postgres precision.
+func toMicros(t time.Time) int64 { return t.UnixMicro() }
+
+func fromMicros(v int64) time.Time { return time.UnixMicro(v).UTC() }
+
+func fromNullMicros(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := fromMicros(v.Int64)
+	return &t
+}
+
+func nullMicros(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: toMicros(*t), Valid: true}
+}
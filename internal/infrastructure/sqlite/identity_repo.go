@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) Create(ctx context.Context, identity *domain.Identity) error {
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, identity.UserID, identity.Provider, identity.Subject, identity.Email, toMicros(identity.CreatedAt))
+	if err != nil {
+		return wrapErr("insert identity", err)
+	}
+	identity.ID = id
+	return nil
+}
+
+func (r *IdentityRepository) FindBySubject(ctx context.Context, provider, subject string) (*domain.Identity, error) {
+	var (
+		identity  domain.Identity
+		createdAt int64
+	)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities WHERE provider = ? AND subject = ?
+	`, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find identity: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find identity", err)
+	}
+	identity.CreatedAt = fromMicros(createdAt)
+	return &identity, nil
+}
+
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID string) ([]domain.Identity, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, wrapErr("list identities", err)
+	}
+	defer rows.Close()
+	var out []domain.Identity
+	for rows.Next() {
+		var (
+			identity  domain.Identity
+			createdAt int64
+		)
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &createdAt); err != nil {
+			return nil, wrapErr("scan identity", err)
+		}
+		identity.CreatedAt = fromMicros(createdAt)
+		out = append(out, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list identities", err)
+	}
+	return out, nil
+}
+
+type FederatedLoginRepository struct {
+	db *sql.DB
+}
+
+func NewFederatedLoginRepository(db *sql.DB) *FederatedLoginRepository {
+	return &FederatedLoginRepository{db: db}
+}
+
+func (r *FederatedLoginRepository) Create(ctx context.Context, login *domain.FederatedLogin) error {
+	if login.CreatedAt.IsZero() {
+		login.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO federated_logins (id, state_hash, binding_hash, provider, tenant_id, code_verifier, nonce, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, login.StateHash, login.BindingHash, login.Provider, login.TenantID, login.CodeVerifier, login.Nonce,
+		toMicros(login.ExpiresAt), toMicros(login.CreatedAt))
+	if err != nil {
+		return wrapErr("insert federated login", err)
+	}
+	login.ID = id
+	return nil
+}
+
+func (r *FederatedLoginRepository) Consume(ctx context.Context, stateHash, bindingHash string, now time.Time) (*domain.FederatedLogin, error) {
+	var (
+		login                domain.FederatedLogin
+		expiresAt, createdAt int64
+		consumedAt           sql.NullInt64
+	)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE federated_logins SET consumed_at = ?
+		WHERE state_hash = ? AND binding_hash = ? AND consumed_at IS NULL AND expires_at > ?
+		RETURNING id, state_hash, binding_hash, provider, tenant_id, code_verifier, nonce, expires_at, consumed_at, created_at
+	`, toMicros(now), stateHash, bindingHash, toMicros(now)).Scan(
+		&login.ID, &login.StateHash, &login.BindingHash, &login.Provider, &login.TenantID,
+		&login.CodeVerifier, &login.Nonce, &expiresAt, &consumedAt, &createdAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: consume federated login: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("consume federated login", err)
+	}
+	login.ExpiresAt, login.CreatedAt, login.ConsumedAt = fromMicros(expiresAt), fromMicros(createdAt), fromNullMicros(consumedAt)
+	return &login, nil
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type ImpersonationRepository struct {
+	db *sql.DB
+}
+
+func NewImpersonationRepository(db *sql.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+const impersonationColumns = `id, actor_id, user_id, reason, end_reason, created_at, expires_at, ended_at`
+
+func scanImpersonation(row interface{ Scan(...any) error }) (*domain.Impersonation, error) {
+	var (
+		i                    domain.Impersonation
+		createdAt, expiresAt int64
+		endedAt              sql.NullInt64
+	)
+	if err := row.Scan(&i.ID, &i.ActorID, &i.UserID, &i.Reason, &i.EndReason, &createdAt, &expiresAt, &endedAt); err != nil {
+		return nil, err
+	}
+	i.CreatedAt, i.ExpiresAt, i.EndedAt = fromMicros(createdAt), fromMicros(expiresAt), fromNullMicros(endedAt)
+	return &i, nil
+}
+
+func (r *ImpersonationRepository) Create(ctx context.Context, imp *domain.Impersonation) error {
+	if imp.CreatedAt.IsZero() {
+		imp.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO impersonations (id, actor_id, user_id, reason, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, imp.ActorID, imp.UserID, imp.Reason, toMicros(imp.CreatedAt), toMicros(imp.ExpiresAt))
+	if err != nil {
+		return wrapErr("insert impersonation", err)
+	}
+	imp.ID = id
+	return nil
+}
+
+func (r *ImpersonationRepository) FindByID(ctx context.Context, id string) (*domain.Impersonation, error) {
+	i, err := scanImpersonation(conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT `+impersonationColumns+` FROM impersonations WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find impersonation: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find impersonation", err)
+	}
+	return i, nil
+}
+
+func (r *ImpersonationRepository) End(ctx context.Context, id string, at time.Time, reason string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE impersonations SET ended_at = ?, end_reason = ? WHERE id = ? AND ended_at IS NULL`, toMicros(at), reason, id)
+	if err != nil {
+		return wrapErr("end impersonation", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("end impersonation", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: end impersonation: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *ImpersonationRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.Impersonation, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+impersonationColumns+` FROM impersonations
+		WHERE ended_at IS NULL AND expires_at < ?
+		ORDER BY expires_at, id
+		LIMIT ?
+	`, toMicros(now), limit)
+	if err != nil {
+		return nil, wrapErr("list expired impersonations", err)
+	}
+	defer rows.Close()
+	var out []domain.Impersonation
+	for rows.Next() {
+		i, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, wrapErr("scan impersonation", err)
+		}
+		out = append(out, *i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list expired impersonations", err)
+	}
+	return out, nil
+}
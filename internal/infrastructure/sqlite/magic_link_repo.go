@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type MagicLinkRepository struct {
+	db *sql.DB
+}
+
+func NewMagicLinkRepository(db *sql.DB) *MagicLinkRepository {
+	return &MagicLinkRepository{db: db}
+}
+
+func (r *MagicLinkRepository) Create(ctx context.Context, link *domain.MagicLink) error {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO magic_links (id, email, tenant_id, token_hash, nonce_hash, auto_register, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, link.Email, link.TenantID, link.TokenHash, link.NonceHash, link.AutoRegister, toMicros(link.ExpiresAt), toMicros(link.CreatedAt))
+	if err != nil {
+		return wrapErr("insert magic link", err)
+	}
+	link.ID = id
+	return nil
+}
+
+func (r *MagicLinkRepository) Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (*domain.MagicLink, error) {
+	var (
+		link                 domain.MagicLink
+		expiresAt, createdAt int64
+		consumedAt           sql.NullInt64
+	)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE magic_links SET consumed_at = ?
+		WHERE token_hash = ? AND nonce_hash = ? AND consumed_at IS NULL AND expires_at > ?
+		RETURNING id, email, tenant_id, token_hash, nonce_hash, auto_register, expires_at, consumed_at, created_at
+	`, toMicros(now), tokenHash, nonceHash, toMicros(now)).Scan(
+		&link.ID, &link.Email, &link.TenantID, &link.TokenHash, &link.NonceHash,
+		&link.AutoRegister, &expiresAt, &consumedAt, &createdAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: consume magic link: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("consume magic link", err)
+	}
+	link.ExpiresAt, link.CreatedAt, link.ConsumedAt = fromMicros(expiresAt), fromMicros(createdAt), fromNullMicros(consumedAt)
+	return &link, nil
+}
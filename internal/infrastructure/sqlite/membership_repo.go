@@ -0,0 +1,299 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type MembershipRepository struct {
+	db *sql.DB
+}
+
+func NewMembershipRepository(db *sql.DB) *MembershipRepository {
+	return &MembershipRepository{db: db}
+}
+
+const membershipColumns = `tenant_id, user_id, user_name, external_id, given_name, family_name, display_name, active, created_account, version, created_at, updated_at`
+
+func scanMembership(row interface{ Scan(...any) error }) (*domain.Membership, error) {
+	var (
+		m                    domain.Membership
+		createdAt, updatedAt int64
+	)
+	if err := row.Scan(&m.TenantID, &m.UserID, &m.UserName, &m.ExternalID, &m.GivenName, &m.FamilyName, &m.DisplayName,
+		&m.Active, &m.CreatedAccount, &m.Version, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	m.CreatedAt, m.UpdatedAt = fromMicros(createdAt), fromMicros(updatedAt)
+	return &m, nil
+}
+
+func (r *MembershipRepository) Create(ctx context.Context, m *domain.Membership) error {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = m.CreatedAt
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO tenant_memberships (`+membershipColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+	`, m.TenantID, m.UserID, m.UserName, m.ExternalID, m.GivenName, m.FamilyName, m.DisplayName, m.Active, m.CreatedAccount,
+		toMicros(m.CreatedAt), toMicros(m.UpdatedAt))
+	if err != nil {
+		return wrapErr("insert membership", err)
+	}
+	m.Version = 1
+	return nil
+}
+
+func (r *MembershipRepository) Get(ctx context.Context, tenantID, userID string) (*domain.Membership, error) {
+	m, err := scanMembership(conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT `+membershipColumns+` FROM tenant_memberships WHERE tenant_id = ? AND user_id = ?
+	`, tenantID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find membership: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find membership", err)
+	}
+	return m, nil
+}
+
+func (r *MembershipRepository) List(ctx context.Context, tenantID string) ([]domain.Membership, error) {
+	return r.list(ctx, `WHERE tenant_id = ?`, tenantID)
+}
+
+func (r *MembershipRepository) ListByUser(ctx context.Context, userID string) ([]domain.Membership, error) {
+	return r.list(ctx, `WHERE user_id = ?`, userID)
+}
+
+func (r *MembershipRepository) list(ctx context.Context, where string, arg string) ([]domain.Membership, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+membershipColumns+` FROM tenant_memberships `+where+`
+		ORDER BY created_at, tenant_id, user_id
+	`, arg)
+	if err != nil {
+		return nil, wrapErr("list memberships", err)
+	}
+	defer rows.Close()
+	var out []domain.Membership
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, wrapErr("scan membership", err)
+		}
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list memberships", err)
+	}
+	return out, nil
+}
+
+func (r *MembershipRepository) Update(ctx context.Context, m *domain.Membership) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE tenant_memberships
+		SET user_name = ?, external_id = ?, given_name = ?, family_name = ?, display_name = ?, active = ?,
+			version = version + 1, updated_at = ?
+		WHERE tenant_id = ? AND user_id = ? AND version = ?
+	`, m.UserName, m.ExternalID, m.GivenName, m.FamilyName, m.DisplayName, m.Active, toMicros(m.UpdatedAt),
+		m.TenantID, m.UserID, m.Version)
+	if err != nil {
+		return wrapErr("update membership", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("update membership", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: update membership: %w", domain.ErrNotFound)
+	}
+	m.Version++
+	return nil
+}
+
+func (r *MembershipRepository) Delete(ctx context.Context, tenantID, userID string) error {
+	// Role memberships go with it through the foreign key.
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM tenant_memberships WHERE tenant_id = ? AND user_id = ?
+	`, tenantID, userID)
+	if err != nil {
+		return wrapErr("delete membership", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete membership", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete membership: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+const roleColumns = `id, tenant_id, name, external_id, version, created_at, updated_at`
+
+func (r *RoleRepository) Create(ctx context.Context, role *domain.Role) error {
+	if role.CreatedAt.IsZero() {
+		role.CreatedAt = time.Now().UTC()
+	}
+	if role.UpdatedAt.IsZero() {
+		role.UpdatedAt = role.CreatedAt
+	}
+	id := uuid.NewString()
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tenant_roles (`+roleColumns+`)
+			VALUES (?, ?, ?, ?, 1, ?, ?)
+		`, id, role.TenantID, role.Name, role.ExternalID, toMicros(role.CreatedAt), toMicros(role.UpdatedAt))
+		if err != nil {
+			return wrapErr("insert role", err)
+		}
+		return insertRoleMembers(ctx, tx, id, role.TenantID, role.Members)
+	})
+	if err != nil {
+		return err
+	}
+	role.ID, role.Version = id, 1
+	return nil
+}
+
+func insertRoleMembers(ctx context.Context, tx *sql.Tx, roleID, tenantID string, members []string) error {
+	for _, userID := range members {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tenant_role_members (role_id, tenant_id, user_id) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING
+		`, roleID, tenantID, userID)
+		if err != nil {
+			return wrapErr("insert role member", err)
+		}
+	}
+	return nil
+}
+
+func (r *RoleRepository) Get(ctx context.Context, tenantID, id string) (*domain.Role, error) {
+	roles, err := r.list(ctx, `WHERE tenant_id = ? AND id = ?`, true, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("sqlite: find role: %w", domain.ErrNotFound)
+	}
+	return &roles[0], nil
+}
+
+func (r *RoleRepository) List(ctx context.Context, tenantID string) ([]domain.Role, error) {
+	return r.list(ctx, `WHERE tenant_id = ?`, true, tenantID)
+}
+
+func (r *RoleRepository) ListByMember(ctx context.Context, tenantID, userID string) ([]domain.Role, error) {
+	return r.list(ctx, `WHERE tenant_id = ? AND id IN (SELECT role_id FROM tenant_role_members WHERE tenant_id = ? AND user_id = ?)`,
+		false, tenantID, tenantID, userID)
+}
+
+func (r *RoleRepository) list(ctx context.Context, where string, withMembers bool, args ...any) ([]domain.Role, error) {
+	db := conn(ctx, r.db)
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+roleColumns+` FROM tenant_roles `+where+`
+		ORDER BY created_at, id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list roles", err)
+	}
+	var (
+		out   []domain.Role
+		index = map[string]int{}
+	)
+	for rows.Next() {
+		var (
+			role                 domain.Role
+			createdAt, updatedAt int64
+		)
+		if err := rows.Scan(&role.ID, &role.TenantID, &role.Name, &role.ExternalID, &role.Version, &createdAt, &updatedAt); err != nil {
+			rows.Close()
+			return nil, wrapErr("scan role", err)
+		}
+		role.CreatedAt, role.UpdatedAt = fromMicros(createdAt), fromMicros(updatedAt)
+		index[role.ID] = len(out)
+		out = append(out, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list roles", err)
+	}
+	if !withMembers || len(out) == 0 {
+		return out, nil
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT role_id, user_id FROM tenant_role_members
+		WHERE role_id IN (SELECT id FROM tenant_roles `+where+`)
+		ORDER BY user_id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list role members", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var roleID, userID string
+		if err := rows.Scan(&roleID, &userID); err != nil {
+			return nil, wrapErr("scan role member", err)
+		}
+		i := index[roleID]
+		out[i].Members = append(out[i].Members, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list role members", err)
+	}
+	return out, nil
+}
+
+func (r *RoleRepository) Update(ctx context.Context, role *domain.Role) error {
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE tenant_roles SET name = ?, external_id = ?, version = version + 1, updated_at = ?
+			WHERE tenant_id = ? AND id = ? AND version = ?
+		`, role.Name, role.ExternalID, toMicros(role.UpdatedAt), role.TenantID, role.ID, role.Version)
+		if err != nil {
+			return wrapErr("update role", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return wrapErr("update role", err)
+		} else if n == 0 {
+			return fmt.Errorf("sqlite: update role: %w", domain.ErrNotFound)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tenant_role_members WHERE role_id = ?`, role.ID); err != nil {
+			return wrapErr("replace role members", err)
+		}
+		return insertRoleMembers(ctx, tx, role.ID, role.TenantID, role.Members)
+	})
+	if err != nil {
+		return err
+	}
+	role.Version++
+	return nil
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, tenantID, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM tenant_roles WHERE tenant_id = ? AND id = ?`, tenantID, id)
+	if err != nil {
+		return wrapErr("delete role", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete role", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete role: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"go-auth/internal/infrastructure/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations holds the SQLite schema migrations, in the layout expected by migrate.Load.
+var Migrations, _ = fs.Sub(migrationFiles, "migrations")
+
+// MigrationDriver implements migrate.Driver for SQLite. Applied versions are
+// recorded in the schema_versions table.
+type MigrationDriver struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+func NewMigrationDriver(db *sql.DB) *MigrationDriver {
+	return &MigrationDriver{db: db}
+}
+
+// Lock serializes runners within the process. Across processes each
+// migration runs in an immediate transaction and its version row is the
+// primary key, so a concurrent runner fails instead of applying it twice.
+func (d *MigrationDriver) Lock(context.Context) (func(), error) {
+	d.mu.Lock()
+	return d.mu.Unlock, nil
+}
+
+func (d *MigrationDriver) Applied(ctx context.Context) (map[int64]time.Time, error) {
+	_, err := d.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_versions (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: create schema_versions: %w", err)
+	}
+
+	rows, err := d.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_versions`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: read schema_versions: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var v, at int64
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("sqlite: scan schema_versions: %w", err)
+		}
+		applied[v] = fromMicros(at)
+	}
+	return applied, rows.Err()
+}
+
+func (d *MigrationDriver) Apply(ctx context.Context, m migrate.Migration, up bool) error {
+	return withTx(ctx, d.db, func(tx *sql.Tx) error {
+		if up {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO schema_versions (version, name, applied_at) VALUES (?, ?, ?)`, m.Version, m.Name, toMicros(time.Now())); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, m.Up)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_versions WHERE version = ?`, m.Version)
+		return err
+	})
+}
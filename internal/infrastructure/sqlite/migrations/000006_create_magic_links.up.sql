@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS magic_links (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    tenant_id TEXT NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    nonce_hash TEXT NOT NULL,
+    auto_register INTEGER NOT NULL DEFAULT 0,
+    expires_at INTEGER NOT NULL,
+    consumed_at INTEGER,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_expires_at ON magic_links(expires_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS scim_tokens;
+DROP TABLE IF EXISTS tenant_role_members;
+DROP TABLE IF EXISTS tenant_roles;
+DROP TABLE IF EXISTS tenant_memberships;
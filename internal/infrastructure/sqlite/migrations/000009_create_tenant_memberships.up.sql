@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS tenant_memberships (
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_name TEXT NOT NULL,
+    external_id TEXT NOT NULL DEFAULT '',
+    given_name TEXT NOT NULL DEFAULT '',
+    family_name TEXT NOT NULL DEFAULT '',
+    display_name TEXT NOT NULL DEFAULT '',
+    active INTEGER NOT NULL DEFAULT 1,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_memberships_user_name ON tenant_memberships(tenant_id, lower(user_name));
+CREATE INDEX IF NOT EXISTS idx_tenant_memberships_user_id ON tenant_memberships(user_id);
+
+CREATE TABLE IF NOT EXISTS tenant_roles (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    external_id TEXT NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_roles_name ON tenant_roles(tenant_id, lower(name));
+
+-- Role members must be members of the role's tenant; removing the
+-- membership removes the user from the tenant's roles.
+CREATE TABLE IF NOT EXISTS tenant_role_members (
+    role_id TEXT NOT NULL REFERENCES tenant_roles(id) ON DELETE CASCADE,
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    PRIMARY KEY (role_id, user_id),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES tenant_memberships(tenant_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_role_members_member ON tenant_role_members(tenant_id, user_id);
+
+CREATE TABLE IF NOT EXISTS scim_tokens (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_tokens_tenant_id ON scim_tokens(tenant_id);
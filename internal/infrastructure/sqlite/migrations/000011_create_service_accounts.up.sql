@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    disabled_at INTEGER,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_accounts_name ON service_accounts(tenant_id, lower(name));
+
+-- Deleting a role takes it away from service accounts as well.
+CREATE TABLE IF NOT EXISTS service_account_roles (
+    service_account_id TEXT NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    role_id TEXT NOT NULL REFERENCES tenant_roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (service_account_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS service_account_credentials (
+    id TEXT PRIMARY KEY,
+    service_account_id TEXT NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL DEFAULT '',
+    secret_hash TEXT UNIQUE, -- API keys only
+    public_key TEXT NOT NULL DEFAULT '',
+    expires_at INTEGER,
+    last_used_at INTEGER,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_account_credentials_account ON service_account_credentials(service_account_id);
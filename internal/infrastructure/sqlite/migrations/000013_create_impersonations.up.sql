@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS impersonations (
+    id TEXT PRIMARY KEY,
+    actor_id TEXT NOT NULL, -- the admin; kept if that account is deleted
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    end_reason TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    ended_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_open ON impersonations(expires_at) WHERE ended_at IS NULL;
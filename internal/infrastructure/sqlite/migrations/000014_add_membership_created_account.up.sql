@@ -0,0 +1,3 @@
+-- Set when provisioning into the tenant created the user's account; only
+-- that tenant may change the account's email.
+ALTER TABLE tenant_memberships ADD COLUMN created_account INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type OTPRepository struct {
+	db *sql.DB
+}
+
+func NewOTPRepository(db *sql.DB) *OTPRepository {
+	return &OTPRepository{db: db}
+}
+
+func (r *OTPRepository) Create(ctx context.Context, otp *domain.OTP) error {
+	if otp.CreatedAt.IsZero() {
+		otp.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO otp_codes (id, purpose, user_id, phone, code_hash, attempts, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, otp.Purpose, otp.UserID, otp.Phone, otp.CodeHash, otp.Attempts, toMicros(otp.ExpiresAt), toMicros(otp.CreatedAt))
+	if err != nil {
+		return wrapErr("insert otp", err)
+	}
+	otp.ID = id
+	return nil
+}
+
+func (r *OTPRepository) RecordAttempt(ctx context.Context, id string) (*domain.OTP, error) {
+	var (
+		otp                  domain.OTP
+		expiresAt, createdAt int64
+		consumedAt           sql.NullInt64
+	)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE otp_codes SET attempts = attempts + 1
+		WHERE id = ?
+		RETURNING id, purpose, user_id, phone, code_hash, attempts, expires_at, consumed_at, created_at
+	`, id).Scan(
+		&otp.ID, &otp.Purpose, &otp.UserID, &otp.Phone, &otp.CodeHash,
+		&otp.Attempts, &expiresAt, &consumedAt, &createdAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: record otp attempt: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("record otp attempt", err)
+	}
+	otp.ExpiresAt, otp.CreatedAt, otp.ConsumedAt = fromMicros(expiresAt), fromMicros(createdAt), fromNullMicros(consumedAt)
+	return &otp, nil
+}
+
+func (r *OTPRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE otp_codes SET consumed_at = ?
+		WHERE id = ? AND consumed_at IS NULL AND expires_at > ?
+	`, toMicros(now), id, toMicros(now))
+	if err != nil {
+		return wrapErr("consume otp", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("consume otp", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: consume otp: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue writes e. Call it within the unit of work of the change it describes
+// so that both commit or roll back together.
+func (r *OutboxRepository) Enqueue(ctx context.Context, e *domain.OutboxEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO outbox_events (id, type, tenant_id, aggregate_id, payload, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, e.Type, e.TenantID, e.AggregateID, string(e.Payload), toMicros(e.CreatedAt))
+	if err != nil {
+		return wrapErr("insert outbox event", err)
+	}
+	e.ID = id
+	return nil
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type PersonalAccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPersonalAccessTokenRepository(db *sql.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{db: db}
+}
+
+const personalTokenColumns = `id, user_id, name, prefix, scopes, token_hash, expires_at, last_used_at, created_at`
+
+func scanPersonalToken(row interface{ Scan(...any) error }) (*domain.PersonalAccessToken, error) {
+	var (
+		t                   domain.PersonalAccessToken
+		scopes              string
+		expiresAt, lastUsed sql.NullInt64
+		createdAt           int64
+	)
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.TokenHash, &expiresAt, &lastUsed, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+		return nil, fmt.Errorf("sqlite: decode scopes: %w", err)
+	}
+	t.ExpiresAt, t.LastUsedAt = fromNullMicros(expiresAt), fromNullMicros(lastUsed)
+	t.CreatedAt = fromMicros(createdAt)
+	return &t, nil
+}
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) error {
+	if token.Scopes == nil {
+		token.Scopes = []string{}
+	}
+	scopes, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return fmt.Errorf("sqlite: encode scopes: %w", err)
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	var expiresAt sql.NullInt64
+	if token.ExpiresAt != nil {
+		expiresAt = sql.NullInt64{Int64: toMicros(*token.ExpiresAt), Valid: true}
+	}
+	id := uuid.NewString()
+	_, err = conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO personal_access_tokens (id, user_id, name, prefix, scopes, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, token.UserID, token.Name, token.Prefix, string(scopes), token.TokenHash, expiresAt, toMicros(token.CreatedAt))
+	if err != nil {
+		return wrapErr("insert personal access token", err)
+	}
+	token.ID = id
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	t, err := scanPersonalToken(conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT `+personalTokenColumns+` FROM personal_access_tokens WHERE token_hash = ?`, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find personal access token: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find personal access token", err)
+	}
+	return t, nil
+}
+
+func (r *PersonalAccessTokenRepository) ListByUser(ctx context.Context, userID string) ([]domain.PersonalAccessToken, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+personalTokenColumns+` FROM personal_access_tokens
+		WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, wrapErr("list personal access tokens", err)
+	}
+	defer rows.Close()
+	var out []domain.PersonalAccessToken
+	for rows.Next() {
+		t, err := scanPersonalToken(rows)
+		if err != nil {
+			return nil, wrapErr("scan personal access token", err)
+		}
+		out = append(out, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list personal access tokens", err)
+	}
+	return out, nil
+}
+
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, userID, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM personal_access_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return wrapErr("delete personal access token", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete personal access token", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete personal access token: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) DeleteAllByUser(ctx context.Context, userID string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM personal_access_tokens WHERE user_id = ?`, userID); err != nil {
+		return wrapErr("delete personal access tokens", err)
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?`, toMicros(at), id)
+	if err != nil {
+		return wrapErr("touch personal access token", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("touch personal access token", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: touch personal access token: %w", domain.ErrNotFound)
+	}
+	return nil
+}
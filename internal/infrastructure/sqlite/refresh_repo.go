@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type RefreshRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshRepository(db *sql.DB) *RefreshRepository {
+	return &RefreshRepository{db: db}
+}
+
+func (r *RefreshRepository) Save(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, uuid.NewString(), userID, tokenHash, toMicros(expiresAt), toMicros(time.Now()))
+	if err != nil {
+		return wrapErr("save refresh", err)
+	}
+	return nil
+}
+
+func (r *RefreshRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	var (
+		rt                   domain.RefreshToken
+		expiresAt, createdAt int64
+		revokedAt            sql.NullInt64
+	)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, user_id, token_hash, expires_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &expiresAt, &revokedAt, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find refresh: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find refresh", err)
+	}
+	rt.ExpiresAt, rt.CreatedAt, rt.RevokedAt = fromMicros(expiresAt), fromMicros(createdAt), fromNullMicros(revokedAt)
+	return &rt, nil
+}
+
+func (r *RefreshRepository) RevokeByHash(ctx context.Context, tokenHash string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL`, toMicros(time.Now()), tokenHash)
+	if err != nil {
+		return wrapErr("revoke refresh", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("revoke refresh", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: revoke refresh: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *RefreshRepository) RevokeAllByUser(ctx context.Context, userID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, toMicros(time.Now()), userID)
+	if err != nil {
+		return wrapErr("revoke user refresh tokens", err)
+	}
+	return nil
+}
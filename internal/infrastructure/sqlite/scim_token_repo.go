@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type SCIMTokenRepository struct {
+	db *sql.DB
+}
+
+func NewSCIMTokenRepository(db *sql.DB) *SCIMTokenRepository {
+	return &SCIMTokenRepository{db: db}
+}
+
+func (r *SCIMTokenRepository) Create(ctx context.Context, token *domain.SCIMToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO scim_tokens (id, tenant_id, description, token_hash, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, token.TenantID, token.Description, token.TokenHash, toMicros(token.CreatedAt))
+	if err != nil {
+		return wrapErr("insert scim token", err)
+	}
+	token.ID = id
+	return nil
+}
+
+func (r *SCIMTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.SCIMToken, error) {
+	var (
+		token     domain.SCIMToken
+		createdAt int64
+	)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, tenant_id, description, token_hash, created_at FROM scim_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&token.ID, &token.TenantID, &token.Description, &token.TokenHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find scim token: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find scim token", err)
+	}
+	token.CreatedAt = fromMicros(createdAt)
+	return &token, nil
+}
+
+func (r *SCIMTokenRepository) List(ctx context.Context, tenantID string) ([]domain.SCIMToken, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, tenant_id, description, token_hash, created_at FROM scim_tokens
+		WHERE tenant_id = ?
+		ORDER BY created_at, id
+	`, tenantID)
+	if err != nil {
+		return nil, wrapErr("list scim tokens", err)
+	}
+	defer rows.Close()
+	var out []domain.SCIMToken
+	for rows.Next() {
+		var (
+			token     domain.SCIMToken
+			createdAt int64
+		)
+		if err := rows.Scan(&token.ID, &token.TenantID, &token.Description, &token.TokenHash, &createdAt); err != nil {
+			return nil, wrapErr("scan scim token", err)
+		}
+		token.CreatedAt = fromMicros(createdAt)
+		out = append(out, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list scim tokens", err)
+	}
+	return out, nil
+}
+
+func (r *SCIMTokenRepository) Delete(ctx context.Context, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM scim_tokens WHERE id = ?`, id)
+	if err != nil {
+		return wrapErr("delete scim token", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete scim token", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete scim token: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type ServiceAccountRepository struct {
+	db *sql.DB
+}
+
+func NewServiceAccountRepository(db *sql.DB) *ServiceAccountRepository {
+	return &ServiceAccountRepository{db: db}
+}
+
+const (
+	serviceAccountColumns    = `id, tenant_id, name, description, disabled_at, created_at, updated_at`
+	serviceCredentialColumns = `id, service_account_id, type, name, prefix, secret_hash, public_key, expires_at, last_used_at, created_at`
+)
+
+func (r *ServiceAccountRepository) Create(ctx context.Context, account *domain.ServiceAccount) error {
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+	if account.UpdatedAt.IsZero() {
+		account.UpdatedAt = account.CreatedAt
+	}
+	id := uuid.NewString()
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO service_accounts (`+serviceAccountColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, account.TenantID, account.Name, account.Description, nullMicros(account.DisabledAt),
+			toMicros(account.CreatedAt), toMicros(account.UpdatedAt))
+		if err != nil {
+			return wrapErr("insert service account", err)
+		}
+		return insertServiceAccountRoles(ctx, tx, id, account.Roles)
+	})
+	if err != nil {
+		return err
+	}
+	account.ID = id
+	return nil
+}
+
+func insertServiceAccountRoles(ctx context.Context, tx *sql.Tx, accountID string, roles []string) error {
+	for _, roleID := range roles {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO service_account_roles (service_account_id, role_id) VALUES (?, ?)
+			ON CONFLICT DO NOTHING
+		`, accountID, roleID)
+		if err != nil {
+			return wrapErr("insert service account role", err)
+		}
+	}
+	return nil
+}
+
+func (r *ServiceAccountRepository) Get(ctx context.Context, id string) (*domain.ServiceAccount, error) {
+	accounts, err := r.list(ctx, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("sqlite: find service account: %w", domain.ErrNotFound)
+	}
+	return &accounts[0], nil
+}
+
+func (r *ServiceAccountRepository) List(ctx context.Context, tenantID string) ([]domain.ServiceAccount, error) {
+	return r.list(ctx, `WHERE tenant_id = ?`, tenantID)
+}
+
+func (r *ServiceAccountRepository) list(ctx context.Context, where string, args ...any) ([]domain.ServiceAccount, error) {
+	db := conn(ctx, r.db)
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+serviceAccountColumns+` FROM service_accounts `+where+`
+		ORDER BY created_at, id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list service accounts", err)
+	}
+	var (
+		out   []domain.ServiceAccount
+		index = map[string]int{}
+	)
+	for rows.Next() {
+		var (
+			a                    domain.ServiceAccount
+			disabledAt           sql.NullInt64
+			createdAt, updatedAt int64
+		)
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.Name, &a.Description, &disabledAt, &createdAt, &updatedAt); err != nil {
+			rows.Close()
+			return nil, wrapErr("scan service account", err)
+		}
+		a.DisabledAt = fromNullMicros(disabledAt)
+		a.CreatedAt, a.UpdatedAt = fromMicros(createdAt), fromMicros(updatedAt)
+		a.Roles = []string{}
+		index[a.ID] = len(out)
+		out = append(out, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list service accounts", err)
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT service_account_id, role_id FROM service_account_roles
+		WHERE service_account_id IN (SELECT id FROM service_accounts `+where+`)
+		ORDER BY role_id
+	`, args...)
+	if err != nil {
+		return nil, wrapErr("list service account roles", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var accountID, roleID string
+		if err := rows.Scan(&accountID, &roleID); err != nil {
+			return nil, wrapErr("scan service account role", err)
+		}
+		i := index[accountID]
+		out[i].Roles = append(out[i].Roles, roleID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list service account roles", err)
+	}
+	return out, nil
+}
+
+func (r *ServiceAccountRepository) Update(ctx context.Context, account *domain.ServiceAccount) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE service_accounts SET name = ?, description = ?, disabled_at = ?, updated_at = ?
+			WHERE id = ?
+		`, account.Name, account.Description, nullMicros(account.DisabledAt), toMicros(account.UpdatedAt), account.ID)
+		if err != nil {
+			return wrapErr("update service account", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return wrapErr("update service account", err)
+		} else if n == 0 {
+			return fmt.Errorf("sqlite: update service account: %w", domain.ErrNotFound)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM service_account_roles WHERE service_account_id = ?`, account.ID); err != nil {
+			return wrapErr("replace service account roles", err)
+		}
+		return insertServiceAccountRoles(ctx, tx, account.ID, account.Roles)
+	})
+}
+
+func (r *ServiceAccountRepository) Delete(ctx context.Context, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM service_accounts WHERE id = ?`, id)
+	if err != nil {
+		return wrapErr("delete service account", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete service account", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete service account: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func scanServiceCredential(row interface{ Scan(...any) error }) (*domain.ServiceAccountCredential, error) {
+	var (
+		c                   domain.ServiceAccountCredential
+		secretHash          sql.NullString
+		expiresAt, lastUsed sql.NullInt64
+		createdAt           int64
+	)
+	if err := row.Scan(&c.ID, &c.ServiceAccountID, &c.Type, &c.Name, &c.Prefix, &secretHash, &c.PublicKey, &expiresAt, &lastUsed, &createdAt); err != nil {
+		return nil, err
+	}
+	c.SecretHash = secretHash.String
+	c.ExpiresAt, c.LastUsedAt = fromNullMicros(expiresAt), fromNullMicros(lastUsed)
+	c.CreatedAt = fromMicros(createdAt)
+	return &c, nil
+}
+
+func (r *ServiceAccountRepository) AddCredential(ctx context.Context, cred *domain.ServiceAccountCredential) error {
+	if cred.CreatedAt.IsZero() {
+		cred.CreatedAt = time.Now().UTC()
+	}
+	// Assertion keys have no secret; NULLs keep them out of the unique index.
+	secretHash := sql.NullString{String: cred.SecretHash, Valid: cred.SecretHash != ""}
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO service_account_credentials (id, service_account_id, type, name, prefix, secret_hash, public_key, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, cred.ServiceAccountID, cred.Type, cred.Name, cred.Prefix, secretHash, cred.PublicKey,
+		nullMicros(cred.ExpiresAt), toMicros(cred.CreatedAt))
+	if err != nil {
+		return wrapErr("insert service account credential", err)
+	}
+	cred.ID = id
+	return nil
+}
+
+func (r *ServiceAccountRepository) ListCredentials(ctx context.Context, accountID string) ([]domain.ServiceAccountCredential, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+serviceCredentialColumns+` FROM service_account_credentials
+		WHERE service_account_id = ?
+		ORDER BY created_at, id
+	`, accountID)
+	if err != nil {
+		return nil, wrapErr("list service account credentials", err)
+	}
+	defer rows.Close()
+	var out []domain.ServiceAccountCredential
+	for rows.Next() {
+		c, err := scanServiceCredential(rows)
+		if err != nil {
+			return nil, wrapErr("scan service account credential", err)
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("list service account credentials", err)
+	}
+	return out, nil
+}
+
+func (r *ServiceAccountRepository) FindCredentialByHash(ctx context.Context, secretHash string) (*domain.ServiceAccountCredential, error) {
+	c, err := scanServiceCredential(conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT `+serviceCredentialColumns+` FROM service_account_credentials WHERE secret_hash = ?`, secretHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find service account credential: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find service account credential", err)
+	}
+	return c, nil
+}
+
+func (r *ServiceAccountRepository) DeleteCredential(ctx context.Context, accountID, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM service_account_credentials WHERE id = ? AND service_account_id = ?`, id, accountID)
+	if err != nil {
+		return wrapErr("delete service account credential", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete service account credential", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete service account credential: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *ServiceAccountRepository) TouchCredential(ctx context.Context, id string, at time.Time) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE service_account_credentials SET last_used_at = ? WHERE id = ?`, toMicros(at), id)
+	if err != nil {
+		return wrapErr("touch service account credential", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("touch service account credential", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: touch service account credential: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/migrate"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db, err := Open(context.Background(), Scheme+filepath.Join(t.TempDir(), "auth.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	runner, err := migrate.New(NewMigrationDriver(db), Migrations, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
+
+func TestMigrations_DownAndUpAgain(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	runner, _ := migrate.New(NewMigrationDriver(db), Migrations, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	all, err := migrate.Load(Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := runner.Goto(ctx, 0); err != nil || n != len(all) {
+		t.Fatalf("down to 0: n=%d err=%v", n, err)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("users table left after full rollback: %d %v", tables, err)
+	}
+	if n, err := runner.Up(ctx); err != nil || n != len(all) {
+		t.Fatalf("up again: n=%d err=%v", n, err)
+	}
+}
+
+func TestUserAndRefreshRepositories(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	tokens := NewRefreshRepository(db)
+
+	u := domain.NewUser("a@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil || u.ID == "" {
+		t.Fatalf("create: id=%q err=%v", u.ID, err)
+	}
+	if err := users.Create(ctx, domain.NewUser("a@ex.com", "other")); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("duplicate email: %v", err)
+	}
+	got, err := users.FindByEmail(ctx, "a@ex.com")
+	if err != nil || got == nil || got.ID != u.ID || !got.CreatedAt.Equal(u.CreatedAt.Truncate(time.Microsecond)) {
+		t.Fatalf("find: %+v %v", got, err)
+	}
+	if got, err := users.FindByEmail(ctx, "missing@ex.com"); got != nil || !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("missing user: %+v %v", got, err)
+	}
+
+	if err := tokens.Save(ctx, u.ID, "h1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.Save(ctx, u.ID, "h2", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.RevokeByHash(ctx, "h1"); err != nil {
+		t.Fatal(err)
+	}
+	rt, err := tokens.FindByHash(ctx, "h1")
+	if err != nil || rt == nil || rt.RevokedAt == nil || rt.UserID != u.ID {
+		t.Fatalf("revoked token: %+v %v", rt, err)
+	}
+	if err := tokens.RevokeAllByUser(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rt, _ := tokens.FindByHash(ctx, "h2"); rt == nil || rt.RevokedAt == nil {
+		t.Fatalf("token not revoked by RevokeAllByUser: %+v", rt)
+	}
+	if rt, err := tokens.FindByHash(ctx, "nope"); rt != nil || !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("missing token: %+v %v", rt, err)
+	}
+}
+
+func TestTxManager_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	tx := NewTxManager(db)
+	users := NewUserRepository(db)
+	outbox := NewOutboxRepository(db)
+
+	boom := errors.New("boom")
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		u := domain.NewUser("tx@ex.com", "hash")
+		if err := users.Create(ctx, u); err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(ctx, domain.NewUserEvent(domain.EventUserRegistered, "", u)); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if u, _ := users.FindByEmail(ctx, "tx@ex.com"); u != nil {
+		t.Fatal("user survived rollback")
+	}
+	var events int
+	if err := db.QueryRow(`SELECT count(*) FROM outbox_events`).Scan(&events); err != nil || events != 0 {
+		t.Fatalf("outbox event survived rollback: %d %v", events, err)
+	}
+}
+
+func TestAuditRepository_AppendOnlyChain(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := NewAuditRepository(db)
+
+	for _, typ := range []domain.AuditEventType{domain.AuditLoginFailed, domain.AuditLoginSucceeded} {
+		e := &domain.AuditEvent{Type: typ, ActorID: "u1", Metadata: map[string]string{"k": "v"}, OccurredAt: time.Now().UTC().Truncate(time.Microsecond)}
+		if err := repo.Append(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := repo.List(ctx, domain.AuditFilter{ActorID: "u1"})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("list: %v %+v", err, events)
+	}
+	if events[0].PrevHash != events[1].Hash || events[0].ChainHash(events[0].PrevHash) != events[0].Hash {
+		t.Fatal("chain broken")
+	}
+	if page, _ := repo.List(ctx, domain.AuditFilter{BeforeSeq: events[0].Seq, Limit: 1}); len(page) != 1 || page[0].Seq != events[1].Seq {
+		t.Fatalf("cursor page: %+v", page)
+	}
+	if _, err := db.Exec(`DELETE FROM audit_events`); err == nil {
+		t.Fatal("audit events deleted")
+	}
+}
+
+func TestWebhookRepository_FanOutClaimComplete(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	outbox := NewOutboxRepository(db)
+	repo := NewWebhookRepository(db)
+
+	all := &domain.WebhookSubscription{TenantID: "t1", URL: "https://a.example", Secret: "s", Active: true}
+	other := &domain.WebhookSubscription{TenantID: "t1", URL: "https://b.example", Secret: "s", EventTypes: []string{domain.EventUserDeleted}, Active: true}
+	for _, s := range []*domain.WebhookSubscription{all, other} {
+		if err := repo.CreateSubscription(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := outbox.Enqueue(ctx, &domain.OutboxEvent{Type: domain.EventUserRegistered, TenantID: "t1", AggregateID: "u1", Payload: []byte(`{"user_id":"u1"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := repo.FanOut(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("fan-out: n=%d err=%v", n, err)
+	}
+	if n, _ := repo.FanOut(ctx, 10); n != 0 {
+		t.Fatalf("event fanned out twice")
+	}
+
+	due, err := repo.ClaimDue(ctx, 10, time.Minute)
+	if err != nil || len(due) != 1 || due[0].SubscriptionID != all.ID || due[0].URL != all.URL || string(due[0].Event.Payload) != `{"user_id":"u1"}` {
+		t.Fatalf("claim: %+v %v", due, err)
+	}
+	if again, _ := repo.ClaimDue(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatal("leased delivery claimed again")
+	}
+
+	if err := repo.Complete(ctx, due[0].ID, domain.DeliveryResult{Status: domain.DeliveryDead, StatusCode: 500, Error: "boom"}); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := repo.ListDeliveries(ctx, domain.DeliveryFilter{TenantID: "t1", Status: domain.DeliveryDead})
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 1 || dead[0].LastStatusCode != 500 {
+		t.Fatalf("list dead: %+v %v", dead, err)
+	}
+	if err := repo.Replay(ctx, dead[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Replay(ctx, "missing"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Fatalf("replay missing: %v", err)
+	}
+	if err := repo.DeleteSubscription(ctx, all.ID); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := repo.ListDeliveries(ctx, domain.DeliveryFilter{}); len(left) != 0 {
+		t.Fatalf("deliveries not cascaded: %d", len(left))
+	}
+	if err := repo.DeleteSubscription(ctx, all.ID); !errors.Is(err, domain.ErrSubscriptionNotFound) {
+		t.Fatalf("delete missing: %v", err)
+	}
+}
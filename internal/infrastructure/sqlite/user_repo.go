@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	id := uuid.NewString()
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO users (id, email, password_hash, is_verified, locale, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, user.Email, user.Password, user.IsVerified, user.Locale, toMicros(user.CreatedAt), toMicros(user.UpdatedAt))
+	if err != nil {
+		return wrapErr("insert user", err)
+	}
+	user.ID = id
+	return nil
+}
+
+const userColumns = `id, email, password_hash, is_verified, locale, COALESCE(phone, ''), phone_verified_at, sms_second_factor, disabled_at, created_at, updated_at`
+
+func scanUser(row interface{ Scan(...any) error }) (*domain.User, error) {
+	var (
+		user                        domain.User
+		createdAt, updatedAt        int64
+		phoneVerifiedAt, disabledAt sql.NullInt64
+	)
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Password, &user.IsVerified, &user.Locale,
+		&user.Phone, &phoneVerifiedAt, &user.SMSSecondFactor, &disabledAt, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	user.CreatedAt, user.UpdatedAt = fromMicros(createdAt), fromMicros(updatedAt)
+	user.PhoneVerifiedAt = fromNullMicros(phoneVerifiedAt)
+	user.DisabledAt = fromNullMicros(disabledAt)
+	return &user, nil
+}
+
+func (r *UserRepository) findBy(ctx context.Context, column, value string) (*domain.User, error) {
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+column+` = ?`, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: find user: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, wrapErr("find user by "+column, err)
+	}
+	return user, nil
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.findBy(ctx, "email", email)
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	return r.findBy(ctx, "id", id)
+}
+
+func (r *UserRepository) FindByPhone(ctx context.Context, phone string) (*domain.User, error) {
+	return r.findBy(ctx, "phone", phone)
+}
+
+func (r *UserRepository) UpdatePhone(ctx context.Context, user *domain.User) error {
+	var verifiedAt sql.NullInt64
+	if user.PhoneVerifiedAt != nil {
+		verifiedAt = sql.NullInt64{Int64: toMicros(*user.PhoneVerifiedAt), Valid: true}
+	}
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE users
+		SET phone = NULLIF(?, ''), phone_verified_at = ?, sms_second_factor = ?, updated_at = ?
+		WHERE id = ?
+	`, user.Phone, verifiedAt, user.SMSSecondFactor, toMicros(user.UpdatedAt), user.ID)
+	if err != nil {
+		return wrapErr("update phone", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("update phone", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: update phone: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *domain.User) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE users SET email = ?, locale = ?, updated_at = ? WHERE id = ?
+	`, user.Email, user.Locale, toMicros(user.UpdatedAt), user.ID)
+	if err != nil {
+		return wrapErr("update profile", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("update profile", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: update profile: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateStatus(ctx context.Context, user *domain.User) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE users SET is_verified = ?, disabled_at = ?, updated_at = ? WHERE id = ?
+	`, user.IsVerified, nullMicros(user.DisabledAt), toMicros(user.UpdatedAt), user.ID)
+	if err != nil {
+		return wrapErr("update status", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("update status", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: update status: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// likeEscaper escapes LIKE wildcards; queries declare ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *UserRepository) Search(ctx context.Context, f domain.UserFilter) ([]domain.User, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v ...any) {
+		where = append(where, cond)
+		args = append(args, v...)
+	}
+	if f.EmailPrefix != "" {
+		add(`lower(email) LIKE ? ESCAPE '\'`, likeEscaper.Replace(strings.ToLower(f.EmailPrefix))+"%")
+	}
+	if f.Verified != nil {
+		add("is_verified = ?", *f.Verified)
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("created_at >= ?", toMicros(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		add("created_at < ?", toMicros(f.CreatedTo))
+	}
+	if f.Before != nil {
+		at := toMicros(f.Before.CreatedAt)
+		add("(created_at < ? OR (created_at = ? AND id < ?))", at, at, f.Before.ID)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr("search users", err)
+	}
+	defer rows.Close()
+	var out []domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, wrapErr("scan user", err)
+		}
+		out = append(out, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr("search users", err)
+	}
+	return out, nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return wrapErr("delete user", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return wrapErr("delete user", err)
+	} else if n == 0 {
+		return fmt.Errorf("sqlite: delete user: %w", domain.ErrNotFound)
+	}
+	return nil
+}
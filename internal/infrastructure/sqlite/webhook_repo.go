@@ -0,0 +1,292 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-auth/internal/domain"
+)
+
+type WebhookRepository struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db, now: time.Now}
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	types, err := json.Marshal(sub.EventTypes)
+	if err != nil {
+		return fmt.Errorf("sqlite: encode event types: %w", err)
+	}
+	id, createdAt := uuid.NewString(), r.now().UTC().Truncate(time.Microsecond)
+	_, err = conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, tenant_id, url, secret, event_types, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, sub.TenantID, sub.URL, sub.Secret, string(types), sub.Active, toMicros(createdAt))
+	if err != nil {
+		return wrapErr("insert webhook subscription", err)
+	}
+	sub.ID, sub.CreatedAt = id, createdAt
+	return nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, tenantID string) ([]domain.WebhookSubscription, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, tenant_id, url, secret, event_types, active, created_at
+		FROM webhook_subscriptions
+		WHERE tenant_id = ?
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, wrapErr("list webhook subscriptions", err)
+	}
+	defer rows.Close()
+
+	var out []domain.WebhookSubscription
+	for rows.Next() {
+		var (
+			s         domain.WebhookSubscription
+			types     string
+			createdAt int64
+		)
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.URL, &s.Secret, &types, &s.Active, &createdAt); err != nil {
+			return nil, wrapErr("scan webhook subscription", err)
+		}
+		if err := json.Unmarshal([]byte(types), &s.EventTypes); err != nil {
+			return nil, fmt.Errorf("sqlite: decode event types: %w", err)
+		}
+		s.CreatedAt = fromMicros(createdAt)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return wrapErr("delete webhook subscription", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) FanOut(ctx context.Context, limit int) (int, error) {
+	n := 0
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, type, tenant_id FROM outbox_events
+			WHERE processed_at IS NULL
+			ORDER BY created_at
+			LIMIT ?
+		`, limit)
+		if err != nil {
+			return wrapErr("claim outbox events", err)
+		}
+		var events []domain.OutboxEvent
+		for rows.Next() {
+			var e domain.OutboxEvent
+			if err := rows.Scan(&e.ID, &e.Type, &e.TenantID); err != nil {
+				rows.Close()
+				return wrapErr("scan outbox event", err)
+			}
+			events = append(events, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return wrapErr("claim outbox events", err)
+		}
+
+		now := toMicros(r.now())
+		for _, e := range events {
+			subs, err := tx.QueryContext(ctx, `
+				SELECT s.id FROM webhook_subscriptions s
+				WHERE (s.tenant_id = '' OR s.tenant_id = ?) AND s.active
+				  AND (json_array_length(s.event_types) = 0
+				       OR EXISTS (SELECT 1 FROM json_each(s.event_types) WHERE value = ?))
+			`, e.TenantID, e.Type)
+			if err != nil {
+				return wrapErr("match webhook subscriptions", err)
+			}
+			var subIDs []string
+			for subs.Next() {
+				var id string
+				if err := subs.Scan(&id); err != nil {
+					subs.Close()
+					return wrapErr("scan webhook subscription", err)
+				}
+				subIDs = append(subIDs, id)
+			}
+			subs.Close()
+			if err := subs.Err(); err != nil {
+				return wrapErr("match webhook subscriptions", err)
+			}
+
+			for _, subID := range subIDs {
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO webhook_deliveries (id, subscription_id, event_id, next_attempt_at, created_at, updated_at)
+					VALUES (?, ?, ?, ?, ?, ?)
+					ON CONFLICT (subscription_id, event_id) DO NOTHING
+				`, uuid.NewString(), subID, e.ID, now, now, now)
+				if err != nil {
+					return wrapErr("create webhook delivery", err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE outbox_events SET processed_at = ? WHERE id = ?`, now, e.ID); err != nil {
+				return wrapErr("mark outbox event processed", err)
+			}
+		}
+		n = len(events)
+		return nil
+	})
+	return n, err
+}
+
+const deliveryColumns = `
+	d.id, d.subscription_id, d.status, d.attempts, d.next_attempt_at, d.last_error, d.last_status_code, d.created_at, d.updated_at,
+	e.id, e.type, e.tenant_id, e.aggregate_id, e.payload, e.created_at,
+	s.url, s.secret`
+
+const deliveryJoins = `
+	FROM webhook_deliveries d
+	JOIN outbox_events e ON e.id = d.event_id
+	JOIN webhook_subscriptions s ON s.id = d.subscription_id`
+
+func scanDeliveries(rows *sql.Rows) ([]domain.WebhookDelivery, error) {
+	defer rows.Close()
+	var out []domain.WebhookDelivery
+	for rows.Next() {
+		var (
+			d                                             domain.WebhookDelivery
+			payload                                       string
+			nextAttempt, created, updated, eventCreatedAt int64
+		)
+		err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.Status, &d.Attempts, &nextAttempt, &d.LastError, &d.LastStatusCode, &created, &updated,
+			&d.Event.ID, &d.Event.Type, &d.Event.TenantID, &d.Event.AggregateID, &payload, &eventCreatedAt,
+			&d.URL, &d.Secret,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if payload != "" {
+			d.Event.Payload = json.RawMessage(payload)
+		}
+		d.NextAttemptAt, d.CreatedAt, d.UpdatedAt = fromMicros(nextAttempt), fromMicros(created), fromMicros(updated)
+		d.Event.CreatedAt = fromMicros(eventCreatedAt)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	var out []domain.WebhookDelivery
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		now := r.now()
+		rows, err := tx.QueryContext(ctx, `SELECT `+deliveryColumns+deliveryJoins+`
+			WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+			ORDER BY d.next_attempt_at
+			LIMIT ?
+		`, toMicros(now), limit)
+		if err != nil {
+			return wrapErr("claim webhook deliveries", err)
+		}
+		if out, err = scanDeliveries(rows); err != nil {
+			return wrapErr("claim webhook deliveries", err)
+		}
+
+		leased := now.Add(lease)
+		for i := range out {
+			if _, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?`, toMicros(leased), out[i].ID); err != nil {
+				return wrapErr("lease webhook delivery", err)
+			}
+			out[i].NextAttemptAt = fromMicros(toMicros(leased))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Complete(ctx context.Context, id string, res domain.DeliveryResult) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?,
+		    last_error = ?, last_status_code = ?, updated_at = ?
+		WHERE id = ?
+	`, res.Status, toMicros(res.NextAttempt), res.Error, res.StatusCode, toMicros(r.now()), id)
+	if err != nil {
+		return wrapErr("complete webhook delivery", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, f domain.DeliveryFilter) ([]domain.WebhookDelivery, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		where = append(where, cond)
+		args = append(args, v)
+	}
+	if f.TenantID != "" {
+		add("s.tenant_id = ?", f.TenantID)
+	}
+	if f.SubscriptionID != "" {
+		add("d.subscription_id = ?", f.SubscriptionID)
+	}
+	if f.Status != "" {
+		add("d.status = ?", f.Status)
+	}
+	query := `SELECT ` + deliveryColumns + deliveryJoins
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY d.created_at DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr("list webhook deliveries", err)
+	}
+	out, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, wrapErr("list webhook deliveries", err)
+	}
+	return out, nil
+}
+
+func (r *WebhookRepository) Replay(ctx context.Context, id string) error {
+	now := toMicros(r.now())
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, now, now, id)
+	if err != nil {
+		return wrapErr("replay webhook delivery", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
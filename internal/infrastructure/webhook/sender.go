@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-ID"
+)
+
+// Sender POSTs events as JSON and signs them Stripe-style:
+// X-Webhook-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "t.body">.
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{client: &http.Client{Timeout: timeout}, now: time.Now}
+}
+
+func (s *Sender) Send(ctx context.Context, url, secret, deliveryID string, event domain.OutboxEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("webhook: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-auth-webhooks/1")
+	req.Header.Set(HeaderID, deliveryID)
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderSignature, Sign(secret, s.now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: post: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+// Verify checks a signature header against body, rejecting signatures older
+// than tolerance. Receivers written in Go can use it directly.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) bool {
+	var ts, sig string
+	for _, part := range bytes.Split([]byte(header), []byte(",")) {
+		k, v, ok := bytes.Cut(part, []byte("="))
+		if !ok {
+			continue
+		}
+		switch string(k) {
+		case "t":
+			ts = string(v)
+		case "v1":
+			sig = string(v)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(mac(secret, ts, body)))
+}
+
+func mac(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-auth/internal/domain"
+)
+
+func TestSender_SignsRequests(t *testing.T) {
+	var (
+		gotBody []byte
+		gotSig  string
+		gotID   string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get(HeaderSignature)
+		gotID = r.Header.Get(HeaderID)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := NewSender(time.Second)
+	event := domain.OutboxEvent{ID: "e1", Type: domain.EventUserRegistered, Payload: []byte(`{"user_id":"u1"}`)}
+	code, err := s.Send(context.Background(), srv.URL, "whsec_test", "d1", event)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("send: code=%d err=%v", code, err)
+	}
+	if gotID != "d1" {
+		t.Fatalf("delivery id header=%q", gotID)
+	}
+	if !Verify("whsec_test", gotSig, gotBody, time.Minute, time.Now()) {
+		t.Fatalf("signature %q does not verify", gotSig)
+	}
+	if Verify("other", gotSig, gotBody, time.Minute, time.Now()) {
+		t.Fatalf("signature verified with the wrong secret")
+	}
+	if Verify("whsec_test", gotSig, gotBody, time.Minute, time.Now().Add(time.Hour)) {
+		t.Fatalf("stale signature accepted")
+	}
+}
+
+func TestSender_Non2xxIsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	code, err := NewSender(time.Second).Send(context.Background(), srv.URL, "s", "d", domain.OutboxEvent{})
+	if err == nil || code != http.StatusBadGateway {
+		t.Fatalf("expected error with status, got code=%d err=%v", code, err)
+	}
+}
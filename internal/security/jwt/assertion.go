@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-auth/internal/app"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for assertions.
+const minRSABits = 2048
+
+// AssertionVerifier checks RFC 7523 JWT assertions against public keys
+// clients registered. Assertions must expire, and no later than
+// maxLifetime from now, which bounds how long a leaked one stays useful.
+type AssertionVerifier struct {
+	maxLifetime time.Duration
+	leeway      time.Duration
+	now         func() time.Time
+}
+
+func NewAssertionVerifier(maxLifetime, leeway time.Duration) *AssertionVerifier {
+	return &AssertionVerifier{maxLifetime: maxLifetime, leeway: leeway, now: time.Now}
+}
+
+// CheckPublicKey accepts PEM "PUBLIC KEY" blocks and certificates holding
+// RSA (2048 bits or more), ECDSA or Ed25519 keys.
+func (v *AssertionVerifier) CheckPublicKey(pemKey string) error {
+	_, err := parsePublicKey(pemKey)
+	return err
+}
+
+func parsePublicKey(pemKey string) (any, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key any
+	switch block.Type {
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		key = k
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		key = cert.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must have at least %d bits", minRSABits)
+		}
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return key, nil
+}
+
+// methodFits reports whether assertions signed with m can be verified
+// with key, so that a key is never used with an algorithm of another
+// family.
+func methodFits(m jwt.SigningMethod, key any) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, rs := m.(*jwt.SigningMethodRSA)
+		_, ps := m.(*jwt.SigningMethodRSAPSS)
+		return rs || ps
+	case *ecdsa.PublicKey:
+		_, ok := m.(*jwt.SigningMethodECDSA)
+		return ok
+	case ed25519.PublicKey:
+		_, ok := m.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+func (v *AssertionVerifier) Issuer(assertion string) (string, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(assertion, &jwt.RegisteredClaims{})
+	if err != nil {
+		return "", fmt.Errorf("parse assertion: %w", err)
+	}
+	return token.Claims.GetIssuer()
+}
+
+func (v *AssertionVerifier) Verify(assertion string, keys []app.AssertionKey, audience string) (*app.JWTAssertion, error) {
+	parser := jwt.NewParser(
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(v.now),
+	)
+	unverified, _, err := parser.ParseUnverified(assertion, &jwt.RegisteredClaims{})
+	if err != nil {
+		return nil, fmt.Errorf("parse assertion: %w", err)
+	}
+	kid, _ := unverified.Header["kid"].(string)
+
+	err = errors.New("no key to verify the assertion with")
+	for _, k := range keys {
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		key, keyErr := parsePublicKey(k.PublicKey)
+		if keyErr != nil {
+			err = fmt.Errorf("key %s: %w", k.ID, keyErr)
+			continue
+		}
+		claims := &jwt.RegisteredClaims{}
+		_, err = parser.ParseWithClaims(assertion, claims, func(t *jwt.Token) (any, error) {
+			if !methodFits(t.Method, key) {
+				return nil, fmt.Errorf("signing method %s does not fit key %s", t.Method.Alg(), k.ID)
+			}
+			return key, nil
+		})
+		if err != nil {
+			continue
+		}
+		expiresAt := claims.ExpiresAt.Time
+		if expiresAt.After(v.now().Add(v.maxLifetime + v.leeway)) {
+			return nil, fmt.Errorf("assertion expires more than %s from now", v.maxLifetime)
+		}
+		return &app.JWTAssertion{Issuer: claims.Issuer, Subject: claims.Subject, KeyID: k.ID, ExpiresAt: expiresAt}, nil
+	}
+	return nil, err
+}
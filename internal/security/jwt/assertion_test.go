@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func publicPEM(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestAssertionVerifier(t *testing.T) {
+	const aud = "https://auth.example/api/v1/oauth/token"
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	keys := []app.AssertionKey{
+		{ID: "ec", PublicKey: publicPEM(t, &ecKey.PublicKey)},
+		{ID: "ed", PublicKey: publicPEM(t, edKey.Public())},
+	}
+	v := NewAssertionVerifier(time.Hour, time.Minute)
+	now := time.Now()
+	claims := func(exp time.Time) jwt.MapClaims {
+		return jwt.MapClaims{"iss": "sa-1", "sub": "sa-1", "aud": aud, "exp": exp.Unix(), "iat": now.Unix()}
+	}
+	sign := func(m jwt.SigningMethod, key any, kid string, c jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(m, c)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	ok := sign(jwt.SigningMethodEdDSA, edKey, "", claims(now.Add(5*time.Minute)))
+	if iss, err := v.Issuer(ok); err != nil || iss != "sa-1" {
+		t.Fatalf("issuer = %q, %v", iss, err)
+	}
+	got, err := v.Verify(ok, keys, aud)
+	if err != nil || got.Subject != "sa-1" || got.KeyID != "ed" {
+		t.Fatalf("verify = %+v, %v", got, err)
+	}
+	got, err = v.Verify(sign(jwt.SigningMethodES256, ecKey, "ec", claims(now.Add(5*time.Minute))), keys, aud)
+	if err != nil || got.KeyID != "ec" {
+		t.Fatalf("verify by kid = %+v, %v", got, err)
+	}
+
+	bad := map[string]string{
+		"wrong kid":     sign(jwt.SigningMethodES256, ecKey, "ed", claims(now.Add(5*time.Minute))),
+		"expired":       sign(jwt.SigningMethodES256, ecKey, "", claims(now.Add(-5*time.Minute))),
+		"too long":      sign(jwt.SigningMethodES256, ecKey, "", claims(now.Add(2*time.Hour))),
+		"no expiry":     sign(jwt.SigningMethodES256, ecKey, "", jwt.MapClaims{"iss": "sa-1", "sub": "sa-1", "aud": aud}),
+		"wrong aud":     sign(jwt.SigningMethodES256, ecKey, "", jwt.MapClaims{"iss": "sa-1", "aud": "other", "exp": now.Add(time.Minute).Unix()}),
+		"hmac with pem": sign(jwt.SigningMethodHS256, []byte(keys[0].PublicKey), "ec", claims(now.Add(5*time.Minute))),
+	}
+	for name, assertion := range bad {
+		if got, err := v.Verify(assertion, keys, aud); err == nil {
+			t.Errorf("%s: verified %+v", name, got)
+		}
+	}
+}
+
+func TestAssertionVerifier_CheckPublicKey(t *testing.T) {
+	v := NewAssertionVerifier(time.Hour, 0)
+	weak, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if err := v.CheckPublicKey(publicPEM(t, &weak.PublicKey)); err == nil {
+		t.Error("accepted a 1024-bit RSA key")
+	}
+	if err := v.CheckPublicKey("not a key"); err == nil {
+		t.Error("accepted garbage")
+	}
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err := v.CheckPublicKey(publicPEM(t, &ecKey.PublicKey)); err != nil {
+		t.Errorf("EC key: %v", err)
+	}
+}
@@ -30,4 +30,56 @@ func TestGenerateAndValidateTokens(t *testing.T) {
 	if err != nil || refresh == "" {
 		t.Fatalf("failed to generate refresh token: %v", err)
 	}
+
+	service, err := s.GenerateServiceToken(app.ServiceClaims{AccountID: "sa-1", TenantID: "acme"})
+	if err != nil {
+		t.Fatalf("failed to generate service token: %v", err)
+	}
+	if _, err := s.ValidateToken(service); err == nil {
+		t.Fatal("service account token accepted as a user token")
+	}
+
+	expires := time.Now().Add(time.Minute).Truncate(time.Second)
+	imp, err := s.GenerateImpersonationToken(app.ImpersonationClaims{ImpersonationID: "imp-1", UserID: "user-1", ActorID: "admin-1", ExpiresAt: expires})
+	if err != nil {
+		t.Fatalf("failed to generate impersonation token: %v", err)
+	}
+	if _, err := s.ValidateToken(imp); err == nil {
+		t.Fatal("impersonation token accepted as a user token")
+	}
+	claims, err := s.ValidateImpersonationToken(imp)
+	if err != nil || claims.ImpersonationID != "imp-1" || claims.UserID != "user-1" || claims.ActorID != "admin-1" || !claims.ExpiresAt.Equal(expires) {
+		t.Fatalf("validate impersonation = %+v, %v", claims, err)
+	}
+	if _, err := s.ValidateImpersonationToken(access); err == nil {
+		t.Fatal("user token accepted as an impersonation token")
+	}
+
+	subject, err := s.ValidateSubjectToken(access)
+	if err != nil || subject.UserID != "user-1" || subject.Scopes != nil || subject.Actor != nil {
+		t.Fatalf("validate subject = %+v, %v", subject, err)
+	}
+	for name, token := range map[string]string{"service": service, "impersonation": imp} {
+		if _, err := s.ValidateSubjectToken(token); err == nil {
+			t.Fatalf("%s token accepted as a subject token", name)
+		}
+	}
+	exchanged, err := s.GenerateExchangedToken(app.ExchangeClaims{
+		UserID: "user-1", ClientID: "orders", Audience: "billing", Actor: &app.Actor{Subject: "gateway"}, ExpiresAt: expires,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate exchanged token: %v", err)
+	}
+	if _, err := s.ValidateToken(exchanged); err == nil {
+		t.Fatal("exchanged token accepted as a user token")
+	}
+	if _, err := s.ValidateImpersonationToken(exchanged); err == nil {
+		t.Fatal("exchanged token accepted as an impersonation token")
+	}
+	subject, err = s.ValidateSubjectToken(exchanged)
+	if err != nil || subject.UserID != "user-1" || subject.Scopes == nil || len(subject.Scopes) != 0 || subject.Actor.Depth() != 2 ||
+		subject.Actor.Subject != "orders" || subject.Actor.Actor.Subject != "gateway" || !subject.ExpiresAt.Equal(expires) ||
+		len(subject.Audience) != 1 || subject.Audience[0] != "billing" {
+		t.Fatalf("validate exchanged subject = %+v, %v", subject, err)
+	}
 }
@@ -0,0 +1,136 @@
+package pow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-auth/internal/app"
+	solver "go-auth/pkg/pow"
+)
+
+var (
+	ErrMalformed = errors.New("pow: malformed response")
+	ErrSignature = errors.New("pow: invalid challenge signature")
+	ErrExpired   = errors.New("pow: challenge expired")
+	ErrUnsolved  = errors.New("pow: insufficient work")
+	ErrReplayed  = errors.New("pow: challenge already used")
+)
+
+// Issuer is a self-hosted app.ChallengeProvider. Challenges are stateless
+// HMAC-signed tokens carrying a random salt, the difficulty and an expiry;
+// spent challenges are remembered in an app.AttemptCounter until they expire.
+type Issuer struct {
+	secret     []byte
+	ttl        time.Duration
+	difficulty atomic.Int32
+	spent      app.AttemptCounter
+	now        func() time.Time
+}
+
+// NewIssuer creates an issuer. spent may be nil, in which case a solved
+// challenge can be replayed until it expires.
+func NewIssuer(secret []byte, difficulty int, ttl time.Duration, spent app.AttemptCounter) *Issuer {
+	i := &Issuer{secret: secret, ttl: ttl, spent: spent, now: time.Now}
+	i.SetDifficulty(difficulty)
+	return i
+}
+
+// SetDifficulty changes the number of leading zero bits required by newly
+// issued challenges. Outstanding challenges keep the difficulty they were signed with.
+func (i *Issuer) SetDifficulty(bits int) {
+	if bits < 1 {
+		bits = 1
+	}
+	if bits > solver.MaxDifficulty {
+		bits = solver.MaxDifficulty
+	}
+	i.difficulty.Store(int32(bits))
+}
+
+func (i *Issuer) Difficulty() int { return int(i.difficulty.Load()) }
+
+func (i *Issuer) Issue(context.Context) (app.Challenge, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return app.Challenge{}, fmt.Errorf("pow: salt: %w", err)
+	}
+	difficulty := i.Difficulty()
+	expires := i.now().Add(i.ttl)
+	payload := fmt.Sprintf("%s.%d.%d", hex.EncodeToString(salt), difficulty, expires.Unix())
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + i.sign(payload)
+	return app.Challenge{
+		Type: "pow",
+		Params: map[string]string{
+			"algorithm":  "sha256",
+			"challenge":  token,
+			"difficulty": strconv.Itoa(difficulty),
+			"expires_at": expires.UTC().Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// Verify checks a "challenge:nonce" response produced by pkg/pow.Solve.
+func (i *Issuer) Verify(ctx context.Context, response, _ string) error {
+	sep := strings.LastIndexByte(response, ':')
+	if sep < 0 {
+		return ErrMalformed
+	}
+	token, nonce := response[:sep], response[sep+1:]
+
+	dot := strings.IndexByte(token, '.')
+	if dot < 0 {
+		return ErrMalformed
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token[:dot])
+	if err != nil {
+		return ErrMalformed
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(token[dot+1:]), []byte(i.sign(payload))) {
+		return ErrSignature
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return ErrMalformed
+	}
+	difficulty, err1 := strconv.Atoi(parts[1])
+	expiresUnix, err2 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return ErrMalformed
+	}
+	remaining := time.Unix(expiresUnix, 0).Sub(i.now())
+	if remaining <= 0 {
+		return ErrExpired
+	}
+	if !solver.Check(token, nonce, difficulty) {
+		return ErrUnsolved
+	}
+
+	if i.spent != nil {
+		n, _, err := i.spent.Incr(ctx, "pow-spent:"+parts[0], remaining)
+		if err != nil {
+			return fmt.Errorf("pow: record spent challenge: %w", err)
+		}
+		if n > 1 {
+			return ErrReplayed
+		}
+	}
+	return nil
+}
+
+func (i *Issuer) sign(payload string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
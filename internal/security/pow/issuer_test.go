@@ -0,0 +1,64 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/infrastructure/memory"
+	solver "go-auth/pkg/pow"
+)
+
+func solve(t *testing.T, i *Issuer) string {
+	t.Helper()
+	ch, err := i.Issue(context.Background())
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if ch.Type != "pow" || ch.Params["difficulty"] != "8" {
+		t.Fatalf("unexpected challenge %+v", ch)
+	}
+	resp, err := solver.Solve(context.Background(), ch.Params["challenge"], 8)
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+	return resp
+}
+
+func TestIssuer_RoundTripAndReplay(t *testing.T) {
+	i := NewIssuer([]byte("secret"), 8, time.Minute, memory.NewAttemptCounter(0))
+	resp := solve(t, i)
+
+	if err := i.Verify(context.Background(), resp, ""); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := i.Verify(context.Background(), resp, ""); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("expected replay rejection, got %v", err)
+	}
+}
+
+func TestIssuer_RejectsForgeriesAndExpired(t *testing.T) {
+	i := NewIssuer([]byte("secret"), 8, time.Minute, nil)
+	resp := solve(t, i)
+
+	other := NewIssuer([]byte("other-secret"), 8, time.Minute, nil)
+	if err := other.Verify(context.Background(), resp, ""); !errors.Is(err, ErrSignature) {
+		t.Fatalf("expected signature error, got %v", err)
+	}
+
+	token := resp[:strings.LastIndexByte(resp, ':')]
+	bad := "x"
+	for solver.Check(token, bad, 8) {
+		bad += "x"
+	}
+	if err := i.Verify(context.Background(), token+":"+bad, ""); !errors.Is(err, ErrUnsolved) {
+		t.Fatalf("expected insufficient work, got %v", err)
+	}
+
+	i.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if err := i.Verify(context.Background(), resp, ""); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected expiry, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+// Limit sets the failure counts at which a dimension escalates.
+// A zero threshold disables that step.
+type Limit struct {
+	Challenge int
+	Block     int
+}
+
+// Policy configures LoginGuard. Failures are counted per dimension in windows
+// of Window that start with the first failure. There is deliberately no device
+// dimension: anything identifying a device arrives in headers the client
+// chooses, so an attacker rotates or omits it at will; the account and the
+// network the request comes from are the controls.
+type Policy struct {
+	Window time.Duration
+	Email  Limit
+	IP     Limit
+	Subnet Limit
+}
+
+// DefaultPolicy tolerates a few typos per account but reacts quickly to one
+// client or network spraying many accounts.
+func DefaultPolicy() Policy {
+	return Policy{
+		Window: 15 * time.Minute,
+		Email:  Limit{Challenge: 3, Block: 10},
+		IP:     Limit{Challenge: 10, Block: 50},
+		Subnet: Limit{Challenge: 30, Block: 200},
+	}
+}
+
+// LoginGuard implements app.LoginGuard on top of an app.AttemptCounter.
+// With a nil challenge provider the challenge step is skipped.
+type LoginGuard struct {
+	counter    app.AttemptCounter
+	challenges app.ChallengeProvider
+	policy     Policy
+}
+
+func NewLoginGuard(counter app.AttemptCounter, challenges app.ChallengeProvider, policy Policy) *LoginGuard {
+	return &LoginGuard{counter: counter, challenges: challenges, policy: policy}
+}
+
+type dimension struct {
+	name  string
+	key   string
+	limit Limit
+}
+
+func (g *LoginGuard) dimensions(a app.LoginAttempt) []dimension {
+	var dims []dimension
+	add := func(name, value string, limit Limit) {
+		if value != "" {
+			dims = append(dims, dimension{name: name, key: "login-fail:" + name + ":" + value, limit: limit})
+		}
+	}
+	add("email", strings.ToLower(strings.TrimSpace(a.Email)), g.policy.Email)
+	add("ip", a.IP, g.policy.IP)
+	add("subnet", Subnet(a.IP), g.policy.Subnet)
+	return dims
+}
+
+func (g *LoginGuard) Check(ctx context.Context, a app.LoginAttempt) (app.LoginDecision, error) {
+	var challengeBy string
+	for _, d := range g.dimensions(a) {
+		n, ttl, err := g.counter.Get(ctx, d.key)
+		if err != nil {
+			return app.LoginDecision{}, fmt.Errorf("throttle: read %s failures: %w", d.name, err)
+		}
+		if d.limit.Block > 0 && n >= d.limit.Block {
+			return app.LoginDecision{Verdict: app.LoginBlock, RetryAfter: ttl, Reason: d.name}, nil
+		}
+		if challengeBy == "" && d.limit.Challenge > 0 && n >= d.limit.Challenge {
+			challengeBy = d.name
+		}
+	}
+	if challengeBy == "" || g.challenges == nil {
+		return app.LoginDecision{Verdict: app.LoginAllow}, nil
+	}
+	if a.ChallengePassed || (a.Challenge != "" && g.challenges.Verify(ctx, a.Challenge, a.IP) == nil) {
+		return app.LoginDecision{Verdict: app.LoginAllow, Reason: challengeBy}, nil
+	}
+	ch, err := g.challenges.Issue(ctx)
+	if err != nil {
+		return app.LoginDecision{}, fmt.Errorf("throttle: issue challenge: %w", err)
+	}
+	return app.LoginDecision{Verdict: app.LoginChallenge, Challenge: &ch, Reason: challengeBy}, nil
+}
+
+func (g *LoginGuard) RecordFailure(ctx context.Context, a app.LoginAttempt) error {
+	for _, d := range g.dimensions(a) {
+		if _, _, err := g.counter.Incr(ctx, d.key, g.policy.Window); err != nil {
+			return fmt.Errorf("throttle: record %s failure: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the account counter. IP and subnet counters are kept
+// so that one valid account cannot launder a spraying source.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, a app.LoginAttempt) error {
+	for _, d := range g.dimensions(a) {
+		if d.name != "email" {
+			continue
+		}
+		if err := g.counter.Reset(ctx, d.key); err != nil {
+			return fmt.Errorf("throttle: reset %s failures: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// Elevated reports whether the client IP or its subnet has reached the
+// challenge threshold. It is meant for gating routes other than login.
+func (g *LoginGuard) Elevated(ctx context.Context, ip string) bool {
+	for _, d := range g.dimensions(app.LoginAttempt{IP: ip}) {
+		if d.limit.Challenge <= 0 {
+			continue
+		}
+		if n, _, err := g.counter.Get(ctx, d.key); err == nil && n >= d.limit.Challenge {
+			return true
+		}
+	}
+	return false
+}
+
+// Subnet returns the /24 (IPv4) or /64 (IPv6) network of ip, or "" if ip is invalid.
+func Subnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
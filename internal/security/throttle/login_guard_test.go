@@ -0,0 +1,94 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/memory"
+)
+
+type fakeChallenges struct{}
+
+func (fakeChallenges) Issue(context.Context) (app.Challenge, error) {
+	return app.Challenge{Type: "test"}, nil
+}
+
+func (fakeChallenges) Verify(_ context.Context, response, _ string) error {
+	if response == "solved" {
+		return nil
+	}
+	return errors.New("wrong")
+}
+
+func TestLoginGuard_EscalatesPerEmail(t *testing.T) {
+	ctx := context.Background()
+	g := NewLoginGuard(memory.NewAttemptCounter(0), fakeChallenges{}, Policy{
+		Window: time.Minute,
+		Email:  Limit{Challenge: 2, Block: 4},
+	})
+	a := app.LoginAttempt{Email: "victim@ex.com", IP: "203.0.113.7"}
+
+	for i := 0; i < 2; i++ {
+		if d, _ := g.Check(ctx, a); d.Verdict != app.LoginAllow {
+			t.Fatalf("attempt %d: verdict=%v", i, d.Verdict)
+		}
+		_ = g.RecordFailure(ctx, a)
+	}
+	d, _ := g.Check(ctx, a)
+	if d.Verdict != app.LoginChallenge || d.Challenge == nil || d.Reason != "email" {
+		t.Fatalf("expected challenge, got %+v", d)
+	}
+
+	solved := a
+	solved.Challenge = "solved"
+	if d, _ := g.Check(ctx, solved); d.Verdict != app.LoginAllow {
+		t.Fatalf("solved challenge should pass, got %+v", d)
+	}
+
+	_ = g.RecordFailure(ctx, a)
+	_ = g.RecordFailure(ctx, a)
+	d, _ = g.Check(ctx, solved)
+	if d.Verdict != app.LoginBlock || d.RetryAfter <= 0 {
+		t.Fatalf("expected block, got %+v", d)
+	}
+
+	_ = g.RecordSuccess(ctx, a)
+	if d, _ := g.Check(ctx, a); d.Verdict != app.LoginAllow {
+		t.Fatalf("success should clear email failures, got %+v", d)
+	}
+}
+
+func TestLoginGuard_SubnetSpansAddresses(t *testing.T) {
+	ctx := context.Background()
+	g := NewLoginGuard(memory.NewAttemptCounter(0), nil, Policy{
+		Window: time.Minute,
+		Subnet: Limit{Block: 3},
+	})
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		_ = g.RecordFailure(ctx, app.LoginAttempt{Email: ip + "@ex.com", IP: ip})
+	}
+	d, _ := g.Check(ctx, app.LoginAttempt{Email: "new@ex.com", IP: "198.51.100.99"})
+	if d.Verdict != app.LoginBlock || d.Reason != "subnet" {
+		t.Fatalf("expected subnet block, got %+v", d)
+	}
+	if d, _ := g.Check(ctx, app.LoginAttempt{Email: "new@ex.com", IP: "198.51.101.1"}); d.Verdict != app.LoginAllow {
+		t.Fatalf("neighbouring /24 must not be blocked, got %+v", d)
+	}
+}
+
+func TestSubnet(t *testing.T) {
+	cases := map[string]string{
+		"192.0.2.77":        "192.0.2.0/24",
+		"2001:db8:1:2:3::4": "2001:db8:1:2::/64",
+		"::ffff:192.0.2.77": "192.0.2.0/24",
+		"not-an-ip":         "",
+	}
+	for in, want := range cases {
+		if got := Subnet(in); got != want {
+			t.Errorf("Subnet(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	log         *slog.Logger
+	listAuditUC *usecase.ListAuditEventsUseCase
+	webhooksUC  *usecase.ManageWebhooksUseCase
+}
+
+func NewAdminHandler(log *slog.Logger, listAuditUC *usecase.ListAuditEventsUseCase, webhooksUC *usecase.ManageWebhooksUseCase) *AdminHandler {
+	return &AdminHandler{log: log, listAuditUC: listAuditUC, webhooksUC: webhooksUC}
+}
+
+// RegisterRoutes mounts the admin API. The caller is responsible for
+// authenticating and authorizing the group.
+func (h *AdminHandler) RegisterRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin")
+	{
+		admin.GET("/audit-events", h.listAuditEvents)
+
+		admin.GET("/webhooks", h.listWebhooks)
+		admin.POST("/webhooks", h.createWebhook)
+		admin.DELETE("/webhooks/:id", h.deleteWebhook)
+		admin.GET("/webhooks/deliveries", h.listWebhookDeliveries)
+		admin.POST("/webhooks/deliveries/:id/replay", h.replayWebhookDelivery)
+	}
+}
+
+func (h *AdminHandler) listAuditEvents(c *gin.Context) {
+	filter := domain.AuditFilter{
+		Type:     domain.AuditEventType(c.Query("type")),
+		ActorID:  c.Query("actor_id"),
+		TargetID: c.Query("target_id"),
+		TenantID: c.Query("tenant_id"),
+	}
+	var err error
+	if filter.From, err = parseTimeParam(c, "from"); err != nil {
+		_ = c.Error(invalidParam("from", "Invalid 'from', expected RFC 3339"))
+		return
+	}
+	if filter.To, err = parseTimeParam(c, "to"); err != nil {
+		_ = c.Error(invalidParam("to", "Invalid 'to', expected RFC 3339"))
+		return
+	}
+	if filter.Limit, err = parseIntParam(c, "limit"); err != nil {
+		_ = c.Error(invalidParam("limit", "Invalid 'limit'"))
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		if filter.BeforeSeq, err = strconv.ParseInt(v, 10, 64); err != nil {
+			_ = c.Error(invalidParam("cursor", "Invalid 'cursor'"))
+			return
+		}
+	}
+
+	res, err := h.listAuditUC.Handle(c.Request.Context(), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	body := gin.H{"items": res.Events}
+	if res.Events == nil {
+		body["items"] = []domain.AuditEvent{}
+	}
+	if res.NextCursor > 0 {
+		body["next_cursor"] = strconv.FormatInt(res.NextCursor, 10)
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+type createWebhookRequest struct {
+	TenantID   string   `json:"tenant_id"`
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (h *AdminHandler) createWebhook(c *gin.Context) {
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	sub, err := h.webhooksUC.CreateSubscription(c.Request.Context(), usecase.CreateWebhookCmd{
+		TenantID:   req.TenantID,
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// The secret is only ever returned here.
+	c.JSON(http.StatusCreated, gin.H{"subscription": sub, "secret": sub.Secret})
+}
+
+func (h *AdminHandler) listWebhooks(c *gin.Context) {
+	subs, err := h.webhooksUC.ListSubscriptions(c.Request.Context(), c.Query("tenant_id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if subs == nil {
+		subs = []domain.WebhookSubscription{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": subs})
+}
+
+func (h *AdminHandler) deleteWebhook(c *gin.Context) {
+	if err := h.webhooksUC.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) listWebhookDeliveries(c *gin.Context) {
+	limit, err := parseIntParam(c, "limit")
+	if err != nil {
+		_ = c.Error(invalidParam("limit", "Invalid 'limit'"))
+		return
+	}
+	deliveries, err := h.webhooksUC.ListDeliveries(c.Request.Context(), domain.DeliveryFilter{
+		TenantID:       c.Query("tenant_id"),
+		SubscriptionID: c.Query("subscription_id"),
+		Status:         domain.DeliveryStatus(c.Query("status")),
+		Limit:          limit,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []domain.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": deliveries})
+}
+
+func (h *AdminHandler) replayWebhookDelivery(c *gin.Context) {
+	if err := h.webhooksUC.Replay(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func invalidParam(name, msg string) error {
+	return app.NewError(app.ErrCodeValidation, msg).
+		WithFields(fieldError(name, "format", ""))
+}
+
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func parseIntParam(c *gin.Context, name string) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
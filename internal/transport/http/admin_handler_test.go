@@ -0,0 +1,83 @@
+package httpv1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+type staticTokens map[string]string
+
+func (s staticTokens) ValidateToken(token string) (string, error) {
+	if uid, ok := s[token]; ok {
+		return uid, nil
+	}
+	return "", errors.New("invalid token")
+}
+
+func TestAdmin_ListAuditEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewAuditRepository()
+	audit := app.NewAuditRecorder(repo)
+	ctx := app.WithRequestMeta(context.Background(), app.RequestMeta{RequestID: "req-1", IP: "192.0.2.1"})
+	for i := 0; i < 3; i++ {
+		_ = audit.Record(ctx, domain.AuditEvent{Type: domain.AuditLoginFailed, TargetID: "u1"})
+	}
+	_ = audit.Record(ctx, domain.AuditEvent{Type: domain.AuditLogout, ActorID: "u2"})
+
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+	group := r.Group("/api/v1", Authenticate(staticTokens{"admin-token": "admin", "user-token": "u1"}, nil, nil), RequireAdmin([]string{"admin"}))
+	NewAdminHandler(slog.Default(), usecase.NewListAuditEventsUseCase(repo), nil).RegisterRoutes(group)
+
+	get := func(token, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/v1/admin/audit-events"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("", ""); w.Code != 401 {
+		t.Fatalf("anonymous code=%d", w.Code)
+	}
+	if w := get("user-token", ""); w.Code != 403 {
+		t.Fatalf("non-admin code=%d", w.Code)
+	}
+
+	w := get("admin-token", "?type=auth.login.failed&limit=2")
+	if w.Code != 200 {
+		t.Fatalf("admin code=%d body=%s", w.Code, w.Body)
+	}
+	var page struct {
+		Items      []domain.AuditEvent `json:"items"`
+		NextCursor string              `json:"next_cursor"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Items) != 2 || page.NextCursor == "" || page.Items[0].RequestID != "req-1" || page.Items[0].IP != "192.0.2.1" {
+		t.Fatalf("unexpected page: %s", w.Body)
+	}
+
+	w = get("admin-token", "?type=auth.login.failed&limit=2&cursor="+page.NextCursor)
+	page.NextCursor = ""
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Items) != 1 || page.NextCursor != "" {
+		t.Fatalf("unexpected second page: %s", w.Body)
+	}
+
+	if w := get("admin-token", "?from=yesterday"); w.Code != 400 {
+		t.Fatalf("bad time code=%d", w.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package httpv1
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminUserHandler struct {
+	log *slog.Logger
+	uc  *usecase.AdminUsersUseCase
+}
+
+func NewAdminUserHandler(log *slog.Logger, uc *usecase.AdminUsersUseCase) *AdminUserHandler {
+	return &AdminUserHandler{log: log, uc: uc}
+}
+
+// RegisterRoutes mounts user management. The caller is responsible for
+// authenticating and authorizing the group.
+func (h *AdminUserHandler) RegisterRoutes(router *gin.RouterGroup) {
+	users := router.Group("/admin/users")
+	{
+		users.GET("", h.search)
+		users.GET("/:id", h.get)
+		users.DELETE("/:id", h.delete)
+		users.POST("/:id/disable", h.setDisabled(true))
+		users.POST("/:id/enable", h.setDisabled(false))
+		users.POST("/:id/logout", h.logout)
+		users.POST("/:id/verify-email", h.verifyEmail)
+	}
+}
+
+func (h *AdminUserHandler) search(c *gin.Context) {
+	filter := domain.UserFilter{EmailPrefix: c.Query("email_prefix")}
+	if v := c.Query("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			_ = c.Error(invalidParam("verified", "Invalid 'verified', expected true or false"))
+			return
+		}
+		filter.Verified = &verified
+	}
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(c, "created_from"); err != nil {
+		_ = c.Error(invalidParam("created_from", "Invalid 'created_from', expected RFC 3339"))
+		return
+	}
+	if filter.CreatedTo, err = parseTimeParam(c, "created_to"); err != nil {
+		_ = c.Error(invalidParam("created_to", "Invalid 'created_to', expected RFC 3339"))
+		return
+	}
+	if filter.Limit, err = parseIntParam(c, "limit"); err != nil {
+		_ = c.Error(invalidParam("limit", "Invalid 'limit'"))
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		if filter.Before, err = decodeUserCursor(v); err != nil {
+			_ = c.Error(invalidParam("cursor", "Invalid 'cursor'"))
+			return
+		}
+	}
+
+	res, err := h.uc.Search(c.Request.Context(), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	body := gin.H{"items": res.Users}
+	if res.Users == nil {
+		body["items"] = []domain.User{}
+	}
+	if res.NextCursor != nil {
+		body["next_cursor"] = encodeUserCursor(*res.NextCursor)
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// encodeUserCursor makes an opaque page token from a keyset position.
+func encodeUserCursor(cur domain.UserCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(cur.CreatedAt.UnixMicro(), 10) + ":" + cur.ID))
+}
+
+func decodeUserCursor(s string) (*domain.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	micros, id, _ := strings.Cut(string(raw), ":")
+	at, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserCursor{CreatedAt: time.UnixMicro(at).UTC(), ID: id}, nil
+}
+
+func (h *AdminUserHandler) get(c *gin.Context) {
+	user, err := h.uc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminUserHandler) setDisabled(disabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := h.uc.SetDisabled(c.Request.Context(), c.GetString(userIDKey), c.Param("id"), disabled)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+func (h *AdminUserHandler) logout(c *gin.Context) {
+	if err := h.uc.Logout(c.Request.Context(), c.GetString(userIDKey), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminUserHandler) verifyEmail(c *gin.Context) {
+	user, err := h.uc.VerifyEmail(c.Request.Context(), c.GetString(userIDKey), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminUserHandler) delete(c *gin.Context) {
+	if err := h.uc.Delete(c.Request.Context(), c.GetString(userIDKey), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
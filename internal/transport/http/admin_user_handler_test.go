@@ -0,0 +1,102 @@
+package httpv1
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_AdminUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := memory.NewUserRepository()
+	var ids []string
+	for _, email := range []string{"ann@ex.com", "bob@ex.com", "anna@ex.com"} {
+		u := domain.NewUser(email, "hash")
+		if err := users.Create(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, u.ID)
+	}
+
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+	uc := usecase.NewAdminUsersUseCase(slog.Default(), nil, users, memory.NewRefreshRepository(), memory.NewPersonalAccessTokenRepository(), nil, nil, nil)
+	group := r.Group("/api/v1", Authenticate(staticTokens{"admin-token": "admin", "user-token": "u1"}, nil, nil), RequireAdmin([]string{"admin"}))
+	NewAdminUserHandler(slog.Default(), uc).RegisterRoutes(group)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/admin/users"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "", "user-token"); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin code=%d", w.Code)
+	}
+	for _, query := range []string{"?verified=maybe", "?created_from=yesterday", "?cursor=!!"} {
+		if w := do("GET", query, "admin-token"); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s code=%d body=%s", query, w.Code, w.Body)
+		}
+	}
+
+	var seen []string
+	cursor := ""
+	for page := 0; page < 3; page++ {
+		w := do("GET", "?email_prefix=an&verified=false&limit=1"+cursor, "admin-token")
+		var body struct {
+			Items      []domain.User `json:"items"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("search code=%d body=%s", w.Code, w.Body)
+		}
+		for _, u := range body.Items {
+			seen = append(seen, u.ID)
+		}
+		if body.NextCursor == "" {
+			break
+		}
+		cursor = "&cursor=" + body.NextCursor
+	}
+	if len(seen) != 2 || seen[0] == seen[1] || seen[0] == ids[1] || seen[1] == ids[1] {
+		t.Fatalf("paged search = %v, users %v", seen, ids)
+	}
+
+	if w := do("POST", "/"+ids[1]+"/disable", "admin-token"); w.Code != http.StatusOK {
+		t.Fatalf("disable code=%d body=%s", w.Code, w.Body)
+	}
+	var user domain.User
+	w := do("GET", "/"+ids[1], "admin-token")
+	if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil || user.DisabledAt == nil {
+		t.Fatalf("get code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("POST", "/admin/disable", "admin-token"); w.Code != http.StatusBadRequest {
+		t.Fatalf("disable self code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("POST", "/"+ids[1]+"/enable", "admin-token"); w.Code != http.StatusOK {
+		t.Fatalf("enable code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("POST", "/"+ids[1]+"/verify-email", "admin-token"); w.Code != http.StatusOK {
+		t.Fatalf("verify code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("POST", "/"+ids[1]+"/logout", "admin-token"); w.Code != http.StatusNoContent {
+		t.Fatalf("logout code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("DELETE", "/"+ids[1], "admin-token"); w.Code != http.StatusNoContent {
+		t.Fatalf("delete code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("GET", "/"+ids[1], "admin-token"); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted code=%d body=%s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,88 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-auth/internal/app/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// federatedBindingCookie binds an upstream login to the browser that
+// started it.
+const federatedBindingCookie = "oidc_binding"
+
+type FederatedHandler struct {
+	log          *slog.Logger
+	uc           *usecase.FederatedLoginUseCase
+	secureCookie bool
+}
+
+// NewFederatedHandler creates the handler. secureCookie marks the binding
+// cookie Secure and should be set whenever the API is served over HTTPS.
+func NewFederatedHandler(log *slog.Logger, uc *usecase.FederatedLoginUseCase, secureCookie bool) *FederatedHandler {
+	return &FederatedHandler{log: log, uc: uc, secureCookie: secureCookie}
+}
+
+func (h *FederatedHandler) RegisterRoutes(router *gin.RouterGroup) {
+	oidc := router.Group("/auth/oidc")
+	{
+		oidc.GET("/providers", h.providers)
+		oidc.GET("/:provider/authorize", h.authorize)
+		oidc.POST("/callback", h.callback)
+	}
+}
+
+func (h *FederatedHandler) providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.uc.Providers(c.Query("tenant_id"))})
+}
+
+func (h *FederatedHandler) authorize(c *gin.Context) {
+	res, err := h.uc.Start(c.Request.Context(), usecase.StartFederatedLoginCmd{
+		TenantID: c.Query("tenant_id"),
+		Provider: c.Param("provider"),
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// Scoped to the callback endpoint, which lives under this path.
+	h.setBinding(c, res.Binding, int(res.ExpiresIn), strings.TrimSuffix(c.FullPath(), "/:provider/authorize"))
+	c.Redirect(http.StatusFound, res.AuthURL)
+}
+
+type federatedCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+func (h *FederatedHandler) callback(c *gin.Context) {
+	var req federatedCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	binding, _ := c.Cookie(federatedBindingCookie)
+	res, err := h.uc.Callback(c.Request.Context(), usecase.FederatedCallbackCmd{Code: req.Code, State: req.State, Binding: binding})
+	if err != nil {
+		h.log.Warn("federated login failed", "error", err)
+		_ = c.Error(err)
+		return
+	}
+	h.setBinding(c, "", -1, strings.TrimSuffix(c.FullPath(), "/callback"))
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"expires_in":    res.ExpiresIn,
+		"token_type":    "Bearer",
+	})
+}
+
+// setBinding uses SameSite=Lax: the browser reaches the callback page via a
+// cross-site redirect from the provider.
+func (h *FederatedHandler) setBinding(c *gin.Context, value string, maxAge int, path string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(federatedBindingCookie, value, maxAge, path, "", h.secureCookie, true)
+}
@@ -0,0 +1,81 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/oidc"
+	"go-auth/internal/infrastructure/oidc/oidctest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_FederatedLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	upstream := oidctest.NewProvider(t)
+	provider := upstream.Config("fake")
+	provider.Name, provider.AutoRegister = "Fake IdP", true
+	users := memory.NewUserRepository()
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, users, nil, app.PasswordService(fakePwd{}), nil)
+	logUC := usecase.NewLoginUserUseCase(slog.Default(), users, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil, nil, nil)
+	uc := usecase.NewFederatedLoginUseCase(slog.Default(), usecase.FederatedLoginConfig{
+		Providers:   app.OIDCProviders{Default: []app.OIDCProvider{provider}},
+		RedirectURL: "https://app.example.com/oidc/callback",
+	}, nil, memory.NewFederatedLoginRepository(), memory.NewIdentityRepository(), users, regUC, logUC, oidc.NewClient(oidc.Options{}), nil)
+	NewFederatedHandler(slog.Default(), uc, true).RegisterRoutes(r.Group("/api/v1"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/auth/oidc/providers", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"providers":[{"id":"fake","name":"Fake IdP"}]}` {
+		t.Fatalf("providers code=%d body=%s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/auth/oidc/fake/authorize", nil))
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), upstream.Issuer+"/authorize?") {
+		t.Fatalf("authorize code=%d location=%s", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != federatedBindingCookie || !cookies[0].HttpOnly || !cookies[0].Secure ||
+		cookies[0].SameSite != http.SameSiteLaxMode || cookies[0].Path != "/api/v1/auth/oidc" {
+		t.Fatalf("binding cookie = %+v", cookies)
+	}
+	code, state := upstream.Authorize(t, w.Header().Get("Location"))
+
+	post := func(body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/auth/oidc/callback", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	body := `{"code":"` + code + `","state":"` + state + `"}`
+	if w := post(body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("callback without cookie code=%d", w.Code)
+	}
+	w = post(body, cookies[0])
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"access_token":"acc:`) {
+		t.Fatalf("callback code=%d body=%s", w.Code, w.Body)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Fatalf("binding cookie not cleared: %+v", c)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/auth/oidc/unknown/authorize", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown provider code=%d", w.Code)
+	}
+}
@@ -47,9 +47,11 @@ func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
 type registerRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
+	Locale   string `json:"locale"`
 }
 
 type loginRequest struct {
+	TenantID string `json:"tenant_id"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
@@ -57,35 +59,23 @@ type loginRequest struct {
 func (h *AuthHandler) register(c *gin.Context) {
 	var req registerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(bindError(err))
 		return
 	}
 
 	cmd := usecase.RegisterUserCmd{
 		Email:    req.Email,
 		Password: req.Password,
+		Locale:   req.Locale,
 	}
 	if !validPassword(req.Password) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet complexity", "code": app.ErrCodeValidation})
+		_ = c.Error(app.NewError(app.ErrCodeValidation, "Password does not meet complexity").
+			WithFields(fieldError("password", "complexity", "")))
 		return
 	}
 
 	if err := h.registerUC.Handle(c.Request.Context(), cmd); err != nil {
-		h.log.Error("registration failed", "error", err)
-		code := app.ErrCodeInternal
-		status := http.StatusInternalServerError
-		msg := "Registration failed"
-		if ae, ok := err.(app.AppError); ok {
-			code = ae.Code
-			msg = ae.Msg
-			switch ae.Code {
-			case app.ErrCodeEmailExists:
-				status = http.StatusConflict
-			case app.ErrCodeValidation:
-				status = http.StatusBadRequest
-			}
-		}
-		c.JSON(status, gin.H{"error": msg, "code": code})
+		_ = c.Error(err)
 		return
 	}
 
@@ -95,26 +85,24 @@ func (h *AuthHandler) register(c *gin.Context) {
 func (h *AuthHandler) login(c *gin.Context) {
 	var req loginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(bindError(err))
 		return
 	}
 
 	cmd := usecase.LoginUserCmd{
-		Email:    req.Email,
-		Password: req.Password,
+		TenantID:  req.TenantID,
+		Email:     req.Email,
+		Password:  req.Password,
+		IP:        c.ClientIP(),
+		Challenge: c.GetHeader(challengeResponseHeader),
+		// Already verified (and spent) by RequireChallenge, if mounted.
+		ChallengePassed: c.GetBool(challengePassedKey),
 	}
 
 	res, err := h.loginUC.Handle(c.Request.Context(), cmd)
 	if err != nil {
 		h.log.Warn("login failed", "error", err)
-		status := http.StatusUnauthorized
-		code := app.ErrCodeInvalidCredentials
-		msg := "Invalid credentials"
-		if ae, ok := err.(app.AppError); ok {
-			code = ae.Code
-			msg = ae.Msg
-		}
-		c.JSON(status, gin.H{"error": msg, "code": code})
+		_ = c.Error(err)
 		return
 	}
 
@@ -126,6 +114,8 @@ func (h *AuthHandler) login(c *gin.Context) {
 	})
 }
 
+const challengeResponseHeader = "X-Challenge-Response"
+
 func validPassword(p string) bool {
 	var up, low, dig, spec bool
 	for i := 0; i < len(p); i++ {
@@ -153,20 +143,13 @@ type refreshRequest struct {
 func (h *AuthHandler) refresh(c *gin.Context) {
 	var req refreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": app.ErrCodeValidation})
+		_ = c.Error(bindError(err))
 		return
 	}
 	res, err := h.refreshUC.Handle(c.Request.Context(), usecase.RefreshCmd{RefreshToken: req.RefreshToken})
 	if err != nil {
 		h.log.Warn("refresh failed", "error", err)
-		status := http.StatusUnauthorized
-		code := app.ErrCodeInvalidCredentials
-		msg := "Invalid refresh token"
-		if ae, ok := err.(app.AppError); ok {
-			code = ae.Code
-			msg = ae.Msg
-		}
-		c.JSON(status, gin.H{"error": msg, "code": code})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"access_token": res.AccessToken, "refresh_token": res.RefreshToken, "expires_in": res.ExpiresIn, "token_type": "Bearer"})
@@ -175,17 +158,17 @@ func (h *AuthHandler) refresh(c *gin.Context) {
 func (h *AuthHandler) logout(c *gin.Context) {
 	auth := c.GetHeader("Authorization")
 	if len(auth) < 8 || auth[:7] != "Bearer " {
-		c.Status(http.StatusUnauthorized)
+		_ = c.Error(app.NewError(app.ErrCodeUnauthorized, "Unauthorized"))
 		return
 	}
 	token := auth[7:]
 	uid, err := h.loginUC.TokenUserID(token)
 	if err != nil {
-		c.Status(http.StatusUnauthorized)
+		_ = c.Error(app.NewError(app.ErrCodeUnauthorized, "Unauthorized"))
 		return
 	}
 	if err := h.logoutUC.Handle(c.Request.Context(), usecase.LogoutCmd{UserID: uid}); err != nil {
-		c.Status(http.StatusInternalServerError)
+		_ = c.Error(err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -12,27 +12,11 @@ import (
     "go-auth/internal/app"
     "go-auth/internal/app/usecase"
     "go-auth/internal/domain"
+    "go-auth/internal/infrastructure/memory"
 
     "github.com/gin-gonic/gin"
 )
 
-type memRepo struct{ users map[string]*domain.User }
-
-func (r *memRepo) Create(_ context.Context, u *domain.User) error {
-	if r.users == nil {
-		r.users = map[string]*domain.User{}
-	}
-	r.users[u.Email] = u
-	u.ID = "id-1"
-	return nil
-}
-func (r *memRepo) FindByEmail(_ context.Context, email string) (*domain.User, error) {
-	if r.users == nil {
-		return nil, nil
-	}
-	return r.users[email], nil
-}
-
 type fakePwd struct{}
 
 func (fakePwd) Hash(p string) (string, error) { return "hash:" + p, nil }
@@ -55,10 +39,11 @@ func (fakeToken) RefreshTTL() time.Duration                          { return 7
 func TestRoutes_RegisterAndLogin(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
+	r.Use(Errors(slog.Default()))
 
-	repo := &memRepo{}
-	regUC := usecase.NewRegisterUserUseCase(slog.Default(), repo, app.PasswordService(fakePwd{}))
-    logUC := usecase.NewLoginUserUseCase(slog.Default(), repo, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil)
+	repo := memory.NewUserRepository()
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, repo, nil, app.PasswordService(fakePwd{}), nil)
+    logUC := usecase.NewLoginUserUseCase(slog.Default(), repo, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil, nil, nil)
 
     h := NewAuthHandler(slog.Default(), regUC, logUC, nil, nil)
 	h.RegisterRoutes(r.Group("/api/v1"))
@@ -79,3 +64,30 @@ func TestRoutes_RegisterAndLogin(t *testing.T) {
 		t.Fatalf("login code=%d", w2.Code)
 	}
 }
+
+type downRepo struct{ domain.UserRepository }
+
+func (downRepo) Create(context.Context, *domain.User) error { return domain.ErrUnavailable }
+func (downRepo) FindByEmail(context.Context, string) (*domain.User, error) {
+	return nil, fmt.Errorf("postgres: find user: %w", domain.ErrUnavailable)
+}
+
+func TestRoutes_StorageUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, downRepo{}, nil, app.PasswordService(fakePwd{}), nil)
+	logUC := usecase.NewLoginUserUseCase(slog.Default(), downRepo{}, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil, nil, nil)
+	NewAuthHandler(slog.Default(), regUC, logUC, nil, nil).RegisterRoutes(r.Group("/api/v1"))
+
+	for _, path := range []string{"/api/v1/auth/register", "/api/v1/auth/login"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"email":"t@e.com","password":"Password123!"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != 503 || !strings.Contains(w.Body.String(), app.ErrCodeUnavailable) {
+			t.Fatalf("%s: code=%d body=%s, want 503 %s", path, w.Code, w.Body.String(), app.ErrCodeUnavailable)
+		}
+	}
+}
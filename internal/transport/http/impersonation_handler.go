@@ -0,0 +1,76 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go-auth/internal/app/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationHandler struct {
+	log *slog.Logger
+	uc  *usecase.ImpersonationUseCase
+}
+
+func NewImpersonationHandler(log *slog.Logger, uc *usecase.ImpersonationUseCase) *ImpersonationHandler {
+	return &ImpersonationHandler{log: log, uc: uc}
+}
+
+// RegisterRoutes mounts impersonation for admins. The caller is responsible
+// for authenticating and authorizing the group, and should keep
+// impersonation tokens out of it with ForbidImpersonation.
+func (h *ImpersonationHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/admin/users/:id/impersonate", h.start)
+	imps := router.Group("/admin/impersonations")
+	{
+		imps.GET("/:id", h.get)
+		imps.DELETE("/:id", h.end)
+	}
+}
+
+type startImpersonationRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+func (h *ImpersonationHandler) start(c *gin.Context) {
+	var req startImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	res, err := h.uc.Start(c.Request.Context(), usecase.StartImpersonationCmd{
+		ActorID: c.GetString(userIDKey),
+		UserID:  c.Param("id"),
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{
+		"access_token":  res.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    res.ExpiresIn,
+		"impersonation": res.Impersonation,
+	})
+}
+
+func (h *ImpersonationHandler) get(c *gin.Context) {
+	imp, err := h.uc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, imp)
+}
+
+func (h *ImpersonationHandler) end(c *gin.Context) {
+	if _, err := h.uc.End(c.Request.Context(), c.GetString(userIDKey), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
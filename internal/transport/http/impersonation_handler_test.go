@@ -0,0 +1,102 @@
+package httpv1
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/security/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users, auditRepo := memory.NewUserRepository(), memory.NewAuditRepository()
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Hour})
+	adminToken, _ := tokens.GenerateAccessToken("admin")
+	audit := app.NewAuditRecorder(auditRepo)
+	uc := usecase.NewImpersonationUseCase(slog.Default(), 15*time.Minute, users, memory.NewImpersonationRepository(), tokens, audit)
+	pats := usecase.NewPersonalTokensUseCase(slog.Default(), memory.NewPersonalAccessTokenRepository(), nil)
+
+	// Routes are mounted as the service mounts them.
+	r := gin.New()
+	r.Use(RequestID(), Errors(slog.Default()))
+	v1 := r.Group("/api/v1")
+	NewImpersonationHandler(slog.Default(), uc).RegisterRoutes(v1.Group("", Authenticate(tokens, pats, uc), ForbidImpersonation(), RequireAdmin([]string{"admin"})))
+	userGroup := v1.Group("", Authenticate(tokens, pats, uc))
+	NewProfileHandler(slog.Default(), usecase.NewProfileUseCase(slog.Default(), users)).RegisterRoutes(userGroup.Group("", RequireScope("user")))
+	NewPersonalTokenHandler(slog.Default(), pats).RegisterRoutes(userGroup.Group("", ForbidImpersonation(), RequireSession()))
+	userGroup.GET("/users/me/probe", func(c *gin.Context) {
+		_ = audit.Record(c.Request.Context(), domain.AuditEvent{Type: domain.AuditPhoneUpdated, ActorID: c.GetString(userIDKey)})
+		c.String(http.StatusOK, c.GetString(userIDKey))
+	})
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/admin/users/"+u.ID+"/impersonate", adminToken, `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing reason code=%d body=%s", w.Code, w.Body)
+	}
+	w := do("POST", "/admin/users/"+u.ID+"/impersonate", adminToken, `{"reason":"ticket 42"}`)
+	var started struct {
+		AccessToken   string               `json:"access_token"`
+		RefreshToken  string               `json:"refresh_token"`
+		Impersonation domain.Impersonation `json:"impersonation"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil || w.Code != http.StatusCreated || started.AccessToken == "" ||
+		started.RefreshToken != "" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("start code=%d body=%s", w.Code, w.Body)
+	}
+
+	// Support sees the user's account as the user does.
+	w = do("GET", "/users/me", started.AccessToken, "")
+	var profile domain.User
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil || w.Code != http.StatusOK || profile.ID != u.ID || profile.Email != "u@ex.com" {
+		t.Fatalf("profile while impersonating code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("GET", "/users/me/probe", started.AccessToken, ""); w.Code != http.StatusOK || w.Body.String() != u.ID {
+		t.Fatalf("impersonated request code=%d body=%s", w.Code, w.Body)
+	}
+	events, _ := auditRepo.List(context.Background(), domain.AuditFilter{Type: domain.AuditPhoneUpdated})
+	if len(events) != 1 || events[0].Metadata["impersonator_id"] != "admin" {
+		t.Fatalf("events during impersonation = %+v", events)
+	}
+	for _, req := range [][2]string{{"GET", "/users/me/tokens"}, {"POST", "/users/me/tokens"}} {
+		if w := do(req[0], req[1], started.AccessToken, `{"name":"x","scopes":["user:read"]}`); w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s while impersonating code=%d body=%s", req[0], req[1], w.Code, w.Body)
+		}
+	}
+	if w := do("POST", "/admin/users/"+u.ID+"/impersonate", started.AccessToken, `{"reason":"chain"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("admin route with impersonation token code=%d body=%s", w.Code, w.Body)
+	}
+
+	if w := do("DELETE", "/admin/impersonations/"+started.Impersonation.ID, adminToken, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("end code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("GET", "/users/me", started.AccessToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("token after end code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("GET", "/admin/impersonations/"+started.Impersonation.ID, adminToken, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"end_reason":"ended"`) {
+		t.Fatalf("get code=%d body=%s", w.Code, w.Body)
+	}
+}
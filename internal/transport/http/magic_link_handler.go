@@ -0,0 +1,89 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-auth/internal/app/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// magicLinkNonceCookie binds a magic link to the browser that requested it.
+const magicLinkNonceCookie = "magic_link_nonce"
+
+type MagicLinkHandler struct {
+	log          *slog.Logger
+	uc           *usecase.MagicLinkUseCase
+	secureCookie bool
+}
+
+// NewMagicLinkHandler creates the handler. secureCookie marks the nonce
+// cookie Secure and should be set whenever the API is served over HTTPS.
+func NewMagicLinkHandler(log *slog.Logger, uc *usecase.MagicLinkUseCase, secureCookie bool) *MagicLinkHandler {
+	return &MagicLinkHandler{log: log, uc: uc, secureCookie: secureCookie}
+}
+
+func (h *MagicLinkHandler) RegisterRoutes(router *gin.RouterGroup) {
+	auth := router.Group("/auth")
+	{
+		auth.POST("/magic-link", h.request)
+		auth.POST("/magic-link/consume", h.consume)
+	}
+}
+
+type magicLinkRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	TenantID string `json:"tenant_id"`
+}
+
+func (h *MagicLinkHandler) request(c *gin.Context) {
+	var req magicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	res, err := h.uc.Request(c.Request.Context(), usecase.RequestMagicLinkCmd{Email: req.Email, TenantID: req.TenantID})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// Scoped to the consume endpoint, which lives under this path.
+	h.setNonce(c, res.Nonce, int(res.ExpiresIn), c.FullPath())
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "If this address can sign in, a link has been sent",
+		"expires_in": res.ExpiresIn,
+	})
+}
+
+type consumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *MagicLinkHandler) consume(c *gin.Context) {
+	var req consumeMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	nonce, _ := c.Cookie(magicLinkNonceCookie)
+	res, err := h.uc.Consume(c.Request.Context(), usecase.ConsumeMagicLinkCmd{Token: req.Token, Nonce: nonce})
+	if err != nil {
+		h.log.Warn("magic link login failed", "error", err)
+		_ = c.Error(err)
+		return
+	}
+	h.setNonce(c, "", -1, strings.TrimSuffix(c.FullPath(), "/consume"))
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"expires_in":    res.ExpiresIn,
+		"token_type":    "Bearer",
+	})
+}
+
+func (h *MagicLinkHandler) setNonce(c *gin.Context, value string, maxAge int, path string) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(magicLinkNonceCookie, value, maxAge, path, "", h.secureCookie, true)
+}
@@ -0,0 +1,78 @@
+package httpv1
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_MagicLinkCookieFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	users := memory.NewUserRepository()
+	box := memory.NewMailbox()
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, users, nil, app.PasswordService(fakePwd{}), nil)
+	logUC := usecase.NewLoginUserUseCase(slog.Default(), users, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil, nil, nil)
+	uc := usecase.NewMagicLinkUseCase(slog.Default(), usecase.MagicLinkConfig{URL: "https://app.example.com/magic"},
+		memory.NewMagicLinkRepository(), users, regUC, logUC, box, app.NewMailTemplates(app.Branding{}, nil), nil)
+	NewMagicLinkHandler(slog.Default(), uc, true).RegisterRoutes(r.Group("/api/v1"))
+	if err := regUC.Handle(context.Background(), usecase.RegisterUserCmd{Email: "t@e.com", Password: "Password123!"}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/v1/auth/magic-link", `{"email":"t@e.com"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("request code=%d body=%s", w.Code, w.Body)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != magicLinkNonceCookie || !cookies[0].HttpOnly || !cookies[0].Secure ||
+		cookies[0].SameSite != http.SameSiteStrictMode || cookies[0].Path != "/api/v1/auth/magic-link" {
+		t.Fatalf("nonce cookie = %+v", cookies)
+	}
+
+	msg, ok := box.Last("t@e.com")
+	if !ok {
+		t.Fatal("no email sent")
+	}
+	i := strings.Index(msg.Text, "https://")
+	raw, _, _ := strings.Cut(msg.Text[i:], "\n")
+	link, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"token":"` + link.Query().Get("token") + `"}`
+
+	if w := post("/api/v1/auth/magic-link/consume", body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("consume without cookie code=%d", w.Code)
+	}
+	w = post("/api/v1/auth/magic-link/consume", body, cookies[0])
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"access_token":"acc:`) {
+		t.Fatalf("consume code=%d body=%s", w.Code, w.Body)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Fatalf("nonce cookie not cleared: %+v", c)
+	}
+}
@@ -1,8 +1,23 @@
 package httpv1
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,6 +29,148 @@ func RequestID() gin.HandlerFunc {
 		}
 		c.Set("request_id", rid)
 		c.Writer.Header().Set("X-Request-ID", rid)
+		c.Request = c.Request.WithContext(app.WithRequestMeta(c.Request.Context(), app.RequestMeta{
+			RequestID: rid,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Locale:    i18n.Negotiate(c.GetHeader("Accept-Language"), ""),
+		}))
+		c.Next()
+	}
+}
+
+// TokenValidator resolves an access token to its subject.
+type TokenValidator interface {
+	ValidateToken(token string) (string, error)
+}
+
+// PersonalTokenValidator resolves a personal access token.
+type PersonalTokenValidator interface {
+	Authenticate(ctx context.Context, token string) (*domain.PersonalAccessToken, error)
+}
+
+// ImpersonationValidator resolves an impersonation token.
+type ImpersonationValidator interface {
+	Authenticate(ctx context.Context, token string) (*domain.Impersonation, error)
+}
+
+const (
+	userIDKey = "user_id"
+	// tokenScopesKey holds the scopes of a personal access token; it is
+	// unset for sessions.
+	tokenScopesKey = "token_scopes"
+	// impersonatorKey holds the admin behind an impersonation token.
+	impersonatorKey = "impersonator_id"
+)
+
+// Authenticate requires a valid bearer access token, or a personal access
+// token if pats is not nil, or an impersonation token if imps is not nil,
+// and stores its subject under "user_id" in the gin context.
+func Authenticate(tokens TokenValidator, pats PersonalTokenValidator, imps ImpersonationValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if len(auth) < 8 || !strings.EqualFold(auth[:7], "Bearer ") {
+			abortWithError(c, app.NewError(app.ErrCodeUnauthorized, "Unauthorized"))
+			return
+		}
+		if pats != nil && usecase.IsPersonalToken(auth[7:]) {
+			pat, err := pats.Authenticate(c.Request.Context(), auth[7:])
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+			c.Set(userIDKey, pat.UserID)
+			c.Set(tokenScopesKey, pat.Scopes)
+			c.Next()
+			return
+		}
+		uid, err := tokens.ValidateToken(auth[7:])
+		if (err != nil || uid == "") && imps != nil {
+			imp, err := imps.Authenticate(c.Request.Context(), auth[7:])
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+			meta := app.RequestMetaFrom(c.Request.Context())
+			meta.ImpersonatorID = imp.ActorID
+			c.Request = c.Request.WithContext(app.WithRequestMeta(c.Request.Context(), meta))
+			c.Set(userIDKey, imp.UserID)
+			c.Set(impersonatorKey, imp.ActorID)
+			c.Next()
+			return
+		}
+		if err != nil || uid == "" {
+			abortWithError(c, app.NewError(app.ErrCodeUnauthorized, "Unauthorized"))
+			return
+		}
+		c.Set(userIDKey, uid)
+		c.Next()
+	}
+}
+
+// RequireScope limits requests made with a personal access token to
+// tokens that hold the area's read scope for safe methods and its write
+// scope otherwise; a write scope implies the read scope. Sessions are not
+// limited. It must run after Authenticate.
+func RequireScope(area string) gin.HandlerFunc {
+	read, write := area+":read", area+":write"
+	return func(c *gin.Context) {
+		v, ok := c.Get(tokenScopesKey)
+		if !ok {
+			c.Next()
+			return
+		}
+		scopes, _ := v.([]string)
+		need := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			need = read
+		}
+		if !slices.Contains(scopes, write) && !(need == read && slices.Contains(scopes, read)) {
+			abortWithError(c, app.NewError(app.ErrCodeForbidden, "Token lacks the required scope").
+				WithDetails(map[string]any{"required_scope": need}))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests made with a personal access token, for
+// routes a token must not reach, such as token management. It must run
+// after Authenticate.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(tokenScopesKey); ok {
+			abortWithError(c, app.NewError(app.ErrCodeForbidden, "Personal access tokens cannot be used here"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ForbidImpersonation rejects requests made with an impersonation token,
+// for sensitive routes such as credential, token and admin management. It
+// must run after Authenticate.
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(impersonatorKey); ok {
+			abortWithError(c, app.NewError(app.ErrCodeForbidden, "Not allowed while impersonating a user"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin allows only the listed user IDs. It must run after Authenticate.
+func RequireAdmin(adminIDs []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+	return func(c *gin.Context) {
+		if !admins[c.GetString(userIDKey)] {
+			abortWithError(c, app.NewError(app.ErrCodeForbidden, "Forbidden"))
+			return
+		}
 		c.Next()
 	}
 }
@@ -22,7 +179,8 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID")
+		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID, X-Client-ID, X-Challenge-Response")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
 		if c.Request.Method == "OPTIONS" {
 			c.Status(204)
 			return
@@ -41,26 +199,170 @@ func SecurityHeaders() gin.HandlerFunc {
 	}
 }
 
-func RateLimit(max int, window time.Duration) gin.HandlerFunc {
-	type bucket struct {
-		count int
-		until time.Time
+// RateLimitKeyFunc extracts the key a rule is counted against.
+// An empty key means the rule does not apply to the request.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitRule applies Policy to requests on Route, counted per Key.
+// Route is a gin route pattern such as "/api/v1/auth/login"; empty matches every route.
+type RateLimitRule struct {
+	Route  string
+	Policy app.RateLimitPolicy
+	Key    RateLimitKeyFunc
+}
+
+// RateLimit enforces rules against store and emits RateLimit-* headers for the
+// most restrictive matching rule. A request one rule rejects is refunded to the
+// rules that already let it through, so it costs no other key budget. Store
+// failures are logged and fail open.
+func RateLimit(store app.RateLimitStore, log *slog.Logger, rules ...RateLimitRule) gin.HandlerFunc {
+	type spent struct {
+		key    string
+		policy app.RateLimitPolicy
 	}
-	store := make(map[string]*bucket)
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		b := store[ip]
-		now := time.Now()
-		if b == nil || now.After(b.until) {
-			b = &bucket{count: 0, until: now.Add(window)}
-			store[ip] = b
-		}
-		if b.count >= max {
-			c.JSON(429, gin.H{"error": "Too many requests", "code": "RATE_LIMITED"})
-			c.Abort()
+		var (
+			tightest *app.RateLimitResult
+			policy   app.RateLimitPolicy
+			charged  []spent
+		)
+		for _, rule := range rules {
+			if rule.Route != "" && rule.Route != c.FullPath() {
+				continue
+			}
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+			res, err := store.Allow(c.Request.Context(), rule.Policy.Name+":"+key, rule.Policy)
+			if err != nil {
+				log.Warn("rate limit store failed", "policy", rule.Policy.Name, "error", err)
+				continue
+			}
+			if tightest == nil || !res.Allowed || res.Remaining < tightest.Remaining {
+				tightest, policy = &res, rule.Policy
+			}
+			if !res.Allowed {
+				for _, prior := range charged {
+					if err := store.Refund(c.Request.Context(), prior.key, prior.policy); err != nil {
+						log.Warn("rate limit refund failed", "policy", prior.policy.Name, "error", err)
+					}
+				}
+				break
+			}
+			charged = append(charged, spent{rule.Policy.Name + ":" + key, rule.Policy})
+		}
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+		h.Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(tightest.ResetAfter), 10))
+		if !tightest.Allowed {
+			h.Set("Retry-After", strconv.FormatInt(ceilSeconds(tightest.RetryAfter), 10))
+			abortWithError(c, app.NewError(app.ErrCodeRateLimited, "Too many requests"))
 			return
 		}
-		b.count++
 		c.Next()
 	}
 }
+
+// RiskFunc decides whether a request must carry a solved challenge.
+type RiskFunc func(c *gin.Context) bool
+
+const challengePassedKey = "challenge_passed"
+
+// RequireChallenge demands a valid X-Challenge-Response on the given routes
+// whenever elevated reports risk. Clients without one receive 403
+// AUTH_CHALLENGE_REQUIRED with a fresh challenge in details.challenge.
+func RequireChallenge(provider app.ChallengeProvider, elevated RiskFunc, log *slog.Logger, routes ...string) gin.HandlerFunc {
+	guarded := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		guarded[r] = true
+	}
+	return func(c *gin.Context) {
+		if !guarded[c.FullPath()] || !elevated(c) {
+			c.Next()
+			return
+		}
+		if resp := c.GetHeader(challengeResponseHeader); resp != "" {
+			err := provider.Verify(c.Request.Context(), resp, c.ClientIP())
+			if err == nil {
+				c.Set(challengePassedKey, true)
+				c.Next()
+				return
+			}
+			log.Warn("challenge rejected", "path", c.FullPath(), "error", err)
+		}
+		ch, err := provider.Issue(c.Request.Context())
+		if err != nil {
+			abortWithError(c, fmt.Errorf("issue challenge: %w", err))
+			return
+		}
+		abortWithError(c, app.NewError(app.ErrCodeChallengeRequired, "Challenge required").
+			WithDetails(map[string]any{"challenge": ch}))
+	}
+}
+
+// KeyByIP counts requests per client IP.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByClientID counts requests per X-Client-ID header.
+func KeyByClientID(c *gin.Context) string {
+	if id := strings.TrimSpace(c.GetHeader("X-Client-ID")); id != "" {
+		return "client:" + id
+	}
+	return ""
+}
+
+// KeyByEmail counts requests per "email" field of a JSON body.
+// The body is restored so handlers can bind it again.
+func KeyByEmail(c *gin.Context) string {
+	if email := peekField(c, "email"); email != "" {
+		return "email:" + strings.ToLower(email)
+	}
+	return ""
+}
+
+// KeyByPhone counts requests per "phone" field of a JSON body, normalized
+// so formatting variants of one number share a limit.
+func KeyByPhone(c *gin.Context) string {
+	if phone, _ := domain.NormalizePhone(peekField(c, "phone")); phone != "" {
+		return "phone:" + phone
+	}
+	return ""
+}
+
+const maxPeekBody = 1 << 20
+
+// peekField returns the trimmed string field name of a JSON body.
+func peekField(c *gin.Context, name string) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBody))
+	_ = c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var payload map[string]any
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	v, _ := payload[name].(string)
+	return strings.TrimSpace(v)
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
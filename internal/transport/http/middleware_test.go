@@ -0,0 +1,121 @@
+package httpv1
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit_PerRouteAndKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewRateLimitStore(0)
+
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+	r.Use(RateLimit(store, slog.Default(),
+		RateLimitRule{Policy: app.RateLimitPolicy{Name: "global", Limit: 100, Window: time.Minute}, Key: KeyByIP},
+		RateLimitRule{Route: "/login", Policy: app.RateLimitPolicy{Name: "login-email", Limit: 2, Window: time.Minute}, Key: KeyByEmail},
+	))
+	var seen string
+	r.POST("/login", func(c *gin.Context) {
+		var body struct{ Email string }
+		_ = c.ShouldBindJSON(&body)
+		seen = body.Email
+		c.Status(200)
+	})
+	r.GET("/other", func(c *gin.Context) { c.Status(200) })
+
+	login := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := login("a@ex.com"); w.Code != 200 {
+			t.Fatalf("login %d code=%d", i, w.Code)
+		}
+	}
+	if seen != "a@ex.com" {
+		t.Fatalf("body not restored for handler, got %q", seen)
+	}
+	w := login("A@ex.com")
+	if w.Code != 429 {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+	if w := login("b@ex.com"); w.Code != 200 {
+		t.Fatalf("other email should not be limited, code=%d", w.Code)
+	}
+
+	// The rejected login was refunded to the global budget.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/other", nil))
+	if w.Code != 200 || w.Header().Get("RateLimit-Remaining") != "96" {
+		t.Fatalf("global policy headers: code=%d remaining=%q", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+type stubChallenges struct{}
+
+func (stubChallenges) Issue(context.Context) (app.Challenge, error) {
+	return app.Challenge{Type: "pow", Params: map[string]string{"challenge": "c"}}, nil
+}
+
+func (stubChallenges) Verify(_ context.Context, response, _ string) error {
+	if response == "c:42" {
+		return nil
+	}
+	return errors.New("wrong")
+}
+
+func TestRequireChallenge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	elevated := false
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+	r.Use(RequireChallenge(stubChallenges{}, func(*gin.Context) bool { return elevated }, slog.Default(), "/guarded"))
+	r.POST("/guarded", func(c *gin.Context) { c.JSON(200, gin.H{"passed": c.GetBool(challengePassedKey)}) })
+	r.POST("/open", func(c *gin.Context) { c.Status(200) })
+
+	do := func(path, solution string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, nil)
+		if solution != "" {
+			req.Header.Set(challengeResponseHeader, solution)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("/guarded", ""); w.Code != 200 {
+		t.Fatalf("low risk should pass, code=%d", w.Code)
+	}
+	elevated = true
+	w := do("/guarded", "")
+	if w.Code != 403 || !strings.Contains(w.Body.String(), app.ErrCodeChallengeRequired) || !strings.Contains(w.Body.String(), `"challenge":"c"`) {
+		t.Fatalf("expected challenge, code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("/guarded", "c:1"); w.Code != 403 {
+		t.Fatalf("wrong solution should be rejected, code=%d", w.Code)
+	}
+	if w := do("/guarded", "c:42"); w.Code != 200 || !strings.Contains(w.Body.String(), `"passed":true`) {
+		t.Fatalf("solved request should pass, code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("/open", ""); w.Code != 200 {
+		t.Fatalf("unguarded route should pass, code=%d", w.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonalTokenHandler struct {
+	log *slog.Logger
+	uc  *usecase.PersonalTokensUseCase
+}
+
+func NewPersonalTokenHandler(log *slog.Logger, uc *usecase.PersonalTokensUseCase) *PersonalTokenHandler {
+	return &PersonalTokenHandler{log: log, uc: uc}
+}
+
+// RegisterRoutes mounts personal access token management for the
+// signed-in user. The caller is responsible for authenticating the group,
+// and should keep personal access tokens out of it with RequireSession.
+func (h *PersonalTokenHandler) RegisterRoutes(router *gin.RouterGroup) {
+	me := router.Group("/users/me")
+	{
+		me.GET("/tokens", h.list)
+		me.POST("/tokens", h.create)
+		me.DELETE("/tokens/:id", h.revoke)
+	}
+}
+
+type createPersonalTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *PersonalTokenHandler) create(c *gin.Context) {
+	var req createPersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	token, secret, err := h.uc.Create(c.Request.Context(), c.GetString(userIDKey), usecase.CreatePersonalTokenCmd{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// The token is only ever returned here.
+	c.JSON(http.StatusCreated, gin.H{"personal_token": token, "token": secret})
+}
+
+func (h *PersonalTokenHandler) list(c *gin.Context) {
+	tokens, err := h.uc.List(c.Request.Context(), c.GetString(userIDKey))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if tokens == nil {
+		tokens = []domain.PersonalAccessToken{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": tokens})
+}
+
+func (h *PersonalTokenHandler) revoke(c *gin.Context) {
+	if err := h.uc.Revoke(c.Request.Context(), c.GetString(userIDKey), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
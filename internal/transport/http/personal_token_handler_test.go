@@ -0,0 +1,78 @@
+package httpv1
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_PersonalTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	uc := usecase.NewPersonalTokensUseCase(slog.Default(), memory.NewPersonalAccessTokenRepository(), nil)
+	sessions := staticTokens{"session": "u1"}
+	v1 := r.Group("/api/v1")
+	NewPersonalTokenHandler(slog.Default(), uc).RegisterRoutes(v1.Group("", Authenticate(sessions, uc, nil), RequireSession()))
+	user := v1.Group("", Authenticate(sessions, uc, nil), RequireScope("user"))
+	user.GET("/users/me/probe", func(c *gin.Context) { c.String(http.StatusOK, c.GetString(userIDKey)) })
+	user.POST("/users/me/probe", func(c *gin.Context) { c.String(http.StatusOK, c.GetString(userIDKey)) })
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/users/me/tokens", "session", `{"name":"ci","scopes":[]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("empty scopes code=%d body=%s", w.Code, w.Body)
+	}
+	w := do("POST", "/users/me/tokens", "session", `{"name":"ci","scopes":["user:read"],"expires_at":"2999-01-01T00:00:00Z"}`)
+	var created struct {
+		Token         string `json:"token"`
+		PersonalToken struct {
+			ID     string `json:"id"`
+			Prefix string `json:"prefix"`
+		} `json:"personal_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated || created.Token == "" ||
+		strings.Contains(w.Body.String(), "token_hash") {
+		t.Fatalf("create code=%d body=%s", w.Code, w.Body)
+	}
+
+	if w := do("GET", "/users/me/probe", created.Token, ""); w.Code != http.StatusOK || w.Body.String() != "u1" {
+		t.Fatalf("read with token code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("POST", "/users/me/probe", created.Token, ""); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "user:write") {
+		t.Fatalf("write with read token code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("POST", "/users/me/probe", "session", ""); w.Code != http.StatusOK {
+		t.Fatalf("write with session code=%d", w.Code)
+	}
+	if w := do("GET", "/users/me/tokens", created.Token, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("token management with token code=%d", w.Code)
+	}
+
+	w = do("GET", "/users/me/tokens", "session", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), created.PersonalToken.Prefix) || !strings.Contains(w.Body.String(), "last_used_at") {
+		t.Fatalf("list code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("DELETE", "/users/me/tokens/"+created.PersonalToken.ID, "session", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke code=%d body=%s", w.Code, w.Body)
+	}
+	if w := do("GET", "/users/me/probe", created.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token code=%d", w.Code)
+	}
+}
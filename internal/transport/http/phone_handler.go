@@ -0,0 +1,172 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PhoneHandler struct {
+	log *slog.Logger
+	uc  *usecase.PhoneUseCase
+}
+
+func NewPhoneHandler(log *slog.Logger, uc *usecase.PhoneUseCase) *PhoneHandler {
+	return &PhoneHandler{log: log, uc: uc}
+}
+
+// RegisterRoutes mounts SMS login and the second-factor step of password
+// login.
+func (h *PhoneHandler) RegisterRoutes(router *gin.RouterGroup) {
+	auth := router.Group("/auth")
+	{
+		auth.POST("/phone/login", h.requestLogin)
+		auth.POST("/phone/login/verify", h.login)
+		auth.POST("/login/sms", h.secondFactor)
+	}
+}
+
+// RegisterUserRoutes mounts phone management for the signed-in user. The
+// caller is responsible for authenticating the group.
+func (h *PhoneHandler) RegisterUserRoutes(router *gin.RouterGroup) {
+	me := router.Group("/users/me")
+	{
+		me.POST("/phone", h.startVerification)
+		me.POST("/phone/verify", h.confirmVerification)
+		me.PUT("/phone/second-factor", h.setSecondFactor)
+	}
+}
+
+type phoneRequest struct {
+	Phone string `json:"phone" binding:"required,max=32"`
+}
+
+type otpRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required"`
+	Code        string `json:"code" binding:"required,numeric,max=10"`
+}
+
+type confirmPhoneRequest struct {
+	otpRequest
+	SecondFactor bool `json:"second_factor"`
+}
+
+type secondFactorRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+func (h *PhoneHandler) requestLogin(c *gin.Context) {
+	var req phoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	ch, err := h.uc.RequestLogin(c.Request.Context(), req.Phone)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, challengeBody(ch))
+}
+
+func (h *PhoneHandler) login(c *gin.Context) {
+	var req otpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	res, err := h.uc.Login(c.Request.Context(), usecase.VerifyOTPCmd{ChallengeID: req.ChallengeID, Code: req.Code})
+	if err != nil {
+		h.log.Warn("phone login failed", "error", err)
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"expires_in":    res.ExpiresIn,
+		"token_type":    "Bearer",
+	})
+}
+
+func (h *PhoneHandler) secondFactor(c *gin.Context) {
+	var req otpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	res, err := h.uc.CompleteSecondFactor(c.Request.Context(), usecase.VerifyOTPCmd{ChallengeID: req.ChallengeID, Code: req.Code})
+	if err != nil {
+		h.log.Warn("second factor failed", "error", err)
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"expires_in":    res.ExpiresIn,
+		"token_type":    "Bearer",
+	})
+}
+
+func (h *PhoneHandler) startVerification(c *gin.Context) {
+	var req phoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	ch, err := h.uc.StartVerification(c.Request.Context(), c.GetString(userIDKey), req.Phone)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, challengeBody(ch))
+}
+
+func (h *PhoneHandler) confirmVerification(c *gin.Context) {
+	var req confirmPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	user, err := h.uc.ConfirmVerification(c.Request.Context(), c.GetString(userIDKey), usecase.ConfirmPhoneCmd{
+		ChallengeID:  req.ChallengeID,
+		Code:         req.Code,
+		SecondFactor: req.SecondFactor,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, phoneBody(user))
+}
+
+func (h *PhoneHandler) setSecondFactor(c *gin.Context) {
+	var req secondFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	user, err := h.uc.SetSecondFactor(c.Request.Context(), c.GetString(userIDKey), *req.Enabled)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, phoneBody(user))
+}
+
+func challengeBody(ch *usecase.OTPChallenge) gin.H {
+	return gin.H{"challenge_id": ch.ID, "phone": ch.Phone, "expires_in": ch.ExpiresIn}
+}
+
+func phoneBody(user *domain.User) gin.H {
+	return gin.H{
+		"phone":             user.Phone,
+		"phone_verified_at": user.PhoneVerifiedAt,
+		"sms_second_factor": user.SMSSecondFactor,
+	}
+}
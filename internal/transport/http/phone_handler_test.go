@@ -0,0 +1,75 @@
+package httpv1
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/infrastructure/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_SMSSecondFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	outbox := memory.NewSMSOutbox()
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, users, nil, app.PasswordService(fakePwd{}), nil)
+	logUC := usecase.NewLoginUserUseCase(slog.Default(), users, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil, nil, nil)
+	phoneUC := usecase.NewPhoneUseCase(slog.Default(), usecase.OTPConfig{}, users, memory.NewOTPRepository(), outbox, logUC, nil)
+	logUC.UseSecondFactor(phoneUC)
+	NewAuthHandler(slog.Default(), regUC, logUC, nil, nil).RegisterRoutes(r.Group("/api/v1"))
+	NewPhoneHandler(slog.Default(), phoneUC).RegisterRoutes(r.Group("/api/v1"))
+
+	if err := regUC.Handle(ctx, usecase.RegisterUserCmd{Email: "t@e.com", Password: "Password123!"}); err != nil {
+		t.Fatal(err)
+	}
+	user, _ := users.FindByEmail(ctx, "t@e.com")
+	now := time.Now().UTC()
+	user.Phone, user.PhoneVerifiedAt, user.SMSSecondFactor = "+14155550123", &now, true
+	if err := users.UpdatePhone(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/v1/auth/login", `{"email":"t@e.com","password":"Password123!"}`)
+	var problem struct {
+		Code    string         `json:"code"`
+		Details map[string]any `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnauthorized || problem.Code != app.ErrCodeSecondFactor || problem.Details["phone"] != "+1********23" {
+		t.Fatalf("login code=%d body=%s", w.Code, w.Body)
+	}
+	msg, _ := outbox.Last("+14155550123")
+	code, _, _ := strings.Cut(msg.Text, " ")
+	challenge, _ := problem.Details["challenge_id"].(string)
+
+	if w := post("/api/v1/auth/login/sms", `{"challenge_id":"`+challenge+`","code":"12ab"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("non-numeric code accepted: %d", w.Code)
+	}
+	w = post("/api/v1/auth/login/sms", `{"challenge_id":"`+challenge+`","code":"`+code+`"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"access_token":"acc:`+user.ID) {
+		t.Fatalf("second factor code=%d body=%s", w.Code, w.Body)
+	}
+}
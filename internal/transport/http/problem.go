@@ -0,0 +1,186 @@
+package httpv1
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const problemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the error code to form the RFC 7807 "type" URI.
+const problemTypeBase = "urn:go-auth:problem:"
+
+// Problem is an RFC 7807 response body. Error, Code and Details repeat the
+// legacy {"error", "code", "details"} shape so existing clients keep working.
+type Problem struct {
+	Type     string           `json:"type"`
+	Title    string           `json:"title"`
+	Status   int              `json:"status"`
+	Detail   string           `json:"detail,omitempty"`
+	Instance string           `json:"instance,omitempty"`
+	Errors   []app.FieldError `json:"errors,omitempty"`
+
+	Error   string         `json:"error"`
+	Code    string         `json:"code"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+var codeStatus = map[string]int{
+	app.ErrCodeValidation:         http.StatusBadRequest,
+	app.ErrCodeInvalidCredentials: http.StatusUnauthorized,
+	app.ErrCodeUnauthorized:       http.StatusUnauthorized,
+	app.ErrCodeForbidden:          http.StatusForbidden,
+	app.ErrCodeChallengeRequired:  http.StatusForbidden,
+	app.ErrCodeSecondFactor:       http.StatusUnauthorized,
+	app.ErrCodeOTPAttempts:        http.StatusTooManyRequests,
+	app.ErrCodeAccountDisabled:    http.StatusForbidden,
+	app.ErrCodeNotFound:           http.StatusNotFound,
+	app.ErrCodeEmailExists:        http.StatusConflict,
+	app.ErrCodeConflict:           http.StatusConflict,
+	app.ErrCodePreconditionFailed: http.StatusPreconditionFailed,
+	app.ErrCodeRateLimited:        http.StatusTooManyRequests,
+	app.ErrCodeInternal:           http.StatusInternalServerError,
+	app.ErrCodeUnavailable:        http.StatusServiceUnavailable,
+}
+
+func init() {
+	// Report validation failures under the JSON field names clients send.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// Errors renders the last error a handler attached with c.Error as
+// application/problem+json. It must be mounted before any middleware or
+// handler that reports errors this way. Server-side failures are logged.
+func Errors(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		locale := app.RequestMetaFrom(c.Request.Context()).Locale
+		if locale == "" {
+			locale = i18n.Negotiate(c.GetHeader("Accept-Language"), "")
+		}
+		p := problemFor(err, locale)
+		p.Instance = c.GetString("request_id")
+		if p.Status >= http.StatusInternalServerError {
+			log.Error("request failed", "method", c.Request.Method, "path", c.FullPath(), "status", p.Status, "error", err)
+		}
+		if ra, ok := p.Details["retry_after"].(int64); ok {
+			c.Header("Retry-After", strconv.FormatInt(ra, 10))
+		}
+		c.Header("Content-Language", locale)
+		c.Header("Content-Type", problemContentType)
+		c.Status(p.Status)
+		_ = json.NewEncoder(c.Writer).Encode(p)
+	}
+}
+
+// abortWithError stops the chain and leaves err for Errors to render.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// bindError turns a request binding failure into a validation error without
+// echoing raw decoder or validator messages.
+func bindError(err error) error {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]app.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, fieldError(fe.Field(), fe.Tag(), fe.Param()))
+		}
+		return app.NewError(app.ErrCodeValidation, "Request validation failed").WithFields(fields...)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return app.NewError(app.ErrCodeValidation, "Request validation failed").
+			WithFields(fieldError(typeErr.Field, "type", ""))
+	}
+	if errors.Is(err, io.EOF) {
+		return app.NewError(app.ErrCodeValidation, "Request body is required")
+	}
+	return app.NewError(app.ErrCodeValidation, "Malformed request body")
+}
+
+// fieldError describes a rejected field with the catalog message for code.
+func fieldError(field, code, param string) app.FieldError {
+	return app.FieldError{
+		Field:   field,
+		Code:    code,
+		Param:   param,
+		Message: i18n.Message(i18n.Default, "field."+code, param, i18n.Message(i18n.Default, "field.invalid", "", "")),
+	}
+}
+
+// problemFor maps err to a problem, translating messages into locale. The
+// default locale keeps the specific message the error was raised with.
+func problemFor(err error, locale string) Problem {
+	var ae app.AppError
+	switch {
+	case errors.As(err, &ae):
+	case errors.Is(err, domain.ErrNotFound):
+		ae = app.NewError(app.ErrCodeNotFound, "Not found")
+	case errors.Is(err, domain.ErrConflict):
+		ae = app.NewError(app.ErrCodeConflict, "Conflict")
+	case errors.Is(err, domain.ErrUnavailable):
+		ae = app.NewError(app.ErrCodeUnavailable, "Service temporarily unavailable")
+	default:
+		var verrs validator.ValidationErrors
+		if errors.As(err, &verrs) {
+			ae, _ = bindError(err).(app.AppError)
+		} else {
+			ae = app.NewError(app.ErrCodeInternal, "Internal error")
+		}
+	}
+	status, ok := codeStatus[ae.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	msg := ae.Msg
+	if locale != i18n.Default {
+		msg = i18n.Message(locale, ae.Code, "", ae.Msg)
+	}
+	var fields []app.FieldError
+	for _, fe := range ae.Fields {
+		fe.Message = i18n.Message(locale, "field."+fe.Code, fe.Param, fe.Message)
+		fields = append(fields, fe)
+	}
+	return Problem{
+		Type:    problemTypeBase + strings.ToLower(strings.ReplaceAll(ae.Code, "_", "-")),
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  msg,
+		Errors:  fields,
+		Error:   msg,
+		Code:    ae.Code,
+		Details: ae.Details,
+	}
+}
@@ -0,0 +1,100 @@
+package httpv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrors_ProblemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), Errors(slog.Default()))
+	r.POST("/bind", func(c *gin.Context) {
+		var req registerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(bindError(err))
+			return
+		}
+		c.Status(200)
+	})
+	r.GET("/missing", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("postgres: find user: %w", domain.ErrNotFound))
+	})
+	r.GET("/limited", func(c *gin.Context) {
+		_ = c.Error(app.NewError(app.ErrCodeRateLimited, "Slow down").WithDetails(map[string]any{"retry_after": int64(7)}))
+	})
+	r.GET("/boom", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("dial tcp 10.0.0.1:5432: secret detail"))
+	})
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, Problem) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "req-42")
+		r.ServeHTTP(w, req)
+		var p Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: decode %q: %v", path, w.Body, err)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+			t.Fatalf("%s: content type %q", path, ct)
+		}
+		return w, p
+	}
+
+	w, p := do("POST", "/bind", `{"email":"nope","password":"short"}`)
+	if w.Code != 400 || p.Status != 400 || p.Code != app.ErrCodeValidation || p.Instance != "req-42" {
+		t.Fatalf("validation problem: code=%d %+v", w.Code, p)
+	}
+	if p.Type != "urn:go-auth:problem:validation-error" || p.Title != "Bad Request" || p.Error != p.Detail {
+		t.Fatalf("problem members: %+v", p)
+	}
+	fields := map[string]string{}
+	for _, fe := range p.Errors {
+		fields[fe.Field] = fe.Code
+	}
+	if fields["email"] != "email" || fields["password"] != "min" || len(fields) != 2 {
+		t.Fatalf("field errors: %+v", p.Errors)
+	}
+	if strings.Contains(w.Body.String(), "registerRequest") {
+		t.Fatalf("validator internals leaked: %s", w.Body)
+	}
+
+	req := httptest.NewRequest("POST", "/bind", strings.NewReader(`{"email":"nope","password":"short"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.5")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), &p)
+	if w.Header().Get("Content-Language") != "ru" || p.Detail != "Запрос не прошёл проверку" || p.Code != app.ErrCodeValidation {
+		t.Fatalf("ru problem: headers=%v %+v", w.Header(), p)
+	}
+	for _, fe := range p.Errors {
+		if fe.Field == "password" && fe.Message != "должно содержать не менее 8 символов" {
+			t.Fatalf("ru field message: %+v", fe)
+		}
+	}
+
+	if w, p = do("POST", "/bind", `{"email":`); w.Code != 400 || p.Detail != "Malformed request body" {
+		t.Fatalf("malformed body: code=%d %+v", w.Code, p)
+	}
+	if w, p = do("GET", "/missing", ""); w.Code != 404 || p.Code != app.ErrCodeNotFound {
+		t.Fatalf("not found: code=%d %+v", w.Code, p)
+	}
+	if w, p = do("GET", "/limited", ""); w.Code != 429 || w.Header().Get("Retry-After") != "7" || p.Details["retry_after"] != float64(7) {
+		t.Fatalf("rate limited: code=%d headers=%v %+v", w.Code, w.Header(), p)
+	}
+	if w, p = do("GET", "/boom", ""); w.Code != 500 || p.Code != app.ErrCodeInternal || strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("internal: code=%d body=%s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,35 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go-auth/internal/app/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	log *slog.Logger
+	uc  *usecase.ProfileUseCase
+}
+
+func NewProfileHandler(log *slog.Logger, uc *usecase.ProfileUseCase) *ProfileHandler {
+	return &ProfileHandler{log: log, uc: uc}
+}
+
+// RegisterRoutes mounts the signed-in user's profile. It only reads, so
+// support staff impersonating the user may reach it; the caller is
+// responsible for authenticating the group.
+func (h *ProfileHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/users/me", h.get)
+}
+
+func (h *ProfileHandler) get(c *gin.Context) {
+	user, err := h.uc.Get(c.Request.Context(), c.GetString(userIDKey))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,108 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-auth/internal/app/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// samlBindingCookie binds a SAML login to the browser that started it.
+const samlBindingCookie = "saml_binding"
+
+type SAMLHandler struct {
+	log          *slog.Logger
+	uc           *usecase.SAMLLoginUseCase
+	secureCookie bool
+}
+
+// NewSAMLHandler creates the handler. secureCookie marks the binding cookie
+// Secure and should be set whenever the API is served over HTTPS.
+func NewSAMLHandler(log *slog.Logger, uc *usecase.SAMLLoginUseCase, secureCookie bool) *SAMLHandler {
+	return &SAMLHandler{log: log, uc: uc, secureCookie: secureCookie}
+}
+
+func (h *SAMLHandler) RegisterRoutes(router *gin.RouterGroup) {
+	saml := router.Group("/auth/saml")
+	{
+		saml.GET("/metadata", h.metadata)
+		saml.GET("/providers", h.providers)
+		saml.GET("/:provider/login", h.login)
+		saml.POST("/acs", h.acs)
+	}
+}
+
+func (h *SAMLHandler) metadata(c *gin.Context) {
+	md, err := h.uc.Metadata()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", md)
+}
+
+func (h *SAMLHandler) providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.uc.IdPs(c.Query("tenant_id"))})
+}
+
+func (h *SAMLHandler) login(c *gin.Context) {
+	res, err := h.uc.Start(c.Request.Context(), usecase.StartFederatedLoginCmd{
+		TenantID: c.Query("tenant_id"),
+		Provider: c.Param("provider"),
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// Scoped to the ACS endpoint, which lives under this path.
+	h.setBinding(c, res.Binding, int(res.ExpiresIn), strings.TrimSuffix(c.FullPath(), "/:provider/login"))
+	c.Redirect(http.StatusFound, res.AuthURL)
+}
+
+// samlResponseForm is the HTTP-POST binding the IdP makes the browser submit.
+type samlResponseForm struct {
+	SAMLResponse string `form:"SAMLResponse" binding:"required"`
+	RelayState   string `form:"RelayState" binding:"required"`
+}
+
+func (h *SAMLHandler) acs(c *gin.Context) {
+	var req samlResponseForm
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	binding, _ := c.Cookie(samlBindingCookie)
+	res, err := h.uc.ACS(c.Request.Context(), usecase.SAMLResponseCmd{
+		Response:   req.SAMLResponse,
+		RelayState: req.RelayState,
+		Binding:    binding,
+	})
+	if err != nil {
+		h.log.Warn("saml login failed", "error", err)
+		_ = c.Error(err)
+		return
+	}
+	h.setBinding(c, "", -1, strings.TrimSuffix(c.FullPath(), "/acs"))
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"expires_in":    res.ExpiresIn,
+		"token_type":    "Bearer",
+	})
+}
+
+// setBinding uses SameSite=None over HTTPS: the IdP makes the browser post
+// to the ACS cross-site, and Lax cookies are withheld from such posts.
+// Browsers refuse None without Secure, so plain-HTTP setups fall back to Lax
+// and only work with an IdP on the same site.
+func (h *SAMLHandler) setBinding(c *gin.Context, value string, maxAge int, path string) {
+	if h.secureCookie {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	c.SetCookie(samlBindingCookie, value, maxAge, path, "", h.secureCookie, true)
+}
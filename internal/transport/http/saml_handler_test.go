@@ -0,0 +1,92 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/infrastructure/saml"
+	"go-auth/internal/infrastructure/saml/samltest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_SAMLLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	idp := samltest.NewIdP(t)
+	cfg := idp.Config("corp")
+	cfg.Name, cfg.AutoRegister = "Corp SSO", true
+	users := memory.NewUserRepository()
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, users, nil, app.PasswordService(fakePwd{}), nil)
+	logUC := usecase.NewLoginUserUseCase(slog.Default(), users, app.PasswordService(fakePwd{}), app.TokenService(fakeToken{}), nil, nil, nil)
+	sp := saml.NewServiceProvider(saml.Options{
+		EntityID: "https://auth.example.com/api/v1/auth/saml/metadata",
+		ACSURL:   "https://auth.example.com/api/v1/auth/saml/acs",
+	})
+	uc := usecase.NewSAMLLoginUseCase(slog.Default(), usecase.SAMLLoginConfig{
+		IdPs: app.SAMLIdPs{Default: []app.SAMLIdP{cfg}},
+	}, nil, memory.NewFederatedLoginRepository(), memory.NewIdentityRepository(), users, regUC, logUC, sp, nil)
+	NewSAMLHandler(slog.Default(), uc, true).RegisterRoutes(r.Group("/api/v1"))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	if w := get("/api/v1/auth/saml/metadata"); w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), `entityID="https://auth.example.com/api/v1/auth/saml/metadata"`) {
+		t.Fatalf("metadata code=%d body=%s", w.Code, w.Body)
+	}
+	if w := get("/api/v1/auth/saml/providers"); w.Code != http.StatusOK || w.Body.String() != `{"providers":[{"id":"corp","name":"Corp SSO"}]}` {
+		t.Fatalf("providers code=%d body=%s", w.Code, w.Body)
+	}
+
+	w := get("/api/v1/auth/saml/corp/login")
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), idp.SSOURL+"?") {
+		t.Fatalf("login code=%d location=%s", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != samlBindingCookie || !cookies[0].HttpOnly || !cookies[0].Secure ||
+		cookies[0].SameSite != http.SameSiteNoneMode || cookies[0].Path != "/api/v1/auth/saml" {
+		t.Fatalf("binding cookie = %+v", cookies)
+	}
+	resp, relay := idp.Respond(t, w.Header().Get("Location"), samltest.User{
+		NameID:     "emp-7",
+		Attributes: map[string][]string{"email": {"dana@ex.com"}},
+	})
+
+	post := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		form := url.Values{"SAMLResponse": {resp}, "RelayState": {relay}}
+		req := httptest.NewRequest("POST", "/api/v1/auth/saml/acs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := post(); w.Code != http.StatusUnauthorized {
+		t.Fatalf("acs without cookie code=%d", w.Code)
+	}
+	w = post(cookies[0])
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"access_token":"acc:`) {
+		t.Fatalf("acs code=%d body=%s", w.Code, w.Body)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Fatalf("binding cookie not cleared: %+v", c)
+	}
+
+	if w := get("/api/v1/auth/saml/unknown/login"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown idp code=%d", w.Code)
+	}
+}
@@ -0,0 +1,163 @@
+package scim
+
+import (
+	"maps"
+	"strings"
+)
+
+// Attr returns the value of the attribute at path, as stored: multi-valued
+// attributes are returned as a whole. It returns nil for a missing
+// attribute or an invalid path.
+func Attr(resource map[string]any, path string) any {
+	p, err := ParseAttrPath(path)
+	if err != nil {
+		return nil
+	}
+	container := p.container(resource, false)
+	if container == nil {
+		return nil
+	}
+	_, v, _ := lookup(container, p.Name)
+	if p.Sub == "" {
+		return v
+	}
+	m, _ := v.(map[string]any)
+	_, sub, _ := lookup(m, p.Sub)
+	return sub
+}
+
+// Project returns the parts of resource a client selected with the
+// attributes or excludedAttributes query parameters (RFC 7644 section
+// 3.9). Both are lists of attribute paths; attributes wins if both are
+// given. schemas and id are always returned. resource is not modified.
+func Project(resource map[string]any, attributes, excluded []string) (map[string]any, error) {
+	if len(attributes) > 0 {
+		return include(resource, attributes)
+	}
+	out := maps.Clone(resource)
+	for _, s := range excluded {
+		p, err := ParseAttrPath(s)
+		if err != nil {
+			return nil, err
+		}
+		if p.URN == "" && p.Sub == "" && (strings.EqualFold(p.Name, "id") || strings.EqualFold(p.Name, "schemas")) {
+			continue
+		}
+		container := p.container(out, false)
+		if container == nil {
+			continue
+		}
+		if p.extension() {
+			// Extension objects are shared with resource.
+			ek, _, _ := lookup(out, p.URN)
+			container = maps.Clone(container)
+			out[ek] = container
+		}
+		k, v, ok := lookup(container, p.Name)
+		if !ok {
+			continue
+		}
+		if p.Sub == "" {
+			delete(container, k)
+			continue
+		}
+		container[k] = mapValues(v, func(m map[string]any) map[string]any {
+			m = maps.Clone(m)
+			if sk, _, ok := lookup(m, p.Sub); ok {
+				delete(m, sk)
+			}
+			return m
+		})
+	}
+	return out, nil
+}
+
+func include(resource map[string]any, attributes []string) (map[string]any, error) {
+	out := map[string]any{}
+	for _, k := range []string{"schemas", "id"} {
+		if v, ok := resource[k]; ok {
+			out[k] = v
+		}
+	}
+	for _, s := range attributes {
+		p, err := ParseAttrPath(s)
+		if err != nil {
+			return nil, err
+		}
+		src := p.container(resource, false)
+		if src == nil {
+			continue
+		}
+		k, v, ok := lookup(src, p.Name)
+		if !ok {
+			continue
+		}
+		dst := out
+		if p.extension() {
+			ek, _, _ := lookup(resource, p.URN)
+			dst, _ = out[ek].(map[string]any)
+			if dst == nil {
+				dst = map[string]any{}
+				out[ek] = dst
+			}
+		}
+		if p.Sub == "" {
+			dst[k] = v
+			continue
+		}
+		prev := dst[k]
+		dst[k] = mapValues(v, func(m map[string]any) map[string]any {
+			picked := map[string]any{}
+			if sk, sv, ok := lookup(m, p.Sub); ok {
+				picked[sk] = sv
+			}
+			return picked
+		})
+		dst[k] = mergeSelected(prev, dst[k])
+	}
+	return out, nil
+}
+
+// mapValues applies fn to a complex value or to every value of a
+// multi-valued one.
+func mapValues(v any, fn func(map[string]any) map[string]any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return fn(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			if m, ok := elem.(map[string]any); ok {
+				out[i] = fn(m)
+			} else {
+				out[i] = elem
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// mergeSelected combines sub-attributes selected by several paths, as in
+// attributes=name.givenName,name.familyName.
+func mergeSelected(prev, next any) any {
+	switch p := prev.(type) {
+	case map[string]any:
+		if n, ok := next.(map[string]any); ok {
+			maps.Copy(p, n)
+			return p
+		}
+	case []any:
+		if n, ok := next.([]any); ok && len(n) == len(p) {
+			for i := range p {
+				pm, ok1 := p[i].(map[string]any)
+				nm, ok2 := n[i].(map[string]any)
+				if ok1 && ok2 {
+					maps.Copy(pm, nm)
+				}
+			}
+			return p
+		}
+	}
+	return next
+}
@@ -0,0 +1,55 @@
+package scim
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	const base = `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"id": "u1",
+		"userName": "bjensen",
+		"name": {"givenName": "Barbara", "familyName": "Jensen"},
+		"emails": [{"value": "b@example.com", "type": "work"}],
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "7", "department": "R&D"}
+	}`
+	tests := []struct {
+		name     string
+		attrs    []string
+		excluded []string
+		want     string
+	}{
+		{
+			name:  "Attributes",
+			attrs: []string{"USERNAME", "name.givenName", "emails.value", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department"},
+			want: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "u1", "userName": "bjensen",
+				"name": {"givenName": "Barbara"}, "emails": [{"value": "b@example.com"}],
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "R&D"}}`,
+		},
+		{
+			name:     "Excluded",
+			excluded: []string{"id", "name.familyName", "emails", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber"},
+			want: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "u1", "userName": "bjensen",
+				"name": {"givenName": "Barbara"},
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "R&D"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := resource(t, base)
+			got, err := Project(r, tt.attrs, tt.excluded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := resource(t, tt.want); !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Fatalf("got %s", gotJSON)
+			}
+			if !reflect.DeepEqual(r, resource(t, base)) {
+				t.Fatal("resource was modified")
+			}
+		})
+	}
+}
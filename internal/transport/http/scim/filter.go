@@ -0,0 +1,557 @@
+// Package scim implements the resource-independent parts of SCIM 2.0
+// (RFC 7643, RFC 7644): filter expressions, attribute paths and PATCH
+// operations. All of them work on resources in their JSON form, decoded into
+// map[string]any, so that they apply to users and groups alike.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Filter is a parsed filter expression (RFC 7644 section 3.4.2.2).
+type Filter struct {
+	root expr
+}
+
+// ParseFilter parses s. It fails with an *Error of type invalidFilter.
+func ParseFilter(s string) (*Filter, error) {
+	p := &parser{}
+	if err := p.lex(s); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr(false)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, filterError("unexpected %q", tok.text)
+	}
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether resource satisfies the filter. Attribute names
+// compare case-insensitively, as do string values except those of
+// identifiers (see caseExact).
+func (f *Filter) Match(resource map[string]any) bool {
+	return f.root.match(resource)
+}
+
+// String returns the filter in canonical form.
+func (f *Filter) String() string { return f.root.String() }
+
+type expr interface {
+	match(resource map[string]any) bool
+	String() string
+}
+
+type (
+	andExpr struct{ left, right expr }
+	orExpr  struct{ left, right expr }
+	notExpr struct{ inner expr }
+	// presentExpr is "attr pr".
+	presentExpr struct{ path AttrPath }
+	// compareExpr is "attr op value".
+	compareExpr struct {
+		path  AttrPath
+		op    string
+		value any
+	}
+	// valuePathExpr is "attr[filter]": some value of the multi-valued
+	// attribute matches filter.
+	valuePathExpr struct {
+		path   AttrPath
+		filter expr
+	}
+)
+
+func (e andExpr) match(r map[string]any) bool { return e.left.match(r) && e.right.match(r) }
+func (e orExpr) match(r map[string]any) bool  { return e.left.match(r) || e.right.match(r) }
+func (e notExpr) match(r map[string]any) bool { return !e.inner.match(r) }
+
+func (e andExpr) String() string { return "(" + e.left.String() + " and " + e.right.String() + ")" }
+func (e orExpr) String() string  { return "(" + e.left.String() + " or " + e.right.String() + ")" }
+func (e notExpr) String() string { return "not (" + e.inner.String() + ")" }
+
+func (e presentExpr) match(r map[string]any) bool {
+	for _, v := range e.path.values(r) {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			if v != "" {
+				return true
+			}
+		case []any:
+			if len(v) > 0 {
+				return true
+			}
+		case map[string]any:
+			if len(v) > 0 {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func (e presentExpr) String() string { return e.path.String() + " pr" }
+
+func (e compareExpr) match(r map[string]any) bool {
+	values := e.path.values(r)
+	if e.op == "ne" {
+		for _, v := range values {
+			if compare(v, "eq", e.value, e.path.caseExact()) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if compare(v, e.op, e.value, e.path.caseExact()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e compareExpr) String() string {
+	v, _ := json.Marshal(e.value)
+	return e.path.String() + " " + e.op + " " + string(v)
+}
+
+func (e valuePathExpr) match(r map[string]any) bool {
+	for _, v := range e.path.values(r) {
+		if m, ok := v.(map[string]any); ok && e.filter.match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e valuePathExpr) String() string { return e.path.String() + "[" + e.filter.String() + "]" }
+
+// compare applies op to an attribute value and a filter literal. Values of a
+// complex multi-valued attribute compare by their "value" sub-attribute.
+func compare(attr any, op string, lit any, caseExact bool) bool {
+	if m, ok := attr.(map[string]any); ok {
+		_, attr, _ = lookup(m, "value")
+	}
+	switch a := attr.(type) {
+	case string:
+		b, ok := lit.(string)
+		if !ok {
+			return false
+		}
+		if !caseExact {
+			a, b = strings.ToLower(a), strings.ToLower(b)
+		}
+		switch op {
+		case "eq":
+			return a == b
+		case "co":
+			return strings.Contains(a, b)
+		case "sw":
+			return strings.HasPrefix(a, b)
+		case "ew":
+			return strings.HasSuffix(a, b)
+		case "gt":
+			return a > b
+		case "ge":
+			return a >= b
+		case "lt":
+			return a < b
+		case "le":
+			return a <= b
+		}
+	case float64:
+		b, ok := lit.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return a == b
+		case "gt":
+			return a > b
+		case "ge":
+			return a >= b
+		case "lt":
+			return a < b
+		case "le":
+			return a <= b
+		}
+	case bool:
+		b, ok := lit.(bool)
+		return ok && op == "eq" && a == b
+	case nil:
+		return op == "eq" && lit == nil
+	}
+	return false
+}
+
+// AttrPath names an attribute: an optional schema URN for extension
+// attributes, the attribute and an optional sub-attribute.
+type AttrPath struct {
+	URN  string
+	Name string
+	Sub  string
+}
+
+// ParseAttrPath parses "name", "name.sub" and their URN-qualified forms,
+// e.g. "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName".
+func ParseAttrPath(s string) (AttrPath, error) {
+	var p AttrPath
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		i := strings.LastIndexByte(s, ':')
+		p.URN, s = s[:i], s[i+1:]
+	}
+	p.Name, p.Sub, _ = strings.Cut(s, ".")
+	if !validAttrName(p.Name) || (p.Sub != "" && !validAttrName(p.Sub)) {
+		return AttrPath{}, &Error{Status: http.StatusBadRequest, Type: TypeInvalidPath, Detail: fmt.Sprintf("invalid attribute path %q", s)}
+	}
+	return p, nil
+}
+
+func validAttrName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '$' && i == 0:
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (p AttrPath) String() string {
+	s := p.Name
+	if p.Sub != "" {
+		s += "." + p.Sub
+	}
+	if p.URN != "" {
+		s = p.URN + ":" + s
+	}
+	return s
+}
+
+// caseExact reports whether string values of the attribute compare
+// exactly: identifiers do, everything else does not.
+func (p AttrPath) caseExact() bool {
+	switch strings.ToLower(p.Name) {
+	case "id", "externalid":
+		return p.Sub == ""
+	case "members", "groups":
+		return strings.EqualFold(p.Sub, "value")
+	}
+	return false
+}
+
+// container returns the object holding the attribute: the resource itself
+// for core attributes, else the extension object named by p.URN, created if
+// create is set and it is missing.
+func (p AttrPath) container(resource map[string]any, create bool) map[string]any {
+	if !p.extension() {
+		return resource
+	}
+	if _, ext, ok := lookup(resource, p.URN); ok {
+		if m, ok := ext.(map[string]any); ok {
+			return m
+		}
+	}
+	if !create {
+		return nil
+	}
+	ext := map[string]any{}
+	resource[p.URN] = ext
+	return ext
+}
+
+// extension reports whether the attribute belongs to a schema extension
+// rather than to the core User or Group schema.
+func (p AttrPath) extension() bool {
+	return p.URN != "" && !strings.EqualFold(p.URN, SchemaUser) && !strings.EqualFold(p.URN, SchemaGroup)
+}
+
+// values returns the attribute's values, flattening multi-valued
+// attributes; a missing attribute has none.
+func (p AttrPath) values(resource map[string]any) []any {
+	container := p.container(resource, false)
+	if container == nil {
+		return nil
+	}
+	_, v, ok := lookup(container, p.Name)
+	if !ok {
+		return nil
+	}
+	var vs []any
+	if arr, ok := v.([]any); ok {
+		vs = arr
+	} else {
+		vs = []any{v}
+	}
+	if p.Sub == "" {
+		return vs
+	}
+	var out []any
+	for _, v := range vs {
+		if m, ok := v.(map[string]any); ok {
+			if _, sub, ok := lookup(m, p.Sub); ok {
+				out = append(out, sub)
+			}
+		}
+	}
+	return out
+}
+
+// lookup finds key in m ignoring case and returns the key as stored.
+func lookup(m map[string]any, key string) (string, any, bool) {
+	if v, ok := m[key]; ok {
+		return key, v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the decoded literal of a tokString.
+	value string
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) lex(s string) error {
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			kind := map[byte]tokenKind{'(': tokLParen, ')': tokRParen, '[': tokLBracket, ']': tokRBracket}[c]
+			p.tokens = append(p.tokens, token{kind: kind, text: string(c)})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return filterError("unterminated string")
+			}
+			var v string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &v); err != nil {
+				return filterError("invalid string %s", s[i:j+1])
+			}
+			p.tokens = append(p.tokens, token{kind: tokString, text: s[i : j+1], value: v})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\r\n()[]\"", rune(s[j])) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: tokWord, text: s[i:j]})
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: tokEOF}
+}
+
+func (p *parser) next() token {
+	tok := p.peek()
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokWord && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if tok := p.next(); tok.kind != kind {
+		if tok.kind == tokEOF {
+			return filterError("expected %q at end of filter", text)
+		}
+		return filterError("expected %q, got %q", text, tok.text)
+	}
+	return nil
+}
+
+// The parse functions take nested, which is true inside a value path where
+// another value path is not allowed.
+
+func (p *parser) parseOr(nested bool) (expr, error) {
+	left, err := p.parseAnd(nested)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd(nested)
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(nested bool) (expr, error) {
+	left, err := p.parseNot(nested)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot(nested)
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot(nested bool) (expr, error) {
+	if !p.keyword("not") {
+		return p.parseAtom(nested)
+	}
+	if err := p.expect(tokLParen, "("); err != nil {
+		return nil, err
+	}
+	inner, err := p.parseOr(nested)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokRParen, ")"); err != nil {
+		return nil, err
+	}
+	return notExpr{inner}, nil
+}
+
+var compareOps = map[string]bool{"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true}
+
+func (p *parser) parseAtom(nested bool) (expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr(nested)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokWord:
+	case tokEOF:
+		return nil, filterError("unexpected end of filter")
+	default:
+		return nil, filterError("unexpected %q", tok.text)
+	}
+
+	path, err := ParseAttrPath(tok.text)
+	if err != nil {
+		return nil, filterError("invalid attribute path %q", tok.text)
+	}
+	if p.peek().kind == tokLBracket {
+		if nested || path.Sub != "" {
+			return nil, filterError("unexpected \"[\" after %q", tok.text)
+		}
+		p.next()
+		inner, err := p.parseOr(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRBracket, "]"); err != nil {
+			return nil, err
+		}
+		return valuePathExpr{path: path, filter: inner}, nil
+	}
+
+	opTok := p.next()
+	op := strings.ToLower(opTok.text)
+	if opTok.kind != tokWord || (op != "pr" && !compareOps[op]) {
+		return nil, filterError("expected an operator after %q", tok.text)
+	}
+	if op == "pr" {
+		return presentExpr{path: path}, nil
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if _, isString := value.(string); !isString && (op == "co" || op == "sw" || op == "ew") {
+		return nil, filterError("%q needs a string value", op)
+	}
+	return compareExpr{path: path, op: op, value: value}, nil
+}
+
+func (p *parser) parseValue() (any, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return tok.value, nil
+	case tokWord:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			return f, nil
+		}
+	case tokEOF:
+		return nil, filterError("missing comparison value")
+	}
+	return nil, filterError("invalid comparison value %q", tok.text)
+}
+
+func filterError(format string, args ...any) error {
+	return &Error{Status: http.StatusBadRequest, Type: TypeInvalidFilter, Detail: fmt.Sprintf(format, args...)}
+}
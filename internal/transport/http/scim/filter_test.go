@@ -0,0 +1,104 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func resource(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFilterMatch(t *testing.T) {
+	user := resource(t, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"id": "2819c223-7f76-453a-919d-413861904646",
+		"externalId": "Ext-1",
+		"userName": "Bjensen@Example.com",
+		"name": {"givenName": "Barbara", "familyName": "Jensen"},
+		"active": true,
+		"emails": [
+			{"value": "bjensen@example.com", "type": "work", "primary": true},
+			{"value": "babs@jensen.org", "type": "home"}
+		],
+		"meta": {"lastModified": "2011-05-13T04:42:34Z", "resourceType": "User"},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "701984"}
+	}`)
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "bjensen@example.com"`, true},
+		{`USERNAME Eq "BJENSEN@EXAMPLE.COM"`, true},
+		{`userName ne "bjensen@example.com"`, false},
+		{`userName ne "other"`, true},
+		{`name.familyName co "ens"`, true},
+		{`name.givenName sw "Bar"`, true},
+		{`userName ew "@example.com"`, true},
+		{`title pr`, false},
+		{`name pr`, true},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`externalId eq "ext-1"`, false},
+		{`externalId eq "Ext-1"`, true},
+		{`id eq "2819c223-7f76-453a-919d-413861904646"`, true},
+		{`meta.lastModified gt "2011-05-13T04:42:34Z"`, false},
+		{`meta.lastModified ge "2011-05-13T04:42:34Z"`, true},
+		{`meta.lastModified lt "2012-01-01T00:00:00Z"`, true},
+		{`emails co "jensen.org"`, true},
+		{`emails.type eq "home"`, true},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "home" and value co "@example.com"]`, false},
+		{`userName eq "nobody" or name.givenName eq "barbara"`, true},
+		{`userName eq "nobody" or name.givenName eq "barbara" and active eq false`, false},
+		{`(userName eq "nobody" or name.givenName eq "barbara") and active eq true`, true},
+		{`not (active eq true)`, false},
+		{`not(userName eq "nobody")`, true},
+		{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bjensen"`, true},
+		{`urn:ietf:params:scim:schemas:extension:other:2.0:User:employeeNumber pr`, false},
+		{`userName eq "with \"quotes\""`, false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.filter, err)
+			continue
+		}
+		if got := f.Match(user); got != tt.want {
+			t.Errorf("%q (parsed %s) = %v, want %v", tt.filter, f, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterRejects(t *testing.T) {
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName eq "unterminated`,
+		`userName xx "a"`,
+		`userName eq bjensen`,
+		`active co true`,
+		`(userName eq "a"`,
+		`userName eq "a")`,
+		`userName eq "a" and`,
+		`emails[type eq "work"`,
+		`emails[type[value eq "a"]]`,
+		`not userName eq "a"`,
+		`1name eq "a"`,
+	} {
+		_, err := ParseFilter(filter)
+		var scimErr *Error
+		if !errors.As(err, &scimErr) || scimErr.Type != TypeInvalidFilter || scimErr.Status != 400 {
+			t.Errorf("ParseFilter(%q): got %v, want invalidFilter", filter, err)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// PatchRequest is the body of a PATCH request (RFC 7644 section 3.5.2).
+type PatchRequest struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// Operation is one PATCH operation. Op is matched case-insensitively, since
+// some identity providers send "Add" and "Replace".
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Path is a PATCH target: an attribute, optionally narrowed to the values
+// of a multi-valued attribute that match Filter and to a sub-attribute of
+// those values, as in `emails[type eq "work"].value`.
+type Path struct {
+	Attr   AttrPath
+	Filter *Filter
+	Sub    string
+}
+
+// ParsePath parses a PATCH path. It fails with an *Error of type
+// invalidPath.
+func ParsePath(s string) (Path, error) {
+	i := strings.IndexByte(s, '[')
+	if i < 0 {
+		attr, err := ParseAttrPath(s)
+		return Path{Attr: attr}, err
+	}
+	j := strings.LastIndexByte(s, ']')
+	if j < i {
+		return Path{}, pathError("unbalanced brackets in path %q", s)
+	}
+	attr, err := ParseAttrPath(s[:i])
+	if err != nil || attr.Sub != "" {
+		return Path{}, pathError("invalid attribute in path %q", s)
+	}
+	filter, err := ParseFilter(s[i+1 : j])
+	if err != nil {
+		return Path{}, pathError("invalid filter in path %q: %v", s, err)
+	}
+	p := Path{Attr: attr, Filter: filter}
+	if rest := s[j+1:]; rest != "" {
+		if rest[0] != '.' || !validAttrName(rest[1:]) {
+			return Path{}, pathError("invalid sub-attribute in path %q", s)
+		}
+		p.Sub = rest[1:]
+	}
+	return p, nil
+}
+
+// Apply applies ops to resource in order. On error resource may be partly
+// modified; callers apply operations to a copy and discard it.
+func Apply(resource map[string]any, ops []Operation) error {
+	for _, op := range ops {
+		if err := apply(resource, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(resource map[string]any, op Operation) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return &Error{Status: http.StatusBadRequest, Type: TypeInvalidSyntax, Detail: fmt.Sprintf("unknown operation %q", op.Op)}
+	}
+	var value any
+	if len(bytes.TrimSpace(op.Value)) > 0 {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return &Error{Status: http.StatusBadRequest, Type: TypeInvalidSyntax, Detail: "operation value is not valid JSON"}
+		}
+	}
+
+	if op.Path == "" {
+		if kind == "remove" {
+			return &Error{Status: http.StatusBadRequest, Type: TypeNoTarget, Detail: "remove requires a path"}
+		}
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return &Error{Status: http.StatusBadRequest, Type: TypeInvalidValue, Detail: "value must be an object when path is omitted"}
+		}
+		return applyObject(resource, kind, "", obj)
+	}
+	path, err := ParsePath(op.Path)
+	if err != nil {
+		return err
+	}
+	if kind != "remove" && value == nil {
+		return &Error{Status: http.StatusBadRequest, Type: TypeInvalidValue, Detail: kind + " requires a value"}
+	}
+	return applyPath(resource, kind, path, value)
+}
+
+// applyObject applies an operation without path: every member of obj is
+// applied as if it were the value of an operation on that attribute.
+// Members named by a schema URN hold the attributes of that schema.
+func applyObject(resource map[string]any, kind, urn string, obj map[string]any) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v := obj[k]
+		if urn == "" && strings.HasPrefix(strings.ToLower(k), "urn:") {
+			if m, ok := v.(map[string]any); ok {
+				if err := applyObject(resource, kind, k, m); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		name := k
+		if urn != "" {
+			name = urn + ":" + k
+		}
+		path, err := ParsePath(name)
+		if err != nil {
+			return err
+		}
+		if err := applyPath(resource, kind, path, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyPath(resource map[string]any, kind string, path Path, value any) error {
+	container := path.Attr.container(resource, kind != "remove")
+	if container == nil {
+		return nil
+	}
+	key, cur, exists := lookup(container, path.Attr.Name)
+	if !exists {
+		key = path.Attr.Name
+	}
+
+	if path.Filter == nil {
+		if path.Attr.Sub != "" {
+			return applySub(container, key, cur, kind, path.Attr.Sub, value)
+		}
+		switch kind {
+		case "remove":
+			arr, isArray := cur.([]any)
+			if isArray && value != nil {
+				// Not in RFC 7644, but sent by Azure AD: remove the listed
+				// values rather than the whole attribute.
+				remaining := slices.DeleteFunc(slices.Clone(arr), func(v any) bool { return containsValue(asList(value), v) })
+				container[key] = remaining
+				return nil
+			}
+			delete(container, key)
+		case "add":
+			container[key] = addValue(cur, value)
+		case "replace":
+			container[key] = mergeValue(cur, value)
+		}
+		return nil
+	}
+
+	arr, _ := cur.([]any)
+	var matched []int
+	for i, v := range arr {
+		if m, ok := v.(map[string]any); ok && path.Filter.Match(m) {
+			matched = append(matched, i)
+		}
+	}
+	if kind == "remove" {
+		if path.Sub == "" {
+			container[key] = slices.DeleteFunc(slices.Clone(arr), func(v any) bool {
+				m, ok := v.(map[string]any)
+				return ok && path.Filter.Match(m)
+			})
+			return nil
+		}
+		for _, i := range matched {
+			m := arr[i].(map[string]any)
+			if k, _, ok := lookup(m, path.Sub); ok {
+				delete(m, k)
+			}
+		}
+		return nil
+	}
+
+	if len(matched) == 0 {
+		// An equality filter describes the value to create, which is what
+		// clients mean by e.g. replace on emails[type eq "work"].value.
+		elem, ok := template(path.Filter.root)
+		if !ok {
+			return &Error{Status: http.StatusBadRequest, Type: TypeNoTarget, Detail: fmt.Sprintf("no value matches %q", path.Filter)}
+		}
+		arr = append(arr, elem)
+		matched = []int{len(arr) - 1}
+	}
+	for _, i := range matched {
+		m, _ := arr[i].(map[string]any)
+		switch {
+		case path.Sub != "":
+			k, _, ok := lookup(m, path.Sub)
+			if !ok {
+				k = path.Sub
+			}
+			m[k] = value
+		case kind == "add":
+			arr[i] = mergeValue(m, value)
+		default:
+			arr[i] = value
+		}
+	}
+	container[key] = arr
+	return nil
+}
+
+// applySub applies an operation on a sub-attribute of a complex attribute.
+func applySub(container map[string]any, key string, cur any, kind, sub string, value any) error {
+	if _, isArray := cur.([]any); isArray {
+		return pathError("%s.%s: select values of a multi-valued attribute with a filter", key, sub)
+	}
+	obj, _ := cur.(map[string]any)
+	if kind == "remove" {
+		if k, _, ok := lookup(obj, sub); ok {
+			delete(obj, k)
+		}
+		return nil
+	}
+	if obj == nil {
+		obj = map[string]any{}
+		container[key] = obj
+	}
+	k, _, ok := lookup(obj, sub)
+	if !ok {
+		k = sub
+	}
+	obj[k] = value
+	return nil
+}
+
+// addValue adds value to a multi-valued attribute, skipping values already
+// present, and otherwise behaves like replace.
+func addValue(cur, value any) any {
+	arr, isArray := cur.([]any)
+	if _, valueIsArray := value.([]any); !isArray && !valueIsArray {
+		return mergeValue(cur, value)
+	}
+	out := slices.Clone(arr)
+	for _, v := range asList(value) {
+		if !containsValue(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// mergeValue replaces cur with value, except that replacing a complex value
+// with another only replaces the sub-attributes value has.
+func mergeValue(cur, value any) any {
+	obj, ok1 := cur.(map[string]any)
+	patch, ok2 := value.(map[string]any)
+	if !ok1 || !ok2 {
+		return value
+	}
+	for k, v := range patch {
+		if stored, _, ok := lookup(obj, k); ok {
+			k = stored
+		}
+		obj[k] = v
+	}
+	return obj
+}
+
+func asList(v any) []any {
+	if arr, ok := v.([]any); ok {
+		return arr
+	}
+	return []any{v}
+}
+
+// containsValue reports whether list has v. Complex values with a "value"
+// sub-attribute, such as group members, are the same if those are.
+func containsValue(list []any, v any) bool {
+	id, hasID := valueOf(v)
+	for _, item := range list {
+		if other, ok := valueOf(item); hasID && ok {
+			if other == id {
+				return true
+			}
+		} else if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func valueOf(v any) (any, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	_, id, ok := lookup(m, "value")
+	return id, ok
+}
+
+// template returns the value an equality filter, or a conjunction of them,
+// selects: type eq "work" gives {"type": "work"}.
+func template(e expr) (map[string]any, bool) {
+	switch e := e.(type) {
+	case compareExpr:
+		if e.op != "eq" || e.path.URN != "" || e.path.Sub != "" {
+			return nil, false
+		}
+		return map[string]any{e.path.Name: e.value}, true
+	case andExpr:
+		left, ok := template(e.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := template(e.right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+func pathError(format string, args ...any) error {
+	return &Error{Status: http.StatusBadRequest, Type: TypeInvalidPath, Detail: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func patch(t *testing.T, raw string) []Operation {
+	t.Helper()
+	var req PatchRequest
+	if err := json.Unmarshal([]byte(raw), &req); err != nil {
+		t.Fatal(err)
+	}
+	return req.Operations
+}
+
+func TestApply(t *testing.T) {
+	const base = `{
+		"userName": "bjensen",
+		"name": {"givenName": "Barbara", "familyName": "Jensen"},
+		"active": true,
+		"emails": [{"value": "bjensen@example.com", "type": "work"}],
+		"members": [{"value": "u1"}, {"value": "u2"}]
+	}`
+	tests := []struct {
+		name string
+		ops  string
+		want string
+	}{
+		{
+			name: "ReplaceAttribute",
+			ops:  `{"Operations": [{"op": "Replace", "path": "active", "value": false}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": false,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}]}`,
+		},
+		{
+			name: "ReplaceWithoutPath",
+			ops: `{"Operations": [{"op": "replace", "value": {"userName": "babs", "name.givenName": "Babs",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "7"}}}]}`,
+			want: `{"userName": "babs", "name": {"givenName": "Babs", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}],
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "7"}}`,
+		},
+		{
+			name: "ReplaceComplexKeepsOtherSubAttributes",
+			ops:  `{"Operations": [{"op": "replace", "path": "NAME", "value": {"familyName": "Doe"}}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Doe"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}]}`,
+		},
+		{
+			name: "ReplaceSubAttributeOfFilteredValue",
+			ops:  `{"Operations": [{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "new@example.com"}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "new@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}]}`,
+		},
+		{
+			name: "ReplaceFilteredValueCreatesMissing",
+			ops:  `{"Operations": [{"op": "replace", "path": "emails[type eq \"home\"].value", "value": "home@example.com"}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}, {"value": "home@example.com", "type": "home"}],
+				"members": [{"value": "u1"}, {"value": "u2"}]}`,
+		},
+		{
+			name: "AddMembersSkipsPresent",
+			ops:  `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "u2"}, {"value": "u3"}]}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}, {"value": "u3"}]}`,
+		},
+		{
+			name: "AddSubAttribute",
+			ops:  `{"Operations": [{"op": "add", "path": "name.middleName", "value": "Ann"}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen", "middleName": "Ann"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}]}`,
+		},
+		{
+			name: "RemoveFilteredMember",
+			ops:  `{"Operations": [{"op": "remove", "path": "members[value eq \"u1\"]"}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u2"}]}`,
+		},
+		{
+			name: "RemoveMembersByValue",
+			ops:  `{"Operations": [{"op": "Remove", "path": "members", "value": [{"value": "u2"}]}]}`,
+			want: `{"userName": "bjensen", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}]}`,
+		},
+		{
+			name: "RemoveAttributeAndSubAttribute",
+			ops:  `{"Operations": [{"op": "remove", "path": "members"}, {"op": "remove", "path": "name.givenName"}, {"op": "remove", "path": "title"}]}`,
+			want: `{"userName": "bjensen", "name": {"familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}]}`,
+		},
+		{
+			name: "URNQualifiedCorePath",
+			ops:  `{"Operations": [{"op": "replace", "path": "urn:ietf:params:scim:schemas:core:2.0:User:userName", "value": "babs"}]}`,
+			want: `{"userName": "babs", "name": {"givenName": "Barbara", "familyName": "Jensen"}, "active": true,
+				"emails": [{"value": "bjensen@example.com", "type": "work"}], "members": [{"value": "u1"}, {"value": "u2"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resource(t, base)
+			if err := Apply(got, patch(t, tt.ops)); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if want := resource(t, tt.want); !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				t.Fatalf("got  %s\nwant %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestApplyRejects(t *testing.T) {
+	tests := []struct {
+		ops      string
+		wantType string
+	}{
+		{`{"Operations": [{"op": "move", "path": "active", "value": true}]}`, TypeInvalidSyntax},
+		{`{"Operations": [{"op": "remove"}]}`, TypeNoTarget},
+		{`{"Operations": [{"op": "replace", "value": "x"}]}`, TypeInvalidValue},
+		{`{"Operations": [{"op": "replace", "path": "active"}]}`, TypeInvalidValue},
+		{`{"Operations": [{"op": "replace", "path": "emails[type eq \"work\"", "value": "x"}]}`, TypeInvalidPath},
+		{`{"Operations": [{"op": "replace", "path": "emails[type eq \"work\"]value", "value": "x"}]}`, TypeInvalidPath},
+		{`{"Operations": [{"op": "replace", "path": "emails.value", "value": "x"}]}`, TypeInvalidPath},
+		{`{"Operations": [{"op": "replace", "path": "emails[value co \"zzz\"].type", "value": "home"}]}`, TypeNoTarget},
+	}
+	for _, tt := range tests {
+		r := resource(t, `{"emails": [{"value": "a@example.com", "type": "work"}]}`)
+		err := Apply(r, patch(t, tt.ops))
+		var scimErr *Error
+		if !errors.As(err, &scimErr) || scimErr.Type != tt.wantType || scimErr.Status != 400 {
+			t.Errorf("%s: got %v, want %s", tt.ops, err, tt.wantType)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package scim
+
+import "strconv"
+
+// Schema and message URNs.
+const (
+	SchemaUser          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp       = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaSPConfig      = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType  = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaEnterpriseExt = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+)
+
+// ContentType is the media type of SCIM requests and responses.
+const ContentType = "application/scim+json"
+
+// Error types (RFC 7644 section 3.12).
+const (
+	TypeInvalidFilter = "invalidFilter"
+	TypeInvalidSyntax = "invalidSyntax"
+	TypeInvalidPath   = "invalidPath"
+	TypeNoTarget      = "noTarget"
+	TypeInvalidValue  = "invalidValue"
+	TypeUniqueness    = "uniqueness"
+	TypeMutability    = "mutability"
+)
+
+// Error is a SCIM error response.
+type Error struct {
+	Status int
+	// Type is one of the Type constants, or empty.
+	Type   string
+	Detail string
+}
+
+func (e *Error) Error() string { return e.Detail }
+
+// ErrorBody is the JSON form of a SCIM error; Status is a string on the wire.
+type ErrorBody struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Body returns the JSON form of e.
+func (e *Error) Body() ErrorBody {
+	return ErrorBody{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(e.Status),
+		ScimType: e.Type,
+		Detail:   e.Detail,
+	}
+}
+
+// ListResponse is the envelope of query results (RFC 7644 section 3.4.2).
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
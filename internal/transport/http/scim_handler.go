@@ -0,0 +1,713 @@
+package httpv1
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+	"go-auth/internal/transport/http/scim"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scimTenantKey = "scim_tenant_id"
+	// scimMaxResults caps the page size of list requests.
+	scimMaxResults = 200
+	scimMaxBody    = 1 << 20
+)
+
+// SCIMHandler serves the SCIM 2.0 API (RFC 7643, RFC 7644) through which a
+// tenant's identity provider provisions its users and groups. Groups are
+// the tenant's roles.
+type SCIMHandler struct {
+	log      *slog.Logger
+	uc       *usecase.ProvisioningUseCase
+	tokensUC *usecase.ManageSCIMTokensUseCase
+	// baseURL is the public URL of the /scim/v2 endpoint.
+	baseURL string
+}
+
+func NewSCIMHandler(log *slog.Logger, uc *usecase.ProvisioningUseCase, tokensUC *usecase.ManageSCIMTokensUseCase, baseURL string) *SCIMHandler {
+	return &SCIMHandler{log: log, uc: uc, tokensUC: tokensUC, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// RegisterRoutes mounts the SCIM API under /scim/v2. Requests authenticate
+// with a tenant's SCIM token and act on that tenant.
+func (h *SCIMHandler) RegisterRoutes(router *gin.RouterGroup) {
+	v2 := router.Group("/scim/v2", h.authenticate)
+	{
+		v2.GET("/ServiceProviderConfig", h.serviceProviderConfig)
+		v2.GET("/ResourceTypes", h.resourceTypes)
+
+		v2.GET("/Users", h.listUsers)
+		v2.POST("/Users", h.createUser)
+		v2.GET("/Users/:id", h.getUser)
+		v2.PUT("/Users/:id", h.replaceUser)
+		v2.PATCH("/Users/:id", h.patchUser)
+		v2.DELETE("/Users/:id", h.deleteUser)
+
+		v2.GET("/Groups", h.listGroups)
+		v2.POST("/Groups", h.createGroup)
+		v2.GET("/Groups/:id", h.getGroup)
+		v2.PUT("/Groups/:id", h.replaceGroup)
+		v2.PATCH("/Groups/:id", h.patchGroup)
+		v2.DELETE("/Groups/:id", h.deleteGroup)
+	}
+}
+
+// RegisterAdminRoutes mounts SCIM token management. The caller is
+// responsible for authenticating and authorizing the group.
+func (h *SCIMHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin")
+	{
+		admin.GET("/scim-tokens", h.listTokens)
+		admin.POST("/scim-tokens", h.createToken)
+		admin.DELETE("/scim-tokens/:id", h.deleteToken)
+	}
+}
+
+func (h *SCIMHandler) authenticate(c *gin.Context) {
+	auth := c.GetHeader("Authorization")
+	if len(auth) < 8 || !strings.EqualFold(auth[:7], "Bearer ") {
+		h.fail(c, app.NewError(app.ErrCodeUnauthorized, "Unauthorized"))
+		return
+	}
+	token, err := h.tokensUC.Authenticate(c.Request.Context(), auth[7:])
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Set(scimTenantKey, token.TenantID)
+	c.Next()
+}
+
+// fail writes err as a SCIM error. SCIM clients do not understand problem
+// documents, so these responses bypass Errors.
+func (h *SCIMHandler) fail(c *gin.Context, err error) {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		p := problemFor(err, i18n.Default)
+		scimErr = &scim.Error{Status: p.Status, Detail: p.Detail}
+		switch p.Code {
+		case app.ErrCodeConflict, app.ErrCodeEmailExists:
+			scimErr.Type = scim.TypeUniqueness
+		case app.ErrCodeValidation:
+			scimErr.Type = scim.TypeInvalidValue
+		}
+		if p.Status >= http.StatusInternalServerError {
+			h.log.Error("scim request failed", "method", c.Request.Method, "path", c.FullPath(), "status", p.Status, "error", err)
+		}
+	}
+	c.Header("Content-Type", scim.ContentType)
+	c.AbortWithStatusJSON(scimErr.Status, scimErr.Body())
+}
+
+func (h *SCIMHandler) write(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", scim.ContentType)
+	c.JSON(status, body)
+}
+
+// writeResource writes a single resource with its ETag, honouring
+// If-None-Match on reads.
+func (h *SCIMHandler) writeResource(c *gin.Context, status int, resource map[string]any, version int64) {
+	etag := etagOf(version)
+	c.Header("ETag", etag)
+	if c.Request.Method == http.MethodGet && c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	out, err := scim.Project(resource, splitList(c.Query("attributes")), splitList(c.Query("excludedAttributes")))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if status == http.StatusCreated {
+		c.Header("Location", scim.Attr(resource, "meta.location").(string))
+	}
+	h.write(c, status, out)
+}
+
+func (h *SCIMHandler) writeList(c *gin.Context, resources []map[string]any) {
+	filter, err := parseSCIMFilter(c.Query("filter"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	start, count := 1, scimMaxResults
+	if v := c.Query("startIndex"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil {
+			h.fail(c, &scim.Error{Status: http.StatusBadRequest, Type: scim.TypeInvalidValue, Detail: "startIndex must be an integer"})
+			return
+		}
+		start = max(start, 1)
+	}
+	if v := c.Query("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil {
+			h.fail(c, &scim.Error{Status: http.StatusBadRequest, Type: scim.TypeInvalidValue, Detail: "count must be an integer"})
+			return
+		}
+		count = min(max(count, 0), scimMaxResults)
+	}
+
+	var matched []map[string]any
+	for _, r := range resources {
+		if filter == nil || filter.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+	page := []any{}
+	for i := start - 1; i < len(matched) && len(page) < count; i++ {
+		out, err := scim.Project(matched[i], splitList(c.Query("attributes")), splitList(c.Query("excludedAttributes")))
+		if err != nil {
+			h.fail(c, err)
+			return
+		}
+		page = append(page, out)
+	}
+	h.write(c, http.StatusOK, scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(matched),
+		StartIndex:   start,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+func parseSCIMFilter(s string) (*scim.Filter, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return scim.ParseFilter(s)
+}
+
+// readResource decodes the request body as a JSON object.
+func readResource(c *gin.Context) (map[string]any, error) {
+	var body map[string]any
+	dec := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, scimMaxBody))
+	if err := dec.Decode(&body); err != nil || body == nil {
+		return nil, &scim.Error{Status: http.StatusBadRequest, Type: scim.TypeInvalidSyntax, Detail: "Request body must be a JSON object"}
+	}
+	return body, nil
+}
+
+func readPatch(c *gin.Context) ([]scim.Operation, error) {
+	var req scim.PatchRequest
+	dec := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, scimMaxBody))
+	if err := dec.Decode(&req); err != nil || len(req.Operations) == 0 {
+		return nil, &scim.Error{Status: http.StatusBadRequest, Type: scim.TypeInvalidSyntax, Detail: "Request body must be a PatchOp with Operations"}
+	}
+	return req.Operations, nil
+}
+
+// ifMatch returns the version an If-Match header requires, or 0 for none.
+// A tag that is not one of ours matches no version.
+func ifMatch(c *gin.Context) (int64, error) {
+	v := strings.TrimSpace(c.GetHeader("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, app.NewError(app.ErrCodePreconditionFailed, "If-Match does not match the resource version")
+	}
+	return n, nil
+}
+
+func etagOf(version int64) string {
+	return `W/"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func (h *SCIMHandler) meta(resourceType, location string, created, modified time.Time, version int64) map[string]any {
+	return map[string]any{
+		"resourceType": resourceType,
+		"created":      created.UTC().Format(time.RFC3339),
+		"lastModified": modified.UTC().Format(time.RFC3339),
+		"location":     location,
+		"version":      etagOf(version),
+	}
+}
+
+func (h *SCIMHandler) userResource(u *usecase.ProvisionedUser) map[string]any {
+	location := h.baseURL + "/Users/" + u.UserID
+	r := map[string]any{
+		"schemas":  []any{scim.SchemaUser},
+		"id":       u.UserID,
+		"userName": u.UserName,
+		"active":   u.Active,
+		"emails":   []any{map[string]any{"value": u.Email, "type": "work", "primary": true}},
+		"meta":     h.meta("User", location, u.CreatedAt, u.UpdatedAt, u.Version),
+	}
+	setString(r, "externalId", u.ExternalID)
+	setString(r, "displayName", u.DisplayName)
+	setString(r, "locale", u.Locale)
+	name := map[string]any{}
+	setString(name, "givenName", u.GivenName)
+	setString(name, "familyName", u.FamilyName)
+	if len(name) > 0 {
+		r["name"] = name
+	}
+	if len(u.Roles) > 0 {
+		groups := make([]any, 0, len(u.Roles))
+		for _, role := range u.Roles {
+			groups = append(groups, map[string]any{"value": role.ID, "display": role.Name, "$ref": h.baseURL + "/Groups/" + role.ID})
+		}
+		r["groups"] = groups
+	}
+	return r
+}
+
+func (h *SCIMHandler) groupResource(g *usecase.ProvisionedGroup) map[string]any {
+	location := h.baseURL + "/Groups/" + g.ID
+	members := make([]any, 0, len(g.Members))
+	for _, userID := range g.Members {
+		members = append(members, map[string]any{"value": userID, "display": g.UserNames[userID], "$ref": h.baseURL + "/Users/" + userID})
+	}
+	r := map[string]any{
+		"schemas":     []any{scim.SchemaGroup},
+		"id":          g.ID,
+		"displayName": g.Name,
+		"members":     members,
+		"meta":        h.meta("Group", location, g.CreatedAt, g.UpdatedAt, g.Version),
+	}
+	setString(r, "externalId", g.ExternalID)
+	return r
+}
+
+func setString(m map[string]any, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+// userCmd reads the attributes this service stores from a User resource.
+// Everything else, including the enterprise extension, is ignored.
+func userCmd(r map[string]any) (usecase.ProvisionUserCmd, error) {
+	cmd := usecase.ProvisionUserCmd{
+		UserName:    stringAttr(r, "userName"),
+		ExternalID:  stringAttr(r, "externalId"),
+		GivenName:   stringAttr(r, "name.givenName"),
+		FamilyName:  stringAttr(r, "name.familyName"),
+		DisplayName: stringAttr(r, "displayName"),
+		Email:       primaryEmail(scim.Attr(r, "emails")),
+		Locale:      stringAttr(r, "locale"),
+		Active:      true,
+	}
+	if cmd.Locale == "" {
+		cmd.Locale = stringAttr(r, "preferredLanguage")
+	}
+	if v := scim.Attr(r, "active"); v != nil {
+		active, ok := scimBool(v)
+		if !ok {
+			return cmd, &scim.Error{Status: http.StatusBadRequest, Type: scim.TypeInvalidValue, Detail: "active must be a boolean"}
+		}
+		cmd.Active = active
+	}
+	return cmd, nil
+}
+
+func groupCmd(r map[string]any) (usecase.ProvisionGroupCmd, error) {
+	cmd := usecase.ProvisionGroupCmd{
+		Name:       stringAttr(r, "displayName"),
+		ExternalID: stringAttr(r, "externalId"),
+	}
+	members, _ := scim.Attr(r, "members").([]any)
+	for _, m := range members {
+		id, ok := scim.Attr(asObject(m), "value").(string)
+		if !ok || id == "" {
+			return cmd, &scim.Error{Status: http.StatusBadRequest, Type: scim.TypeInvalidValue, Detail: "members must have a value"}
+		}
+		cmd.Members = append(cmd.Members, id)
+	}
+	return cmd, nil
+}
+
+func stringAttr(r map[string]any, path string) string {
+	s, _ := scim.Attr(r, path).(string)
+	return s
+}
+
+func asObject(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// primaryEmail picks the primary email, else the first work email, else
+// the first one.
+func primaryEmail(v any) string {
+	emails, _ := v.([]any)
+	var work, first string
+	for _, e := range emails {
+		m := asObject(e)
+		value, _ := scim.Attr(m, "value").(string)
+		if value == "" {
+			continue
+		}
+		if primary, _ := scimBool(scim.Attr(m, "primary")); primary {
+			return value
+		}
+		if t, _ := scim.Attr(m, "type").(string); work == "" && strings.EqualFold(t, "work") {
+			work = value
+		}
+		if first == "" {
+			first = value
+		}
+	}
+	if work != "" {
+		return work
+	}
+	return first
+}
+
+// scimBool reads a boolean, accepting the "True" and "False" strings some
+// identity providers send.
+func scimBool(v any) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+func (h *SCIMHandler) listUsers(c *gin.Context) {
+	users, err := h.uc.ListUsers(c.Request.Context(), c.GetString(scimTenantKey))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	resources := make([]map[string]any, 0, len(users))
+	for i := range users {
+		resources = append(resources, h.userResource(&users[i]))
+	}
+	h.writeList(c, resources)
+}
+
+func (h *SCIMHandler) getUser(c *gin.Context) {
+	u, err := h.uc.GetUser(c.Request.Context(), c.GetString(scimTenantKey), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, h.userResource(u), u.Version)
+}
+
+func (h *SCIMHandler) createUser(c *gin.Context) {
+	body, err := readResource(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	cmd, err := userCmd(body)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	u, err := h.uc.CreateUser(c.Request.Context(), c.GetString(scimTenantKey), cmd)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusCreated, h.userResource(u), u.Version)
+}
+
+func (h *SCIMHandler) replaceUser(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	body, err := readResource(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	cmd, err := userCmd(body)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	u, err := h.uc.ReplaceUser(c.Request.Context(), c.GetString(scimTenantKey), c.Param("id"), version, cmd)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, h.userResource(u), u.Version)
+}
+
+// patchUser applies the operations to the current resource and stores the
+// result, failing if the user changed in between.
+func (h *SCIMHandler) patchUser(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	ops, err := readPatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	ctx, tenantID := c.Request.Context(), c.GetString(scimTenantKey)
+	cur, err := h.uc.GetUser(ctx, tenantID, c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if version == 0 {
+		version = cur.Version
+	}
+	r := h.userResource(cur)
+	if err := scim.Apply(r, ops); err != nil {
+		h.fail(c, err)
+		return
+	}
+	cmd, err := userCmd(r)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	u, err := h.uc.ReplaceUser(ctx, tenantID, cur.UserID, version, cmd)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, h.userResource(u), u.Version)
+}
+
+func (h *SCIMHandler) deleteUser(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if err := h.uc.DeleteUser(c.Request.Context(), c.GetString(scimTenantKey), c.Param("id"), version); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) listGroups(c *gin.Context) {
+	groups, err := h.uc.ListGroups(c.Request.Context(), c.GetString(scimTenantKey))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	resources := make([]map[string]any, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, h.groupResource(&groups[i]))
+	}
+	h.writeList(c, resources)
+}
+
+func (h *SCIMHandler) getGroup(c *gin.Context) {
+	g, err := h.uc.GetGroup(c.Request.Context(), c.GetString(scimTenantKey), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, h.groupResource(g), g.Version)
+}
+
+func (h *SCIMHandler) createGroup(c *gin.Context) {
+	body, err := readResource(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	cmd, err := groupCmd(body)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	g, err := h.uc.CreateGroup(c.Request.Context(), c.GetString(scimTenantKey), cmd)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusCreated, h.groupResource(g), g.Version)
+}
+
+func (h *SCIMHandler) replaceGroup(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	body, err := readResource(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	cmd, err := groupCmd(body)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	g, err := h.uc.ReplaceGroup(c.Request.Context(), c.GetString(scimTenantKey), c.Param("id"), version, cmd)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, h.groupResource(g), g.Version)
+}
+
+func (h *SCIMHandler) patchGroup(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	ops, err := readPatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	ctx, tenantID := c.Request.Context(), c.GetString(scimTenantKey)
+	cur, err := h.uc.GetGroup(ctx, tenantID, c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if version == 0 {
+		version = cur.Version
+	}
+	r := h.groupResource(cur)
+	if err := scim.Apply(r, ops); err != nil {
+		h.fail(c, err)
+		return
+	}
+	cmd, err := groupCmd(r)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	g, err := h.uc.ReplaceGroup(ctx, tenantID, cur.ID, version, cmd)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, h.groupResource(g), g.Version)
+}
+
+func (h *SCIMHandler) deleteGroup(c *gin.Context) {
+	version, err := ifMatch(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if err := h.uc.DeleteGroup(c.Request.Context(), c.GetString(scimTenantKey), c.Param("id"), version); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) serviceProviderConfig(c *gin.Context) {
+	h.write(c, http.StatusOK, gin.H{
+		"schemas":        []string{scim.SchemaSPConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": true},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "A SCIM token issued to the tenant by an administrator",
+			"primary":     true,
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "location": h.baseURL + "/ServiceProviderConfig"},
+	})
+}
+
+func (h *SCIMHandler) resourceTypes(c *gin.Context) {
+	types := []any{
+		gin.H{
+			"schemas":          []string{scim.SchemaResourceType},
+			"id":               "User",
+			"name":             "User",
+			"endpoint":         "/Users",
+			"schema":           scim.SchemaUser,
+			"schemaExtensions": []gin.H{{"schema": scim.SchemaEnterpriseExt, "required": false}},
+			"meta":             gin.H{"resourceType": "ResourceType", "location": h.baseURL + "/ResourceTypes/User"},
+		},
+		gin.H{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   scim.SchemaGroup,
+			"meta":     gin.H{"resourceType": "ResourceType", "location": h.baseURL + "/ResourceTypes/Group"},
+		},
+	}
+	h.write(c, http.StatusOK, scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(types),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	})
+}
+
+type createSCIMTokenRequest struct {
+	TenantID    string `json:"tenant_id" binding:"required"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+func (h *SCIMHandler) createToken(c *gin.Context) {
+	var req createSCIMTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	token, secret, err := h.tokensUC.Create(c.Request.Context(), req.TenantID, req.Description)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// The token is only ever returned here.
+	c.JSON(http.StatusCreated, gin.H{"scim_token": token, "token": secret})
+}
+
+func (h *SCIMHandler) listTokens(c *gin.Context) {
+	tokens, err := h.tokensUC.List(c.Request.Context(), c.Query("tenant_id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if tokens == nil {
+		tokens = []domain.SCIMToken{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": tokens})
+}
+
+func (h *SCIMHandler) deleteToken(c *gin.Context) {
+	if err := h.tokensUC.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+package httpv1
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/transport/http/scim"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutes_SCIM(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	users := memory.NewUserRepository()
+	members, roles := memory.NewTenantRepositories()
+	regUC := usecase.NewRegisterUserUseCase(slog.Default(), nil, users, nil, app.PasswordService(fakePwd{}), nil)
+	provUC := usecase.NewProvisioningUseCase(slog.Default(), memory.NewTxManager(), nil, members, roles, users, memory.NewRefreshRepository(), memory.NewPersonalAccessTokenRepository(), regUC, nil)
+	tokensUC := usecase.NewManageSCIMTokensUseCase(memory.NewSCIMTokenRepository())
+	NewSCIMHandler(slog.Default(), provUC, tokensUC, "https://auth.example/api/v1/scim/v2/").RegisterRoutes(r.Group("/api/v1"))
+	_, secret, err := tokensUC.Create(context.Background(), "acme", "idp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, body string, header ...string) (*httptest.ResponseRecorder, map[string]any) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/scim/v2"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", scim.ContentType)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		r.ServeHTTP(w, req)
+		var m map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &m)
+		return w, m
+	}
+
+	w, user := do("POST", "/Users", `{"schemas":["`+scim.SchemaUser+`"],"userName":"bjensen@example.com",
+		"name":{"givenName":"Barbara","familyName":"Jensen"},"active":"True","externalId":"ext-1"}`)
+	if w.Code != http.StatusCreated || !strings.HasPrefix(w.Header().Get("Content-Type"), scim.ContentType) {
+		t.Fatalf("create user code=%d body=%s", w.Code, w.Body)
+	}
+	id, _ := user["id"].(string)
+	if w.Header().Get("Location") != "https://auth.example/api/v1/scim/v2/Users/"+id || w.Header().Get("ETag") != `W/"1"` {
+		t.Fatalf("create user headers = %v", w.Header())
+	}
+	if w, body := do("POST", "/Users", `{"userName":"bjensen@example.com"}`); w.Code != http.StatusConflict || body["scimType"] != scim.TypeUniqueness || body["status"] != "409" {
+		t.Fatalf("duplicate user code=%d body=%s", w.Code, w.Body)
+	}
+
+	w, list := do("GET", `/Users?filter=userName+eq+"BJENSEN@example.com"&attributes=userName`, "")
+	resources, _ := list["Resources"].([]any)
+	if w.Code != http.StatusOK || list["totalResults"] != 1.0 || len(resources) != 1 {
+		t.Fatalf("filter users code=%d body=%s", w.Code, w.Body)
+	}
+	if got := resources[0].(map[string]any); got["id"] != id || got["name"] != nil {
+		t.Fatalf("projected user = %v", got)
+	}
+	if w, body := do("GET", `/Users?filter=userName+eq`, ""); w.Code != http.StatusBadRequest || body["scimType"] != scim.TypeInvalidFilter {
+		t.Fatalf("bad filter code=%d body=%s", w.Code, w.Body)
+	}
+
+	if w, _ := do("GET", "/Users/"+id, "", "If-None-Match", `W/"1"`); w.Code != http.StatusNotModified {
+		t.Fatalf("if-none-match code=%d", w.Code)
+	}
+	patch := `{"schemas":["` + scim.SchemaPatchOp + `"],"Operations":[{"op":"Replace","path":"active","value":false}]}`
+	if w, _ := do("PATCH", "/Users/"+id, patch, "If-Match", `W/"7"`); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale patch code=%d body=%s", w.Code, w.Body)
+	}
+	w, user = do("PATCH", "/Users/"+id, patch, "If-Match", `W/"1"`)
+	if w.Code != http.StatusOK || user["active"] != false || w.Header().Get("ETag") != `W/"2"` {
+		t.Fatalf("patch code=%d body=%s", w.Code, w.Body)
+	}
+
+	w, group := do("POST", "/Groups", `{"displayName":"Admins","members":[{"value":"`+id+`"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create group code=%d body=%s", w.Code, w.Body)
+	}
+	gid, _ := group["id"].(string)
+	w, group = do("PATCH", "/Groups/"+gid, `{"Operations":[{"op":"remove","path":"members[value eq \"`+id+`\"]"}]}`)
+	if gm, _ := group["members"].([]any); w.Code != http.StatusOK || len(gm) != 0 {
+		t.Fatalf("remove member code=%d body=%s", w.Code, w.Body)
+	}
+
+	if w, _ := do("DELETE", "/Users/"+id, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete code=%d", w.Code)
+	}
+	if w, body := do("GET", "/Users/"+id, ""); w.Code != http.StatusNotFound || body["schemas"].([]any)[0] != scim.SchemaError {
+		t.Fatalf("get deleted code=%d body=%s", w.Code, w.Body)
+	}
+
+	secret = "scim_wrong"
+	if w, _ := do("GET", "/Users", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token code=%d", w.Code)
+	}
+}
@@ -0,0 +1,315 @@
+package httpv1
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceAccountHandler struct {
+	log      *slog.Logger
+	uc       *usecase.ServiceAccountsUseCase
+	exchange *usecase.TokenExchangeUseCase
+}
+
+// NewServiceAccountHandler returns the handler; with a nil exchange the
+// token endpoint does not support the token exchange grant.
+func NewServiceAccountHandler(log *slog.Logger, uc *usecase.ServiceAccountsUseCase, exchange *usecase.TokenExchangeUseCase) *ServiceAccountHandler {
+	return &ServiceAccountHandler{log: log, uc: uc, exchange: exchange}
+}
+
+// RegisterRoutes mounts the OAuth token endpoint service accounts obtain
+// access tokens from.
+func (h *ServiceAccountHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/oauth/token", h.token)
+}
+
+// RegisterAdminRoutes mounts service account management. The caller is
+// responsible for authenticating and authorizing the group.
+func (h *ServiceAccountHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin/service-accounts")
+	{
+		admin.GET("", h.list)
+		admin.POST("", h.create)
+		admin.GET("/:id", h.get)
+		admin.PUT("/:id", h.update)
+		admin.DELETE("/:id", h.delete)
+		admin.GET("/:id/credentials", h.listCredentials)
+		admin.POST("/:id/credentials", h.addCredential)
+		admin.DELETE("/:id/credentials/:credential_id", h.revokeCredential)
+	}
+}
+
+// token implements the client credentials grant, with the API key as
+// client secret, the JWT bearer grant (RFC 7523) and the token exchange
+// grant (RFC 8693).
+func (h *ServiceAccountHandler) token(c *gin.Context) {
+	grant := c.PostForm("grant_type")
+	if grant == usecase.GrantTokenExchange && h.exchange != nil {
+		h.exchangeToken(c)
+		return
+	}
+	var (
+		res *usecase.ServiceTokenResult
+		err error
+	)
+	switch grant {
+	case usecase.GrantClientCredentials:
+		clientID, secret, ok := clientCredentials(c)
+		if !ok {
+			h.oauthFail(c, grant, app.NewError(app.ErrCodeValidation, "Client credentials are required"))
+			return
+		}
+		res, err = h.uc.TokenForKey(c.Request.Context(), clientID, secret)
+	case usecase.GrantJWTBearer:
+		assertion := c.PostForm("assertion")
+		if assertion == "" {
+			h.oauthFail(c, grant, app.NewError(app.ErrCodeValidation, "assertion is required"))
+			return
+		}
+		res, err = h.uc.TokenForAssertion(c.Request.Context(), assertion)
+	case "":
+		h.oauthFail(c, grant, app.NewError(app.ErrCodeValidation, "grant_type is required"))
+		return
+	default:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+	if err != nil {
+		h.oauthFail(c, grant, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": res.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   res.ExpiresIn,
+	})
+}
+
+// exchangeToken implements the token exchange grant for service accounts
+// authenticated with an API key.
+func (h *ServiceAccountHandler) exchangeToken(c *gin.Context) {
+	grant := usecase.GrantTokenExchange
+	clientID, secret, ok := clientCredentials(c)
+	if !ok {
+		h.oauthFail(c, grant, app.NewError(app.ErrCodeValidation, "Client credentials are required"))
+		return
+	}
+	client, err := h.uc.AuthenticateClient(c.Request.Context(), clientID, secret)
+	if err != nil {
+		// Failing client authentication is invalid_client, as for the
+		// client credentials grant.
+		h.oauthFail(c, usecase.GrantClientCredentials, err)
+		return
+	}
+	cmd := usecase.TokenExchangeCmd{
+		SubjectToken:     c.PostForm("subject_token"),
+		SubjectTokenType: c.PostForm("subject_token_type"),
+		Audience:         c.PostForm("audience"),
+		Scopes:           strings.Fields(c.PostForm("scope")),
+	}
+	if cmd.SubjectToken == "" || cmd.SubjectTokenType == "" || cmd.Audience == "" {
+		h.oauthFail(c, grant, app.NewError(app.ErrCodeValidation, "subject_token, subject_token_type and audience are required"))
+		return
+	}
+	res, err := h.exchange.Exchange(c.Request.Context(), client, cmd)
+	if err != nil {
+		h.oauthFail(c, grant, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	body := gin.H{
+		"access_token":      res.AccessToken,
+		"issued_token_type": usecase.TokenTypeAccessToken,
+		"token_type":        "Bearer",
+		"expires_in":        res.ExpiresIn,
+	}
+	if len(res.Scopes) > 0 {
+		body["scope"] = strings.Join(res.Scopes, " ")
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// clientCredentials reads client_id and client_secret from HTTP Basic
+// authentication or, failing that, the form (RFC 6749 section 2.3.1).
+func clientCredentials(c *gin.Context) (clientID, secret string, ok bool) {
+	if id, pw, basic := c.Request.BasicAuth(); basic {
+		id, errID := url.QueryUnescape(id)
+		pw, errPW := url.QueryUnescape(pw)
+		return id, pw, errID == nil && errPW == nil && pw != "" && c.PostForm("client_secret") == ""
+	}
+	secret = c.PostForm("client_secret")
+	return c.PostForm("client_id"), secret, secret != ""
+}
+
+// oauthFail writes err as an RFC 6749 error response. OAuth clients do
+// not understand problem documents, so these responses bypass Errors.
+func (h *ServiceAccountHandler) oauthFail(c *gin.Context, grant string, err error) {
+	p := problemFor(err, i18n.Default)
+	status, code := p.Status, "server_error"
+	switch {
+	case p.Code == app.ErrCodeValidation && len(p.Errors) > 0 && p.Errors[0].Field == "audience":
+		status, code = http.StatusBadRequest, "invalid_target"
+	case p.Code == app.ErrCodeValidation && len(p.Errors) > 0 && p.Errors[0].Field == "scope":
+		status, code = http.StatusBadRequest, "invalid_scope"
+	case p.Code == app.ErrCodeValidation:
+		status, code = http.StatusBadRequest, "invalid_request"
+	case p.Code == app.ErrCodeInvalidCredentials && grant == usecase.GrantClientCredentials:
+		status, code = http.StatusUnauthorized, "invalid_client"
+		if _, _, basic := c.Request.BasicAuth(); basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	case p.Code == app.ErrCodeInvalidCredentials:
+		status, code = http.StatusBadRequest, "invalid_grant"
+	case p.Code == app.ErrCodeUnavailable:
+		code = "temporarily_unavailable"
+	}
+	if status >= http.StatusInternalServerError {
+		h.log.Error("token request failed", "grant_type", grant, "status", status, "error", err)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.AbortWithStatusJSON(status, gin.H{"error": code, "error_description": p.Detail})
+}
+
+type serviceAccountRequest struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	Roles       []string `json:"roles"`
+	Disabled    bool     `json:"disabled"`
+}
+
+func (r serviceAccountRequest) cmd(tenantID string) usecase.ServiceAccountCmd {
+	return usecase.ServiceAccountCmd{
+		TenantID:    tenantID,
+		Name:        r.Name,
+		Description: r.Description,
+		Roles:       r.Roles,
+		Disabled:    r.Disabled,
+	}
+}
+
+type createServiceAccountRequest struct {
+	TenantID string `json:"tenant_id" binding:"required"`
+	serviceAccountRequest
+}
+
+func (h *ServiceAccountHandler) create(c *gin.Context) {
+	var req createServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	account, err := h.uc.Create(c.Request.Context(), c.GetString(userIDKey), req.cmd(req.TenantID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, account)
+}
+
+func (h *ServiceAccountHandler) list(c *gin.Context) {
+	accounts, err := h.uc.List(c.Request.Context(), c.Query("tenant_id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if accounts == nil {
+		accounts = []domain.ServiceAccount{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": accounts})
+}
+
+func (h *ServiceAccountHandler) get(c *gin.Context) {
+	account, err := h.uc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *ServiceAccountHandler) update(c *gin.Context) {
+	var req serviceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	account, err := h.uc.Update(c.Request.Context(), c.GetString(userIDKey), c.Param("id"), req.cmd(""))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *ServiceAccountHandler) delete(c *gin.Context) {
+	if err := h.uc.Delete(c.Request.Context(), c.GetString(userIDKey), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type addServiceCredentialRequest struct {
+	Type      string     `json:"type" binding:"required,oneof=api_key public_key"`
+	Name      string     `json:"name" binding:"required,max=100"`
+	PublicKey string     `json:"public_key" binding:"required_if=Type public_key,max=16384"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *ServiceAccountHandler) addCredential(c *gin.Context) {
+	var req addServiceCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	cred, secret, err := h.uc.AddCredential(c.Request.Context(), c.GetString(userIDKey), c.Param("id"), usecase.AddServiceCredentialCmd{
+		Type:      domain.ServiceAccountCredentialType(req.Type),
+		Name:      req.Name,
+		PublicKey: req.PublicKey,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	body := gin.H{"credential": cred}
+	if secret != "" {
+		// The API key is only ever returned here.
+		body["api_key"] = secret
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+func (h *ServiceAccountHandler) listCredentials(c *gin.Context) {
+	creds, err := h.uc.ListCredentials(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if creds == nil {
+		creds = []domain.ServiceAccountCredential{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": creds})
+}
+
+func (h *ServiceAccountHandler) revokeCredential(c *gin.Context) {
+	err := h.uc.RevokeCredential(c.Request.Context(), c.GetString(userIDKey), c.Param("id"), c.Param("credential_id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
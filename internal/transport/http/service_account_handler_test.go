@@ -0,0 +1,221 @@
+package httpv1
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/app/usecase"
+	"go-auth/internal/domain"
+	"go-auth/internal/infrastructure/memory"
+	"go-auth/internal/security/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+type serviceTokens struct{}
+
+func (serviceTokens) GenerateServiceToken(c app.ServiceClaims) (string, error) {
+	return "svc:" + c.AccountID, nil
+}
+
+func (serviceTokens) AccessTTL() time.Duration { return time.Minute }
+
+func TestRoutes_ServiceAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+
+	_, roles := memory.NewTenantRepositories()
+	uc := usecase.NewServiceAccountsUseCase(slog.Default(), usecase.ServiceAccountsConfig{Audience: "http://example.com/api/v1/oauth/token"},
+		memory.NewServiceAccountRepository(), roles, serviceTokens{}, jwt.NewAssertionVerifier(time.Hour, time.Minute), nil)
+	h := NewServiceAccountHandler(slog.Default(), uc, nil)
+	v1 := r.Group("/api/v1")
+	h.RegisterRoutes(v1)
+	h.RegisterAdminRoutes(v1.Group("", Authenticate(staticTokens{"admin-token": "admin", "user-token": "u1"}, nil, nil), RequireAdmin([]string{"admin"})))
+
+	admin := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/admin/service-accounts"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	token := func(form url.Values, user, pass string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := admin("POST", "", "user-token", `{"tenant_id":"acme","name":"ci"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin code=%d", w.Code)
+	}
+	w := admin("POST", "", "admin-token", `{"tenant_id":"acme","name":"ci"}`)
+	var account struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("create code=%d body=%s", w.Code, w.Body)
+	}
+	if w := admin("GET", "?tenant_id=acme", "admin-token", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), account.ID) {
+		t.Fatalf("list code=%d body=%s", w.Code, w.Body)
+	}
+
+	if w := admin("POST", "/"+account.ID+"/credentials", "admin-token", `{"type":"public_key","name":"signer"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("public key missing code=%d body=%s", w.Code, w.Body)
+	}
+	w = admin("POST", "/"+account.ID+"/credentials", "admin-token", `{"type":"api_key","name":"deploy"}`)
+	var created struct {
+		APIKey string `json:"api_key"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated || created.APIKey == "" ||
+		strings.Contains(w.Body.String(), "secret_hash") {
+		t.Fatalf("add credential code=%d body=%s", w.Code, w.Body)
+	}
+
+	w = token(url.Values{"grant_type": {"client_credentials"}}, account.ID, created.APIKey)
+	var issued struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &issued); err != nil || w.Code != http.StatusOK ||
+		issued.AccessToken != "svc:"+account.ID || issued.TokenType != "Bearer" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("token code=%d body=%s", w.Code, w.Body)
+	}
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {account.ID}, "client_secret": {created.APIKey}}
+	if w := token(form, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("form credentials code=%d body=%s", w.Code, w.Body)
+	}
+
+	for name, tc := range map[string]struct {
+		form       url.Values
+		user, pass string
+		status     int
+		error      string
+	}{
+		"wrong secret":      {url.Values{"grant_type": {"client_credentials"}}, account.ID, created.APIKey + "x", http.StatusUnauthorized, "invalid_client"},
+		"no credentials":    {url.Values{"grant_type": {"client_credentials"}}, "", "", http.StatusBadRequest, "invalid_request"},
+		"bad assertion":     {url.Values{"grant_type": {usecase.GrantJWTBearer}, "assertion": {"junk"}}, "", "", http.StatusBadRequest, "invalid_grant"},
+		"unsupported":       {url.Values{"grant_type": {"password"}}, "", "", http.StatusBadRequest, "unsupported_grant_type"},
+		"missing grant":     {url.Values{}, "", "", http.StatusBadRequest, "invalid_request"},
+		"missing assertion": {url.Values{"grant_type": {usecase.GrantJWTBearer}}, "", "", http.StatusBadRequest, "invalid_request"},
+		"exchange disabled": {url.Values{"grant_type": {usecase.GrantTokenExchange}}, account.ID, created.APIKey, http.StatusBadRequest, "unsupported_grant_type"},
+	} {
+		w := token(tc.form, tc.user, tc.pass)
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != tc.status || body.Error != tc.error {
+			t.Errorf("%s: code=%d body=%s", name, w.Code, w.Body)
+		}
+	}
+
+	if w := admin("PUT", "/"+account.ID, "admin-token", `{"name":"ci","disabled":true}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "disabled_at") {
+		t.Fatalf("disable code=%d body=%s", w.Code, w.Body)
+	}
+	if w := token(url.Values{"grant_type": {"client_credentials"}}, account.ID, created.APIKey); w.Code != http.StatusUnauthorized {
+		t.Fatalf("disabled account code=%d body=%s", w.Code, w.Body)
+	}
+	if w := admin("DELETE", "/"+account.ID, "admin-token", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete code=%d body=%s", w.Code, w.Body)
+	}
+	if w := admin("GET", "/"+account.ID, "admin-token", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted code=%d body=%s", w.Code, w.Body)
+	}
+}
+
+func TestRoutes_TokenExchange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	u := domain.NewUser("u@ex.com", "hash")
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	tokens := jwt.NewJWTService(app.TokenConfig{AccessSecret: "access", RefreshSecret: "refresh", AccessTTL: time.Hour})
+	userToken, _ := tokens.GenerateAccessToken(u.ID)
+
+	_, roles := memory.NewTenantRepositories()
+	accounts := usecase.NewServiceAccountsUseCase(slog.Default(), usecase.ServiceAccountsConfig{Audience: "http://example.com/api/v1/oauth/token"},
+		memory.NewServiceAccountRepository(), roles, tokens, jwt.NewAssertionVerifier(time.Hour, time.Minute), nil)
+	gateway, err := accounts.Create(ctx, "admin", usecase.ServiceAccountCmd{TenantID: "acme", Name: "gateway"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, err := accounts.AddCredential(ctx, "admin", gateway.ID, usecase.AddServiceCredentialCmd{Type: domain.CredentialAPIKey, Name: "gw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange := usecase.NewTokenExchangeUseCase(slog.Default(), app.TokenExchangePolicy{Clients: map[string][]app.TokenExchangeRule{
+		gateway.ID: {{Audience: "billing", Scopes: []string{"read", "write"}}},
+	}}, users, tokens, nil)
+
+	r := gin.New()
+	r.Use(Errors(slog.Default()))
+	NewServiceAccountHandler(slog.Default(), accounts, exchange).RegisterRoutes(r.Group("/api/v1"))
+	token := func(form url.Values, pass string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(gateway.ID, pass)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	form := func(audience, scope string) url.Values {
+		return url.Values{
+			"grant_type":         {usecase.GrantTokenExchange},
+			"subject_token":      {userToken},
+			"subject_token_type": {usecase.TokenTypeAccessToken},
+			"audience":           {audience},
+			"scope":              {scope},
+		}
+	}
+
+	w := token(form("billing", "read"), key)
+	var issued struct {
+		AccessToken     string `json:"access_token"`
+		IssuedTokenType string `json:"issued_token_type"`
+		Scope           string `json:"scope"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &issued); err != nil || w.Code != http.StatusOK || issued.AccessToken == "" ||
+		issued.IssuedTokenType != usecase.TokenTypeAccessToken || issued.Scope != "read" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("exchange code=%d body=%s", w.Code, w.Body)
+	}
+
+	noSubject := form("billing", "")
+	noSubject.Del("subject_token")
+	for name, tc := range map[string]struct {
+		form   url.Values
+		pass   string
+		status int
+		error  string
+	}{
+		"wrong secret":      {form("billing", ""), key + "x", http.StatusUnauthorized, "invalid_client"},
+		"missing subject":   {noSubject, key, http.StatusBadRequest, "invalid_request"},
+		"audience denied":   {form("ledger", ""), key, http.StatusBadRequest, "invalid_target"},
+		"scope denied":      {form("billing", "admin"), key, http.StatusBadRequest, "invalid_scope"},
+		"bad subject token": {url.Values{"grant_type": {usecase.GrantTokenExchange}, "subject_token": {"junk"}, "subject_token_type": {usecase.TokenTypeAccessToken}, "audience": {"billing"}}, key, http.StatusBadRequest, "invalid_grant"},
+	} {
+		w := token(tc.form, tc.pass)
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != tc.status || body.Error != tc.error {
+			t.Errorf("%s: code=%d body=%s", name, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS otp_codes;
+
+DROP INDEX IF EXISTS idx_users_phone;
+ALTER TABLE users DROP COLUMN IF EXISTS sms_second_factor;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
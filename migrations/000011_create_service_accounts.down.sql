@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS service_account_credentials;
+DROP TABLE IF EXISTS service_account_roles;
+DROP TABLE IF EXISTS service_accounts;
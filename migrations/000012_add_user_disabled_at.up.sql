@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at DESC, id DESC);
@@ -0,0 +1,10 @@
+// Package migrations embeds the Postgres schema migrations.
+//
+// Files are named NNNNNN_description.up.sql with an optional matching
+// .down.sql and are applied in version order by internal/infrastructure/migrate.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
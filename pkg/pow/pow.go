@@ -0,0 +1,55 @@
+// Package pow implements the client side of the service's hashcash-style
+// proof-of-work challenge. A challenge is solved by finding a nonce such that
+// SHA-256(challenge ":" nonce) starts with at least difficulty zero bits; the
+// solution is sent back as "challenge:nonce" in the X-Challenge-Response header.
+package pow
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+	"strconv"
+)
+
+// MaxDifficulty bounds the work a client is willing to do.
+const MaxDifficulty = 32
+
+var ErrDifficulty = errors.New("pow: difficulty out of range")
+
+// Solve searches for a nonce and returns the full response string.
+// It checks ctx periodically so callers can bound the time spent.
+func Solve(ctx context.Context, challenge string, difficulty int) (string, error) {
+	if difficulty < 0 || difficulty > MaxDifficulty {
+		return "", ErrDifficulty
+	}
+	for nonce := uint64(0); ; nonce++ {
+		if nonce&0xffff == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		n := strconv.FormatUint(nonce, 10)
+		if Check(challenge, n, difficulty) {
+			return challenge + ":" + n, nil
+		}
+	}
+}
+
+// Check reports whether nonce solves challenge at the given difficulty.
+func Check(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	return LeadingZeroBits(sum[:]) >= difficulty
+}
+
+// LeadingZeroBits counts the zero bits at the start of b.
+func LeadingZeroBits(b []byte) int {
+	n := 0
+	for _, x := range b {
+		if x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,33 @@
+package pow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSolveAndCheck(t *testing.T) {
+	resp, err := Solve(context.Background(), "abc", 12)
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+	i := strings.LastIndex(resp, ":")
+	if resp[:i] != "abc" || !Check("abc", resp[i+1:], 12) {
+		t.Fatalf("invalid solution %q", resp)
+	}
+}
+
+func TestSolve_RespectsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Solve(ctx, "abc", MaxDifficulty); err == nil {
+		t.Fatalf("expected context error")
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	if n := LeadingZeroBits([]byte{0, 0x10, 0xff}); n != 11 {
+		t.Fatalf("got %d", n)
+	}
+}